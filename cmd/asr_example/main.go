@@ -139,9 +139,11 @@ func main() {
 					segmentTranscriptions, err := service.TranscribeSpeechSegments(
 						ctx3, audioData, sampleRate, vadResponse.SpeechSegments)
 
+					// Partial results are still returned when some segments fail
 					if err != nil {
 						log.Printf("   ✗ Segment transcription failed: %v", err)
-					} else {
+					}
+					if len(segmentTranscriptions) > 0 {
 						fmt.Printf("   ✓ Transcribed %d segments:\n", len(segmentTranscriptions))
 						for _, st := range segmentTranscriptions {
 							fmt.Printf("     - Segment %d (%.2fs-%.2fs): %q\n",
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"audio-assistant/internal/asr"
+	transcription "audio-assistant/internal/grpc"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/server"
+	"audio-assistant/internal/tts"
+	"audio-assistant/internal/vad"
+)
+
+func main() {
+	fmt.Println("Voice Assistant WebSocket Server")
+	fmt.Println("================================")
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		fmt.Println("❌ OPENAI_API_KEY environment variable not set")
+		fmt.Println("Please set your OpenAI API key:")
+		fmt.Println("export OPENAI_API_KEY=your_api_key_here")
+		return
+	}
+
+	addr := os.Getenv("LISTEN_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	pipeline, stop, err := newPipeline(ctx, apiKey)
+	cancel()
+	if err != nil {
+		log.Fatalf("Failed to start pipeline: %v", err)
+	}
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws", server.NewHandler(pipeline, server.DefaultHandlerConfig()))
+	mux.Handle("/tts", server.NewTTSHandler(pipeline.TTS, nil))
+	mux.Handle("/transcribe", server.NewTranscribeHandler(pipeline.ASR, nil))
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+
+	// Streaming transcription is optional, it needs its own port
+	var grpcServer *grpc.Server
+	if grpcAddr := os.Getenv("GRPC_ADDR"); grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", grpcAddr, err)
+		}
+		grpcServer = grpc.NewServer()
+		transcription.NewServer(pipeline.ASR, transcription.DefaultConfig()).Register(grpcServer)
+		go grpcServer.Serve(listener)
+		fmt.Printf("✓ Streaming transcription over gRPC on %s\n", grpcAddr)
+	}
+
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+
+		fmt.Println("\nShutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("✓ Listening on ws://%s/ws\n", addr)
+	fmt.Printf("✓ Text-to-speech at POST http://%s/tts\n", addr)
+	fmt.Printf("✓ Transcription at POST http://%s/transcribe\n", addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// newPipeline creates and starts the services, returning a function that stops them
+func newPipeline(ctx context.Context, apiKey string) (*server.ServicePipeline, func(), error) {
+	vadConfig := vad.DefaultConfig()
+	if vadURL := os.Getenv("VAD_SERVER_URL"); vadURL != "" {
+		vadConfig.ServerURL = vadURL
+	}
+	vadService := vad.NewService(vadConfig, nil)
+	if err := vadService.StartContext(ctx); err != nil {
+		return nil, nil, fmt.Errorf("VAD: %w", err)
+	}
+
+	asrConfig := asr.DefaultConfig()
+	asrConfig.APIKey = apiKey
+	asrService, err := asr.NewService(asrConfig)
+	if err != nil {
+		vadService.Stop()
+		return nil, nil, fmt.Errorf("ASR: %w", err)
+	}
+
+	llmConfig := llm.DefaultConfig()
+	llmConfig.APIKey = apiKey
+	if model := os.Getenv("LLM_MODEL"); model != "" {
+		llmConfig.Model = model
+	}
+	llmService, err := llm.NewService(llmConfig)
+	if err != nil {
+		vadService.Stop()
+		return nil, nil, fmt.Errorf("LLM: %w", err)
+	}
+
+	ttsService, err := tts.NewTTSService(apiKey, tts.DefaultTTSServiceConfig())
+	if err != nil {
+		vadService.Stop()
+		return nil, nil, fmt.Errorf("TTS: %w", err)
+	}
+
+	stop := func() {
+		vadService.Stop()
+		asrService.Stop()
+		llmService.Stop()
+		ttsService.Stop()
+	}
+
+	if err := asrService.Start(ctx); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("ASR: %w", err)
+	}
+	if err := llmService.Start(ctx); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("LLM: %w", err)
+	}
+	if err := ttsService.Start(); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("TTS: %w", err)
+	}
+
+	return &server.ServicePipeline{
+		VAD: vadService,
+		ASR: asrService,
+		LLM: llmService,
+		TTS: ttsService,
+	}, stop, nil
+}
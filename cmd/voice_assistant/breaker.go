@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/breaker"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/tts"
+)
+
+// 熔断器对应的上游名称，与 HealthCheck 的组件名一致
+var upstreamNames = []string{"llm", "asr", "tts"}
+
+// newUpstreamBreakers 为每个上游创建熔断器，BreakerFailureThreshold 为 0 时熔断器不生效
+func newUpstreamBreakers(config *Config) map[string]*breaker.Breaker {
+	breakerConfig := breaker.Config{
+		FailureThreshold: config.BreakerFailureThreshold,
+		Cooldown:         time.Duration(config.BreakerCooldownSec) * time.Second,
+	}
+	breakers := make(map[string]*breaker.Breaker, len(upstreamNames))
+	for _, name := range upstreamNames {
+		breakers[name] = breaker.New(breakerConfig)
+	}
+	return breakers
+}
+
+// BreakerStates 返回各上游熔断器的状态，供健康检查使用
+func (va *VoiceAssistant) BreakerStates() map[string]breaker.State {
+	states := make(map[string]breaker.State, len(va.breakers))
+	for name, b := range va.breakers {
+		states[name] = b.State()
+	}
+	return states
+}
+
+// breakerLLMClient 在对话请求外加熔断，上游故障时快速失败而不是等待超时
+type breakerLLMClient struct {
+	llm.Client
+	breaker *breaker.Breaker
+}
+
+func (c breakerLLMClient) ChatCompletion(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	var resp *llm.ChatResponse
+	err := c.breaker.Do(func() error {
+		var err error
+		resp, err = c.Client.ChatCompletion(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ChatCompletionStream 在流式请求外加熔断，流结束时按结果记录，上游不支持流式时返回错误
+func (c breakerLLMClient) ChatCompletionStream(ctx context.Context, req *llm.ChatRequest) (<-chan string, <-chan error) {
+	streamer, ok := c.Client.(llm.StreamingClient)
+	if !ok {
+		return failedStream(errStreamingUnsupported)
+	}
+	done, err := c.breaker.Allow()
+	if err != nil {
+		return failedStream(err)
+	}
+
+	deltas, upstreamErrs := streamer.ChatCompletionStream(ctx, req)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		err := <-upstreamErrs
+		done(err)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return deltas, errs
+}
+
+// failedStream 返回已关闭的增量通道和只含 err 的错误通道
+func failedStream(err error) (<-chan string, <-chan error) {
+	deltas := make(chan string)
+	errs := make(chan error, 1)
+	close(deltas)
+	errs <- err
+	close(errs)
+	return deltas, errs
+}
+
+// breakerASRClient 在识别请求外加熔断
+type breakerASRClient struct {
+	asr.ASRInterface
+	breaker *breaker.Breaker
+}
+
+func (c breakerASRClient) TranscribeFile(ctx context.Context, audioFilePath string, req *asr.TranscribeRequest) (*asr.TranscribeResponse, error) {
+	var resp *asr.TranscribeResponse
+	err := c.breaker.Do(func() error {
+		var err error
+		resp, err = c.ASRInterface.TranscribeFile(ctx, audioFilePath, req)
+		return err
+	})
+	return resp, err
+}
+
+// breakerTTSClient 在合成请求外加熔断，并保留被包装客户端的切换音色和 Ping 能力
+// 健康检查（ValidateAPIKey、Ping）不经过熔断器，熔断期间仍能探测上游
+type breakerTTSClient struct {
+	tts.TTSInterface
+	breaker *breaker.Breaker
+}
+
+func (c breakerTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	var audioData []byte
+	err := c.breaker.Do(func() error {
+		var err error
+		audioData, err = c.TTSInterface.SynthesizeText(ctx, text, format)
+		return err
+	})
+	return audioData, err
+}
+
+// SynthesizeTextWithVoice 被包装的客户端不支持切换音色时使用默认音色
+func (c breakerTTSClient) SynthesizeTextWithVoice(ctx context.Context, text string, format string, voice string) ([]byte, error) {
+	synthesizer, ok := c.TTSInterface.(tts.VoiceSynthesizer)
+	if !ok {
+		return c.SynthesizeText(ctx, text, format)
+	}
+
+	var audioData []byte
+	err := c.breaker.Do(func() error {
+		var err error
+		audioData, err = synthesizer.SynthesizeTextWithVoice(ctx, text, format, voice)
+		return err
+	})
+	return audioData, err
+}
+
+// Ping 被包装的客户端没有轻量检查时退回 ValidateAPIKey，与 HealthCheck 的行为一致
+func (c breakerTTSClient) Ping(ctx context.Context) error {
+	if p, ok := c.TTSInterface.(pinger); ok {
+		return p.Ping(ctx)
+	}
+	return c.TTSInterface.ValidateAPIKey(ctx)
+}
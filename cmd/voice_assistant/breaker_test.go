@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/breaker"
+	"audio-assistant/internal/retry"
+	"audio-assistant/internal/tts"
+)
+
+// countingASRClient 在 healthy 为 false 时失败，并记录到达上游的请求数
+type countingASRClient struct {
+	asr.ASRInterface
+	healthy *bool
+	calls   *int
+}
+
+func (c countingASRClient) TranscribeFile(ctx context.Context, path string, req *asr.TranscribeRequest) (*asr.TranscribeResponse, error) {
+	*c.calls++
+	if !*c.healthy {
+		return nil, retry.NewStatusError(503, errors.New("service unavailable"))
+	}
+	return &asr.TranscribeResponse{Text: "你好"}, nil
+}
+
+func TestUpstreamBreakers(t *testing.T) {
+	config := getDefaultConfig()
+	config.BreakerFailureThreshold = 2
+	config.BreakerCooldownSec = 0 // 冷却立即结束，下一次请求就是探测
+	breakers := newUpstreamBreakers(config)
+
+	healthy, calls := false, 0
+	client := breakerASRClient{ASRInterface: countingASRClient{healthy: &healthy, calls: &calls}, breaker: breakers["asr"]}
+	va := &VoiceAssistant{breakers: breakers}
+
+	client.TranscribeFile(context.Background(), "a.wav", nil)
+	client.TranscribeFile(context.Background(), "a.wav", nil)
+	if calls != 2 {
+		t.Fatalf("Expected failures to reach the upstream, got %d calls", calls)
+	}
+	if state := va.BreakerStates()["asr"]; state != breaker.HalfOpen {
+		t.Errorf("Expected the asr breaker to wait for a probe, got %v", state)
+	}
+	if state := va.BreakerStates()["llm"]; state != breaker.Closed {
+		t.Errorf("Expected other upstreams unaffected, got %v", state)
+	}
+
+	// 上游恢复后探测成功，熔断器关闭
+	healthy = true
+	result, err := client.TranscribeFile(context.Background(), "a.wav", nil)
+	if err != nil || result.Text != "你好" {
+		t.Fatalf("Expected the probe to pass through, got %+v, %v", result, err)
+	}
+	if state := va.BreakerStates()["asr"]; state != breaker.Closed {
+		t.Errorf("Expected closed after recovery, got %v", state)
+	}
+}
+
+func TestBreakerTTSClientFailsFast(t *testing.T) {
+	b := breaker.New(breaker.Config{FailureThreshold: 1, Cooldown: time.Minute})
+	b.Do(func() error { return context.DeadlineExceeded })
+
+	pinged := false
+	client := breakerTTSClient{TTSInterface: stubTTSClient{pinged: &pinged}, breaker: b}
+	if _, err := client.SynthesizeText(context.Background(), "你好", tts.FormatWAV); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("Expected fail-fast while open, got %v", err)
+	}
+	if _, err := client.SynthesizeTextWithVoice(context.Background(), "你好", tts.FormatWAV, tts.VoiceNova); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("Expected fail-fast for voice synthesis while open, got %v", err)
+	}
+
+	// 健康检查不经过熔断器
+	if err := client.Ping(context.Background()); err != nil || !pinged {
+		t.Errorf("Expected Ping to reach the upstream while open, got %v", err)
+	}
+}
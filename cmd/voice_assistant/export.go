@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 对话导出格式
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatMarkdown = "markdown"
+)
+
+// ConversationTurn 导出记录中的一条消息，用户消息在保存录音时带有录音文件路径
+type ConversationTurn struct {
+	Timestamp time.Time `json:"timestamp"`
+	Role      string    `json:"role"`
+	Text      string    `json:"text"`
+	AudioPath string    `json:"audioPath,omitempty"`
+}
+
+// recordTurn 把一轮完成的对话加入导出记录，与发给 LLM 的历史不同，这里不做裁剪
+func (va *VoiceAssistant) recordTurn(userText, assistantText, audioPath string) {
+	now := time.Now()
+
+	va.mu.Lock()
+	defer va.mu.Unlock()
+	va.transcript = append(va.transcript,
+		ConversationTurn{Timestamp: now, Role: "user", Text: userText, AudioPath: audioPath},
+		ConversationTurn{Timestamp: now, Role: "assistant", Text: assistantText},
+	)
+}
+
+// ExportConversation 按 format（json 或 markdown，md 也可）导出本次运行的全部对话
+// conversation.log 仍照常写入，导出内容便于程序分析
+func (va *VoiceAssistant) ExportConversation(format string) ([]byte, error) {
+	va.mu.RLock()
+	turns := make([]ConversationTurn, len(va.transcript))
+	copy(turns, va.transcript)
+	va.mu.RUnlock()
+
+	switch strings.ToLower(format) {
+	case ExportFormatJSON:
+		return json.MarshalIndent(turns, "", "  ")
+	case ExportFormatMarkdown, "md":
+		return exportMarkdown(turns), nil
+	default:
+		return nil, fmt.Errorf("不支持的导出格式: %q", format)
+	}
+}
+
+// exportMarkdown 每条消息一节，标题为角色和时间，录音路径以引用块附在用户消息后
+func exportMarkdown(turns []ConversationTurn) []byte {
+	var sb strings.Builder
+	sb.WriteString("# 对话记录\n")
+	for _, turn := range turns {
+		name := "助手"
+		if turn.Role == "user" {
+			name = "用户"
+		}
+		fmt.Fprintf(&sb, "\n### %s · %s\n\n%s\n", name, turn.Timestamp.Format("2006-01-02 15:04:05"), turn.Text)
+		if turn.AudioPath != "" {
+			fmt.Fprintf(&sb, "\n> 录音: `%s`\n", turn.AudioPath)
+		}
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportConversation(t *testing.T) {
+	va := &VoiceAssistant{config: getDefaultConfig()}
+	va.recordTurn("今天天气怎么样", "今天晴，25 度。", "audio/recording_20240101_120000.wav")
+	va.recordTurn("明天呢", "明天有雨。", "")
+
+	data, err := va.ExportConversation("json")
+	if err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var turns []map[string]interface{}
+	if err := json.Unmarshal(data, &turns); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, data)
+	}
+	if len(turns) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(turns))
+	}
+	expected := []struct{ role, text, audio string }{
+		{"user", "今天天气怎么样", "audio/recording_20240101_120000.wav"},
+		{"assistant", "今天晴，25 度。", ""},
+		{"user", "明天呢", ""},
+		{"assistant", "明天有雨。", ""},
+	}
+	for i, e := range expected {
+		audio, _ := turns[i]["audioPath"].(string)
+		if turns[i]["role"] != e.role || turns[i]["text"] != e.text || audio != e.audio {
+			t.Errorf("Message %d: expected %+v, got %v", i, e, turns[i])
+		}
+		if _, ok := turns[i]["timestamp"].(string); !ok {
+			t.Errorf("Message %d: expected a timestamp, got %v", i, turns[i])
+		}
+	}
+
+	data, err = va.ExportConversation("Markdown")
+	if err != nil {
+		t.Fatalf("Markdown export failed: %v", err)
+	}
+	markdown := string(data)
+	for _, want := range []string{"# 对话记录", "### 用户 · ", "今天天气怎么样\n\n> 录音: `audio/recording_20240101_120000.wav`", "### 助手 · ", "明天有雨。"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in Markdown export:\n%s", want, markdown)
+		}
+	}
+	if strings.Count(markdown, "> 录音") != 1 {
+		t.Errorf("Expected only the first user message to have audio:\n%s", markdown)
+	}
+
+	if _, err := va.ExportConversation("csv"); err == nil {
+		t.Error("Expected error for an unsupported format")
+	}
+}
+
+func TestExportConversationProcessFile(t *testing.T) {
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+	var asrRate int
+	var spoken []string
+	va := &VoiceAssistant{
+		config:    config,
+		ctx:       context.Background(),
+		asrClient: replayASRClient{text: "你好", sampleRate: &asrRate},
+		llmClient: echoLLMClient{},
+		ttsClient: recordingTTSClient{texts: &spoken},
+	}
+
+	// 回放的录音与识别出的用户消息关联
+	fixture := writeFixture(t)
+	if _, _, err := va.ProcessFile(context.Background(), fixture); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	data, _ := va.ExportConversation("json")
+	var turns []ConversationTurn
+	if err := json.Unmarshal(data, &turns); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if len(turns) != 2 || turns[0].AudioPath != fixture || turns[1].Text != "你说的是：你好" {
+		t.Errorf("Unexpected export: %+v", turns)
+	}
+}
@@ -3,23 +3,46 @@ package main
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"audio-assistant/internal/asr"
 	"audio-assistant/internal/audio"
+	"audio-assistant/internal/breaker"
 	"audio-assistant/internal/llm"
+	"audio-assistant/internal/metrics"
 	"audio-assistant/internal/state"
 	"audio-assistant/internal/tts"
 	"audio-assistant/internal/vad"
 )
 
+// ASR/TTS 提供方名称
+const (
+	providerOpenAI  = "openai"
+	providerQwen    = "qwen"
+	providerCommand = "command" // 本地命令（离线运行）
+)
+
+// 回声抑制参数
+const (
+	// 扬声器到麦克风的最大延迟（含设备缓冲）
+	echoMaxDelayMs = 300
+	// 输入与播放内容的互相关达到此值时视为回声
+	echoCorrelationThreshold = 0.6
+)
+
+// ErrEmptyAudio 音频为空或样本数低于最小值，跳过 VAD/ASR 调用
+var ErrEmptyAudio = errors.New("audio buffer is empty or too short")
+
 // VoiceAssistant 语音助手结构体
 type VoiceAssistant struct {
 	// 音频模块
@@ -29,9 +52,18 @@ type VoiceAssistant struct {
 
 	// API 客户端
 	vadClient *vad.Client
-	asrClient *asr.Client
-	llmClient *llm.OpenAISDKClient
-	ttsClient *tts.TTSClient
+	localVAD  *vad.LocalDetector // VAD 服务不可用时使用的本地能量检测器，正常时为 nil
+
+	localInterruptVAD *vad.LocalDetector // 本地打断检测器，最小持续时间为 InterruptMinDurationMs，与 localVAD 同时创建
+
+	asrClient asr.ASRInterface
+	llmClient llm.Client
+	ttsClient tts.TTSInterface
+	ttsFormat string                      // 实际请求的 TTS 格式，保证播放端能解码
+	breakers  map[string]*breaker.Breaker // 各上游（llm、asr、tts）的熔断器，客户端已被包装
+
+	// 各阶段耗时和错误计数
+	metrics metrics.Metrics
 
 	// 控制
 	ctx          context.Context
@@ -41,15 +73,39 @@ type VoiceAssistant struct {
 	// 状态变量
 	isListening         bool
 	conversationHistory []llm.Message
+	transcript          []ConversationTurn // 本次运行的全部对话，供 ExportConversation 导出
+	endpointer          vad.Endpointer
+	inputHighPass       *audio.HighPass // 麦克风输入高通滤波器，未启用时为 nil
+	pendingClarify      string          // 等待用户确认的低置信度识别文本
+	detectedLanguage    string          // 最近一次识别自动检测到的语言（ISO-639-1），未启用自动检测时为空
+	wakeWord            *wakeWordGate   // 唤醒词门控，未启用时为 nil
 
 	// 打断检测状态
 	interruptDetectionStart time.Time
 	isDetectingInterrupt    bool
+	interruptCandidateStart time.Time // 首次检测到打断语音的时间，去抖期内不改变 isDetectingInterrupt
+	lastInterruptSpeech     time.Time // 最近一次检测到打断语音的时间
+	interruptCooldownUntil  time.Time // 打断后在此之前不开始新的录音
+
+	// VAD 阈值和统计
+	adaptive        *vad.AdaptiveThreshold     // 按环境噪声调整的 VAD 阈值，未启用 AdaptiveVAD 时为 nil
+	recordingVAD    vad.DetectStatistics       // 当前录音逐块 VAD 统计的累计
+	onVADStatistics func(vad.DetectStatistics) // 每段录音结束时接收统计，未设置时只打印日志
+
+	// 检测窗口：单个输入块比最小语音时长短，VAD 在最近一段音频上检测，首次使用时按配置创建
+	speechWindow    *vad.Window // 空闲和录音时使用，长度由 MinSpeechDurationMs 决定
+	interruptWindow *vad.Window // 播放时检测打断使用，长度由 InterruptMinDurationMs 决定
 
 	// 播放控制
 	playbackCtx    context.Context
 	playbackCancel context.CancelFunc
 
+	// 关闭控制
+	shutdownMu sync.Mutex
+	stopped    bool                // Stop 之后不再开始新的录音处理
+	processing sync.WaitGroup      // 进行中的录音处理
+	tempFiles  map[string]struct{} // saveAudioToTempFile 创建且尚未删除的临时文件
+
 	// 同步控制
 	mu sync.RWMutex
 
@@ -65,28 +121,108 @@ type Config struct {
 
 	// 音频配置
 	VADThreshold            float64
+	AdaptiveVAD             bool    // 按环境噪声自动调整 VAD 阈值：启动时先采集一段环境音校准，之后在静音时持续跟踪
+	AdaptiveVADMinThreshold float64 // 自适应阈值下限（安静环境）
+	AdaptiveVADMaxThreshold float64 // 自适应阈值上限（嘈杂环境）
+	VADCalibrationMs        int     // 启动时采集环境音的时长，期间不检测语音
 	MinSpeechDurationMs     int
 	MinSilenceDurationMs    int
 	MaxRecordingDurationSec int
+	MinVADSamples           int     // VAD 检测所需的最少样本数，低于此值直接跳过
+	InputFramesPerBuffer    int     // 每次读取麦克风的帧数，决定 VAD 和打断检测的响应粒度（1024 帧约 64ms）
+	InputQueueSize          int     // 读取协程与处理循环之间的队列长度（块数），处理跟不上时丢弃最旧的块
+	MinASRSamples           int     // 语音识别所需的最少样本数，低于此值直接跳过
+	MinSpeechEnergy         float64 // 录音中最响的 20ms 帧 RMS 低于此值时视为静音，不调用 ASR，0 表示不检查
+	NormalizeInput          bool    // 识别前按峰值归一化录音，改善小声说话时的识别
+	NormalizeTargetPeak     float32 // 归一化目标峰值（0-1）
+	TrimSilence             bool    // 识别前裁掉录音首尾的静音
+	TrimSilenceDB           float64 // 静音判定阈值（dBFS）
+	RemoveInputDC           bool    // 去除每块麦克风输入的直流偏置
+	InputHighPassHz         float64 // 麦克风输入高通滤波截止频率，0 表示不启用
+
+	// 本地 VAD 配置（VAD 服务不可用时使用）
+	LocalEnergyThreshold float64 // 本地检测的帧 RMS 门限，启用自适应 VAD 时作为校准前的初始值
+	LocalFrameSizeMs     int     // 本地检测的分析帧长度
+
+	// 端点检测配置
+	EndpointerMode       string // 端点检测方式: "silence"（固定静音时长）或 "energy"（能量衰减）
+	QuestionPauseExtraMs int    // energy 模式下，疑问语调后额外的等待时间
 
 	// 打断控制配置
-	AllowInterrupt         bool    // 是否允许打断播放
-	InterruptThreshold     float64 // 打断检测阈值（更高=更难打断）
-	InterruptMinDurationMs int     // 打断最小持续时间
+	AllowInterrupt           bool    // 是否允许打断播放
+	InterruptThreshold       float64 // 打断检测阈值（更高=更难打断）
+	InterruptMinDurationMs   int     // 打断最小持续时间
+	InterruptEnergyThreshold float64 // 打断能量门限（RMS），低于此值直接判定为无打断，不请求 VAD 服务
+	EchoSuppression          bool    // 麦克风输入与最近播放内容高度相关时视为回声，不触发打断
+	InterruptDebounceMs      int     // 打断检测的去抖时长：语音持续这么久才开始计时，间断不超过这么久不重置
+	InterruptCooldownMs      int     // 打断后暂不开始新录音的时长，避免回声或尾音录成半句话，0 表示不等待
+
+	// 唤醒词配置
+	WakeWordEnabled   bool   // 启用后休眠时只响应包含唤醒词的语音
+	WakeWord          string // 唤醒词，匹配时忽略大小写、空白和标点
+	WakeWordActiveSec int    // 唤醒后无需再说唤醒词的时长，每次交互后顺延，0 表示每句话都需要唤醒词
+	WakeWordReply     string // 只说了唤醒词时的应答，为空时不应答
+
+	// 低置信度澄清配置
+	ClarifyEnabled       bool    // 识别置信度过低时先向用户确认，而不是直接交给 LLM
+	ClarifyMinConfidence float64 // 低于此置信度（0-1）时发起澄清
+	ClarifyMinRunes      int     // 识别文本少于此字数时视为含糊，请用户重说
+
+	// ASR/TTS 提供方，默认 "openai"，也可用 "command" 调用本地程序
+	ASRProvider string
+	TTSProvider string
+
+	// ASR 配置
+	ASRModel string // whisper-1、gpt-4o-transcribe 或 gpt-4o-mini-transcribe
+	// ASRCommand 和 ASRModelPath 配置 "command" 提供方（whisper.cpp 可执行文件和 ggml 模型）
+	ASRCommand   string
+	ASRModelPath string
 
 	// LLM 配置
-	LLMModel       string
+	LLMProvider    string // LLM 提供方: "openai"、"openai-sdk" 或 "qwen"
+	LLMAPIKey      string // LLM 专用 API Key，为空时使用 OpenAIAPIKey
+	LLMModel       string // 为空时使用提供方的默认模型（openai 为 gpt-4o-mini，qwen 为 qwen-plus）
 	LLMTemperature float32
 	SystemPrompt   string
+	// MaxHistoryMessages 对话历史保留的最多消息数（不含系统提示），超出时从最早的一轮问答开始整轮删除，0 表示不限制
+	MaxHistoryMessages int
+	// AutoDetectLanguage 不指定识别语言，由 Whisper 自动检测，并让 LLM 用相同语言回复
+	AutoDetectLanguage bool
+	// StreamingReply 边生成边播报：LLM 流式输出按句合成播放，需要 LLM 客户端支持流式
+	StreamingReply bool
 
 	// TTS 配置
 	TTSModel string
 	TTSVoice string
 	TTSSpeed float64
+	// TTSVoiceByLanguage 按自动检测到的语言（ISO-639-1）选择音色，未配置的语言使用 TTSVoice
+	TTSVoiceByLanguage map[string]string
+	// TTSFormat TTS 输出格式，为空时使用 WAV；播放端无法解码的格式会警告并回退到 WAV
+	TTSFormat string
+	// TTSCommand 和 TTSCommandArgs 配置 "command" 提供方，{text}、{voice} 会被替换，TTSVoice 作为 {voice}
+	TTSCommand     string
+	TTSCommandArgs []string
+
+	// 播放配置
+	PlaybackSincResample bool // TTS 音频重采样使用加窗 sinc（音质更好，CPU 开销更高）
+	OutputChannels       int  // 输出声道数，只支持立体声的设备设为 2
+	PlaybackSampleRate   int  // 输出流采样率，默认 24000 与 OpenAI TTS 一致，无需重采样
+
+	// 熔断配置：上游连续失败 BreakerFailureThreshold 次后快速失败 BreakerCooldownSec 秒，然后放行一次探测请求
+	BreakerFailureThreshold int // 0 表示不熔断
+	BreakerCooldownSec      int
+
+	// 关闭配置
+	ShutdownTimeoutMs int // Stop 等待进行中处理完成的最长时间，超时后取消请求
 
 	// 调试配置
 	SaveAudioFiles bool
 	AudioOutputDir string
+	ArchiveFormat  string // 保存录音和 TTS 音频的格式：wav、mp3 或 opus（压缩格式需要 ffmpeg，不可用时回退到 wav）
+
+	// 临时文件配置
+	TempDir           string // 识别和 VAD 使用的临时 WAV 目录
+	TempFileMaxAgeSec int    // 启动时清理早于此时长的遗留临时文件，0 表示不清理
 }
 
 // getDefaultConfig 获取默认配置
@@ -94,26 +230,85 @@ func getDefaultConfig() *Config {
 	return &Config{
 		VADServerURL:            "http://localhost:8080",
 		VADThreshold:            0.5,
+		AdaptiveVADMinThreshold: vad.DefaultAdaptiveThresholdConfig().MinThreshold,
+		AdaptiveVADMaxThreshold: vad.DefaultAdaptiveThresholdConfig().MaxThreshold,
+		VADCalibrationMs:        vad.DefaultAdaptiveThresholdConfig().CalibrationMs,
 		MinSpeechDurationMs:     500,
 		MinSilenceDurationMs:    1000,
 		MaxRecordingDurationSec: 30,
+		InputFramesPerBuffer:    audio.DefaultFramesPerBuffer,
+		InputQueueSize:          state.DefaultInputQueueSize,
+		MinVADSamples:           160,  // 16kHz 下 10ms
+		MinASRSamples:           1600, // 16kHz 下 100ms
+		MinSpeechEnergy:         0.01, // 约 -40 dBFS
+		NormalizeInput:          false,
+		NormalizeTargetPeak:     0.9,
+		TrimSilence:             true,
+		TrimSilenceDB:           -45,
+		RemoveInputDC:           false,
+		InputHighPassHz:         0,
+		EndpointerMode:          "silence",
+		QuestionPauseExtraMs:    800,
+		LocalEnergyThreshold:    vad.DefaultLocalDetectorConfig().EnergyThreshold,
+		LocalFrameSizeMs:        vad.DefaultLocalDetectorConfig().FrameSizeMs,
 		// 打断控制配置
-		AllowInterrupt:         true, // 默认允许打断
-		InterruptThreshold:     0.7,  // 较高的阈值，避免误触发
-		InterruptMinDurationMs: 200,  // 需要持续200ms的语音才能打断
-		LLMModel:               "gpt-4o-mini",
-		LLMTemperature:         0.7,
-		SystemPrompt:           "你是一个有帮助的AI助手。请用简洁、友好的方式回答问题。",
-		TTSModel:               "tts-1",
-		TTSVoice:               "alloy",
-		TTSSpeed:               1.0,
-		SaveAudioFiles:         false,
-		AudioOutputDir:         "temp",
+		AllowInterrupt:           true, // 默认允许打断
+		InterruptThreshold:       0.7,  // 较高的阈值，避免误触发
+		InterruptMinDurationMs:   200,  // 需要持续200ms的语音才能打断
+		InterruptEnergyThreshold: 0.02,
+		EchoSuppression:          true,
+		InterruptDebounceMs:      100,
+		InterruptCooldownMs:      800,
+		WakeWordEnabled:          false,
+		WakeWord:                 "你好助手",
+		WakeWordActiveSec:        15,
+		WakeWordReply:            "我在",
+		ClarifyEnabled:           true,
+		ClarifyMinConfidence:     0.45,
+		ClarifyMinRunes:          2,
+		ASRProvider:              providerOpenAI,
+		TTSProvider:              providerOpenAI,
+		ASRModel:                 asr.ModelWhisper1,
+		LLMProvider:              llm.ProviderOpenAI,
+		LLMTemperature:           0.7,
+		SystemPrompt:             "你是一个有帮助的AI助手。请用简洁、友好的方式回答问题。",
+		MaxHistoryMessages:       20,
+		TTSModel:                 "tts-1",
+		TTSVoice:                 "alloy",
+		TTSSpeed:                 1.0,
+		PlaybackSincResample:     false,
+		OutputChannels:           1,
+		PlaybackSampleRate:       24000,
+		BreakerFailureThreshold:  breaker.DefaultFailureThreshold,
+		BreakerCooldownSec:       int(breaker.DefaultCooldown / time.Second),
+		ShutdownTimeoutMs:        5000,
+		SaveAudioFiles:           false,
+		AudioOutputDir:           "temp",
+		ArchiveFormat:            string(audio.ArchiveWAV),
+		TempDir:                  "temp",
+		TempFileMaxAgeSec:        3600,
 	}
 }
 
 // NewVoiceAssistant 创建新的语音助手
 func NewVoiceAssistant(config *Config) (*VoiceAssistant, error) {
+	if _, err := audio.ParseArchiveFormat(config.ArchiveFormat); err != nil {
+		return nil, err
+	}
+
+	// 创建临时目录，清理上次崩溃或异常退出遗留的临时文件
+	if err := os.MkdirAll(config.TempDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	if config.TempFileMaxAgeSec > 0 {
+		removed, err := sweepTempFiles(config.TempDir, time.Duration(config.TempFileMaxAgeSec)*time.Second, time.Now())
+		if err != nil {
+			log.Printf("清理临时文件失败: %v", err)
+		} else if removed > 0 {
+			log.Printf("已清理 %d 个遗留临时文件", removed)
+		}
+	}
+
 	// 创建输出目录
 	if config.SaveAudioFiles {
 		if err := os.MkdirAll(config.AudioOutputDir, 0755); err != nil {
@@ -121,35 +316,86 @@ func NewVoiceAssistant(config *Config) (*VoiceAssistant, error) {
 		}
 	}
 
+	// 创建端点检测器
+	endpointer, err := newEndpointer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	config.LLMModel = llmModel(config)
+
+	// 创建 API 客户端（先于音频设备，未知的提供方直接报错）
+	llmClient, err := newLLMClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	asrClient, err := newASRClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ttsClient, err := newTTSClient(config)
+	if err != nil {
+		return nil, err
+	}
+	// 上游故障时快速失败，避免每轮对话都等到超时
+	breakers := newUpstreamBreakers(config)
+	llmClient = breakerLLMClient{Client: llmClient, breaker: breakers["llm"]}
+	asrClient = breakerASRClient{ASRInterface: asrClient, breaker: breakers["asr"]}
+	ttsClient = breakerTTSClient{TTSInterface: ttsClient, breaker: breakers["tts"]}
+	if config.StreamingReply && !supportsStreaming(llmClient) {
+		log.Printf("⚠️  LLM 客户端不支持流式输出，StreamingReply 不生效")
+	}
+
+	ttsFormat, ok := tts.ResolvePlaybackFormat(config.TTSFormat, tts.FormatWAV)
+	if !ok {
+		log.Printf("⚠️  TTS 格式 %s 无法解码播放，改用 %s", config.TTSFormat, ttsFormat)
+	}
+
 	// 创建状态管理器
 	stateManager := state.NewManager()
 
 	// 创建音频模块
-	audioInput, err := audio.NewInput()
+	audioInput, err := audio.NewInputWithConfig(audio.InputConfig{FramesPerBuffer: config.InputFramesPerBuffer})
 	if err != nil {
 		return nil, fmt.Errorf("创建音频输入失败: %w", err)
 	}
+	log.Printf("麦克风输入延迟: %v（每次读取 %d 帧）", audioInput.Latency(), audioInput.FramesPerBuffer())
 
-	audioOutput, err := audio.NewAudioOutput(16000)
+	audioOutput, err := audio.NewAudioOutputWithChannels(config.PlaybackSampleRate, config.OutputChannels)
 	if err != nil {
 		audioInput.Close()
 		return nil, fmt.Errorf("创建音频输出失败: %w", err)
 	}
+	if config.PlaybackSincResample {
+		audioOutput.SetResampleMethod(audio.ResampleMethodSinc)
+	}
 
 	// 创建客户端
 	vadClient := vad.NewClient(config.VADServerURL)
 
-	asrClient := asr.NewClient(config.OpenAIAPIKey)
+	var inputHighPass *audio.HighPass
+	if config.InputHighPassHz > 0 {
+		inputHighPass = audio.NewHighPass(audio.GetTargetSampleRate(), config.InputHighPassHz)
+	}
 
-	llmConfig := &llm.Config{
-		APIKey: config.OpenAIAPIKey,
+	var adaptive *vad.AdaptiveThreshold
+	if config.AdaptiveVAD {
+		adaptiveConfig := vad.DefaultAdaptiveThresholdConfig()
+		adaptiveConfig.MinThreshold = config.AdaptiveVADMinThreshold
+		adaptiveConfig.MaxThreshold = config.AdaptiveVADMaxThreshold
+		adaptiveConfig.CalibrationMs = config.VADCalibrationMs
+		adaptive = vad.NewAdaptiveThreshold(adaptiveConfig)
 	}
-	llmClient := llm.NewClient(llmConfig)
 
-	ttsClient := tts.NewTTSClient(config.OpenAIAPIKey)
-	ttsClient.SetModel(config.TTSModel)
-	ttsClient.SetVoice(config.TTSVoice)
-	ttsClient.SetSpeed(config.TTSSpeed)
+	var wakeWord *wakeWordGate
+	if config.WakeWordEnabled {
+		if config.WakeWord == "" {
+			return nil, fmt.Errorf("启用唤醒词时 WakeWord 不能为空")
+		}
+		wakeWord = newWakeWordGate(config.WakeWord, time.Duration(config.WakeWordActiveSec)*time.Second)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -158,25 +404,117 @@ func NewVoiceAssistant(config *Config) (*VoiceAssistant, error) {
 		audioOutput:         audioOutput,
 		stateManager:        stateManager,
 		vadClient:           vadClient,
+		adaptive:            adaptive,
 		asrClient:           asrClient,
 		llmClient:           llmClient,
 		ttsClient:           ttsClient,
+		ttsFormat:           ttsFormat,
+		breakers:            breakers,
+		metrics:             metrics.Nop(),
 		ctx:                 ctx,
 		cancel:              cancel,
 		shutdownChan:        make(chan bool, 1),
 		isListening:         false,
 		conversationHistory: make([]llm.Message, 0),
+		endpointer:          endpointer,
+		inputHighPass:       inputHighPass,
+		wakeWord:            wakeWord,
 		config:              config,
 	}, nil
 }
 
+// llmModel 返回配置的 LLM 模型，未指定时按提供方选择，避免把 OpenAI 的模型名发给 DashScope
+func llmModel(config *Config) string {
+	if config.LLMModel != "" {
+		return config.LLMModel
+	}
+	return llm.DefaultModelFor(config.LLMProvider)
+}
+
+// newLLMClient 根据配置的提供方创建 LLM 客户端
+func newLLMClient(config *Config) (llm.Client, error) {
+	llmConfig := llm.DefaultConfig()
+	llmConfig.APIKey = config.OpenAIAPIKey
+	if config.LLMAPIKey != "" {
+		llmConfig.APIKey = config.LLMAPIKey
+	}
+
+	client, err := llm.NewProviderClient(config.LLMProvider, llmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("创建 LLM 客户端失败: %w", err)
+	}
+	return client, nil
+}
+
+// newASRClient 根据配置的提供方创建语音识别客户端
+func newASRClient(config *Config) (asr.ASRInterface, error) {
+	switch config.ASRProvider {
+	case "", providerOpenAI:
+		return asr.NewClient(config.OpenAIAPIKey), nil
+	case providerCommand:
+		commandConfig := asr.DefaultCommandASRConfig()
+		if config.ASRCommand != "" {
+			commandConfig.Command = config.ASRCommand
+		}
+		commandConfig.ModelPath = config.ASRModelPath
+		return asr.NewCommandASRClient(commandConfig)
+	case providerQwen:
+		return nil, fmt.Errorf("ASR 提供方 %q 暂未实现", config.ASRProvider)
+	default:
+		return nil, fmt.Errorf("未知的 ASR 提供方: %q", config.ASRProvider)
+	}
+}
+
+// newTTSClient 根据配置的提供方创建语音合成客户端
+func newTTSClient(config *Config) (tts.TTSInterface, error) {
+	switch config.TTSProvider {
+	case "", providerOpenAI:
+		client := tts.NewTTSClient(config.OpenAIAPIKey)
+		client.SetModel(config.TTSModel)
+		client.SetVoice(config.TTSVoice)
+		client.SetSpeed(config.TTSSpeed)
+		return client, nil
+	case providerCommand:
+		commandConfig := tts.DefaultCommandTTSConfig()
+		if config.TTSCommand != "" {
+			commandConfig.Command = config.TTSCommand
+			commandConfig.Args = config.TTSCommandArgs
+		}
+		commandConfig.Voice = config.TTSVoice
+		return tts.NewCommandTTSClient(commandConfig)
+	case providerQwen:
+		return nil, fmt.Errorf("TTS 提供方 %q 暂未实现", config.TTSProvider)
+	default:
+		return nil, fmt.Errorf("未知的 TTS 提供方: %q", config.TTSProvider)
+	}
+}
+
+// newEndpointer 根据配置创建端点检测器
+func newEndpointer(config *Config) (vad.Endpointer, error) {
+	switch config.EndpointerMode {
+	case "", "silence":
+		return vad.NewSilenceEndpointer(config.MinSilenceDurationMs), nil
+	case "energy":
+		endpointerConfig := vad.DefaultEnergyEndpointerConfig()
+		endpointerConfig.SilenceDurationMs = config.MinSilenceDurationMs
+		endpointerConfig.QuestionExtraMs = config.QuestionPauseExtraMs
+		return vad.NewEnergyEndpointer(endpointerConfig), nil
+	default:
+		return nil, fmt.Errorf("未知的端点检测方式: %s", config.EndpointerMode)
+	}
+}
+
 // Start 启动语音助手
 func (va *VoiceAssistant) Start(ctx context.Context) error {
 	// 检查 VAD 服务是否可用
-	if err := va.checkVADService(); err != nil {
-		return fmt.Errorf("VAD服务检查失败: %w", err)
+	if err := va.checkVADService(ctx); err != nil {
+		log.Printf("VAD服务不可用，改用本地能量检测: %v", err)
+		va.useLocalVAD()
 	}
 
+	if va.adaptive != nil {
+		fmt.Printf("🎚️  正在校准环境噪声（%dms），请保持安静...\n", va.config.VADCalibrationMs)
+	}
 	fmt.Println("=== 语音助手已就绪，您可以开始对话 ===")
 
 	// 启动主处理循环
@@ -191,14 +529,30 @@ func (va *VoiceAssistant) Start(ctx context.Context) error {
 	}
 }
 
+// useLocalVAD 改用本地能量检测，语音检测与打断检测各创建一个检测器，之后每个音频块复用
+func (va *VoiceAssistant) useLocalVAD() {
+	va.localVAD = newLocalDetector(va.config, va.config.MinSpeechDurationMs)
+	va.localInterruptVAD = newLocalDetector(va.config, va.config.InterruptMinDurationMs)
+}
+
+// newLocalDetector 按配置创建本地能量检测器，minSpeechMs 为判定为语音的最小持续时间
+func newLocalDetector(config *Config, minSpeechMs int) *vad.LocalDetector {
+	localConfig := vad.DefaultLocalDetectorConfig()
+	localConfig.EnergyThreshold = config.LocalEnergyThreshold
+	localConfig.FrameSizeMs = config.LocalFrameSizeMs
+	localConfig.MinSpeechDurationMs = minSpeechMs
+	localConfig.MinSilenceDurationMs = config.MinSilenceDurationMs
+	return vad.NewLocalDetector(localConfig)
+}
+
 // checkVADService 检查VAD服务是否可用
-func (va *VoiceAssistant) checkVADService() error {
+func (va *VoiceAssistant) checkVADService(ctx context.Context) error {
 	tempAudio := make([]float32, 8000) // 0.5秒的静音
 	tempFile, err := va.saveAudioToTempFile(tempAudio)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tempFile)
+	defer va.removeTempFile(tempFile)
 
 	vadReq := &vad.DetectRequest{
 		Threshold:            va.config.VADThreshold,
@@ -206,7 +560,7 @@ func (va *VoiceAssistant) checkVADService() error {
 		MinSilenceDurationMs: va.config.MinSilenceDurationMs,
 	}
 
-	_, err = va.vadClient.HasSpeech(tempFile, vadReq)
+	_, err = va.vadClient.HasSpeechContext(ctx, tempFile, vadReq)
 	return err
 }
 
@@ -214,10 +568,12 @@ func (va *VoiceAssistant) checkVADService() error {
 func (va *VoiceAssistant) processingLoop() {
 	audioBuffer := make([][]float32, 0)
 	recordingStart := time.Time{}
-	silenceStart := time.Time{}
 
-	ticker := time.NewTicker(50 * time.Millisecond)
-	defer ticker.Stop()
+	// 独立协程按设备节奏读取麦克风，处理循环只从队列消费，避免定时读取与设备缓冲错位而丢音
+	inputQueue := state.NewInputQueue(va.audioInput, va.config.InputQueueSize, nil)
+	go inputQueue.Run(va.ctx)
+	var reportedDrops int64
+	lastState := va.stateManager.GetState()
 
 	for {
 		select {
@@ -225,113 +581,244 @@ func (va *VoiceAssistant) processingLoop() {
 			va.shutdownChan <- true
 			return
 
-		case <-ticker.C:
-			// 读取音频数据
-			audioData, err := va.audioInput.Read()
-			if err != nil {
-				log.Printf("读取音频数据失败: %v", err)
+		case audioData, ok := <-inputQueue.Chunks():
+			if !ok {
 				continue
 			}
+			if dropped := inputQueue.Dropped(); dropped > reportedDrops {
+				log.Printf("⚠️  处理跟不上麦克风输入，已丢弃 %d 块音频", dropped)
+				reportedDrops = dropped
+			}
+			audioData = va.filterInput(audioData)
 
 			currentState := va.stateManager.GetState()
+			if currentState != lastState {
+				// 播放前后的音频不连续，检测窗口重新开始
+				va.resetDetectionWindows()
+				lastState = currentState
+			}
 
 			switch currentState {
 			case state.StateIdle, state.StateListening:
-				// 检测语音活动
-				hasSpeech, err := va.detectSpeechActivity(audioData)
-				if err != nil {
-					log.Printf("语音活动检测失败: %v", err)
-					continue
+				va.handleListeningInput(audioData, time.Now(), &audioBuffer, &recordingStart)
+
+			case state.StateSpeaking:
+				// 播放中，检测打断（使用更严格的条件）
+				if va.handleSpeakingInput(audioData, time.Now()) {
+					fmt.Println("🚫 确认用户打断")
+					va.handleInterrupt()
 				}
+			}
+		}
+	}
+}
 
-				if hasSpeech {
-					// 检测到语音，开始或继续录音
-					if !va.isListening {
-						va.isListening = true
-						recordingStart = time.Now()
-						audioBuffer = audioBuffer[:0]
-						va.stateManager.SetState(state.StateListening)
-						fmt.Println("🎤 开始录音...")
-					}
+// handleListeningInput 处理 Idle 和 Listening 状态下的一块麦克风输入，now 为读到这块音频的时间
+func (va *VoiceAssistant) handleListeningInput(audioData []float32, now time.Time, audioBuffer *[][]float32, recordingStart *time.Time) {
+	// 打断后的冷却期内不开始新录音
+	if !va.isListening && va.inInterruptCooldown(now) {
+		return
+	}
 
-					audioBuffer = append(audioBuffer, audioData)
-					silenceStart = time.Time{} // 重置静音开始时间
+	// 校准期间只采集环境音
+	if va.calibrateAmbient(audioData) {
+		return
+	}
 
-					// 检查录音时长限制
-					if time.Since(recordingStart) > time.Duration(va.config.MaxRecordingDurationSec)*time.Second {
-						fmt.Println("⏰ 录音时间超过限制，自动结束录音")
-						va.processRecording(audioBuffer)
-						va.resetRecording(&audioBuffer, &recordingStart, &silenceStart)
-					}
-				} else if va.isListening {
-					// 在录音中检测到静音
-					if silenceStart.IsZero() {
-						silenceStart = time.Now()
-					}
+	// 检测语音活动（在包含这一块的检测窗口上）
+	detection, err := va.detectSpeechActivity(audioData)
+	if errors.Is(err, ErrEmptyAudio) {
+		return
+	}
+	if err != nil {
+		log.Printf("语音活动检测失败: %v", err)
+		return
+	}
+	window := va.speechDetectionWindow()
+	chunkVAD := window.Statistics(detection)
+
+	if hasSpeech := chunkVAD.TotalSpeechDuration > 0; hasSpeech {
+		// 检测到语音，开始或继续录音
+		if !va.isListening {
+			va.isListening = true
+			*recordingStart = now
+			// 语音持续 MinSpeechDurationMs 后才能检测到，用整个窗口作为录音开头，避免丢掉第一个字
+			*audioBuffer = append((*audioBuffer)[:0], window.Samples())
+			va.recordingVAD = detection.Statistics
+			va.endpointer.Reset()
+			va.stateManager.SetState(state.StateListening)
+			fmt.Println("🎤 开始录音...")
+		} else {
+			*audioBuffer = append(*audioBuffer, audioData)
+			va.recordingVAD.Add(chunkVAD)
+		}
+		va.endpointer.Update(audioData, audio.GetTargetSampleRate(), true, now)
 
-					audioBuffer = append(audioBuffer, audioData)
+		// 检查录音时长限制
+		if now.Sub(*recordingStart) > time.Duration(va.config.MaxRecordingDurationSec)*time.Second {
+			fmt.Println("⏰ 录音时间超过限制，自动结束录音")
+			va.processRecording(*audioBuffer)
+			va.resetRecording(audioBuffer, recordingStart)
+		}
+	} else if !va.isListening {
+		// 空闲时的静音用于持续跟踪环境噪声
+		if va.adaptive != nil {
+			va.adaptive.Observe(audioData, audio.GetTargetSampleRate())
+		}
+	} else {
+		// 在录音中检测到静音
+		*audioBuffer = append(*audioBuffer, audioData)
+		va.recordingVAD.Add(chunkVAD)
+
+		// 由端点检测器判断是否结束本轮
+		if va.endpointer.Update(audioData, audio.GetTargetSampleRate(), false, now) {
+			fmt.Println("🔇 检测到静音，结束录音")
+			va.processRecording(*audioBuffer)
+			va.resetRecording(audioBuffer, recordingStart)
+		}
+	}
+}
 
-					// 检查静音时长
-					if time.Since(silenceStart) > time.Duration(va.config.MinSilenceDurationMs)*time.Millisecond {
-						fmt.Println("🔇 检测到静音，结束录音")
-						va.processRecording(audioBuffer)
-						va.resetRecording(&audioBuffer, &recordingStart, &silenceStart)
-					}
-				}
+// handleSpeakingInput 播放中检测一块麦克风输入，确认用户打断时返回 true
+func (va *VoiceAssistant) handleSpeakingInput(audioData []float32, now time.Time) bool {
+	if !va.config.AllowInterrupt {
+		return false
+	}
+	hasInterrupt, err := va.detectInterrupt(audioData)
+	return va.updateInterruptDetection(err == nil && hasInterrupt, now)
+}
 
-			case state.StateSpeaking:
-				// 播放中，检测打断（使用更严格的条件）
-				if va.config.AllowInterrupt {
-					hasInterrupt, err := va.detectInterrupt(audioData)
-					if err == nil && hasInterrupt {
-						// 检测到潜在打断，开始计时验证
-						if !va.isDetectingInterrupt {
-							va.isDetectingInterrupt = true
-							va.interruptDetectionStart = time.Now()
-							fmt.Println("🎯 检测到可能的打断...")
-						}
-
-						// 检查打断持续时间
-						if time.Since(va.interruptDetectionStart) > time.Duration(va.config.InterruptMinDurationMs)*time.Millisecond {
-							fmt.Println("🚫 确认用户打断")
-							va.handleInterrupt()
-							va.isDetectingInterrupt = false
-						}
-					} else {
-						// 没有检测到打断，重置状态
-						if va.isDetectingInterrupt {
-							va.isDetectingInterrupt = false
-							fmt.Println("📢 继续播放...")
-						}
-					}
-				}
-			}
-		}
+// speechDetectionWindow 返回空闲和录音时的检测窗口
+func (va *VoiceAssistant) speechDetectionWindow() *vad.Window {
+	if va.speechWindow == nil {
+		va.speechWindow = vad.NewWindow(audio.GetTargetSampleRate(), vad.WindowMsFor(va.config.MinSpeechDurationMs))
 	}
+	return va.speechWindow
 }
 
-// detectSpeechActivity 检测语音活动
-func (va *VoiceAssistant) detectSpeechActivity(audioData []float32) (bool, error) {
-	// 将 float32 音频数据保存为临时 WAV 文件
-	tempFile, err := va.saveAudioToTempFile(audioData)
+// interruptDetectionWindow 返回播放时的打断检测窗口
+func (va *VoiceAssistant) interruptDetectionWindow() *vad.Window {
+	if va.interruptWindow == nil {
+		va.interruptWindow = vad.NewWindow(audio.GetTargetSampleRate(), vad.WindowMsFor(va.config.InterruptMinDurationMs))
+	}
+	return va.interruptWindow
+}
+
+// resetDetectionWindows 清空两个检测窗口
+func (va *VoiceAssistant) resetDetectionWindows() {
+	va.speechDetectionWindow().Reset()
+	va.interruptDetectionWindow().Reset()
+}
+
+// filterInput 对麦克风输入块做直流去除和高通滤波（按配置启用）
+func (va *VoiceAssistant) filterInput(audioData []float32) []float32 {
+	if va.config.RemoveInputDC {
+		audioData = audio.RemoveDCOffset(audioData)
+	}
+	if va.inputHighPass != nil {
+		audioData = va.inputHighPass.Process(audioData)
+	}
+	return audioData
+}
+
+// SetVADStatisticsHandler 设置每段录音结束时的 VAD 统计回调（语音占比、时长、阈值），便于调整阈值；nil 表示只打印日志
+// 回调在处理循环中同步调用，不应阻塞
+func (va *VoiceAssistant) SetVADStatisticsHandler(handler func(vad.DetectStatistics)) {
+	va.onVADStatistics = handler
+}
+
+// reportVADStatistics 打印并回调当前录音的 VAD 统计
+func (va *VoiceAssistant) reportVADStatistics() {
+	stats := va.recordingVAD
+	log.Printf("📊 VAD 统计: 语音占比 %.0f%%（语音 %.2fs / 录音 %.2fs，%d 段，阈值 %.2f）",
+		stats.SpeechRatio*100, stats.TotalSpeechDuration, stats.TotalAudioDuration, stats.TotalSegments, stats.ThresholdUsed)
+	if va.onVADStatistics != nil {
+		va.onVADStatistics(stats)
+	}
+}
+
+// SetMetrics 设置各阶段耗时和错误计数的接收者，nil 表示不记录
+func (va *VoiceAssistant) SetMetrics(m metrics.Metrics) {
+	if m == nil {
+		m = metrics.Nop()
+	}
+	va.metrics = m
+}
+
+// observeStage 记录一个阶段的耗时，失败时同时计入错误数
+func (va *VoiceAssistant) observeStage(stage string, start time.Time, err error) {
+	if va.metrics == nil {
+		return
+	}
+	va.metrics.ObserveLatency(stage, time.Since(start))
 	if err != nil {
-		return false, err
+		va.metrics.IncError(stage)
+	}
+}
+
+// requestContext 返回外部请求使用的上下文，关闭助手时取消进行中的请求
+func (va *VoiceAssistant) requestContext() context.Context {
+	if va.ctx == nil {
+		return context.Background()
+	}
+	return va.ctx
+}
+
+// calibrateAmbient 自适应阈值校准期间采集环境音，返回 true 表示本块已用于校准、不做语音检测
+func (va *VoiceAssistant) calibrateAmbient(audioData []float32) bool {
+	if va.adaptive == nil || va.adaptive.Calibrated() {
+		return false
+	}
+
+	va.adaptive.Observe(audioData, audio.GetTargetSampleRate())
+	if threshold, ok := va.adaptive.Threshold(); ok {
+		log.Printf("🎚️  环境噪声校准完成: RMS %.4f，VAD 阈值 %.2f", va.adaptive.NoiseRMS(), threshold)
+	}
+	return true
+}
+
+// vadThreshold 返回当前的 VAD 阈值，启用自适应且校准完成后按环境噪声调整
+func (va *VoiceAssistant) vadThreshold() float64 {
+	if va.adaptive != nil {
+		if threshold, ok := va.adaptive.Threshold(); ok {
+			return threshold
+		}
+	}
+	return va.config.VADThreshold
+}
+
+// detectSpeechActivity 把音频块加入检测窗口并检测语音活动，返回整个窗口的检测结果（语音段和统计）
+// 这一块的结果用 speechDetectionWindow().Statistics 取出
+func (va *VoiceAssistant) detectSpeechActivity(audioData []float32) (*vad.DetectResponse, error) {
+	if len(audioData) < va.config.MinVADSamples || len(audioData) == 0 {
+		return nil, ErrEmptyAudio
+	}
+	audioData = va.speechDetectionWindow().Push(audioData)
+
+	start := time.Now()
+	if va.localVAD != nil {
+		if va.adaptive != nil && va.adaptive.Calibrated() {
+			va.localVAD.SetEnergyThreshold(va.adaptive.EnergyThreshold(va.config.LocalEnergyThreshold))
+		}
+		response := va.localVAD.DetectResponse(audioData, audio.GetTargetSampleRate())
+		va.observeStage(metrics.StageVAD, start, nil)
+		return response, nil
 	}
-	defer os.Remove(tempFile)
 
-	// 调用 VAD 服务
+	// 调用 VAD 服务（内存中编码 WAV，不再为每个音频块写临时文件）
 	vadReq := &vad.DetectRequest{
-		Threshold:            va.config.VADThreshold,
+		Threshold:            va.vadThreshold(),
 		MinSpeechDurationMs:  va.config.MinSpeechDurationMs,
 		MinSilenceDurationMs: va.config.MinSilenceDurationMs,
 	}
 
-	hasSpeech, err := va.vadClient.HasSpeech(tempFile, vadReq)
+	response, err := va.vadClient.DetectFromSamplesContext(va.requestContext(), audioData, audio.GetTargetSampleRate(), vadReq)
+	va.observeStage(metrics.StageVAD, start, err)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return hasSpeech, nil
+	return response, nil
 }
 
 // detectInterrupt 检测打断（使用更严格的阈值）
@@ -340,12 +827,26 @@ func (va *VoiceAssistant) detectInterrupt(audioData []float32) (bool, error) {
 		return false, nil
 	}
 
-	// 将 float32 音频数据保存为临时 WAV 文件
-	tempFile, err := va.saveAudioToTempFile(audioData)
-	if err != nil {
-		return false, err
+	if len(audioData) < va.config.MinVADSamples || len(audioData) == 0 {
+		return false, ErrEmptyAudio
+	}
+	window := va.interruptDetectionWindow()
+	samples := window.Push(audioData)
+
+	// 能量门限：安静的音频块直接跳过，避免播放期间每个块都请求一次 VAD 服务
+	if !va.passesInterruptEnergyGate(audioData) {
+		return false, nil
+	}
+
+	// 回声抑制：扬声器播放的内容被麦克风拾取时不应打断自己
+	if va.config.EchoSuppression && va.isEcho(audioData, va.echoReference(len(audioData))) {
+		return false, nil
+	}
+
+	if va.localInterruptVAD != nil {
+		// 本地检测同样要求更长的最小持续时间
+		return window.HasSpeech(va.localInterruptVAD.Detect(samples, audio.GetTargetSampleRate())), nil
 	}
-	defer os.Remove(tempFile)
 
 	// 使用更严格的打断检测参数
 	vadReq := &vad.DetectRequest{
@@ -354,22 +855,70 @@ func (va *VoiceAssistant) detectInterrupt(audioData []float32) (bool, error) {
 		MinSilenceDurationMs: va.config.MinSilenceDurationMs,
 	}
 
-	hasSpeech, err := va.vadClient.HasSpeech(tempFile, vadReq)
+	response, err := va.vadClient.DetectFromSamplesContext(va.requestContext(), samples, audio.GetTargetSampleRate(), vadReq)
 	if err != nil {
 		return false, err
 	}
 
-	return hasSpeech, nil
+	return window.HasSpeech(response.SpeechSegments), nil
+}
+
+// speechEnergyFrameMs 检查录音能量时的帧长
+const speechEnergyFrameMs = 20
+
+// hasSpeechEnergy 判断录音中是否有帧的 RMS 达到 MinSpeechEnergy，返回最响帧的 RMS
+// 按帧取最大值，长段静音中的短句也能通过
+func (va *VoiceAssistant) hasSpeechEnergy(samples []float32) (float64, bool) {
+	frame := audio.GetTargetSampleRate() * speechEnergyFrameMs / 1000
+	var peak float64
+	for start := 0; start < len(samples); start += frame {
+		peak = max(peak, audio.RMS(samples[start:min(start+frame, len(samples))]))
+	}
+	return peak, va.config.MinSpeechEnergy <= 0 || peak >= va.config.MinSpeechEnergy
+}
+
+// passesInterruptEnergyGate 判断音频块的短时能量是否达到打断门限
+func (va *VoiceAssistant) passesInterruptEnergyGate(audioData []float32) bool {
+	if va.config.InterruptEnergyThreshold <= 0 {
+		return true
+	}
+	return audio.RMS(audioData) >= va.config.InterruptEnergyThreshold
+}
+
+// echoReference 取出与输入块对齐的最近播放输出（已重采样到输入采样率），额外包含 echoMaxDelayMs 的延迟余量
+func (va *VoiceAssistant) echoReference(inputLength int) []float32 {
+	if va.audioOutput == nil {
+		return nil
+	}
+
+	inputRate := audio.GetTargetSampleRate()
+	outputRate := va.audioOutput.GetSampleRate()
+	maxDelay := inputRate * echoMaxDelayMs / 1000
+
+	recent := va.audioOutput.RecentOutput((inputLength + maxDelay) * outputRate / inputRate)
+	if outputRate == inputRate {
+		return recent
+	}
+	return audio.ResampleLinear(recent, outputRate, inputRate)
+}
+
+// isEcho 判断输入块是否主要是播放内容的回声
+func (va *VoiceAssistant) isEcho(audioData, reference []float32) bool {
+	if len(reference) < len(audioData) {
+		return false
+	}
+	return audio.EchoCorrelation(audioData, reference, len(reference)-len(audioData)) >= echoCorrelationThreshold
 }
 
 // saveAudioToTempFile 将音频数据保存为临时文件
 func (va *VoiceAssistant) saveAudioToTempFile(audioData []float32) (string, error) {
 	// 创建临时文件
-	tempFile, err := os.CreateTemp("temp", "audio_*.wav")
+	tempFile, err := os.CreateTemp(va.config.TempDir, tempFilePattern)
 	if err != nil {
 		return "", err
 	}
 	defer tempFile.Close()
+	va.trackTempFile(tempFile.Name())
 
 	// 写入简单的 WAV 头
 	sampleRate := 16000
@@ -410,11 +959,102 @@ func (va *VoiceAssistant) saveAudioToTempFile(audioData []float32) (string, erro
 	return tempFile.Name(), nil
 }
 
+// tempFilePattern 临时音频文件名模式
+const tempFilePattern = "audio_*.wav"
+
+// sweepTempFiles 删除 dir 中修改时间早于 now-maxAge 的临时音频文件，返回删除数量
+// 只处理匹配 tempFilePattern 的文件，输出目录中保存的录音不受影响
+func sweepTempFiles(dir string, maxAge time.Duration, now time.Time) (int, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, tempFilePattern))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || now.Sub(info.ModTime()) < maxAge {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("删除临时文件失败: %v", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// trackTempFile 登记临时文件，关闭时删除遗留的文件
+func (va *VoiceAssistant) trackTempFile(path string) {
+	va.shutdownMu.Lock()
+	defer va.shutdownMu.Unlock()
+	if va.tempFiles == nil {
+		va.tempFiles = make(map[string]struct{})
+	}
+	va.tempFiles[path] = struct{}{}
+}
+
+// removeTempFile 删除临时文件并取消登记
+func (va *VoiceAssistant) removeTempFile(path string) {
+	va.shutdownMu.Lock()
+	delete(va.tempFiles, path)
+	va.shutdownMu.Unlock()
+	os.Remove(path)
+}
+
+// removeTempFiles 删除所有遗留的临时文件
+func (va *VoiceAssistant) removeTempFiles() {
+	va.shutdownMu.Lock()
+	files := va.tempFiles
+	va.tempFiles = nil
+	va.shutdownMu.Unlock()
+
+	for path := range files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除临时文件失败: %v", err)
+		}
+	}
+}
+
+// startProcessing 在后台执行处理任务并登记到 processing，Stop 之后返回 false 且不执行
+func (va *VoiceAssistant) startProcessing(task func()) bool {
+	va.shutdownMu.Lock()
+	defer va.shutdownMu.Unlock()
+	if va.stopped {
+		return false
+	}
+
+	va.processing.Add(1)
+	go func() {
+		defer va.processing.Done()
+		task()
+	}()
+	return true
+}
+
+// waitProcessing 等待进行中的处理完成，超时返回 false
+func (va *VoiceAssistant) waitProcessing(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		va.processing.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // processRecording 处理录音
 func (va *VoiceAssistant) processRecording(audioBuffer [][]float32) {
 	va.stateManager.SetState(state.StateProcessing)
+	va.reportVADStatistics()
 
-	go func() {
+	started := va.startProcessing(func() {
 		defer va.stateManager.SetState(state.StateIdle)
 
 		// 合并音频缓冲区
@@ -428,8 +1068,25 @@ func (va *VoiceAssistant) processRecording(audioBuffer [][]float32) {
 			return
 		}
 
+		// 在归一化之前检查能量，避免把静音放大后送去识别
+		if peak, ok := va.hasSpeechEnergy(combinedAudio); !ok {
+			log.Printf("录音能量过低（最大帧 RMS %.4f < %.4f），跳过识别", peak, va.config.MinSpeechEnergy)
+			return
+		}
+
 		fmt.Println("🔄 正在处理音频...")
 
+		// 裁掉首尾静音（包括触发结束的那段静音），减少 ASR 需要处理的时长
+		if va.config.TrimSilence {
+			trimmed := audio.TrimSilence(combinedAudio, audio.GetTargetSampleRate(), va.config.TrimSilenceDB)
+			log.Printf("静音裁剪: %d -> %d 样本", len(combinedAudio), len(trimmed))
+			combinedAudio = trimmed
+		}
+
+		if va.config.NormalizeInput {
+			combinedAudio = audio.Normalize(combinedAudio, va.config.NormalizeTargetPeak)
+		}
+
 		// 保存音频文件（如果启用）
 		var audioFilePath string
 		if va.config.SaveAudioFiles {
@@ -437,7 +1094,11 @@ func (va *VoiceAssistant) processRecording(audioBuffer [][]float32) {
 		}
 
 		// 1. ASR - 语音转文本
-		text, err := va.performASR(combinedAudio)
+		text, confidence, err := va.performASR(combinedAudio)
+		if errors.Is(err, ErrEmptyAudio) {
+			log.Printf("音频过短（%d 样本），跳过识别", len(combinedAudio))
+			return
+		}
 		if err != nil {
 			log.Printf("语音识别失败: %v", err)
 			va.playErrorMessage("抱歉，语音识别失败了")
@@ -449,7 +1110,55 @@ func (va *VoiceAssistant) processRecording(audioBuffer [][]float32) {
 			return
 		}
 
-		fmt.Printf("👤 用户: %s\n", text)
+		fmt.Printf("👤 用户: %s (置信度: %.2f)\n", text, confidence)
+
+		// 唤醒词门控：休眠时忽略不含唤醒词的语音
+		if va.wakeWord != nil {
+			command, ok := va.admitWakeWord(text)
+			if !ok {
+				return
+			}
+			// 在回复播放结束时计时，而不是在放行时
+			defer func() { va.wakeWord.Touch(time.Now()) }()
+			if command == "" {
+				if va.config.WakeWordReply != "" {
+					if err := va.performTTS(va.config.WakeWordReply); err != nil {
+						log.Printf("TTS处理失败: %v", err)
+					}
+				}
+				return
+			}
+			text = command
+		}
+
+		// 用户确认了上一次的澄清问题，使用当时的识别文本
+		if confirmed, ok := va.resolveClarification(text); ok {
+			text = confirmed
+		} else if question, ok := va.clarificationFor(text, confidence); ok {
+			// 置信度过低时请用户确认，避免对错误识别结果自信作答
+			fmt.Printf("❓ 识别不确定，请求澄清: %s\n", question)
+			if err := va.performTTS(question); err != nil {
+				log.Printf("TTS处理失败: %v", err)
+			}
+			return
+		}
+
+		// 2+3. 流式回复：边生成边合成播放
+		if va.config.StreamingReply && supportsStreaming(va.llmClient) {
+			response, err := va.replyStreaming(text)
+			if response != "" {
+				fmt.Printf("🤖 助手: %s\n", response)
+				va.recordTurn(text, response, audioFilePath)
+				if va.config.SaveAudioFiles {
+					va.logConversation(text, response, audioFilePath)
+				}
+			}
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("流式回复失败: %v", err)
+				va.playErrorMessage("抱歉，我现在无法处理您的请求")
+			}
+			return
+		}
 
 		// 2. LLM - 生成回复
 		response, err := va.performLLM(text)
@@ -460,6 +1169,7 @@ func (va *VoiceAssistant) processRecording(audioBuffer [][]float32) {
 		}
 
 		fmt.Printf("🤖 助手: %s\n", response)
+		va.recordTurn(text, response, audioFilePath)
 
 		// 记录对话日志
 		if va.config.SaveAudioFiles {
@@ -472,34 +1182,148 @@ func (va *VoiceAssistant) processRecording(audioBuffer [][]float32) {
 			va.playErrorMessage("抱歉，语音合成失败了")
 			return
 		}
-	}()
+	})
+	if !started {
+		va.stateManager.SetState(state.StateIdle)
+	}
+}
+
+// admitWakeWord 用唤醒词门控过滤识别文本，返回去掉唤醒词后的指令
+func (va *VoiceAssistant) admitWakeWord(text string) (string, bool) {
+	wasAwake := va.wakeWord.Awake(time.Now())
+	command, ok := va.wakeWord.Admit(text, time.Now())
+	switch {
+	case !ok:
+		log.Printf("未检测到唤醒词，忽略: %s", text)
+	case !wasAwake:
+		fmt.Println("👂 已唤醒")
+	}
+	return command, ok
 }
 
 // performASR 执行语音识别
-func (va *VoiceAssistant) performASR(audioData []float32) (string, error) {
+func (va *VoiceAssistant) performASR(audioData []float32) (string, float64, error) {
+	return va.transcribe(va.ctx, audioData)
+}
+
+// transcribe 在 ctx 下识别音频，返回文本和置信度
+func (va *VoiceAssistant) transcribe(ctx context.Context, audioData []float32) (string, float64, error) {
+	if len(audioData) < va.config.MinASRSamples || len(audioData) == 0 {
+		return "", 0, ErrEmptyAudio
+	}
+
 	// 将音频数据保存为临时文件
 	tempFile, err := va.saveAudioToTempFile(audioData)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	defer os.Remove(tempFile)
+	defer va.removeTempFile(tempFile)
 
 	// 调用 ASR
+	start := time.Now()
+	result, err := va.asrClient.TranscribeFile(ctx, tempFile, va.transcribeRequest())
+	va.observeStage(metrics.StageASR, start, err)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if va.config.AutoDetectLanguage && result.Language != "" {
+		va.mu.Lock()
+		va.detectedLanguage = asr.NormalizeLanguage(result.Language)
+		va.mu.Unlock()
+	}
+
+	return result.Text, result.Confidence(), nil
+}
+
+// transcribeRequest 构造识别请求，自动检测语言时不填 Language
+func (va *VoiceAssistant) transcribeRequest() *asr.TranscribeRequest {
 	req := &asr.TranscribeRequest{
 		Language: "zh",
-		Model:    "whisper-1",
+		Model:    va.config.ASRModel,
+	}
+	if va.config.AutoDetectLanguage {
+		req.Language = ""
 	}
+	return req
+}
 
-	result, err := va.asrClient.TranscribeFile(va.ctx, tempFile, req)
-	if err != nil {
-		return "", err
+// DetectedLanguage 返回最近一次自动检测到的语言，可用于选择匹配的 TTS 音色
+func (va *VoiceAssistant) DetectedLanguage() string {
+	va.mu.Lock()
+	defer va.mu.Unlock()
+	return va.detectedLanguage
+}
+
+// SelectVoice 返回 lang 对应的 TTS 音色，未配置或为空时使用默认音色 TTSVoice
+func (va *VoiceAssistant) SelectVoice(lang string) string {
+	return tts.VoiceForLanguage(va.config.TTSVoiceByLanguage, lang, va.config.TTSVoice)
+}
+
+// synthesize 按检测到的语言选择音色合成语音，客户端不支持按次指定音色时使用其默认音色
+func (va *VoiceAssistant) synthesize(ctx context.Context, text string) ([]byte, error) {
+	voice := va.SelectVoice(va.DetectedLanguage())
+	if synthesizer, ok := va.ttsClient.(tts.VoiceSynthesizer); ok && voice != va.config.TTSVoice {
+		return synthesizer.SynthesizeTextWithVoice(ctx, text, va.ttsFormat, voice)
 	}
+	return va.ttsClient.SynthesizeText(ctx, text, va.ttsFormat)
+}
 
-	return result.Text, nil
+// systemPrompt 返回系统提示词，检测到语言时要求用相同语言回复（调用方需持有 va.mu）
+func (va *VoiceAssistant) systemPrompt() string {
+	if !va.config.AutoDetectLanguage || va.detectedLanguage == "" {
+		return va.config.SystemPrompt
+	}
+	return fmt.Sprintf("%s\n用户使用的语言代码是 %s，请使用相同的语言回复。", va.config.SystemPrompt, va.detectedLanguage)
+}
+
+// clarificationFor 判断识别结果是否需要澄清，需要时返回要播报的澄清问题
+func (va *VoiceAssistant) clarificationFor(text string, confidence float64) (string, bool) {
+	if !va.config.ClarifyEnabled {
+		return "", false
+	}
+
+	trimmed := strings.TrimSpace(text)
+	if utf8.RuneCountInString(trimmed) < va.config.ClarifyMinRunes {
+		return "抱歉，我没听清，能再说一遍吗？", true
+	}
+
+	if confidence < va.config.ClarifyMinConfidence {
+		va.mu.Lock()
+		va.pendingClarify = trimmed
+		va.mu.Unlock()
+		return fmt.Sprintf("你是说“%s”吗？", trimmed), true
+	}
+
+	return "", false
+}
+
+// resolveClarification 如果用户对上一次澄清给出肯定回答，返回被确认的文本
+func (va *VoiceAssistant) resolveClarification(text string) (string, bool) {
+	va.mu.Lock()
+	defer va.mu.Unlock()
+
+	pending := va.pendingClarify
+	va.pendingClarify = ""
+	if pending == "" {
+		return "", false
+	}
+
+	reply := strings.TrimRight(strings.TrimSpace(text), "。！!，,.")
+	switch reply {
+	case "是", "是的", "对", "对的", "嗯", "没错", "对啊", "是啊":
+		return pending, true
+	}
+	return "", false
 }
 
 // performLLM 执行LLM对话
 func (va *VoiceAssistant) performLLM(userText string) (string, error) {
+	return va.chat(va.ctx, userText)
+}
+
+// chat 在 ctx 下把用户消息加入对话历史并生成回复
+func (va *VoiceAssistant) chat(ctx context.Context, userText string) (string, error) {
 	va.mu.Lock()
 	defer va.mu.Unlock()
 
@@ -513,7 +1337,7 @@ func (va *VoiceAssistant) performLLM(userText string) (string, error) {
 	messages := []llm.Message{
 		{
 			Role:    "system",
-			Content: va.config.SystemPrompt,
+			Content: va.systemPrompt(),
 		},
 	}
 	messages = append(messages, va.conversationHistory...)
@@ -526,7 +1350,9 @@ func (va *VoiceAssistant) performLLM(userText string) (string, error) {
 		MaxTokens:   500,
 	}
 
-	result, err := va.llmClient.ChatCompletion(va.ctx, req)
+	start := time.Now()
+	result, err := va.llmClient.ChatCompletion(ctx, req)
+	va.observeStage(metrics.StageLLM, start, err)
 	if err != nil {
 		return "", err
 	}
@@ -540,36 +1366,50 @@ func (va *VoiceAssistant) performLLM(userText string) (string, error) {
 	})
 
 	// 限制历史长度
-	if len(va.conversationHistory) > 20 {
-		va.conversationHistory = va.conversationHistory[2:]
-	}
+	va.conversationHistory = trimHistory(va.conversationHistory, va.config.MaxHistoryMessages)
 
 	return response, nil
 }
 
+// trimHistory 从最早的一轮开始整轮删除，直到消息数不超过 max（max <= 0 表示不限制）
+// 一轮从用户消息开始，包含其后的所有非用户消息，保证保留的历史总是以用户消息开头
+func trimHistory(history []llm.Message, max int) []llm.Message {
+	if max <= 0 {
+		return history
+	}
+
+	start := 0
+	for len(history)-start > max {
+		start++
+		for start < len(history) && history[start].Role != "user" {
+			start++
+		}
+	}
+	return history[start:]
+}
+
+// ClearConversation 清空对话历史和待确认的澄清，下一轮对话从头开始
+func (va *VoiceAssistant) ClearConversation() {
+	va.mu.Lock()
+	defer va.mu.Unlock()
+
+	va.conversationHistory = make([]llm.Message, 0)
+	va.pendingClarify = ""
+}
+
 // performTTS 执行文本转语音
 func (va *VoiceAssistant) performTTS(text string) error {
 	va.stateManager.SetState(state.StateSpeaking)
 	defer va.stateManager.SetState(state.StateIdle)
 
 	// 创建播放专用的上下文
-	va.mu.Lock()
-	va.playbackCtx, va.playbackCancel = context.WithCancel(va.ctx)
-	playCtx := va.playbackCtx
-	va.mu.Unlock()
-
-	defer func() {
-		va.mu.Lock()
-		if va.playbackCancel != nil {
-			va.playbackCancel()
-			va.playbackCancel = nil
-			va.playbackCtx = nil
-		}
-		va.mu.Unlock()
-	}()
+	playCtx, done := va.beginPlayback(va.ctx)
+	defer done()
 
 	// 调用 TTS
-	audioData, err := va.ttsClient.SynthesizeText(playCtx, text, tts.FormatWAV)
+	start := time.Now()
+	audioData, err := va.synthesize(playCtx, text)
+	va.observeStage(metrics.StageTTS, start, err)
 	if err != nil {
 		return err
 	}
@@ -577,14 +1417,20 @@ func (va *VoiceAssistant) performTTS(text string) error {
 	// 保存 TTS 音频（如果启用）
 	if va.config.SaveAudioFiles {
 		timestamp := time.Now().Format("20060102_150405")
-		filename := filepath.Join(va.config.AudioOutputDir, fmt.Sprintf("tts_%s.wav", timestamp))
-		if err := os.WriteFile(filename, audioData, 0644); err != nil {
+		name := fmt.Sprintf("tts_%s", timestamp)
+		if va.ttsFormat == tts.FormatWAV {
+			_, err = va.archiveAudio(name, audioData)
+		} else {
+			// 压缩格式按原样保存，archiveAudio 只接受 WAV 输入
+			err = os.WriteFile(filepath.Join(va.config.AudioOutputDir, name+tts.GetFileExtensionForFormat(va.ttsFormat)), audioData, 0644)
+		}
+		if err != nil {
 			log.Printf("保存 TTS 音频失败: %v", err)
 		}
 	}
 
 	// 播放音频 - 使用播放专用上下文
-	err = va.audioOutput.PlayAudioData(playCtx, audioData, 16000)
+	err = va.audioOutput.PlayAudioData(playCtx, audioData, va.audioOutput.GetSampleRate())
 	if err != nil && err != context.Canceled {
 		return fmt.Errorf("播放音频失败: %w", err)
 	}
@@ -606,11 +1452,12 @@ func (va *VoiceAssistant) playErrorMessage(message string) {
 }
 
 // resetRecording 重置录音状态
-func (va *VoiceAssistant) resetRecording(audioBuffer *[][]float32, recordingStart, silenceStart *time.Time) {
+func (va *VoiceAssistant) resetRecording(audioBuffer *[][]float32, recordingStart *time.Time) {
 	va.isListening = false
 	*audioBuffer = (*audioBuffer)[:0]
 	*recordingStart = time.Time{}
-	*silenceStart = time.Time{}
+	va.endpointer.Reset()
+	va.speechDetectionWindow().Reset()
 	va.stateManager.SetState(state.StateIdle)
 }
 
@@ -627,45 +1474,119 @@ func (va *VoiceAssistant) handleInterrupt() {
 	}
 
 	// 同时调用音频输出的停止方法（双重保险）
-	va.audioOutput.Stop()
+	if va.audioOutput != nil {
+		va.audioOutput.Stop()
+	}
 
 	// 重置所有状态
 	va.isDetectingInterrupt = false
 	va.interruptDetectionStart = time.Time{}
+	va.interruptCandidateStart = time.Time{}
+	va.interruptCooldownUntil = time.Now().Add(time.Duration(va.config.InterruptCooldownMs) * time.Millisecond)
 	va.stateManager.SetState(state.StateIdle)
 
 	fmt.Println("🛑 播放已停止，可以开始新的对话")
 }
 
+// updateInterruptDetection 用一块音频的打断检测结果推进打断状态机，返回是否确认打断
+// 语音持续 InterruptDebounceMs 才进入打断检测，检测中的间断不超过该时长也不重置，
+// 因此播放中的短暂尖峰和说话时的短暂停顿都不会改变 isDetectingInterrupt
+func (va *VoiceAssistant) updateInterruptDetection(hasSpeech bool, now time.Time) bool {
+	debounce := time.Duration(va.config.InterruptDebounceMs) * time.Millisecond
+
+	if !hasSpeech {
+		if va.isDetectingInterrupt {
+			if now.Sub(va.lastInterruptSpeech) <= debounce {
+				return false
+			}
+			va.isDetectingInterrupt = false
+			fmt.Println("📢 继续播放...")
+		}
+		va.interruptCandidateStart = time.Time{}
+		return false
+	}
+
+	va.lastInterruptSpeech = now
+	if va.interruptCandidateStart.IsZero() {
+		va.interruptCandidateStart = now
+	}
+	if !va.isDetectingInterrupt {
+		if now.Sub(va.interruptCandidateStart) < debounce {
+			return false
+		}
+		// 检测到持续的语音，从其开始时刻计时验证
+		va.isDetectingInterrupt = true
+		va.interruptDetectionStart = va.interruptCandidateStart
+		fmt.Println("🎯 检测到可能的打断...")
+	}
+
+	// 检查打断持续时间
+	if now.Sub(va.interruptDetectionStart) <= time.Duration(va.config.InterruptMinDurationMs)*time.Millisecond {
+		return false
+	}
+	va.isDetectingInterrupt = false
+	va.interruptCandidateStart = time.Time{}
+	return true
+}
+
+// inInterruptCooldown 返回 now 是否仍在打断后的冷却期内
+func (va *VoiceAssistant) inInterruptCooldown(now time.Time) bool {
+	va.mu.RLock()
+	defer va.mu.RUnlock()
+	return now.Before(va.interruptCooldownUntil)
+}
+
 // saveRecordedAudio 保存录音
 func (va *VoiceAssistant) saveRecordedAudio(audioData []float32) string {
 	timestamp := time.Now().Format("20060102_150405")
-	filename := filepath.Join(va.config.AudioOutputDir, fmt.Sprintf("recording_%s.wav", timestamp))
 
 	tempFile, err := va.saveAudioToTempFile(audioData)
 	if err != nil {
 		log.Printf("保存录音失败: %v", err)
 		return ""
 	}
+	defer va.removeTempFile(tempFile)
 
-	// 复制临时文件到输出目录
 	data, err := os.ReadFile(tempFile)
 	if err != nil {
 		log.Printf("读取临时文件失败: %v", err)
-		os.Remove(tempFile)
 		return ""
 	}
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	filename, err := va.archiveAudio(fmt.Sprintf("recording_%s", timestamp), data)
+	if err != nil {
 		log.Printf("保存录音文件失败: %v", err)
-		os.Remove(tempFile)
 		return ""
 	}
-
-	os.Remove(tempFile)
 	return filename
 }
 
+// archiveAudio 按 ArchiveFormat 编码 WAV 数据并写入输出目录，返回文件路径
+// 编码器不可用或编码失败时回退为原始 WAV
+func (va *VoiceAssistant) archiveAudio(name string, wavData []byte) (string, error) {
+	format, err := audio.ParseArchiveFormat(va.config.ArchiveFormat)
+	if err != nil {
+		return "", err
+	}
+
+	data := wavData
+	if format != audio.ArchiveWAV {
+		encoded, err := audio.EncodeArchiveWAV(wavData, format)
+		if err != nil {
+			log.Printf("⚠️  %s 编码失败，保存为 WAV: %v", format, err)
+			format = audio.ArchiveWAV
+		} else {
+			data = encoded
+		}
+	}
+
+	filename := filepath.Join(va.config.AudioOutputDir, name+format.Extension())
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
 // logConversation 记录对话
 func (va *VoiceAssistant) logConversation(userText, assistantText, audioFile string) {
 	logFile := filepath.Join(va.config.AudioOutputDir, "conversation.log")
@@ -691,10 +1612,50 @@ func (va *VoiceAssistant) logConversation(userText, assistantText, audioFile str
 	}
 }
 
+// pinger 由提供了轻量存活检查的客户端实现（如 tts.TTSClient，避免为检查合成音频）
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck 检查各依赖服务是否可达，返回组件名到错误的映射，nil 表示正常
+// 使用本地 VAD 回退时仍会报告 VAD 服务器的状态
+func (va *VoiceAssistant) HealthCheck(ctx context.Context) map[string]error {
+	results := map[string]error{
+		"llm": va.llmClient.ValidateAPIKey(ctx),
+		"asr": va.asrClient.ValidateAPIKey(ctx),
+	}
+
+	if p, ok := va.ttsClient.(pinger); ok {
+		results["tts"] = p.Ping(ctx)
+	} else {
+		results["tts"] = va.ttsClient.ValidateAPIKey(ctx)
+	}
+
+	_, err := va.vadClient.HealthContext(ctx)
+	results["vad"] = err
+
+	return results
+}
+
 // Stop 停止语音助手
 func (va *VoiceAssistant) Stop() error {
 	log.Println("正在停止语音助手...")
 
+	// 不再开始新的处理，等待进行中的处理完成（避免中断 TTS 播放或遗留临时文件）
+	va.shutdownMu.Lock()
+	va.stopped = true
+	va.shutdownMu.Unlock()
+
+	timeout := time.Duration(va.config.ShutdownTimeoutMs) * time.Millisecond
+	if !va.waitProcessing(timeout) {
+		// 超时后取消上下文，ASR/LLM 请求和播放会在各自的取消点退出
+		log.Printf("等待处理完成超时（%v），取消进行中的请求", timeout)
+		va.cancel()
+		if !va.waitProcessing(timeout) {
+			log.Println("处理仍未结束，强制停止")
+		}
+	}
+
 	// 取消上下文
 	va.cancel()
 
@@ -707,6 +1668,8 @@ func (va *VoiceAssistant) Stop() error {
 		va.audioOutput.Close()
 	}
 
+	va.removeTempFiles()
+
 	log.Println("语音助手已停止")
 	return nil
 }
@@ -719,12 +1682,76 @@ func main() {
 		config.OpenAIAPIKey = apiKey
 	}
 
+	if provider := os.Getenv("LLM_PROVIDER"); provider != "" {
+		config.LLMProvider = provider
+	}
+
+	if provider := os.Getenv("ASR_PROVIDER"); provider != "" {
+		config.ASRProvider = provider
+	}
+
+	if provider := os.Getenv("TTS_PROVIDER"); provider != "" {
+		config.TTSProvider = provider
+	}
+	if command := os.Getenv("TTS_COMMAND"); command != "" {
+		config.TTSCommand = command
+		config.TTSCommandArgs = strings.Fields(os.Getenv("TTS_COMMAND_ARGS"))
+	}
+	if voice := os.Getenv("TTS_VOICE"); voice != "" {
+		config.TTSVoice = voice
+	}
+	// 格式为 "zh=nova,en=alloy"
+	if voices := os.Getenv("TTS_VOICE_BY_LANGUAGE"); voices != "" {
+		config.TTSVoiceByLanguage = make(map[string]string)
+		for _, pair := range strings.Split(voices, ",") {
+			if language, voice, ok := strings.Cut(pair, "="); ok {
+				config.TTSVoiceByLanguage[strings.TrimSpace(language)] = strings.TrimSpace(voice)
+			}
+		}
+	}
+
+	if model := os.Getenv("ASR_MODEL"); model != "" {
+		config.ASRModel = model
+	}
+	if command := os.Getenv("ASR_COMMAND"); command != "" {
+		config.ASRCommand = command
+	}
+	if modelPath := os.Getenv("ASR_MODEL_PATH"); modelPath != "" {
+		config.ASRModelPath = modelPath
+	}
+	if os.Getenv("AUTO_DETECT_LANGUAGE") == "true" {
+		config.AutoDetectLanguage = true
+	}
+
+	if llmKey := os.Getenv("LLM_API_KEY"); llmKey != "" {
+		config.LLMAPIKey = llmKey
+	}
+
+	if model := os.Getenv("LLM_MODEL"); model != "" {
+		config.LLMModel = model
+	}
+	if os.Getenv("STREAMING_REPLY") == "true" {
+		config.StreamingReply = true
+	}
+
 	if vadURL := os.Getenv("VAD_SERVER_URL"); vadURL != "" {
 		config.VADServerURL = vadURL
 	}
 
+	// 设置唤醒词后只在听到唤醒词时响应
+	if wakeWord := os.Getenv("WAKE_WORD"); wakeWord != "" {
+		config.WakeWordEnabled = true
+		config.WakeWord = wakeWord
+	}
+
 	// 启用音频文件保存（用于调试）
 	config.SaveAudioFiles = false
+	if tempDir := os.Getenv("TEMP_DIR"); tempDir != "" {
+		config.TempDir = tempDir
+	}
+	if format := os.Getenv("ARCHIVE_FORMAT"); format != "" {
+		config.ArchiveFormat = format
+	}
 
 	// 检查环境变量是否禁用打断功能
 	if disableInterrupt := os.Getenv("DISABLE_INTERRUPT"); disableInterrupt == "true" {
@@ -0,0 +1,1057 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/metrics"
+	"audio-assistant/internal/state"
+	"audio-assistant/internal/tts"
+	"audio-assistant/internal/vad"
+)
+
+func TestNewLLMClientProviders(t *testing.T) {
+	tests := []struct {
+		provider string
+		check    func(llm.Client) bool
+	}{
+		{"", func(c llm.Client) bool { _, ok := c.(*llm.OpenAISDKClient); return ok }},
+		{"openai", func(c llm.Client) bool { _, ok := c.(*llm.OpenAISDKClient); return ok }},
+		{"openai-sdk", func(c llm.Client) bool { _, ok := c.(*llm.OpenAISDKClient); return ok }},
+		{"qwen", func(c llm.Client) bool { _, ok := c.(*llm.QwenClient); return ok }},
+	}
+
+	for _, tt := range tests {
+		config := getDefaultConfig()
+		config.OpenAIAPIKey = "test-key"
+		config.LLMProvider = tt.provider
+
+		client, err := newLLMClient(config)
+		if err != nil {
+			t.Fatalf("newLLMClient(%q) failed: %v", tt.provider, err)
+		}
+		if !tt.check(client) {
+			t.Errorf("newLLMClient(%q) wired unexpected client type %T", tt.provider, client)
+		}
+	}
+}
+
+func TestLLMModelDefaults(t *testing.T) {
+	tests := []struct {
+		provider, model, expected string
+	}{
+		{"", "", "gpt-4o-mini"},
+		{"openai", "", "gpt-4o-mini"},
+		{"qwen", "", "qwen-plus"},
+		{"qwen", "qwen-max", "qwen-max"},
+	}
+
+	for _, tt := range tests {
+		config := getDefaultConfig()
+		config.LLMProvider = tt.provider
+		config.LLMModel = tt.model
+		if got := llmModel(config); got != tt.expected {
+			t.Errorf("llmModel(%q, %q) = %q, expected %q", tt.provider, tt.model, got, tt.expected)
+		}
+	}
+}
+
+func TestNewLLMClientUnknownProvider(t *testing.T) {
+	config := getDefaultConfig()
+	config.LLMProvider = "unknown"
+
+	if _, err := newLLMClient(config); err == nil {
+		t.Error("Expected error for unknown LLM provider")
+	}
+}
+
+func TestNewASRClientProviders(t *testing.T) {
+	for _, provider := range []string{"", "openai"} {
+		config := getDefaultConfig()
+		config.ASRProvider = provider
+
+		client, err := newASRClient(config)
+		if err != nil {
+			t.Fatalf("newASRClient(%q) failed: %v", provider, err)
+		}
+		if _, ok := client.(*asr.Client); !ok {
+			t.Errorf("newASRClient(%q) wired unexpected client type %T", provider, client)
+		}
+	}
+
+	for _, provider := range []string{"qwen", "unknown"} {
+		config := getDefaultConfig()
+		config.ASRProvider = provider
+		if _, err := newASRClient(config); err == nil {
+			t.Errorf("Expected error for ASR provider %q", provider)
+		}
+	}
+}
+
+func TestNewTTSClientProviders(t *testing.T) {
+	for _, provider := range []string{"", "openai"} {
+		config := getDefaultConfig()
+		config.TTSProvider = provider
+		config.TTSVoice = "nova"
+
+		client, err := newTTSClient(config)
+		if err != nil {
+			t.Fatalf("newTTSClient(%q) failed: %v", provider, err)
+		}
+		ttsClient, ok := client.(*tts.TTSClient)
+		if !ok {
+			t.Fatalf("newTTSClient(%q) wired unexpected client type %T", provider, client)
+		}
+		if voice := ttsClient.GetConfig().Voice; voice != "nova" {
+			t.Errorf("Expected voice nova, got %s", voice)
+		}
+	}
+
+	for _, provider := range []string{"qwen", "unknown"} {
+		config := getDefaultConfig()
+		config.TTSProvider = provider
+		if _, err := newTTSClient(config); err == nil {
+			t.Errorf("Expected error for TTS provider %q", provider)
+		}
+	}
+}
+
+func TestInterruptEnergyGate(t *testing.T) {
+	config := getDefaultConfig()
+	config.InterruptEnergyThreshold = 0.02
+	va := &VoiceAssistant{config: config}
+
+	silence := make([]float32, 10240)
+	if va.passesInterruptEnergyGate(silence) {
+		t.Error("Expected silence to be rejected by the energy gate")
+	}
+
+	// 静音在到达 VAD 服务前即被拦截，vadClient 为 nil 也不会出错
+	hasInterrupt, err := va.detectInterrupt(silence)
+	if err != nil || hasInterrupt {
+		t.Errorf("Expected no interrupt for silence, got %v, %v", hasInterrupt, err)
+	}
+
+	burst := make([]float32, 10240)
+	for i := range burst {
+		burst[i] = float32(0.5 * math.Sin(2*math.Pi*200*float64(i)/16000))
+	}
+	if !va.passesInterruptEnergyGate(burst) {
+		t.Error("Expected loud burst to pass the energy gate")
+	}
+
+	config.InterruptEnergyThreshold = 0
+	if !va.passesInterruptEnergyGate(silence) {
+		t.Error("Expected gate to be disabled when threshold is 0")
+	}
+}
+
+func TestHasSpeechEnergy(t *testing.T) {
+	config := getDefaultConfig()
+	config.MinSpeechEnergy = 0.01
+	va := &VoiceAssistant{config: config}
+
+	// 底噪级别的静音被跳过
+	silence := make([]float32, 32000)
+	for i := range silence {
+		silence[i] = float32(0.002 * math.Sin(float64(i)))
+	}
+	if peak, ok := va.hasSpeechEnergy(silence); ok {
+		t.Errorf("Expected near-silence to be skipped, peak RMS %.4f", peak)
+	}
+
+	// 2 秒静音中 0.3 秒的语音仍然通过
+	speech := append([]float32(nil), silence...)
+	for i := 16000; i < 16000+4800; i++ {
+		speech[i] = float32(0.2 * math.Sin(2*math.Pi*220*float64(i)/16000))
+	}
+	if peak, ok := va.hasSpeechEnergy(speech); !ok || peak < 0.1 {
+		t.Errorf("Expected short speech to pass, got peak RMS %.4f, %v", peak, ok)
+	}
+
+	config.MinSpeechEnergy = 0
+	if _, ok := va.hasSpeechEnergy(make([]float32, 1600)); !ok {
+		t.Error("Expected the check to be disabled when MinSpeechEnergy is 0")
+	}
+}
+
+func TestEchoSuppressionPreventsSelfInterrupt(t *testing.T) {
+	config := getDefaultConfig()
+	va := &VoiceAssistant{config: config}
+	va.useLocalVAD()
+
+	// 播放内容：类似元音的谐波信号，前面留出最大延迟的余量
+	maxDelay := audio.GetTargetSampleRate() * echoMaxDelayMs / 1000
+	output := make([]float32, 10240+maxDelay)
+	for i := range output {
+		tt := float64(i) / 16000
+		output[i] = float32(0.4*math.Sin(2*math.Pi*180*tt) + 0.2*math.Sin(2*math.Pi*360*tt)*math.Sin(2*math.Pi*3*tt))
+	}
+
+	// 麦克风拾取到延迟 80ms、衰减后的播放内容
+	delay := 1280
+	input := make([]float32, 10240)
+	for i := range input {
+		input[i] = 0.5 * output[maxDelay-delay+i]
+	}
+
+	if !va.isEcho(input, output) {
+		t.Fatal("Expected fed-back output to be recognized as echo")
+	}
+
+	// 没有回声抑制时，本地 VAD 会把回声当作打断
+	config.EchoSuppression = false
+	if hasInterrupt, err := va.detectInterrupt(input); err != nil || !hasInterrupt {
+		t.Fatalf("Expected echo to trigger interrupt without suppression, got %v, %v", hasInterrupt, err)
+	}
+
+	// 用户说话（与播放内容无关）不应被当作回声
+	speech := make([]float32, 10240)
+	for i := range speech {
+		speech[i] = float32(0.4 * math.Sin(2*math.Pi*230*float64(i)/16000+math.Sin(2*math.Pi*5*float64(i)/16000)))
+	}
+	if va.isEcho(speech, output) {
+		t.Error("Expected unrelated speech not to be treated as echo")
+	}
+}
+
+// newTestStateManager 在临时目录中创建状态管理器，它会在当前目录下创建临时文件
+func newTestStateManager(t *testing.T) *state.Manager {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	manager := state.NewManager()
+	manager.SetLogger(logging.Discard())
+	return manager
+}
+
+func TestInterruptDebounce(t *testing.T) {
+	config := getDefaultConfig()
+	config.InterruptDebounceMs = 100
+	config.InterruptMinDurationMs = 200
+	va := &VoiceAssistant{config: config}
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	// 播放中的单块尖峰不进入打断检测
+	va.updateInterruptDetection(true, at(0))
+	va.updateInterruptDetection(false, at(64))
+	if va.isDetectingInterrupt {
+		t.Error("Expected a momentary spike to be ignored")
+	}
+
+	// 持续语音在去抖后进入检测，从语音开始计时
+	for ms := 128; ms <= 256; ms += 64 {
+		if va.updateInterruptDetection(true, at(ms)) {
+			t.Fatalf("Expected no interrupt before the minimum duration at %dms", ms)
+		}
+	}
+	if !va.isDetectingInterrupt || !va.interruptDetectionStart.Equal(at(128)) {
+		t.Fatalf("Expected detection to start at the first speech chunk, got %v, %v", va.isDetectingInterrupt, va.interruptDetectionStart.Sub(start))
+	}
+
+	// 短暂停顿不重置检测，持续时间够了即确认打断
+	va.updateInterruptDetection(false, at(320))
+	if !va.isDetectingInterrupt {
+		t.Error("Expected a short gap to keep detecting")
+	}
+	if !va.updateInterruptDetection(true, at(384)) {
+		t.Error("Expected the interrupt to be confirmed after the minimum duration")
+	}
+	if va.isDetectingInterrupt {
+		t.Error("Expected detection reset after confirming")
+	}
+
+	// 长时间静音结束检测
+	va.updateInterruptDetection(true, at(1000))
+	va.updateInterruptDetection(true, at(1100))
+	va.updateInterruptDetection(false, at(1300))
+	if va.isDetectingInterrupt {
+		t.Error("Expected silence longer than the debounce to stop detecting")
+	}
+}
+
+func TestHandleInterruptCooldown(t *testing.T) {
+	config := getDefaultConfig()
+	config.InterruptCooldownMs = 300
+	va := &VoiceAssistant{config: config, stateManager: newTestStateManager(t)}
+	va.stateManager.SetState(state.StateSpeaking)
+	playCtx, done := va.beginPlayback(context.Background())
+	defer done()
+	va.isDetectingInterrupt = true
+
+	before := time.Now()
+	va.handleInterrupt()
+	if playCtx.Err() == nil {
+		t.Error("Expected the playback context to be cancelled")
+	}
+	if va.stateManager.GetState() != state.StateIdle || va.isDetectingInterrupt {
+		t.Errorf("Expected idle without interrupt detection, got %v, %v", va.stateManager.GetState(), va.isDetectingInterrupt)
+	}
+	if !va.inInterruptCooldown(before.Add(250 * time.Millisecond)) {
+		t.Error("Expected new listening suppressed during the cooldown")
+	}
+	if va.inInterruptCooldown(time.Now().Add(300 * time.Millisecond)) {
+		t.Error("Expected the cooldown to end after InterruptCooldownMs")
+	}
+
+	// 冷却为 0 时立即可以开始新录音
+	va.config.InterruptCooldownMs = 0
+	va.handleInterrupt()
+	if va.inInterruptCooldown(time.Now()) {
+		t.Error("Expected no cooldown when InterruptCooldownMs is 0")
+	}
+}
+
+func TestTranscribeRequestLanguage(t *testing.T) {
+	config := getDefaultConfig()
+	va := &VoiceAssistant{config: config}
+
+	if req := va.transcribeRequest(); req.Language != "zh" || req.Model != config.ASRModel {
+		t.Errorf("Expected fixed zh request by default, got %+v", req)
+	}
+
+	config.AutoDetectLanguage = true
+	if req := va.transcribeRequest(); req.Language != "" {
+		t.Errorf("Expected empty language for auto detection, got %q", req.Language)
+	}
+
+	// 检测到的语言会写入系统提示词
+	if prompt := va.systemPrompt(); prompt != config.SystemPrompt {
+		t.Errorf("Expected unchanged prompt before detection, got %q", prompt)
+	}
+	va.detectedLanguage = "en"
+	if prompt := va.systemPrompt(); !strings.Contains(prompt, "en") || !strings.HasPrefix(prompt, config.SystemPrompt) {
+		t.Errorf("Expected detected language in prompt, got %q", prompt)
+	}
+}
+
+func TestArchiveAudio(t *testing.T) {
+	config := getDefaultConfig()
+	config.AudioOutputDir = t.TempDir()
+	va := &VoiceAssistant{config: config}
+
+	wavData, err := audio.EncodeWAV(make([]float32, 1600), 16000)
+	if err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+
+	filename, err := va.archiveAudio("recording_test", wavData)
+	if err != nil {
+		t.Fatalf("archiveAudio failed: %v", err)
+	}
+	if filepath.Ext(filename) != ".wav" {
+		t.Errorf("Expected WAV archive by default, got %s", filename)
+	}
+
+	// 压缩格式在没有编码器时回退为 WAV
+	config.ArchiveFormat = "mp3"
+	filename, err = va.archiveAudio("recording_test", wavData)
+	if err != nil {
+		t.Fatalf("archiveAudio failed: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	switch filepath.Ext(filename) {
+	case ".mp3":
+		if len(data) == 0 || len(data) >= len(wavData) {
+			t.Errorf("Expected compressed MP3 smaller than %d bytes, got %d", len(wavData), len(data))
+		}
+	case ".wav":
+		if !bytes.Equal(data, wavData) {
+			t.Error("Expected fallback to keep the original WAV data")
+		}
+	default:
+		t.Errorf("Unexpected archive file %s", filename)
+	}
+
+	config.ArchiveFormat = "flac"
+	if _, err := va.archiveAudio("recording_test", wavData); err == nil {
+		t.Error("Expected error for unsupported archive format")
+	}
+}
+
+// stubLLMClient 只实现健康检查需要的行为
+type stubLLMClient struct {
+	llm.Client
+	err error
+}
+
+func (c stubLLMClient) ValidateAPIKey(ctx context.Context) error { return c.err }
+
+type stubASRClient struct {
+	asr.ASRInterface
+	err error
+}
+
+func (c stubASRClient) ValidateAPIKey(ctx context.Context) error { return c.err }
+
+// stubTTSClient 记录使用的是 Ping 还是 ValidateAPIKey
+type stubTTSClient struct {
+	tts.TTSInterface
+	err    error
+	pinged *bool
+}
+
+func (c stubTTSClient) ValidateAPIKey(ctx context.Context) error {
+	return errors.New("should use Ping")
+}
+
+func (c stubTTSClient) Ping(ctx context.Context) error {
+	*c.pinged = true
+	return c.err
+}
+
+func TestHealthCheck(t *testing.T) {
+	server := vad.NewTestServer()
+	defer server.Close()
+
+	pinged := false
+	va := &VoiceAssistant{
+		config:    getDefaultConfig(),
+		llmClient: stubLLMClient{},
+		asrClient: stubASRClient{},
+		ttsClient: stubTTSClient{pinged: &pinged},
+		vadClient: vad.NewClient(server.URL),
+	}
+
+	results := va.HealthCheck(context.Background())
+	for _, name := range []string{"llm", "asr", "tts", "vad"} {
+		err, ok := results[name]
+		if !ok {
+			t.Errorf("Expected %s in health check results", name)
+		}
+		if err != nil {
+			t.Errorf("Expected %s to be healthy, got %v", name, err)
+		}
+	}
+	if !pinged {
+		t.Error("Expected TTS health check to use Ping instead of synthesizing audio")
+	}
+
+	// 降级：LLM 鉴权失败，VAD 服务器不可达
+	server.Close()
+	va.llmClient = stubLLMClient{err: errors.New("invalid API key")}
+	va.vadClient = vad.NewClient(server.URL)
+	va.vadClient.SetRetryPolicy(0, 0)
+
+	results = va.HealthCheck(context.Background())
+	if results["llm"] == nil || results["vad"] == nil {
+		t.Errorf("Expected llm and vad to be degraded, got %v", results)
+	}
+	if results["asr"] != nil || results["tts"] != nil {
+		t.Errorf("Expected asr and tts to stay healthy, got %v", results)
+	}
+}
+
+func TestStopWaitsForProcessing(t *testing.T) {
+	config := getDefaultConfig()
+	config.ShutdownTimeoutMs = 2000
+	ctx, cancel := context.WithCancel(context.Background())
+	va := &VoiceAssistant{config: config, ctx: ctx, cancel: cancel}
+
+	// 模拟进行中的处理留下的临时文件
+	tempFile := filepath.Join(t.TempDir(), "audio_test.wav")
+	if err := os.WriteFile(tempFile, []byte("RIFF"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	va.trackTempFile(tempFile)
+
+	var finished atomic.Bool
+	va.startProcessing(func() {
+		time.Sleep(200 * time.Millisecond)
+		if va.ctx.Err() != nil {
+			t.Error("Expected context to stay alive while processing drains")
+		}
+		finished.Store(true)
+	})
+
+	start := time.Now()
+	if err := va.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Expected Stop to wait for the processing step")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Stop to return once processing finished, took %v", elapsed)
+	}
+	if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
+		t.Errorf("Expected temp file to be removed on shutdown, got %v", err)
+	}
+	if va.startProcessing(func() { t.Error("Expected no new processing after Stop") }) {
+		t.Error("Expected startProcessing to refuse work after Stop")
+	}
+}
+
+func TestStopCancelsAfterTimeout(t *testing.T) {
+	config := getDefaultConfig()
+	config.ShutdownTimeoutMs = 100
+	ctx, cancel := context.WithCancel(context.Background())
+	va := &VoiceAssistant{config: config, ctx: ctx, cancel: cancel}
+
+	var cancelled atomic.Bool
+	va.startProcessing(func() {
+		// 长时间运行的请求只会在上下文取消时退出
+		<-va.ctx.Done()
+		cancelled.Store(true)
+	})
+
+	start := time.Now()
+	va.Stop()
+	if !cancelled.Load() {
+		t.Error("Expected the stuck processing step to be cancelled and awaited")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected Stop to give up after the timeout, took %v", elapsed)
+	}
+}
+
+func TestSweepTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	seed := func(name string, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("RIFF"), 0644); err != nil {
+			t.Fatalf("Failed to seed %s: %v", name, err)
+		}
+		modTime := now.Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to age %s: %v", name, err)
+		}
+		return path
+	}
+
+	stale := []string{seed("audio_1.wav", 3*time.Hour), seed("audio_2.wav", 2*time.Hour)}
+	fresh := seed("audio_3.wav", time.Minute)
+	recording := seed("recording_20240101_120000.wav", 3*time.Hour) // 保存的录音不是临时文件
+
+	removed, err := sweepTempFiles(dir, time.Hour, now)
+	if err != nil {
+		t.Fatalf("sweepTempFiles failed: %v", err)
+	}
+	if removed != len(stale) {
+		t.Errorf("Expected %d stale files removed, got %d", len(stale), removed)
+	}
+	for _, path := range stale {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{fresh, recording} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestSaveAudioToTempFileUsesTempDir(t *testing.T) {
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+	va := &VoiceAssistant{config: config}
+
+	path, err := va.saveAudioToTempFile(make([]float32, 160))
+	if err != nil {
+		t.Fatalf("saveAudioToTempFile failed: %v", err)
+	}
+	if filepath.Dir(path) != config.TempDir {
+		t.Errorf("Expected temp file in %s, got %s", config.TempDir, path)
+	}
+	va.removeTempFile(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected temp file to be removed")
+	}
+}
+
+// scriptedASRClient 返回固定的识别结果或错误
+type scriptedASRClient struct {
+	asr.ASRInterface
+	err error
+}
+
+func (c scriptedASRClient) TranscribeFile(ctx context.Context, path string, req *asr.TranscribeRequest) (*asr.TranscribeResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &asr.TranscribeResponse{Text: "你好"}, nil
+}
+
+// scriptedLLMClient 返回固定的回复或错误
+type scriptedLLMClient struct {
+	llm.Client
+	err error
+}
+
+func (c scriptedLLMClient) ChatCompletion(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: "你好！"}}}}, nil
+}
+
+func TestStageMetrics(t *testing.T) {
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+	samples := make([]float32, config.MinASRSamples)
+
+	recorder := metrics.NewMemory()
+	va := &VoiceAssistant{
+		config:    config,
+		ctx:       context.Background(),
+		asrClient: scriptedASRClient{},
+		llmClient: scriptedLLMClient{},
+	}
+	va.useLocalVAD()
+	va.SetMetrics(recorder)
+
+	if _, _, err := va.performASR(samples); err != nil {
+		t.Fatalf("performASR failed: %v", err)
+	}
+	if _, err := va.performLLM("你好"); err != nil {
+		t.Fatalf("performLLM failed: %v", err)
+	}
+	if _, err := va.detectSpeechActivity(samples); err != nil {
+		t.Fatalf("detectSpeechActivity failed: %v", err)
+	}
+	for _, stage := range []string{metrics.StageASR, metrics.StageLLM, metrics.StageVAD} {
+		if recorder.Count(stage) != 1 || recorder.Errors(stage) != 0 {
+			t.Errorf("Expected one successful %s observation, got count=%d errors=%d", stage, recorder.Count(stage), recorder.Errors(stage))
+		}
+	}
+
+	// 失败路径同样记录耗时并计入错误
+	failure := errors.New("upstream unavailable")
+	va.asrClient = scriptedASRClient{err: failure}
+	va.llmClient = scriptedLLMClient{err: failure}
+	if _, _, err := va.performASR(samples); !errors.Is(err, failure) {
+		t.Errorf("Expected ASR failure, got %v", err)
+	}
+	if _, err := va.performLLM("你好"); !errors.Is(err, failure) {
+		t.Errorf("Expected LLM failure, got %v", err)
+	}
+	for _, stage := range []string{metrics.StageASR, metrics.StageLLM} {
+		if recorder.Count(stage) != 2 || recorder.Errors(stage) != 1 {
+			t.Errorf("Expected %s failure to be counted, got count=%d errors=%d", stage, recorder.Count(stage), recorder.Errors(stage))
+		}
+	}
+
+	// 样本不足时直接跳过，不算作一次调用
+	if _, _, err := va.performASR(nil); !errors.Is(err, ErrEmptyAudio) {
+		t.Errorf("Expected ErrEmptyAudio, got %v", err)
+	}
+	if recorder.Count(metrics.StageASR) != 2 {
+		t.Errorf("Expected skipped ASR call not to be observed, got %d", recorder.Count(metrics.StageASR))
+	}
+
+	// nil 恢复为不记录
+	va.SetMetrics(nil)
+	if _, err := va.performLLM("你好"); !errors.Is(err, failure) {
+		t.Errorf("Expected LLM failure, got %v", err)
+	}
+	if recorder.Count(metrics.StageLLM) != 2 {
+		t.Errorf("Expected no observations after SetMetrics(nil), got %d", recorder.Count(metrics.StageLLM))
+	}
+}
+
+// history 按角色序列构造对话历史，内容为序号便于断言
+func history(roles ...string) []llm.Message {
+	messages := make([]llm.Message, len(roles))
+	for i, role := range roles {
+		messages[i] = llm.Message{Role: role, Content: string(rune('a' + i))}
+	}
+	return messages
+}
+
+// contents 拼接消息内容
+func contents(messages []llm.Message) string {
+	var sb strings.Builder
+	for _, m := range messages {
+		sb.WriteString(m.Content)
+	}
+	return sb.String()
+}
+
+func TestTrimHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []llm.Message
+		max      int
+		expected string
+	}{
+		{"within limit", history("user", "assistant", "user", "assistant"), 4, "abcd"},
+		{"drops oldest pair", history("user", "assistant", "user", "assistant", "user", "assistant"), 4, "cdef"},
+		{"odd limit keeps whole pairs", history("user", "assistant", "user", "assistant"), 3, "cd"},
+		// LLM 失败时留下的单独用户消息算作一轮
+		{"unanswered user message", history("user", "user", "assistant", "user", "assistant"), 4, "bcde"},
+		{"limit below one pair", history("user", "assistant"), 1, ""},
+		{"unlimited", history("user", "assistant", "user", "assistant"), 0, "abcd"},
+	}
+
+	for _, tt := range tests {
+		trimmed := trimHistory(tt.history, tt.max)
+		if got := contents(trimmed); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+		if len(trimmed) > 0 && trimmed[0].Role != "user" {
+			t.Errorf("%s: expected history to start with a user message, got %s", tt.name, trimmed[0].Role)
+		}
+	}
+}
+
+func TestPerformLLMHistoryLimit(t *testing.T) {
+	config := getDefaultConfig()
+	config.MaxHistoryMessages = 4
+	va := &VoiceAssistant{config: config, ctx: context.Background(), llmClient: scriptedLLMClient{}, metrics: metrics.Nop()}
+
+	for _, question := range []string{"一", "二", "三"} {
+		if _, err := va.performLLM(question); err != nil {
+			t.Fatalf("performLLM failed: %v", err)
+		}
+	}
+	if len(va.conversationHistory) != 4 || va.conversationHistory[0].Content != "二" {
+		t.Errorf("Expected the two latest turns, got %+v", va.conversationHistory)
+	}
+
+	va.pendingClarify = "待确认"
+	va.ClearConversation()
+	if len(va.conversationHistory) != 0 || va.pendingClarify != "" {
+		t.Errorf("Expected ClearConversation to reset history and clarification, got %+v %q", va.conversationHistory, va.pendingClarify)
+	}
+}
+
+func TestClarificationFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		disabled   bool
+		text       string
+		confidence float64
+		question   string
+		pending    string
+	}{
+		{"too short", false, " 嗯 ", 0.9, "抱歉，我没听清，能再说一遍吗？", ""},
+		{"low confidence", false, " 打开空调 ", 0.3, "你是说“打开空调”吗？", "打开空调"},
+		{"confident", false, "打开空调", 0.9, "", ""},
+		{"disabled", true, "嗯", 0.1, "", ""},
+	}
+
+	for _, tt := range tests {
+		config := getDefaultConfig()
+		config.ClarifyEnabled = !tt.disabled
+		va := &VoiceAssistant{config: config}
+
+		question, ok := va.clarificationFor(tt.text, tt.confidence)
+		if question != tt.question || ok != (tt.question != "") {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.name, tt.question, question, ok)
+		}
+		if va.pendingClarify != tt.pending {
+			t.Errorf("%s: expected pending %q, got %q", tt.name, tt.pending, va.pendingClarify)
+		}
+	}
+}
+
+func TestResolveClarification(t *testing.T) {
+	tests := []struct {
+		name     string
+		pending  string
+		reply    string
+		expected string
+	}{
+		{"affirmative", "打开空调", "是的。", "打开空调"},
+		{"affirmative with spaces", "打开空调", " 对！", "打开空调"},
+		{"not affirmative", "打开空调", "不是，关掉空调", ""},
+		{"nothing pending", "", "是的", ""},
+	}
+
+	for _, tt := range tests {
+		va := &VoiceAssistant{config: getDefaultConfig(), pendingClarify: tt.pending}
+
+		text, ok := va.resolveClarification(tt.reply)
+		if text != tt.expected || ok != (tt.expected != "") {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.name, tt.expected, text, ok)
+		}
+		// 无论是否确认，待澄清的文本都只使用一次
+		if va.pendingClarify != "" {
+			t.Errorf("%s: expected pending clarification cleared, got %q", tt.name, va.pendingClarify)
+		}
+	}
+}
+
+// voiceTTSClient 记录每次合成使用的音色，空字符串表示调用了 SynthesizeText
+type voiceTTSClient struct {
+	tts.TTSInterface
+	voices *[]string
+}
+
+func (c voiceTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	*c.voices = append(*c.voices, "")
+	return nil, nil
+}
+
+func (c voiceTTSClient) SynthesizeTextWithVoice(ctx context.Context, text string, format string, voice string) ([]byte, error) {
+	*c.voices = append(*c.voices, voice)
+	return nil, nil
+}
+
+func TestSelectVoice(t *testing.T) {
+	config := getDefaultConfig()
+	config.TTSVoice = tts.VoiceAlloy
+	config.TTSVoiceByLanguage = map[string]string{"zh": tts.VoiceNova, "en": tts.VoiceEcho}
+
+	var voices []string
+	va := &VoiceAssistant{config: config, ttsClient: voiceTTSClient{voices: &voices}}
+
+	for language, expected := range map[string]string{"zh": tts.VoiceNova, "en": tts.VoiceEcho, "ja": tts.VoiceAlloy, "": tts.VoiceAlloy} {
+		if voice := va.SelectVoice(language); voice != expected {
+			t.Errorf("SelectVoice(%q) = %q, expected %q", language, voice, expected)
+		}
+	}
+
+	// 检测到中文时按次指定音色，未检测到语言时使用客户端默认音色
+	va.detectedLanguage = "zh"
+	va.synthesize(context.Background(), "你好")
+	va.detectedLanguage = ""
+	va.synthesize(context.Background(), "hello")
+	if len(voices) != 2 || voices[0] != tts.VoiceNova || voices[1] != "" {
+		t.Errorf("Expected nova then the default voice, got %q", voices)
+	}
+}
+
+func TestVADStatisticsHandler(t *testing.T) {
+	server := vad.NewTestServer()
+	defer server.Close()
+
+	config := getDefaultConfig()
+	va := &VoiceAssistant{
+		config:       config,
+		ctx:          context.Background(),
+		vadClient:    vad.NewClient(server.URL),
+		stateManager: newTestStateManager(t),
+		endpointer:   vad.NewSilenceEndpointer(config.MinSilenceDurationMs),
+	}
+	var reported []vad.DetectStatistics
+	va.SetVADStatisticsHandler(func(stats vad.DetectStatistics) {
+		reported = append(reported, stats)
+	})
+
+	// 检测窗口重叠，但整段录音的统计只计入每块新增的音频
+	var audioBuffer [][]float32
+	var recordingStart time.Time
+	for i := 0; i < 2; i++ {
+		va.handleListeningInput(make([]float32, 8000), time.Now(), &audioBuffer, &recordingStart)
+	}
+	if !va.isListening {
+		t.Fatal("Expected the test server to report speech")
+	}
+
+	va.reportVADStatistics()
+	if len(reported) != 1 {
+		t.Fatalf("Expected one report per recording, got %d", len(reported))
+	}
+	if reported[0].TotalAudioDuration < 0.99 || reported[0].TotalAudioDuration > 1.01 {
+		t.Errorf("Expected about 1s of audio over two chunks, got %v", reported[0].TotalAudioDuration)
+	}
+	if reported[0].TotalSegments != 1 || reported[0].SpeechRatio != 1 {
+		t.Errorf("Expected one segment of continuous speech, got %+v", reported[0])
+	}
+}
+
+// lengthASRClient 记录送去识别的录音时长，返回空文本结束处理
+type lengthASRClient struct {
+	asr.ASRInterface
+	seconds chan float64
+}
+
+func (c lengthASRClient) TranscribeFile(ctx context.Context, path string, req *asr.TranscribeRequest) (*asr.TranscribeResponse, error) {
+	samples, rate, err := audio.LoadFromWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	c.seconds <- float64(len(samples)) / float64(rate)
+	return &asr.TranscribeResponse{}, nil
+}
+
+// toneChunks 生成 n 块输入大小的 220Hz 正弦波，amplitude 为 0 时是静音
+func toneChunks(config *Config, n int, amplitude float64) [][]float32 {
+	chunks := make([][]float32, n)
+	for c := range chunks {
+		chunks[c] = make([]float32, config.InputFramesPerBuffer)
+		for i := range chunks[c] {
+			chunks[c][i] = float32(amplitude * math.Sin(2*math.Pi*220*float64(i)/float64(audio.GetTargetSampleRate())))
+		}
+	}
+	return chunks
+}
+
+func TestListeningDefaultConfig(t *testing.T) {
+	// 默认配置：64ms 的输入块、500ms 的最小语音时长，VAD 服务不可用时使用本地检测
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+	seconds := make(chan float64, 1)
+	va := &VoiceAssistant{
+		config:       config,
+		ctx:          context.Background(),
+		asrClient:    lengthASRClient{seconds: seconds},
+		stateManager: newTestStateManager(t),
+		endpointer:   vad.NewSilenceEndpointer(config.MinSilenceDurationMs),
+	}
+	va.useLocalVAD()
+
+	chunkDuration := time.Duration(config.InputFramesPerBuffer) * time.Second / time.Duration(audio.GetTargetSampleRate())
+	now := time.Now()
+	var audioBuffer [][]float32
+	var recordingStart time.Time
+	feed := func(chunks [][]float32) {
+		for _, chunk := range chunks {
+			va.handleListeningInput(chunk, now, &audioBuffer, &recordingStart)
+			now = now.Add(chunkDuration)
+		}
+	}
+
+	// 0.5 秒静音后说 1.5 秒，再静音 1.5 秒
+	feed(toneChunks(config, 8, 0))
+	if va.isListening {
+		t.Fatal("Expected silence not to start recording")
+	}
+	feed(toneChunks(config, 24, 0.3))
+	if !va.isListening {
+		t.Fatal("Expected 1.5 seconds of speech to start recording")
+	}
+	feed(toneChunks(config, 24, 0))
+
+	select {
+	case got := <-seconds:
+		// 录音包含完整的语音（开头来自检测窗口），末尾静音已被裁掉
+		if got < 1.5 || got > 2.0 {
+			t.Errorf("Expected the whole utterance sent to ASR, got %.2fs", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the silence to end the recording")
+	}
+	va.processing.Wait()
+	if va.isListening {
+		t.Error("Expected recording to stop after the utterance")
+	}
+}
+
+func TestInterruptDefaultConfig(t *testing.T) {
+	config := getDefaultConfig()
+	va := &VoiceAssistant{config: config}
+	va.useLocalVAD()
+
+	chunkDuration := time.Duration(config.InputFramesPerBuffer) * time.Second / time.Duration(audio.GetTargetSampleRate())
+	now := time.Now()
+	for i, chunk := range toneChunks(config, 20, 0.3) {
+		if va.handleSpeakingInput(chunk, now) {
+			// VAD 需要 200ms 语音，之后去抖 100ms，再持续 200ms 确认
+			if elapsed := time.Duration(i+1) * chunkDuration; elapsed > time.Second {
+				t.Errorf("Expected the interrupt confirmed within a second, took %v", elapsed)
+			}
+			return
+		}
+		now = now.Add(chunkDuration)
+	}
+	t.Fatal("Expected sustained speech during playback to interrupt")
+}
+
+func TestLocalVADConfig(t *testing.T) {
+	config := getDefaultConfig()
+	config.LocalEnergyThreshold = 0.03
+	config.LocalFrameSizeMs = 20
+	adaptiveConfig := vad.DefaultAdaptiveThresholdConfig()
+	adaptiveConfig.CalibrationMs = 100
+	va := &VoiceAssistant{config: config, ctx: context.Background(), adaptive: vad.NewAdaptiveThreshold(adaptiveConfig)}
+	va.useLocalVAD()
+
+	speech, interrupt := va.localVAD.Config(), va.localInterruptVAD.Config()
+	if speech.EnergyThreshold != 0.03 || speech.FrameSizeMs != 20 || interrupt.EnergyThreshold != 0.03 || interrupt.FrameSizeMs != 20 {
+		t.Errorf("Expected the local VAD settings to reach both detectors, got %+v and %+v", speech, interrupt)
+	}
+	if speech.MinSpeechDurationMs != config.MinSpeechDurationMs || interrupt.MinSpeechDurationMs != config.InterruptMinDurationMs {
+		t.Errorf("Expected min speech %d and %d, got %d and %d",
+			config.MinSpeechDurationMs, config.InterruptMinDurationMs, speech.MinSpeechDurationMs, interrupt.MinSpeechDurationMs)
+	}
+
+	// 校准后调整已有检测器的门限，而不是每块重新创建
+	noise := make([]float32, 1600)
+	for i := range noise {
+		noise[i] = 0.05 * float32(math.Sin(float64(i)))
+	}
+	va.calibrateAmbient(noise)
+	detector := va.localVAD
+	if _, err := va.detectSpeechActivity(make([]float32, 1024)); err != nil {
+		t.Fatalf("detectSpeechActivity failed: %v", err)
+	}
+	if va.localVAD != detector {
+		t.Error("Expected the local detector to be reused")
+	}
+	if want := va.adaptive.EnergyThreshold(config.LocalEnergyThreshold); va.localVAD.Config().EnergyThreshold != want {
+		t.Errorf("Expected the adaptive threshold %v, got %v", want, va.localVAD.Config().EnergyThreshold)
+	}
+}
+
+func TestAdaptiveVADThreshold(t *testing.T) {
+	server := vad.NewTestServer()
+	defer server.Close()
+
+	config := getDefaultConfig()
+	adaptiveConfig := vad.DefaultAdaptiveThresholdConfig()
+	adaptiveConfig.CalibrationMs = 200
+	va := &VoiceAssistant{
+		config:    config,
+		ctx:       context.Background(),
+		vadClient: vad.NewClient(server.URL),
+		adaptive:  vad.NewAdaptiveThreshold(adaptiveConfig),
+	}
+
+	// 校准期间的音频只用于估计环境噪声，不送去检测
+	noise := make([]float32, 1600)
+	for i := range noise {
+		noise[i] = 0.05 * float32(math.Sin(float64(i)))
+	}
+	for i := 0; i < 2; i++ {
+		if !va.calibrateAmbient(noise) {
+			t.Fatalf("Expected chunk %d to be used for calibration", i)
+		}
+	}
+	if va.calibrateAmbient(noise) || server.DetectCount() != 0 {
+		t.Fatal("Expected calibration to finish after 200ms without VAD requests")
+	}
+
+	// 嘈杂环境下请求使用上限阈值
+	detection, err := va.detectSpeechActivity(make([]float32, 8000))
+	if err != nil {
+		t.Fatalf("detectSpeechActivity failed: %v", err)
+	}
+	if used := detection.Statistics.ThresholdUsed; used != config.AdaptiveVADMaxThreshold {
+		t.Errorf("Expected the adaptive threshold %v to be sent, got %v", config.AdaptiveVADMaxThreshold, used)
+	}
+
+	// 未启用时使用固定阈值
+	va.adaptive = nil
+	if va.calibrateAmbient(noise) || va.vadThreshold() != config.VADThreshold {
+		t.Errorf("Expected the fixed threshold without AdaptiveVAD, got %v", va.vadThreshold())
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/metrics"
+)
+
+// ProcessFile 把一段保存的录音（如 SaveAudioFiles 写出的 recording_*.wav）送入 ASR→LLM→TTS 流程，返回识别文本和回复
+// 用于在没有麦克风时调试和回归测试提示词：不经过 VAD、唤醒词和澄清，回复会计入对话历史
+// 合成的语音在有音频输出时播放，否则只检查合成是否成功
+func (va *VoiceAssistant) ProcessFile(ctx context.Context, path string) (transcript, reply string, err error) {
+	if ctx == nil {
+		ctx = va.requestContext()
+	}
+
+	samples, sampleRate, err := audio.LoadFromWAV(path)
+	if err != nil {
+		return "", "", fmt.Errorf("读取录音失败: %w", err)
+	}
+	if target := audio.GetTargetSampleRate(); sampleRate != target {
+		samples = audio.ResampleLinear(samples, sampleRate, target)
+	}
+
+	transcript, _, err = va.transcribe(ctx, samples)
+	if err != nil {
+		return "", "", fmt.Errorf("语音识别失败: %w", err)
+	}
+	if transcript == "" {
+		return "", "", fmt.Errorf("语音识别失败: 识别结果为空")
+	}
+	log.Printf("👤 回放识别: %s", transcript)
+
+	reply, err = va.chat(ctx, transcript)
+	if err != nil {
+		return transcript, "", fmt.Errorf("LLM处理失败: %w", err)
+	}
+	log.Printf("🤖 回放回复: %s", reply)
+	va.recordTurn(transcript, reply, path)
+
+	start := time.Now()
+	audioData, err := va.synthesize(ctx, reply)
+	va.observeStage(metrics.StageTTS, start, err)
+	if err != nil {
+		return transcript, reply, fmt.Errorf("TTS处理失败: %w", err)
+	}
+	if va.audioOutput != nil {
+		if err := va.audioOutput.PlayAudioData(ctx, audioData, va.audioOutput.GetSampleRate()); err != nil {
+			return transcript, reply, fmt.Errorf("播放音频失败: %w", err)
+		}
+	}
+
+	return transcript, reply, nil
+}
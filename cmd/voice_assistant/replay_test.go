@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/tts"
+)
+
+// replayASRClient 检查送来识别的音频并返回固定文本
+type replayASRClient struct {
+	asr.ASRInterface
+	text       string
+	sampleRate *int
+}
+
+func (c replayASRClient) TranscribeFile(ctx context.Context, path string, req *asr.TranscribeRequest) (*asr.TranscribeResponse, error) {
+	_, rate, err := audio.LoadFromWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	*c.sampleRate = rate
+	return &asr.TranscribeResponse{Text: c.text}, nil
+}
+
+// echoLLMClient 复述最后一条用户消息
+type echoLLMClient struct {
+	llm.Client
+}
+
+func (echoLLMClient) ChatCompletion(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: "你说的是：" + last.Content}}}}, nil
+}
+
+// recordingTTSClient 记录合成的文本
+type recordingTTSClient struct {
+	tts.TTSInterface
+	texts *[]string
+	err   error
+}
+
+func (c recordingTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	*c.texts = append(*c.texts, text)
+	return []byte("RIFF"), nil
+}
+
+// writeFixture 写出一段 44.1kHz 的录音，模拟来自其他设备的文件
+func writeFixture(t *testing.T) string {
+	t.Helper()
+	samples := make([]float32, 44100/2)
+	for i := range samples {
+		samples[i] = 0.3 * float32(math.Sin(2*math.Pi*220*float64(i)/44100))
+	}
+	path := filepath.Join(t.TempDir(), "recording_20240101_120000.wav")
+	if err := audio.SaveToWAV(path, samples, 44100); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestProcessFile(t *testing.T) {
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+
+	var asrRate int
+	var spoken []string
+	va := &VoiceAssistant{
+		config:    config,
+		ctx:       context.Background(),
+		asrClient: replayASRClient{text: "今天天气怎么样", sampleRate: &asrRate},
+		llmClient: echoLLMClient{},
+		ttsClient: recordingTTSClient{texts: &spoken},
+		ttsFormat: tts.FormatWAV,
+	}
+
+	transcript, reply, err := va.ProcessFile(context.Background(), writeFixture(t))
+	if err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	if transcript != "今天天气怎么样" || reply != "你说的是：今天天气怎么样" {
+		t.Errorf("Unexpected results: %q, %q", transcript, reply)
+	}
+	// 录音重采样到识别使用的采样率
+	if asrRate != audio.GetTargetSampleRate() {
+		t.Errorf("Expected ASR input at %d Hz, got %d", audio.GetTargetSampleRate(), asrRate)
+	}
+	if len(spoken) != 1 || spoken[0] != reply {
+		t.Errorf("Expected the reply to be synthesized, got %v", spoken)
+	}
+	if len(va.conversationHistory) != 2 {
+		t.Errorf("Expected the exchange in the conversation history, got %d messages", len(va.conversationHistory))
+	}
+}
+
+func TestProcessFileErrors(t *testing.T) {
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+	var asrRate int
+	var spoken []string
+	va := &VoiceAssistant{
+		config:    config,
+		ctx:       context.Background(),
+		asrClient: replayASRClient{sampleRate: &asrRate},
+		llmClient: echoLLMClient{},
+		ttsClient: recordingTTSClient{texts: &spoken},
+	}
+
+	if _, _, err := va.ProcessFile(context.Background(), filepath.Join(t.TempDir(), "missing.wav")); err == nil {
+		t.Error("Expected error for a missing recording")
+	}
+
+	// 识别结果为空时不调用 LLM
+	fixture := writeFixture(t)
+	if _, _, err := va.ProcessFile(context.Background(), fixture); err == nil || !strings.Contains(err.Error(), "识别结果为空") {
+		t.Errorf("Expected empty transcript error, got %v", err)
+	}
+	if len(va.conversationHistory) != 0 {
+		t.Error("Expected no LLM call for an empty transcript")
+	}
+
+	// TTS 失败时仍返回已得到的识别文本和回复
+	va.asrClient = replayASRClient{text: "你好", sampleRate: &asrRate}
+	va.ttsClient = recordingTTSClient{texts: &spoken, err: errors.New("quota exceeded")}
+	transcript, reply, err := va.ProcessFile(context.Background(), fixture)
+	if err == nil || transcript != "你好" || reply == "" {
+		t.Errorf("Expected partial results with the TTS error, got %q, %q, %v", transcript, reply, err)
+	}
+}
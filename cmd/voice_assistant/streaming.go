@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/metrics"
+	"audio-assistant/internal/state"
+	"audio-assistant/internal/tts"
+)
+
+// streamPrefetchSentences 播放当前句时最多预先合成的句子数
+const streamPrefetchSentences = 2
+
+var errStreamingUnsupported = errors.New("LLM 客户端不支持流式输出")
+
+// speechQueue 流式播放使用的输出队列，*audio.AudioOutput 满足该接口
+type speechQueue interface {
+	Enqueue(samples []float32) error
+	Drain(ctx context.Context) error
+}
+
+// supportsStreaming 判断 LLM 客户端（包括熔断包装的客户端）是否支持流式输出
+func supportsStreaming(client llm.Client) bool {
+	if wrapped, ok := client.(breakerLLMClient); ok {
+		client = wrapped.Client
+	}
+	_, ok := client.(llm.StreamingClient)
+	return ok
+}
+
+// beginPlayback 创建可被 handleInterrupt 取消的播放上下文，返回的函数结束本次播放
+func (va *VoiceAssistant) beginPlayback(parent context.Context) (context.Context, func()) {
+	va.mu.Lock()
+	va.playbackCtx, va.playbackCancel = context.WithCancel(parent)
+	playCtx := va.playbackCtx
+	va.mu.Unlock()
+
+	return playCtx, func() {
+		va.mu.Lock()
+		if va.playbackCancel != nil {
+			va.playbackCancel()
+			va.playbackCancel = nil
+			va.playbackCtx = nil
+		}
+		va.mu.Unlock()
+	}
+}
+
+// replyStreaming 以流式方式请求 LLM，边生成边按句合成播放，返回完整（被打断时为已生成部分的）回复
+// LLM 请求和播放共用播放上下文，打断时两者一起停止
+func (va *VoiceAssistant) replyStreaming(userText string) (string, error) {
+	streamer, ok := va.llmClient.(llm.StreamingClient)
+	if !ok {
+		return "", errStreamingUnsupported
+	}
+
+	va.mu.Lock()
+	va.conversationHistory = append(va.conversationHistory, llm.Message{Role: "user", Content: userText})
+	messages := append([]llm.Message{{Role: "system", Content: va.systemPrompt()}}, va.conversationHistory...)
+	va.mu.Unlock()
+
+	playCtx, done := va.beginPlayback(va.ctx)
+	defer done()
+
+	start := time.Now()
+	deltas, llmErrs := streamer.ChatCompletionStream(playCtx, &llm.ChatRequest{
+		Model:       va.config.LLMModel,
+		Messages:    messages,
+		Temperature: va.config.LLMTemperature,
+		MaxTokens:   500,
+	})
+
+	response, err := va.speakStreaming(playCtx, deltas)
+	llmErr := <-llmErrs
+	va.observeStage(metrics.StageLLM, start, llmErr)
+
+	if response != "" {
+		va.mu.Lock()
+		va.conversationHistory = append(va.conversationHistory, llm.Message{Role: "assistant", Content: response})
+		va.conversationHistory = trimHistory(va.conversationHistory, va.config.MaxHistoryMessages)
+		va.mu.Unlock()
+	}
+
+	if llmErr != nil && !errors.Is(llmErr, context.Canceled) {
+		return response, fmt.Errorf("LLM处理失败: %w", llmErr)
+	}
+	return response, err
+}
+
+// speakStreaming 把 textChan 中的增量文本按句合成并排入音频输出，下一句在当前句播放时合成
+// textChan 的生产者应使用同一个 ctx，ctx 取消（打断）时立即停止播放并返回 context.Canceled
+// 返回从 textChan 收到的全部文本
+func (va *VoiceAssistant) speakStreaming(ctx context.Context, textChan <-chan string) (string, error) {
+	va.stateManager.SetState(state.StateSpeaking)
+	defer va.stateManager.SetState(state.StateIdle)
+
+	text, err := va.streamSpeech(ctx, textChan, va.audioOutput, va.audioOutput.GetSampleRate())
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("🛑 音频播放被打断")
+	} else if err == nil {
+		fmt.Println("🔊 语音播放完成")
+	}
+	return text, err
+}
+
+// streamSpeech 是 speakStreaming 的流水线：分句、合成、排队播放各在一个环节中进行
+// 任一环节失败或 ctx 取消时整条流水线停止，已排队的音频由 Drain 在取消的上下文下清空
+func (va *VoiceAssistant) streamSpeech(ctx context.Context, textChan <-chan string, queue speechQueue, sampleRate int) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sentences := make(chan string, streamPrefetchSentences)
+	chunks := make(chan []float32, streamPrefetchSentences)
+	var text strings.Builder
+	var synthErr error
+	var wg sync.WaitGroup
+
+	// 分句：累积增量文本，完整的句子交给合成，最后一段等更多文本到达再判断
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(sentences)
+
+		send := func(sentence string) bool {
+			if sentence = strings.TrimSpace(sentence); sentence == "" {
+				return true
+			}
+			select {
+			case sentences <- sentence:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var pending string
+		for {
+			select {
+			case delta, ok := <-textChan:
+				if !ok {
+					send(pending)
+					return
+				}
+				text.WriteString(delta)
+				parts := tts.SplitSentences(pending + delta)
+				if len(parts) == 0 {
+					continue
+				}
+				for _, sentence := range parts[:len(parts)-1] {
+					if !send(sentence) {
+						return
+					}
+				}
+				pending = parts[len(parts)-1]
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 合成：逐句合成并解码为输出采样率的样本
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(chunks)
+
+		for sentence := range sentences {
+			start := time.Now()
+			audioData, err := va.synthesize(ctx, sentence)
+			va.observeStage(metrics.StageTTS, start, err)
+			var samples []float32
+			if err == nil {
+				samples, err = decodeSpeech(audioData, sampleRate)
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					synthErr = fmt.Errorf("合成 %q 失败: %w", sentence, err)
+				}
+				cancel()
+				return
+			}
+
+			select {
+			case chunks <- samples:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 播放：合成好的句子直接追加到播放队列，句子之间没有停顿
+	var err error
+	for samples := range chunks {
+		if err = queue.Enqueue(samples); err != nil {
+			err = fmt.Errorf("播放音频失败: %w", err)
+			cancel()
+			break
+		}
+	}
+	// 出错时 ctx 已取消，分句和合成会很快退出，等待它们结束后再读取文本
+	wg.Wait()
+
+	if err == nil {
+		err = synthErr
+	}
+	// 取消的上下文下 Drain 会停止播放并清空队列
+	if drainErr := queue.Drain(ctx); err == nil {
+		err = drainErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return text.String(), err
+}
+
+// decodeSpeech 解码 TTS 音频并重采样到输出采样率
+func decodeSpeech(audioData []byte, sampleRate int) ([]float32, error) {
+	samples, sourceRate, err := audio.NewAudioDecoder().DecodeAudioData(audioData)
+	if err != nil {
+		return nil, fmt.Errorf("解码音频失败: %w", err)
+	}
+	if sourceRate != sampleRate {
+		samples = audio.ResampleLinear(samples, sourceRate, sampleRate)
+	}
+	return samples, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/breaker"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/tts"
+)
+
+// sentenceTTSClient 每个字合成 10 个样本，便于从样本数还原句子
+type sentenceTTSClient struct {
+	tts.TTSInterface
+	mu    sync.Mutex
+	texts []string
+	fail  string // 合成该句时返回错误
+}
+
+func (c *sentenceTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	c.mu.Lock()
+	c.texts = append(c.texts, text)
+	c.mu.Unlock()
+	if text == c.fail {
+		return nil, errors.New("quota exceeded")
+	}
+	return audio.EncodeWAV(make([]float32, utf8.RuneCountInString(text)*10), 16000)
+}
+
+// fakeQueue 记录排队的样本数和 Drain 调用
+type fakeQueue struct {
+	mu       sync.Mutex
+	events   []string
+	lengths  []int
+	enqueued chan struct{}
+}
+
+func (q *fakeQueue) Enqueue(samples []float32) error {
+	q.mu.Lock()
+	q.events = append(q.events, "enqueue")
+	q.lengths = append(q.lengths, len(samples))
+	q.mu.Unlock()
+	if q.enqueued != nil {
+		q.enqueued <- struct{}{}
+	}
+	return nil
+}
+
+func (q *fakeQueue) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if ctx.Err() != nil {
+		q.events = append(q.events, "stop")
+		return ctx.Err()
+	}
+	q.events = append(q.events, "drain")
+	return nil
+}
+
+// tokens 把文本按 n 个字切成增量，模拟 LLM 流式输出
+func tokens(ctx context.Context, text string, n int) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		runes := []rune(text)
+		for i := 0; i < len(runes); i += n {
+			end := min(i+n, len(runes))
+			select {
+			case out <- string(runes[i:end]):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func newStreamingAssistant(client tts.TTSInterface) *VoiceAssistant {
+	return &VoiceAssistant{config: getDefaultConfig(), ttsClient: client, ttsFormat: tts.FormatWAV}
+}
+
+func TestStreamSpeech(t *testing.T) {
+	client := &sentenceTTSClient{}
+	va := newStreamingAssistant(client)
+	queue := &fakeQueue{}
+
+	reply := "今天天气晴朗。气温 25 度，适合出门！要带伞吗？不用"
+	text, err := va.streamSpeech(context.Background(), tokens(context.Background(), reply, 3), queue, 16000)
+	if err != nil {
+		t.Fatalf("streamSpeech failed: %v", err)
+	}
+	if text != reply {
+		t.Errorf("Expected the full reply, got %q", text)
+	}
+
+	// 按句合成，最后不完整的一段在流结束时合成
+	expected := []string{"今天天气晴朗。", "气温 25 度，适合出门！", "要带伞吗？", "不用"}
+	if strings.Join(client.texts, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected sentences %q, got %q", expected, client.texts)
+	}
+
+	// 所有句子按顺序排入同一个队列，只在最后 Drain 一次，句间不停顿
+	if len(queue.lengths) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %v", len(expected), queue.lengths)
+	}
+	for i, sentence := range expected {
+		if queue.lengths[i] != utf8.RuneCountInString(sentence)*10 {
+			t.Errorf("Chunk %d: expected audio for %q, got %d samples", i, sentence, queue.lengths[i])
+		}
+	}
+	if events := strings.Join(queue.events, ","); events != "enqueue,enqueue,enqueue,enqueue,drain" {
+		t.Errorf("Expected one drain after all enqueues, got %s", events)
+	}
+}
+
+func TestStreamSpeechResample(t *testing.T) {
+	queue := &fakeQueue{}
+	va := newStreamingAssistant(&sentenceTTSClient{})
+
+	// 16kHz 的合成结果重采样到 24kHz 输出
+	if _, err := va.streamSpeech(context.Background(), tokens(context.Background(), "你好。", 10), queue, 24000); err != nil {
+		t.Fatalf("streamSpeech failed: %v", err)
+	}
+	if len(queue.lengths) != 1 || queue.lengths[0] != 45 {
+		t.Errorf("Expected 45 samples at 24kHz, got %v", queue.lengths)
+	}
+}
+
+func TestStreamSpeechInterrupt(t *testing.T) {
+	client := &sentenceTTSClient{}
+	va := newStreamingAssistant(client)
+	queue := &fakeQueue{enqueued: make(chan struct{}, 1)}
+
+	// LLM 还在生成时用户打断
+	ctx, cancel := context.WithCancel(context.Background())
+	textChan := make(chan string)
+	go func() {
+		textChan <- "第一句。"
+		textChan <- "第二"
+		<-ctx.Done()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := va.streamSpeech(ctx, textChan, queue, 16000)
+		done <- err
+	}()
+
+	<-queue.enqueued
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected streamSpeech to stop after the interrupt")
+	}
+
+	// 已排队的音频被停止，未完成的句子不再合成
+	if events := strings.Join(queue.events, ","); events != "enqueue,stop" {
+		t.Errorf("Expected playback stopped after the first sentence, got %s", events)
+	}
+	if len(client.texts) != 1 {
+		t.Errorf("Expected only the first sentence synthesized, got %q", client.texts)
+	}
+}
+
+func TestStreamSpeechSynthesisError(t *testing.T) {
+	client := &sentenceTTSClient{fail: "第二句。"}
+	va := newStreamingAssistant(client)
+	queue := &fakeQueue{}
+
+	_, err := va.streamSpeech(context.Background(), tokens(context.Background(), "第一句。第二句。第三句。", 2), queue, 16000)
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("Expected the synthesis error, got %v", err)
+	}
+	if events := strings.Join(queue.events, ","); events != "enqueue,stop" {
+		t.Errorf("Expected the first sentence played then stopped, got %s", events)
+	}
+}
+
+// streamingLLMClient 按字流式返回固定回复
+type streamingLLMClient struct {
+	llm.Client
+	reply string
+}
+
+func (c streamingLLMClient) ChatCompletionStream(ctx context.Context, req *llm.ChatRequest) (<-chan string, <-chan error) {
+	errs := make(chan error)
+	close(errs)
+	return tokens(ctx, c.reply, 1), errs
+}
+
+func TestBreakerLLMClientStreaming(t *testing.T) {
+	b := breaker.New(breaker.Config{FailureThreshold: 1, Cooldown: time.Minute})
+	wrapped := breakerLLMClient{Client: streamingLLMClient{reply: "你好。"}, breaker: b}
+	if !supportsStreaming(wrapped) || supportsStreaming(breakerLLMClient{Client: echoLLMClient{}, breaker: b}) {
+		t.Fatal("Expected streaming support to follow the wrapped client")
+	}
+
+	deltas, errs := wrapped.ChatCompletionStream(context.Background(), &llm.ChatRequest{})
+	var reply strings.Builder
+	for delta := range deltas {
+		reply.WriteString(delta)
+	}
+	if err := <-errs; err != nil || reply.String() != "你好。" {
+		t.Errorf("Expected the streamed reply, got %q, %v", reply.String(), err)
+	}
+
+	_, errs = breakerLLMClient{Client: echoLLMClient{}, breaker: b}.ChatCompletionStream(context.Background(), &llm.ChatRequest{})
+	if err := <-errs; !errors.Is(err, errStreamingUnsupported) {
+		t.Errorf("Expected unsupported streaming error, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// wakeWordGate 唤醒词门控：休眠时只处理包含唤醒词的语音，唤醒后在活跃时长内无需再次唤醒
+// 每段录音识别后的文本就是检测窗口，识别结果交给 Admit 判断
+type wakeWordGate struct {
+	mu         sync.Mutex
+	phrase     []rune        // 规范化后的唤醒词
+	active     time.Duration // 唤醒后保持活跃的时长，每次交互后顺延
+	awakeUntil time.Time
+	engaged    bool // Admit 放行了一次交互，等待 Touch 在回复结束后重新计时
+}
+
+// newWakeWordGate 创建唤醒词门控，active 为 0 时每句话都需要唤醒词
+func newWakeWordGate(phrase string, active time.Duration) *wakeWordGate {
+	normalized, _ := normalizeWakeText(phrase)
+	return &wakeWordGate{phrase: normalized, active: active}
+}
+
+// Awake 返回当前是否处于唤醒状态
+func (g *wakeWordGate) Awake(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return now.Before(g.awakeUntil)
+}
+
+// Admit 判断识别文本是否放行，返回去掉唤醒词后的指令
+// 休眠且不含唤醒词时 ok 为 false；只说了唤醒词时 ok 为 true、command 为空
+func (g *wakeWordGate) Admit(text string, now time.Time) (command string, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if end, found := g.find(text); found {
+		g.awakeUntil = now.Add(g.active)
+		g.engaged = true
+		return strings.TrimLeftFunc(text[end:], isWakeSeparator), true
+	}
+	if now.Before(g.awakeUntil) {
+		g.awakeUntil = now.Add(g.active)
+		g.engaged = true
+		return text, true
+	}
+	return "", false
+}
+
+// Touch 从 now 起重新计算活跃时长（回复播放完成后调用）
+// 只对 Admit 放行的交互生效，回复比活跃时长更久时也不会因此休眠；未放行或已调用 Sleep 时不会唤醒
+func (g *wakeWordGate) Touch(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.engaged {
+		g.awakeUntil = now.Add(g.active)
+		g.engaged = false
+	}
+}
+
+// Sleep 立即回到休眠状态
+func (g *wakeWordGate) Sleep() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.awakeUntil = time.Time{}
+	g.engaged = false
+}
+
+// find 在 text 中查找唤醒词，忽略大小写、空白和标点，返回唤醒词结束处在 text 中的字节偏移
+func (g *wakeWordGate) find(text string) (int, bool) {
+	if len(g.phrase) == 0 {
+		return 0, false
+	}
+
+	normalized, offsets := normalizeWakeText(text)
+	for i := 0; i+len(g.phrase) <= len(normalized); i++ {
+		if string(normalized[i:i+len(g.phrase)]) == string(g.phrase) {
+			return offsets[i+len(g.phrase)-1], true
+		}
+	}
+	return 0, false
+}
+
+// normalizeWakeText 去掉空白和标点并转为小写，offsets[i] 为第 i 个字符结束处在原文中的字节偏移
+func normalizeWakeText(text string) ([]rune, []int) {
+	var runes []rune
+	var offsets []int
+	for i, r := range text {
+		if isWakeSeparator(r) {
+			continue
+		}
+		runes = append(runes, unicode.ToLower(r))
+		offsets = append(offsets, i+len(string(r)))
+	}
+	return runes, offsets
+}
+
+// isWakeSeparator 判断字符是否在匹配唤醒词时被忽略
+func isWakeSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/tts"
+)
+
+func TestWakeWordGate(t *testing.T) {
+	gate := newWakeWordGate("你好助手", 10*time.Second)
+	now := time.Now()
+
+	// 休眠时不含唤醒词的语音被忽略
+	if _, ok := gate.Admit("今天天气怎么样", now); ok {
+		t.Error("Expected speech without the wake word to be ignored while asleep")
+	}
+	if gate.Awake(now) {
+		t.Error("Expected gate to stay asleep")
+	}
+
+	// 唤醒词和指令在同一句话里，标点和空格不影响匹配
+	command, ok := gate.Admit("嗯，你好，助手！ 今天天气怎么样", now)
+	if !ok || command != "今天天气怎么样" {
+		t.Errorf("Expected command after the wake word, got %q, %v", command, ok)
+	}
+
+	// 活跃期内无需唤醒词，每次交互顺延
+	now = now.Add(8 * time.Second)
+	if command, ok := gate.Admit("明天呢", now); !ok || command != "明天呢" {
+		t.Errorf("Expected follow-up to pass while awake, got %q, %v", command, ok)
+	}
+	now = now.Add(8 * time.Second)
+	if !gate.Awake(now) {
+		t.Error("Expected interaction to extend the active window")
+	}
+
+	// 超时后回到休眠
+	now = now.Add(3 * time.Second)
+	if _, ok := gate.Admit("明天呢", now); ok {
+		t.Error("Expected gate to fall asleep after the active window")
+	}
+
+	// 只说唤醒词时唤醒但没有指令
+	if command, ok := gate.Admit("你好助手。", now); !ok || command != "" {
+		t.Errorf("Expected wake word alone to wake with an empty command, got %q, %v", command, ok)
+	}
+	gate.Sleep()
+	if gate.Awake(now) {
+		t.Error("Expected Sleep to end the active window")
+	}
+}
+
+func TestWakeWordGateTouch(t *testing.T) {
+	gate := newWakeWordGate("Hey Assistant", 5*time.Second)
+	now := time.Now()
+
+	// 休眠时 Touch 不会唤醒
+	gate.Touch(now)
+	if gate.Awake(now) {
+		t.Error("Expected Touch not to wake a sleeping gate")
+	}
+
+	// 英文唤醒词忽略大小写
+	if command, ok := gate.Admit("hey, assistant what time is it", now); !ok || command != "what time is it" {
+		t.Errorf("Expected case-insensitive match, got %q, %v", command, ok)
+	}
+
+	// 回复播放较久时从播放结束起重新计时
+	gate.Touch(now.Add(4 * time.Second))
+	if !gate.Awake(now.Add(8 * time.Second)) {
+		t.Error("Expected Touch to restart the active window")
+	}
+
+	// 回复比活跃时长更久时，播放结束后仍从结束时刻计时
+	if _, ok := gate.Admit("hey assistant tell me a story", now); !ok {
+		t.Fatal("Expected the wake word to be admitted")
+	}
+	gate.Touch(now.Add(20 * time.Second))
+	if !gate.Awake(now.Add(24 * time.Second)) {
+		t.Error("Expected a reply longer than the active window to keep the gate awake")
+	}
+
+	// Sleep 之后的 Touch 不会重新唤醒
+	gate.Admit("hey assistant stop", now)
+	gate.Sleep()
+	gate.Touch(now)
+	if gate.Awake(now) {
+		t.Error("Expected Touch after Sleep not to wake the gate")
+	}
+
+	// 活跃时长为 0 时每句话都需要唤醒词
+	strict := newWakeWordGate("你好助手", 0)
+	strict.Admit("你好助手 开灯", now)
+	if _, ok := strict.Admit("关灯", now); ok {
+		t.Error("Expected every utterance to need the wake word with no active window")
+	}
+}
+
+// wakeASRClient 识别结果固定为 text
+type wakeASRClient struct {
+	asr.ASRInterface
+	text string
+}
+
+func (c wakeASRClient) TranscribeFile(ctx context.Context, path string, req *asr.TranscribeRequest) (*asr.TranscribeResponse, error) {
+	return &asr.TranscribeResponse{Text: c.text}, nil
+}
+
+// slowTTSClient 等待 delay 后返回 err，模拟较长的回复播放
+type slowTTSClient struct {
+	tts.TTSInterface
+	delay time.Duration
+}
+
+func (c slowTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	time.Sleep(c.delay)
+	return nil, errors.New("synthesis unavailable")
+}
+
+func TestWakeWordWindowFromPlaybackEnd(t *testing.T) {
+	config := getDefaultConfig()
+	config.TempDir = t.TempDir()
+	config.ClarifyEnabled = false
+	active := 200 * time.Millisecond
+	va := &VoiceAssistant{
+		config:       config,
+		ctx:          context.Background(),
+		asrClient:    wakeASRClient{text: "你好助手 讲个故事"},
+		llmClient:    echoLLMClient{},
+		ttsClient:    slowTTSClient{delay: 300 * time.Millisecond},
+		stateManager: newTestStateManager(t),
+		wakeWord:     newWakeWordGate(config.WakeWord, active),
+	}
+
+	// 回复（连同失败提示）播放超过活跃时长
+	start := time.Now()
+	va.processRecording(toneChunks(config, 16, 0.3))
+	va.processing.Wait()
+	end := time.Now()
+	if end.Sub(start) <= active {
+		t.Fatalf("Expected the reply to outlast the active window, took %v", end.Sub(start))
+	}
+
+	// 活跃时长从播放结束起计算，而不是从放行时
+	if !va.wakeWord.Awake(end) {
+		t.Error("Expected the gate to stay awake after a reply longer than the active window")
+	}
+	if va.wakeWord.Awake(end.Add(active)) {
+		t.Error("Expected the active window to end one window after playback")
+	}
+}
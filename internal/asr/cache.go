@@ -0,0 +1,66 @@
+package asr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// resultCache stores transcription results on disk keyed by audio hash
+type resultCache struct {
+	dir string
+}
+
+// newResultCache creates a disk-backed result cache in dir
+func newResultCache(dir string) (*resultCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+	return &resultCache{dir: dir}, nil
+}
+
+// key builds a cache key from the audio bytes and the request parameters that affect the result
+func (c *resultCache) key(audioData []byte, req *TranscribeRequest) string {
+	hash := sha256.New()
+	hash.Write(audioData)
+	fmt.Fprintf(hash, "\x00%s\x00%s\x00%s\x00%.2f\x00%s\x00%t",
+		req.Model, req.Language, req.Prompt, req.Temperature, req.Format, req.WordTimestamps)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// path returns the file path for a cache key
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// get returns the cached response for key, if any
+func (c *resultCache) get(key string) (*TranscribeResponse, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var resp TranscribeResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// put stores a response under key
+func (c *resultCache) put(key string, resp *TranscribeResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached response: %w", err)
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated entry
+	tempPath := c.path(key) + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return os.Rename(tempPath, c.path(key))
+}
@@ -0,0 +1,150 @@
+package asr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResultCacheGetPut(t *testing.T) {
+	cache, err := newResultCache(filepath.Join(t.TempDir(), "nested", "cache"))
+	if err != nil {
+		t.Fatalf("newResultCache failed: %v", err)
+	}
+
+	key := cache.key([]byte("RIFF audio"), &TranscribeRequest{Model: "whisper-1", Language: "zh"})
+	if _, ok := cache.get(key); ok {
+		t.Fatal("Expected a miss before anything is stored")
+	}
+
+	stored := &TranscribeResponse{Text: "你好", Language: "zh", Duration: 1.5}
+	if err := cache.put(key, stored); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	cached, ok := cache.get(key)
+	if !ok {
+		t.Fatal("Expected a hit after put")
+	}
+	if cached.Text != stored.Text || cached.Language != stored.Language || cached.Duration != stored.Duration {
+		t.Errorf("Expected %+v from the cache, got %+v", stored, cached)
+	}
+
+	// A corrupt entry is a miss rather than an error
+	if err := os.WriteFile(cache.path(key), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt entry: %v", err)
+	}
+	if _, ok := cache.get(key); ok {
+		t.Error("Expected a corrupt entry to be a miss")
+	}
+}
+
+func TestResultCacheKey(t *testing.T) {
+	cache := &resultCache{dir: t.TempDir()}
+	audioData := []byte("RIFF audio")
+	base := TranscribeRequest{Model: "whisper-1", Language: "zh", Format: "text"}
+
+	if cache.key(audioData, &base) != cache.key(audioData, &base) {
+		t.Fatal("Expected the same audio and options to give the same key")
+	}
+
+	variants := map[string]TranscribeRequest{
+		"language":        {Model: "whisper-1", Language: "en", Format: "text"},
+		"model":           {Model: "gpt-4o-transcribe", Language: "zh", Format: "text"},
+		"prompt":          {Model: "whisper-1", Language: "zh", Format: "text", Prompt: "音频助手"},
+		"format":          {Model: "whisper-1", Language: "zh", Format: "verbose_json"},
+		"temperature":     {Model: "whisper-1", Language: "zh", Format: "text", Temperature: 0.5},
+		"word timestamps": {Model: "whisper-1", Language: "zh", Format: "text", WordTimestamps: true},
+	}
+	for name, req := range variants {
+		req := req
+		if cache.key(audioData, &req) == cache.key(audioData, &base) {
+			t.Errorf("Expected a different %s to give a different key", name)
+		}
+	}
+	if cache.key([]byte("other audio"), &base) == cache.key(audioData, &base) {
+		t.Error("Expected different audio to give a different key")
+	}
+}
+
+// newCountingTranscriptionServer replies with the request language and counts the requests
+func newCountingTranscriptionServer(t *testing.T) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		fmt.Fprintf(w, "%s-%d", r.FormValue("language"), n)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newCacheTestService(t *testing.T, serverURL, cacheDir string) *Service {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = serverURL
+	config.TempDir = t.TempDir()
+	config.CacheDir = cacheDir
+	config.Language = "zh"
+
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create ASR service: %v", err)
+	}
+	service.isRunning = true
+	return service
+}
+
+func TestServiceResultCache(t *testing.T) {
+	server, calls := newCountingTranscriptionServer(t)
+	service := newCacheTestService(t, server.URL, filepath.Join(t.TempDir(), "cache"))
+	path := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(path, []byte("RIFF audio"), 0644); err != nil {
+		t.Fatalf("Failed to write audio: %v", err)
+	}
+
+	first, err := service.TranscribeFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeFile failed: %v", err)
+	}
+	second, err := service.TranscribeFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeFile failed: %v", err)
+	}
+	if first != "zh-1" || second != first || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("Expected the second call served from the cache, got %q, %q after %d requests", first, second, *calls)
+	}
+
+	// Other options are a miss and get their own entry
+	english, err := service.TranscribeFileWithOptions(context.Background(), path, TranscribeRequest{Language: "en"})
+	if err != nil {
+		t.Fatalf("TranscribeFileWithOptions failed: %v", err)
+	}
+	if english != "en-2" || atomic.LoadInt32(calls) != 2 {
+		t.Errorf("Expected a different language to reach the server, got %q after %d requests", english, *calls)
+	}
+}
+
+func TestServiceResultCacheDisabled(t *testing.T) {
+	server, calls := newCountingTranscriptionServer(t)
+	service := newCacheTestService(t, server.URL, "")
+	if service.cache != nil {
+		t.Fatal("Expected an empty CacheDir to disable the cache")
+	}
+
+	path := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(path, []byte("RIFF audio"), 0644); err != nil {
+		t.Fatalf("Failed to write audio: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := service.TranscribeFile(context.Background(), path); err != nil {
+			t.Fatalf("TranscribeFile failed: %v", err)
+		}
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("Expected every call to reach the server without a cache, got %d requests", *calls)
+	}
+}
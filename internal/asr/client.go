@@ -6,14 +6,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"audio-assistant/internal/retry"
 )
 
+// ASRInterface is implemented by speech recognition backends the assistant can switch between
+type ASRInterface interface {
+	TranscribeFile(ctx context.Context, audioFilePath string, req *TranscribeRequest) (*TranscribeResponse, error)
+	ValidateAPIKey(ctx context.Context) error
+}
+
 // Client represents an ASR client for OpenAI Whisper API
 type Client struct {
 	apiKey     string
@@ -28,6 +37,8 @@ type TranscribeRequest struct {
 	Prompt      string  `json:"prompt,omitempty"`          // Optional text to guide the model's style
 	Temperature float32 `json:"temperature,omitempty"`     // Sampling temperature (0-1)
 	Format      string  `json:"response_format,omitempty"` // json, text, srt, verbose_json, vtt
+	// WordTimestamps requests per-word timing, only available with verbose_json
+	WordTimestamps bool `json:"-"`
 }
 
 // TranscribeResponse represents the response from transcription
@@ -36,6 +47,14 @@ type TranscribeResponse struct {
 	Language string    `json:"language,omitempty"`
 	Duration float64   `json:"duration,omitempty"`
 	Segments []Segment `json:"segments,omitempty"`
+	Words    []Word    `json:"words,omitempty"` // Only present when word timestamps were requested
+}
+
+// Word represents a single recognized word with timing
+type Word struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
 }
 
 // Segment represents a transcription segment with timing
@@ -52,6 +71,27 @@ type Segment struct {
 	NoSpeechProb     float64 `json:"no_speech_prob"`
 }
 
+// Confidence estimates transcription confidence (0-1) from segment log probabilities
+// Each segment contributes exp(AvgLogprob) * (1 - NoSpeechProb), weighted by its duration
+// Returns 1 when no segments are available (e.g. text response format)
+func (r *TranscribeResponse) Confidence() float64 {
+	if len(r.Segments) == 0 {
+		return 1
+	}
+
+	var weighted, totalDuration float64
+	for _, seg := range r.Segments {
+		duration := seg.End - seg.Start
+		if duration <= 0 {
+			duration = 1
+		}
+		weighted += math.Exp(seg.AvgLogprob) * (1 - seg.NoSpeechProb) * duration
+		totalDuration += duration
+	}
+
+	return weighted / totalDuration
+}
+
 // ErrorResponse represents an error response from the API
 type ErrorResponse struct {
 	Error struct {
@@ -61,28 +101,83 @@ type ErrorResponse struct {
 	} `json:"error"`
 }
 
+// Transcription models
+const (
+	ModelWhisper1            = "whisper-1"
+	ModelGPT4oTranscribe     = "gpt-4o-transcribe"
+	ModelGPT4oMiniTranscribe = "gpt-4o-mini-transcribe"
+)
+
+// supportedModels lists the transcription models accepted by the client
+var supportedModels = []string{ModelWhisper1, ModelGPT4oTranscribe, ModelGPT4oMiniTranscribe}
+
+// IsSupportedModel checks whether the model is a known transcription model
+func IsSupportedModel(model string) bool {
+	for _, m := range supportedModels {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsVerboseJSON reports whether the model returns verbose_json with segments
+// The gpt-4o transcribe models only support json and text
+func supportsVerboseJSON(model string) bool {
+	return model == ModelWhisper1
+}
+
+// MaxFileSize is the OpenAI upload limit (25MB)
+const MaxFileSize = 25 * 1024 * 1024
+
+// supportedFormats lists audio file extensions accepted by the transcription API
+var supportedFormats = []string{".mp3", ".mp4", ".mpeg", ".mpga", ".m4a", ".wav", ".webm"}
+
+// IsSupportedFormat checks whether the lower-case file extension (with dot) is accepted by the API
+func IsSupportedFormat(ext string) bool {
+	for _, format := range supportedFormats {
+		if ext == format {
+			return true
+		}
+	}
+	return false
+}
+
 // NewClient creates a new ASR client
 func NewClient(apiKey string) *Client {
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: "https://api.openai.com/v1",
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second, // Longer timeout for audio processing
-		},
+		// Longer timeout for audio processing
+		httpClient: retry.NewClient(60*time.Second, retry.DefaultMaxRetries, retry.DefaultBaseDelay),
 	}
 }
 
 // NewClientWithConfig creates a new ASR client with custom configuration
 func NewClientWithConfig(apiKey, baseURL string, timeout time.Duration) *Client {
 	return &Client{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		httpClient: retry.NewClient(timeout, retry.DefaultMaxRetries, retry.DefaultBaseDelay),
 	}
 }
 
+// SetRetryPolicy sets how transient API failures (429/5xx) are retried
+func (c *Client) SetRetryPolicy(maxRetries int, baseDelay time.Duration) {
+	retry.SetPolicy(c.httpClient, maxRetries, baseDelay)
+}
+
+// SetTransport sends requests through transport (e.g. a proxy or custom TLS config), keeping the retry policy
+// By default http.DefaultTransport is used, which honors HTTP_PROXY and HTTPS_PROXY
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	retry.SetBaseTransport(c.httpClient, transport)
+}
+
+// SetHTTPClient replaces the HTTP client, which is used as is (its own timeout and retries apply)
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
 // TranscribeFile transcribes an audio file to text
 func (c *Client) TranscribeFile(ctx context.Context, audioFilePath string, req *TranscribeRequest) (*TranscribeResponse, error) {
 	// Open the audio file
@@ -99,22 +194,13 @@ func (c *Client) TranscribeFile(ctx context.Context, audioFilePath string, req *
 	}
 
 	// Check file size (OpenAI limit is 25MB)
-	const maxFileSize = 25 * 1024 * 1024 // 25MB
-	if fileInfo.Size() > maxFileSize {
-		return nil, fmt.Errorf("file size %d bytes exceeds maximum allowed size of %d bytes", fileInfo.Size(), maxFileSize)
+	if fileInfo.Size() > MaxFileSize {
+		return nil, fmt.Errorf("file size %d bytes exceeds maximum allowed size of %d bytes", fileInfo.Size(), MaxFileSize)
 	}
 
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(audioFilePath))
-	supportedFormats := []string{".mp3", ".mp4", ".mpeg", ".mpga", ".m4a", ".wav", ".webm"}
-	isSupported := false
-	for _, format := range supportedFormats {
-		if ext == format {
-			isSupported = true
-			break
-		}
-	}
-	if !isSupported {
+	if !IsSupportedFormat(ext) {
 		return nil, fmt.Errorf("unsupported audio format: %s. Supported formats: %v", ext, supportedFormats)
 	}
 
@@ -124,9 +210,8 @@ func (c *Client) TranscribeFile(ctx context.Context, audioFilePath string, req *
 // TranscribeBytes transcribes audio data from bytes to text
 func (c *Client) TranscribeBytes(ctx context.Context, audioData []byte, filename string, req *TranscribeRequest) (*TranscribeResponse, error) {
 	// Check data size
-	const maxFileSize = 25 * 1024 * 1024 // 25MB
-	if len(audioData) > maxFileSize {
-		return nil, fmt.Errorf("data size %d bytes exceeds maximum allowed size of %d bytes", len(audioData), maxFileSize)
+	if len(audioData) > MaxFileSize {
+		return nil, fmt.Errorf("data size %d bytes exceeds maximum allowed size of %d bytes", len(audioData), MaxFileSize)
 	}
 
 	reader := bytes.NewReader(audioData)
@@ -140,10 +225,23 @@ func (c *Client) transcribeReader(ctx context.Context, reader io.Reader, filenam
 		req = &TranscribeRequest{}
 	}
 	if req.Model == "" {
-		req.Model = "whisper-1"
+		req.Model = ModelWhisper1
+	}
+	if !IsSupportedModel(req.Model) {
+		return nil, fmt.Errorf("unsupported model: %s", req.Model)
 	}
-	if req.Format == "" {
-		req.Format = "verbose_json" // Get detailed response with segments
+	if req.WordTimestamps && !supportsVerboseJSON(req.Model) {
+		return nil, fmt.Errorf("word timestamps are not supported by model %s", req.Model)
+	}
+	if req.Format == "" || (req.Format == "verbose_json" && !supportsVerboseJSON(req.Model)) {
+		if supportsVerboseJSON(req.Model) {
+			req.Format = "verbose_json" // Get detailed response with segments
+		} else {
+			req.Format = "json" // Newer models return text only, without segments
+		}
+	}
+	if req.WordTimestamps && req.Format != "verbose_json" {
+		return nil, fmt.Errorf("word timestamps require verbose_json format, got %s", req.Format)
 	}
 
 	// Create multipart form
@@ -190,6 +288,15 @@ func (c *Client) transcribeReader(ctx context.Context, reader io.Reader, filenam
 		}
 	}
 
+	if req.WordTimestamps {
+		// Requesting only words drops segments, ask for both to keep confidence estimation working
+		for _, granularity := range []string{"word", "segment"} {
+			if err := writer.WriteField("timestamp_granularities[]", granularity); err != nil {
+				return nil, fmt.Errorf("failed to write timestamp_granularities field: %w", err)
+			}
+		}
+	}
+
 	writer.Close()
 
 	// Create HTTP request
@@ -219,10 +326,11 @@ func (c *Client) transcribeReader(ctx context.Context, reader io.Reader, filenam
 	if resp.StatusCode != http.StatusOK {
 		var errorResp ErrorResponse
 		if err := json.Unmarshal(body, &errorResp); err != nil {
-			return nil, fmt.Errorf("transcription failed with status %d: %s", resp.StatusCode, string(body))
+			return nil, retry.NewStatusError(resp.StatusCode,
+				fmt.Errorf("transcription failed with status %d: %s", resp.StatusCode, string(body)))
 		}
-		return nil, fmt.Errorf("transcription failed: %s (type: %s, code: %s)",
-			errorResp.Error.Message, errorResp.Error.Type, errorResp.Error.Code)
+		return nil, retry.NewStatusError(resp.StatusCode, fmt.Errorf("transcription failed: %s (type: %s, code: %s)",
+			errorResp.Error.Message, errorResp.Error.Type, errorResp.Error.Code))
 	}
 
 	// Parse response based on format
@@ -232,7 +340,7 @@ func (c *Client) transcribeReader(ctx context.Context, reader io.Reader, filenam
 		}, nil
 	}
 
-	// Parse JSON response
+	// Parse JSON response, segments and words are absent for json format and newer models
 	var transcribeResp TranscribeResponse
 	if err := json.Unmarshal(body, &transcribeResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
@@ -244,7 +352,7 @@ func (c *Client) transcribeReader(ctx context.Context, reader io.Reader, filenam
 // TranscribeSimple provides a simple interface for transcription with default settings
 func (c *Client) TranscribeSimple(ctx context.Context, audioFilePath string) (string, error) {
 	req := &TranscribeRequest{
-		Model:  "whisper-1",
+		Model:  ModelWhisper1,
 		Format: "text",
 	}
 
@@ -259,7 +367,7 @@ func (c *Client) TranscribeSimple(ctx context.Context, audioFilePath string) (st
 // TranscribeSimpleBytes provides a simple interface for transcription from bytes
 func (c *Client) TranscribeSimpleBytes(ctx context.Context, audioData []byte, filename string) (string, error) {
 	req := &TranscribeRequest{
-		Model:  "whisper-1",
+		Model:  ModelWhisper1,
 		Format: "text",
 	}
 
@@ -274,7 +382,7 @@ func (c *Client) TranscribeSimpleBytes(ctx context.Context, audioData []byte, fi
 // TranscribeWithLanguage transcribes audio with a specific language hint
 func (c *Client) TranscribeWithLanguage(ctx context.Context, audioFilePath, language string) (string, error) {
 	req := &TranscribeRequest{
-		Model:    "whisper-1",
+		Model:    ModelWhisper1,
 		Language: language,
 		Format:   "text",
 	}
@@ -287,6 +395,13 @@ func (c *Client) TranscribeWithLanguage(ctx context.Context, audioFilePath, lang
 	return strings.TrimSpace(resp.Text), nil
 }
 
+// GetSupportedModels returns the supported transcription models
+func (c *Client) GetSupportedModels() []string {
+	models := make([]string, len(supportedModels))
+	copy(models, supportedModels)
+	return models
+}
+
 // GetSupportedLanguages returns a list of supported language codes
 func (c *Client) GetSupportedLanguages() []string {
 	return []string{
@@ -294,6 +409,39 @@ func (c *Client) GetSupportedLanguages() []string {
 	}
 }
 
+// whisperLanguageNames maps language names returned in verbose_json to ISO-639-1 codes
+var whisperLanguageNames = map[string]string{
+	"chinese":    "zh",
+	"english":    "en",
+	"japanese":   "ja",
+	"korean":     "ko",
+	"french":     "fr",
+	"german":     "de",
+	"spanish":    "es",
+	"russian":    "ru",
+	"italian":    "it",
+	"portuguese": "pt",
+	"arabic":     "ar",
+	"hindi":      "hi",
+	"thai":       "th",
+	"vietnamese": "vi",
+	"indonesian": "id",
+	"turkish":    "tr",
+	"dutch":      "nl",
+	"polish":     "pl",
+	"ukrainian":  "uk",
+}
+
+// NormalizeLanguage converts a detected language ("chinese", "English", "zh") to an ISO-639-1 code
+// Unknown names are returned lowercased
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := whisperLanguageNames[language]; ok {
+		return code
+	}
+	return language
+}
+
 // ValidateAPIKey checks if the API key is valid by making a simple request
 func (c *Client) ValidateAPIKey(ctx context.Context) error {
 	// Create a minimal test request
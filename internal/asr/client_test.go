@@ -2,7 +2,10 @@ package asr
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -209,3 +212,165 @@ func TestASRRequestValidation(t *testing.T) {
 		t.Error("Expected error for unsupported format")
 	}
 }
+
+func TestTranscribeResponseConfidence(t *testing.T) {
+	resp := &TranscribeResponse{Text: "你好"}
+	if c := resp.Confidence(); c != 1 {
+		t.Errorf("Expected confidence 1 without segments, got %f", c)
+	}
+
+	resp.Segments = []Segment{
+		{Start: 0, End: 1, AvgLogprob: 0, NoSpeechProb: 0},
+		{Start: 1, End: 2, AvgLogprob: math.Log(0.5), NoSpeechProb: 0},
+	}
+	if c := resp.Confidence(); math.Abs(c-0.75) > 1e-9 {
+		t.Errorf("Expected confidence 0.75, got %f", c)
+	}
+
+	resp.Segments = []Segment{{Start: 0, End: 1, AvgLogprob: 0, NoSpeechProb: 0.9}}
+	if c := resp.Confidence(); math.Abs(c-0.1) > 1e-9 {
+		t.Errorf("Expected confidence 0.1 for likely non-speech, got %f", c)
+	}
+}
+
+// verboseWordsPayload is a verbose_json response captured with timestamp_granularities word and segment
+const verboseWordsPayload = `{
+  "task": "transcribe",
+  "language": "english",
+  "duration": 1.72,
+  "text": "Hello there.",
+  "words": [
+    {"word": "Hello", "start": 0.0, "end": 0.56},
+    {"word": "there", "start": 0.56, "end": 1.1}
+  ],
+  "segments": [
+    {"id": 0, "seek": 0, "start": 0.0, "end": 1.72, "text": " Hello there.", "tokens": [50364, 2425, 456, 13, 50450],
+     "temperature": 0.0, "avg_logprob": -0.29, "compression_ratio": 0.6, "no_speech_prob": 0.01}
+  ]
+}`
+
+func TestTranscribeWordTimestamps(t *testing.T) {
+	payload := verboseWordsPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		granularities := r.MultipartForm.Value["timestamp_granularities[]"]
+		if len(granularities) != 2 || granularities[0] != "word" || granularities[1] != "segment" {
+			t.Errorf("Unexpected timestamp granularities: %v", granularities)
+		}
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	client := NewClientWithConfig("test-key", server.URL, 5*time.Second)
+	req := &TranscribeRequest{WordTimestamps: true}
+
+	resp, err := client.TranscribeBytes(context.Background(), []byte("fake"), "test.wav", req)
+	if err != nil {
+		t.Fatalf("TranscribeBytes failed: %v", err)
+	}
+	if len(resp.Words) != 2 {
+		t.Fatalf("Expected 2 words, got %d", len(resp.Words))
+	}
+	if resp.Words[1].Word != "there" || resp.Words[1].Start != 0.56 || resp.Words[1].End != 1.1 {
+		t.Errorf("Unexpected word timing: %+v", resp.Words[1])
+	}
+	if len(resp.Segments) != 1 {
+		t.Errorf("Expected segments to be kept, got %d", len(resp.Segments))
+	}
+
+	// The API may omit words, the response should still parse
+	payload = `{"text": "Hello there.", "duration": 1.72, "segments": []}`
+	resp, err = client.TranscribeBytes(context.Background(), []byte("fake"), "test.wav", req)
+	if err != nil {
+		t.Fatalf("TranscribeBytes without words failed: %v", err)
+	}
+	if resp.Text != "Hello there." || len(resp.Words) != 0 {
+		t.Errorf("Unexpected response without words: %+v", resp)
+	}
+
+	// Word timestamps are only available with verbose_json
+	_, err = client.TranscribeBytes(context.Background(), []byte("fake"), "test.wav",
+		&TranscribeRequest{WordTimestamps: true, Format: "text"})
+	if err == nil {
+		t.Error("Expected error for word timestamps with text format")
+	}
+}
+
+func TestTranscribeModelResponseFormats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch model, format := r.FormValue("model"), r.FormValue("response_format"); {
+		case model == ModelWhisper1 && format == "verbose_json":
+			w.Write([]byte(verboseWordsPayload))
+		case model != ModelWhisper1 && format == "json":
+			// Newer models return text and usage only, without segments
+			w.Write([]byte(`{"text": "Hello there.", "usage": {"type": "tokens", "input_tokens": 14, "output_tokens": 4, "total_tokens": 18}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":{"message":"response_format '%s' is not compatible with model '%s'"}}`, format, model)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClientWithConfig("test-key", server.URL, 5*time.Second)
+	ctx := context.Background()
+
+	resp, err := client.TranscribeBytes(ctx, []byte("fake"), "test.wav", &TranscribeRequest{Model: ModelWhisper1})
+	if err != nil {
+		t.Fatalf("Verbose transcription failed: %v", err)
+	}
+	if len(resp.Segments) != 1 || resp.Language != "english" {
+		t.Errorf("Expected verbose response with segments, got %+v", resp)
+	}
+
+	for _, model := range []string{ModelGPT4oTranscribe, ModelGPT4oMiniTranscribe} {
+		// verbose_json is downgraded to json for models without segment output
+		resp, err := client.TranscribeBytes(ctx, []byte("fake"), "test.wav", &TranscribeRequest{Model: model, Format: "verbose_json"})
+		if err != nil {
+			t.Fatalf("Transcription with %s failed: %v", model, err)
+		}
+		if resp.Text != "Hello there." || len(resp.Segments) != 0 {
+			t.Errorf("Unexpected response for %s: %+v", model, resp)
+		}
+		if c := resp.Confidence(); c != 1 {
+			t.Errorf("Expected confidence 1 without segments for %s, got %f", model, c)
+		}
+	}
+
+	if _, err := client.TranscribeBytes(ctx, []byte("fake"), "test.wav", &TranscribeRequest{Model: "whisper-2"}); err == nil {
+		t.Error("Expected error for unsupported model")
+	}
+	if _, err := client.TranscribeBytes(ctx, []byte("fake"), "test.wav",
+		&TranscribeRequest{Model: ModelGPT4oTranscribe, WordTimestamps: true}); err == nil {
+		t.Error("Expected error for word timestamps with gpt-4o-transcribe")
+	}
+}
+
+func TestTranscribeAutoDetectLanguage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		if _, ok := r.MultipartForm.Value["language"]; ok {
+			t.Errorf("Expected no language field for auto detection, got %v", r.MultipartForm.Value["language"])
+		}
+		w.Write([]byte(verboseWordsPayload))
+	}))
+	defer server.Close()
+
+	client := NewClientWithConfig("test-key", server.URL, 5*time.Second)
+	resp, err := client.TranscribeBytes(context.Background(), []byte("fake"), "test.wav", &TranscribeRequest{Language: ""})
+	if err != nil {
+		t.Fatalf("TranscribeBytes failed: %v", err)
+	}
+	if code := NormalizeLanguage(resp.Language); code != "en" {
+		t.Errorf("Expected detected language en, got %q (%q)", code, resp.Language)
+	}
+
+	for name, code := range map[string]string{"Chinese": "zh", "zh": "zh", " japanese ": "ja", "klingon": "klingon"} {
+		if got := NormalizeLanguage(name); got != code {
+			t.Errorf("NormalizeLanguage(%q) = %q, expected %q", name, got, code)
+		}
+	}
+}
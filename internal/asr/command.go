@@ -0,0 +1,175 @@
+package asr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommandASRConfig configures an ASR backend that runs a local whisper.cpp binary
+type CommandASRConfig struct {
+	Command   string        // whisper.cpp binary, whisper-cli in current builds (main in older ones)
+	ModelPath string        // ggml model file passed with -m
+	Language  string        // Default language when the request sets none, "" lets whisper.cpp detect it
+	ExtraArgs []string      // Appended to the generated arguments, e.g. thread count
+	Timeout   time.Duration // Limit for one transcription, 0 relies on the caller's context only
+}
+
+// DefaultCommandASRConfig returns the default whisper.cpp configuration
+func DefaultCommandASRConfig() CommandASRConfig {
+	return CommandASRConfig{
+		Command: "whisper-cli",
+		Timeout: 120 * time.Second,
+	}
+}
+
+// CommandASRClient transcribes audio offline with a local whisper.cpp binary
+// The JSON output file is parsed when the binary writes one, otherwise stdout is used as plain text
+type CommandASRClient struct {
+	config CommandASRConfig
+}
+
+// whisperCppOutput is the JSON file written by whisper.cpp with -oj
+type whisperCppOutput struct {
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"` // Milliseconds
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text string `json:"text"`
+	} `json:"transcription"`
+}
+
+// NewCommandASRClient creates a whisper.cpp ASR client
+func NewCommandASRClient(config CommandASRConfig) (*CommandASRClient, error) {
+	if config.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	return &CommandASRClient{config: config}, nil
+}
+
+// TranscribeFile runs whisper.cpp on a WAV file, the process is killed when ctx is done
+func (c *CommandASRClient) TranscribeFile(ctx context.Context, audioFilePath string, req *TranscribeRequest) (*TranscribeResponse, error) {
+	if _, err := os.Stat(audioFilePath); err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+
+	outputDir, err := os.MkdirTemp("", "whisper_cpp_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+	outputBase := filepath.Join(outputDir, "transcript")
+
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.config.Command, c.args(audioFilePath, outputBase, req)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Stop waiting for output held open by children of a killed command
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("%s interrupted: %w", c.config.Command, ctxErr)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s exited with code %d: %s", c.config.Command, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to run %s: %w", c.config.Command, err)
+	}
+
+	data, err := os.ReadFile(outputBase + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return &TranscribeResponse{Text: strings.TrimSpace(stdout.String())}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return parseWhisperCppOutput(data)
+}
+
+// ValidateAPIKey checks that the binary and model file exist, there is no key to validate
+func (c *CommandASRClient) ValidateAPIKey(ctx context.Context) error {
+	if _, err := exec.LookPath(c.config.Command); err != nil {
+		return fmt.Errorf("ASR command not available: %w", err)
+	}
+	if c.config.ModelPath != "" {
+		if _, err := os.Stat(c.config.ModelPath); err != nil {
+			return fmt.Errorf("ASR model not available: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetConfig returns the client configuration
+func (c *CommandASRClient) GetConfig() CommandASRConfig {
+	return c.config
+}
+
+// args builds the whisper.cpp command line
+func (c *CommandASRClient) args(audioFilePath, outputBase string, req *TranscribeRequest) []string {
+	var args []string
+	if c.config.ModelPath != "" {
+		args = append(args, "-m", c.config.ModelPath)
+	}
+
+	language := c.config.Language
+	if req != nil && req.Language != "" {
+		language = req.Language
+	}
+	if language == "" {
+		language = "auto"
+	}
+	args = append(args, "-l", language)
+
+	if req != nil && req.Prompt != "" {
+		args = append(args, "--prompt", req.Prompt)
+	}
+
+	args = append(args, "-np", "-nt", "-oj", "-of", outputBase)
+	args = append(args, c.config.ExtraArgs...)
+	return append(args, "-f", audioFilePath)
+}
+
+// parseWhisperCppOutput converts whisper.cpp JSON output to a TranscribeResponse
+func parseWhisperCppOutput(data []byte) (*TranscribeResponse, error) {
+	var output whisperCppOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse whisper.cpp output: %w", err)
+	}
+
+	response := &TranscribeResponse{Language: output.Result.Language}
+	var text strings.Builder
+	for i, item := range output.Transcription {
+		// Segment text keeps the leading space whisper.cpp puts between words, so plain concatenation works for every language
+		text.WriteString(item.Text)
+		segment := Segment{
+			ID:    i,
+			Start: float64(item.Offsets.From) / 1000,
+			End:   float64(item.Offsets.To) / 1000,
+			Text:  strings.TrimSpace(item.Text),
+		}
+		response.Segments = append(response.Segments, segment)
+		response.Duration = segment.End
+	}
+	response.Text = strings.TrimSpace(text.String())
+
+	return response, nil
+}
@@ -0,0 +1,116 @@
+package asr
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const whisperCppTranscript = `{
+  "result": {"language": "zh"},
+  "transcription": [
+    {"offsets": {"from": 0, "to": 1200}, "text": "今天天气"},
+    {"offsets": {"from": 1200, "to": 2500}, "text": "不错"}
+  ]
+}`
+
+// stubWhisper writes a shell script standing in for whisper.cpp and returns its path
+// The script records its arguments in args.txt next to it, then runs body
+func stubWhisper(t *testing.T, body string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("Skipping command ASR test: %v", err)
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args.txt")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n" +
+		"while [ $# -gt 0 ]; do [ \"$1\" = -of ] && out=\"$2\"; shift; done\n" + body + "\n"
+	path := filepath.Join(dir, "whisper-cli")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+	return path, argsFile
+}
+
+// wavFile creates an input file for the stub, its content is never decoded
+func wavFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(path, []byte("RIFF"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	return path
+}
+
+func TestCommandASRClient(t *testing.T) {
+	command, argsFile := stubWhisper(t, "cat > \"$out.json\" <<'EOF'\n"+whisperCppTranscript+"\nEOF")
+	client, err := NewCommandASRClient(CommandASRConfig{Command: command, ModelPath: "ggml-base.bin", Language: "en"})
+	if err != nil {
+		t.Fatalf("NewCommandASRClient failed: %v", err)
+	}
+
+	response, err := client.TranscribeFile(context.Background(), wavFile(t), &TranscribeRequest{Language: "zh"})
+	if err != nil {
+		t.Fatalf("TranscribeFile failed: %v", err)
+	}
+	if response.Text != "今天天气不错" || response.Language != "zh" || response.Duration != 2.5 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if len(response.Segments) != 2 || response.Segments[1].Start != 1.2 || response.Segments[1].Text != "不错" {
+		t.Errorf("Unexpected segments: %+v", response.Segments)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "-m ggml-base.bin") || !strings.Contains(string(args), "-l zh") {
+		t.Errorf("Expected model and request language in arguments, got %q", args)
+	}
+
+	// Without a JSON file the standard output is the transcript
+	command, _ = stubWhisper(t, "echo ' hello world '")
+	client, _ = NewCommandASRClient(CommandASRConfig{Command: command})
+	response, err = client.TranscribeFile(context.Background(), wavFile(t), nil)
+	if err != nil || response.Text != "hello world" {
+		t.Errorf("Expected plain text transcript, got %+v, %v", response, err)
+	}
+}
+
+func TestCommandASRClientErrors(t *testing.T) {
+	if _, err := NewCommandASRClient(CommandASRConfig{}); err == nil {
+		t.Error("Expected error for missing command")
+	}
+
+	command, _ := stubWhisper(t, "echo 'failed to load model' >&2; exit 2")
+	client, _ := NewCommandASRClient(CommandASRConfig{Command: command})
+	_, err := client.TranscribeFile(context.Background(), wavFile(t), nil)
+	if err == nil || !strings.Contains(err.Error(), "code 2") || !strings.Contains(err.Error(), "failed to load model") {
+		t.Errorf("Expected exit code and stderr in error, got %v", err)
+	}
+
+	command, _ = stubWhisper(t, "echo 'not json' > \"$out.json\"")
+	client, _ = NewCommandASRClient(CommandASRConfig{Command: command})
+	if _, err := client.TranscribeFile(context.Background(), wavFile(t), nil); err == nil {
+		t.Error("Expected error for malformed JSON output")
+	}
+
+	if _, err := client.TranscribeFile(context.Background(), filepath.Join(t.TempDir(), "missing.wav"), nil); err == nil {
+		t.Error("Expected error for missing audio file")
+	}
+
+	// Cancelling the context kills the process
+	command, _ = stubWhisper(t, "sleep 5")
+	client, _ = NewCommandASRClient(CommandASRConfig{Command: command})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.TranscribeFile(ctx, wavFile(t), nil); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the process to be killed, took %v", elapsed)
+	}
+}
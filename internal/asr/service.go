@@ -3,12 +3,19 @@ package asr
 import (
 	"context"
 	"fmt"
-	"log"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"audio-assistant/internal/audio"
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/retry"
 	"audio-assistant/internal/vad"
 )
 
@@ -18,28 +25,42 @@ type Service struct {
 	config    *Config
 	isRunning bool
 	tempDir   string
+	cache     *resultCache
+	logger    logging.Logger
 }
 
 // Config represents ASR service configuration
 type Config struct {
-	APIKey      string
-	BaseURL     string
-	Model       string
-	Language    string
-	Temperature float32
-	Timeout     time.Duration
-	TempDir     string
+	APIKey                string
+	BaseURL               string
+	Model                 string
+	Language              string
+	Temperature           float32
+	Timeout               time.Duration
+	TempDir               string
+	MaxDownloadBytes      int64             // Size limit for audio downloaded by TranscribeURL
+	CacheDir              string            // Directory for cached transcription results (empty disables caching)
+	MaxRetries            int               // Retries for transient API failures (429/5xx)
+	RetryBaseDelay        time.Duration     // Initial backoff delay, doubled on each retry
+	MaxConcurrentSegments int               // Parallel requests in TranscribeSpeechSegments
+	Transport             http.RoundTripper // Underlying transport (proxy, custom TLS), nil uses http.DefaultTransport
+	HTTPClient            *http.Client      // Replaces the default client entirely when set
+	Logger                logging.Logger    // Receives service logs, nil uses the standard log package
 }
 
 // DefaultConfig returns default ASR configuration
 func DefaultConfig() *Config {
 	return &Config{
-		BaseURL:     "https://api.openai.com/v1",
-		Model:       "whisper-1",
-		Language:    "", // Auto-detect
-		Temperature: 0.0,
-		Timeout:     60 * time.Second,
-		TempDir:     "temp",
+		BaseURL:               "https://api.openai.com/v1",
+		Model:                 ModelWhisper1,
+		Language:              "", // Auto-detect
+		Temperature:           0.0,
+		Timeout:               60 * time.Second,
+		TempDir:               "temp",
+		MaxDownloadBytes:      MaxFileSize,
+		MaxRetries:            retry.DefaultMaxRetries,
+		RetryBaseDelay:        retry.DefaultBaseDelay,
+		MaxConcurrentSegments: 4,
 	}
 }
 
@@ -53,9 +74,11 @@ func NewService(config *Config) (*Service, error) {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
+	logger := logging.OrStd(config.Logger)
+
 	// Ensure temp directory exists
 	if err := os.MkdirAll(config.TempDir, 0755); err != nil {
-		log.Printf("Warning: failed to create temp directory %s: %v", config.TempDir, err)
+		logger.Warn("failed to create temp directory %s: %v", config.TempDir, err)
 		config.TempDir = "."
 	}
 
@@ -67,12 +90,32 @@ func NewService(config *Config) (*Service, error) {
 		client = NewClient(config.APIKey)
 		client.httpClient.Timeout = config.Timeout
 	}
+	client.SetRetryPolicy(config.MaxRetries, config.RetryBaseDelay)
+	if config.Transport != nil {
+		client.SetTransport(config.Transport)
+	}
+	if config.HTTPClient != nil {
+		client.SetHTTPClient(config.HTTPClient)
+	}
 
-	return &Service{
+	service := &Service{
 		client:  client,
 		config:  config,
 		tempDir: config.TempDir,
-	}, nil
+		logger:  logger,
+	}
+
+	// Create result cache
+	if config.CacheDir != "" {
+		cache, err := newResultCache(config.CacheDir)
+		if err != nil {
+			logger.Warn("ASR result cache disabled: %v", err)
+		} else {
+			service.cache = cache
+		}
+	}
+
+	return service, nil
 }
 
 // Start starts the ASR service
@@ -87,7 +130,7 @@ func (s *Service) Start(ctx context.Context) error {
 	}
 
 	s.isRunning = true
-	log.Println("ASR service started successfully")
+	s.logger.Info("ASR service started successfully")
 
 	return nil
 }
@@ -99,7 +142,7 @@ func (s *Service) Stop() {
 	}
 
 	s.isRunning = false
-	log.Println("ASR service stopped")
+	s.logger.Info("ASR service stopped")
 }
 
 // IsRunning returns whether the service is running
@@ -107,6 +150,11 @@ func (s *Service) IsRunning() bool {
 	return s.isRunning
 }
 
+// Ping checks that the ASR API is still reachable, for periodic liveness checks
+func (s *Service) Ping(ctx context.Context) error {
+	return s.client.ValidateAPIKey(ctx)
+}
+
 // TranscribeAudioData transcribes audio data to text
 func (s *Service) TranscribeAudioData(ctx context.Context, audioData []float32, sampleRate int) (string, error) {
 	if !s.isRunning {
@@ -132,28 +180,176 @@ func (s *Service) TranscribeFile(ctx context.Context, filePath string) (string,
 		return "", fmt.Errorf("ASR service is not running")
 	}
 
-	text, err := s.client.TranscribeWithLanguage(ctx, filePath, s.config.Language)
+	req := &TranscribeRequest{
+		Model:    s.config.Model,
+		Language: s.config.Language,
+		Format:   "text",
+	}
+
+	response, err := s.transcribeFileCached(ctx, filePath, req)
 	if err != nil {
 		return "", fmt.Errorf("transcription failed: %w", err)
 	}
 
-	return text, nil
+	return strings.TrimSpace(response.Text), nil
 }
 
-// TranscribeWithDetails transcribes audio and returns detailed response
-func (s *Service) TranscribeWithDetails(ctx context.Context, filePath string) (*TranscribeResponse, error) {
+// TranscribeFileWithOptions transcribes an audio file with per-call overrides
+// Empty fields in opts (zero Temperature included) fall back to the service config, Format defaults to text
+// Use Prompt to bias recognition toward names and domain vocabulary without touching the service config
+func (s *Service) TranscribeFileWithOptions(ctx context.Context, filePath string, opts TranscribeRequest) (string, error) {
 	if !s.isRunning {
-		return nil, fmt.Errorf("ASR service is not running")
+		return "", fmt.Errorf("ASR service is not running")
+	}
+
+	req := s.resolveRequest(opts)
+	if req.Format == "" {
+		req.Format = "text"
+	}
+
+	response, err := s.transcribeFileCached(ctx, filePath, &req)
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %w", err)
+	}
+
+	return strings.TrimSpace(response.Text), nil
+}
+
+// resolveRequest fills empty Model, Language and Temperature from the service config
+func (s *Service) resolveRequest(opts TranscribeRequest) TranscribeRequest {
+	if opts.Model == "" {
+		opts.Model = s.config.Model
+	}
+	if opts.Language == "" {
+		opts.Language = s.config.Language
+	}
+	if opts.Temperature == 0 {
+		opts.Temperature = s.config.Temperature
+	}
+	return opts
+}
+
+// transcribeFileCached transcribes a file, consulting the result cache when enabled
+func (s *Service) transcribeFileCached(ctx context.Context, filePath string, req *TranscribeRequest) (*TranscribeResponse, error) {
+	if s.cache == nil {
+		return s.client.TranscribeFile(ctx, filePath, req)
+	}
+
+	audioData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+
+	key := s.cache.key(audioData, req)
+	if cached, ok := s.cache.get(key); ok {
+		s.logger.Debug("ASR cache hit: %s", key[:12])
+		return cached, nil
+	}
+
+	response, err := s.client.TranscribeFile(ctx, filePath, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.put(key, response); err != nil {
+		s.logger.Warn("failed to cache ASR result: %v", err)
+	}
+
+	return response, nil
+}
+
+// TranscribeURL downloads remote audio and transcribes it to text
+func (s *Service) TranscribeURL(ctx context.Context, audioURL string) (string, error) {
+	if !s.isRunning {
+		return "", fmt.Errorf("ASR service is not running")
+	}
+
+	maxBytes := s.config.MaxDownloadBytes
+	if maxBytes <= 0 || maxBytes > MaxFileSize {
+		maxBytes = MaxFileSize
+	}
+
+	data, contentType, err := audio.DownloadAudio(ctx, s.client.httpClient, audioURL, maxBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	filename, err := filenameForDownload(audioURL, contentType)
+	if err != nil {
+		return "", err
 	}
 
 	req := &TranscribeRequest{
 		Model:       s.config.Model,
 		Language:    s.config.Language,
 		Temperature: s.config.Temperature,
-		Format:      "verbose_json",
+		Format:      "text",
 	}
 
-	response, err := s.client.TranscribeFile(ctx, filePath, req)
+	response, err := s.client.TranscribeBytes(ctx, data, filename, req)
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %w", err)
+	}
+
+	return strings.TrimSpace(response.Text), nil
+}
+
+// contentTypeExtensions maps audio MIME types to supported file extensions
+var contentTypeExtensions = map[string]string{
+	"audio/mpeg":    ".mp3",
+	"audio/mp3":     ".mp3",
+	"audio/mp4":     ".m4a",
+	"audio/x-m4a":   ".m4a",
+	"audio/m4a":     ".m4a",
+	"audio/wav":     ".wav",
+	"audio/x-wav":   ".wav",
+	"audio/wave":    ".wav",
+	"audio/vnd.wav": ".wav",
+	"audio/webm":    ".webm",
+	"video/mp4":     ".mp4",
+	"video/webm":    ".webm",
+}
+
+// filenameForDownload derives an upload filename from the URL path or content type
+func filenameForDownload(audioURL, contentType string) (string, error) {
+	name := "audio"
+	if parsed, err := url.Parse(audioURL); err == nil {
+		base := path.Base(parsed.Path)
+		ext := strings.ToLower(path.Ext(base))
+		if IsSupportedFormat(ext) {
+			return base, nil
+		}
+		if base != "." && base != "/" && base != "" {
+			name = strings.TrimSuffix(base, path.Ext(base))
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if ext, ok := contentTypeExtensions[strings.ToLower(mediaType)]; ok {
+			return name + ext, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported audio format: content type %q. Supported formats: %v", contentType, supportedFormats)
+}
+
+// TranscribeWithDetails transcribes audio and returns detailed response
+func (s *Service) TranscribeWithDetails(ctx context.Context, filePath string) (*TranscribeResponse, error) {
+	return s.TranscribeWithDetailsOptions(ctx, filePath, TranscribeRequest{})
+}
+
+// TranscribeWithDetailsOptions is TranscribeWithDetails with per-call overrides, empty fields fall back to the service config
+// Format is always verbose_json, the client downgrades it for models that only return text
+func (s *Service) TranscribeWithDetailsOptions(ctx context.Context, filePath string, opts TranscribeRequest) (*TranscribeResponse, error) {
+	if !s.isRunning {
+		return nil, fmt.Errorf("ASR service is not running")
+	}
+
+	req := s.resolveRequest(opts)
+	req.Format = "verbose_json"
+
+	response, err := s.transcribeFileCached(ctx, filePath, &req)
 	if err != nil {
 		return nil, fmt.Errorf("transcription failed: %w", err)
 	}
@@ -181,6 +377,8 @@ func (s *Service) TranscribeAudioDataWithDetails(ctx context.Context, audioData
 }
 
 // TranscribeSpeechSegments transcribes speech segments detected by VAD
+// Segments are transcribed concurrently (up to MaxConcurrentSegments), results are ordered by SegmentIndex
+// Failed segments don't abort the batch: the successful results are returned together with a SegmentErrors error
 func (s *Service) TranscribeSpeechSegments(ctx context.Context, audioData []float32, sampleRate int, segments []vad.SpeechSegment) ([]SegmentTranscription, error) {
 	if !s.isRunning {
 		return nil, fmt.Errorf("ASR service is not running")
@@ -190,47 +388,108 @@ func (s *Service) TranscribeSpeechSegments(ctx context.Context, audioData []floa
 		return nil, nil
 	}
 
-	var results []SegmentTranscription
-
-	for i, segment := range segments {
-		// Extract audio segment
-		startSample := int(segment.Start * float64(sampleRate))
-		endSample := int(segment.End * float64(sampleRate))
+	workers := s.config.MaxConcurrentSegments
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(segments) {
+		workers = len(segments)
+	}
 
-		// Bounds checking
-		if startSample < 0 {
-			startSample = 0
-		}
-		if endSample > len(audioData) {
-			endSample = len(audioData)
-		}
-		if startSample >= endSample {
-			continue
-		}
+	// One slot per segment keeps ordering without sorting
+	texts := make([]string, len(segments))
+	errs := make([]error, len(segments))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				texts[i], errs[i] = s.transcribeSegment(ctx, audioData, sampleRate, segments[i], i)
+			}
+		}()
+	}
 
-		segmentAudio := audioData[startSample:endSample]
+	for i := range segments {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 
-		// Transcribe segment
-		text, err := s.TranscribeAudioData(ctx, segmentAudio, sampleRate)
-		if err != nil {
-			log.Printf("Failed to transcribe segment %d: %v", i, err)
+	var results []SegmentTranscription
+	var failures SegmentErrors
+	for i, segment := range segments {
+		if errs[i] != nil {
+			s.logger.Warn("failed to transcribe segment %d: %v", i, errs[i])
+			failures = append(failures, SegmentError{SegmentIndex: i, Err: errs[i]})
 			continue
 		}
 
-		if text != "" {
+		if texts[i] != "" {
 			results = append(results, SegmentTranscription{
 				SegmentIndex: i,
 				Start:        segment.Start,
 				End:          segment.End,
 				Duration:     segment.Duration,
-				Text:         text,
+				Text:         texts[i],
 			})
 		}
 	}
 
+	if len(failures) > 0 {
+		return results, failures
+	}
 	return results, nil
 }
 
+// transcribeSegment extracts one segment into its own temp WAV and transcribes it
+func (s *Service) transcribeSegment(ctx context.Context, audioData []float32, sampleRate int, segment vad.SpeechSegment, index int) (string, error) {
+	// Extract audio segment
+	startSample := int(segment.Start * float64(sampleRate))
+	endSample := int(segment.End * float64(sampleRate))
+
+	// Bounds checking
+	if startSample < 0 {
+		startSample = 0
+	}
+	if endSample > len(audioData) {
+		endSample = len(audioData)
+	}
+	if startSample >= endSample {
+		return "", nil
+	}
+
+	// Segment index in the name keeps concurrent temp files apart
+	tempFile := filepath.Join(s.tempDir, fmt.Sprintf("asr_segment_%d_%d.wav", time.Now().UnixNano(), index))
+	defer os.Remove(tempFile) // Clean up temp file
+
+	if err := audio.SaveToWAV(tempFile, audioData[startSample:endSample], sampleRate); err != nil {
+		return "", fmt.Errorf("failed to save audio to WAV: %w", err)
+	}
+
+	return s.TranscribeFile(ctx, tempFile)
+}
+
+// SegmentError records a segment that failed to transcribe
+type SegmentError struct {
+	SegmentIndex int
+	Err          error
+}
+
+// SegmentErrors collects the failures of a TranscribeSpeechSegments batch
+type SegmentErrors []SegmentError
+
+// Error implements error
+func (e SegmentErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, failure := range e {
+		parts[i] = fmt.Sprintf("segment %d: %v", failure.SegmentIndex, failure.Err)
+	}
+	return fmt.Sprintf("%d segment(s) failed to transcribe: %s", len(e), strings.Join(parts, "; "))
+}
+
 // SegmentTranscription represents a transcribed speech segment
 type SegmentTranscription struct {
 	SegmentIndex int     `json:"segment_index"`
@@ -256,12 +515,18 @@ func (s *Service) TranscribeWithLanguageHint(ctx context.Context, audioData []fl
 	}
 
 	// Transcribe with language hint
-	text, err := s.client.TranscribeWithLanguage(ctx, tempFile, language)
+	req := &TranscribeRequest{
+		Model:    s.config.Model,
+		Language: language,
+		Format:   "text",
+	}
+
+	response, err := s.transcribeFileCached(ctx, tempFile, req)
 	if err != nil {
 		return "", fmt.Errorf("transcription failed: %w", err)
 	}
 
-	return text, nil
+	return strings.TrimSpace(response.Text), nil
 }
 
 // UpdateConfig updates ASR configuration
@@ -270,20 +535,28 @@ func (s *Service) UpdateConfig(model, language string, temperature float32) {
 	s.config.Language = language
 	s.config.Temperature = temperature
 
-	log.Printf("ASR config updated: model=%s, language=%s, temperature=%.2f",
+	s.logger.Info("ASR config updated: model=%s, language=%s, temperature=%.2f",
 		model, language, temperature)
 }
 
 // GetConfig returns current ASR configuration
 func (s *Service) GetConfig() *Config {
 	return &Config{
-		APIKey:      s.config.APIKey,
-		BaseURL:     s.config.BaseURL,
-		Model:       s.config.Model,
-		Language:    s.config.Language,
-		Temperature: s.config.Temperature,
-		Timeout:     s.config.Timeout,
-		TempDir:     s.config.TempDir,
+		APIKey:                s.config.APIKey,
+		BaseURL:               s.config.BaseURL,
+		Model:                 s.config.Model,
+		Language:              s.config.Language,
+		Temperature:           s.config.Temperature,
+		Timeout:               s.config.Timeout,
+		TempDir:               s.config.TempDir,
+		MaxDownloadBytes:      s.config.MaxDownloadBytes,
+		CacheDir:              s.config.CacheDir,
+		MaxRetries:            s.config.MaxRetries,
+		RetryBaseDelay:        s.config.RetryBaseDelay,
+		MaxConcurrentSegments: s.config.MaxConcurrentSegments,
+		Transport:             s.config.Transport,
+		HTTPClient:            s.config.HTTPClient,
+		Logger:                s.config.Logger,
 	}
 }
 
@@ -302,6 +575,10 @@ func (s *Service) ValidateConfiguration(ctx context.Context) error {
 		return fmt.Errorf("model is required")
 	}
 
+	if !IsSupportedModel(s.config.Model) {
+		return fmt.Errorf("unsupported model: %s", s.config.Model)
+	}
+
 	if s.config.Temperature < 0 || s.config.Temperature > 1 {
 		return fmt.Errorf("temperature must be between 0 and 1")
 	}
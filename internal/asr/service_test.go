@@ -0,0 +1,331 @@
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/vad"
+)
+
+// newSlowTranscriptionServer replies with the uploaded file size after a delay
+// Files of failSize bytes get a 400 response
+func newSlowTranscriptionServer(delay time.Duration, failSize int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+
+		if len(data) == failSize {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"bad segment"}}`))
+			return
+		}
+		fmt.Fprintf(w, "%d", len(data))
+	}))
+}
+
+func newSegmentTestService(t *testing.T, serverURL string, concurrency int) *Service {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = serverURL
+	config.TempDir = t.TempDir()
+	config.MaxConcurrentSegments = concurrency
+
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create ASR service: %v", err)
+	}
+	service.isRunning = true
+	return service
+}
+
+// testSegments returns n segments of increasing length so each one has a distinct WAV size
+func testSegments(n int) ([]float32, []vad.SpeechSegment) {
+	segments := make([]vad.SpeechSegment, n)
+	for i := range segments {
+		start := float64(i)
+		segments[i] = vad.SpeechSegment{Start: start, End: start + 0.1*float64(i+1), Duration: 0.1 * float64(i+1)}
+	}
+	return make([]float32, 16000*n), segments
+}
+
+// wavSize is the size of a 16-bit mono WAV holding the given samples
+func wavSize(segment vad.SpeechSegment, sampleRate int) int {
+	samples := int(segment.End*float64(sampleRate)) - int(segment.Start*float64(sampleRate))
+	return 44 + samples*2
+}
+
+func TestTranscribeSpeechSegmentsConcurrent(t *testing.T) {
+	server := newSlowTranscriptionServer(100*time.Millisecond, -1)
+	defer server.Close()
+
+	audioData, segments := testSegments(8)
+
+	run := func(concurrency int) ([]SegmentTranscription, time.Duration) {
+		service := newSegmentTestService(t, server.URL, concurrency)
+		start := time.Now()
+		results, err := service.TranscribeSpeechSegments(context.Background(), audioData, 16000, segments)
+		if err != nil {
+			t.Fatalf("TranscribeSpeechSegments failed: %v", err)
+		}
+		return results, time.Since(start)
+	}
+
+	_, sequential := run(1)
+	results, concurrent := run(4)
+
+	if concurrent > sequential/2 {
+		t.Errorf("Expected concurrent run to be much faster: sequential %v, concurrent %v", sequential, concurrent)
+	}
+
+	if len(results) != len(segments) {
+		t.Fatalf("Expected %d results, got %d", len(segments), len(results))
+	}
+	for i, result := range results {
+		if result.SegmentIndex != i {
+			t.Errorf("Result %d has segment index %d", i, result.SegmentIndex)
+		}
+		if expected := fmt.Sprint(wavSize(segments[i], 16000)); result.Text != expected {
+			t.Errorf("Segment %d: expected text %q, got %q", i, expected, result.Text)
+		}
+	}
+}
+
+func TestTranscribeSpeechSegmentsCollectsFailures(t *testing.T) {
+	audioData, segments := testSegments(4)
+
+	server := newSlowTranscriptionServer(0, wavSize(segments[2], 16000))
+	defer server.Close()
+
+	service := newSegmentTestService(t, server.URL, 4)
+	service.client.SetRetryPolicy(0, time.Millisecond)
+
+	results, err := service.TranscribeSpeechSegments(context.Background(), audioData, 16000, segments)
+
+	var failures SegmentErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("Expected SegmentErrors, got %v", err)
+	}
+	if len(failures) != 1 || failures[0].SegmentIndex != 2 {
+		t.Errorf("Expected segment 2 to fail, got %v", failures)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 successful results, got %d", len(results))
+	}
+	for i, expected := range []int{0, 1, 3} {
+		if results[i].SegmentIndex != expected {
+			t.Errorf("Result %d: expected segment index %d, got %d", i, expected, results[i].SegmentIndex)
+		}
+	}
+}
+
+func TestTranscribeFileWithOptions(t *testing.T) {
+	var fields []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		values := map[string]string{}
+		for key := range r.MultipartForm.Value {
+			values[key] = r.FormValue(key)
+		}
+		fields = append(fields, values)
+		w.Write([]byte(" 张伟在 Kubernetes 集群上部署 "))
+	}))
+	defer server.Close()
+
+	service := newSegmentTestService(t, server.URL, 1)
+	service.config.Language = "zh"
+	service.config.Temperature = 0.2
+
+	audioFile := filepath.Join(t.TempDir(), "speech.wav")
+	if err := audio.SaveToWAV(audioFile, make([]float32, 1600), 16000); err != nil {
+		t.Fatalf("Failed to write test audio: %v", err)
+	}
+
+	text, err := service.TranscribeFileWithOptions(context.Background(), audioFile, TranscribeRequest{
+		Prompt:      "张伟, Kubernetes",
+		Temperature: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("TranscribeFileWithOptions failed: %v", err)
+	}
+	if text != "张伟在 Kubernetes 集群上部署" {
+		t.Errorf("Unexpected transcription: %q", text)
+	}
+
+	got := fields[0]
+	if got["prompt"] != "张伟, Kubernetes" || got["temperature"] != "0.50" {
+		t.Errorf("Expected prompt and temperature overrides, got %v", got)
+	}
+	if got["language"] != "zh" || got["model"] != ModelWhisper1 || got["response_format"] != "text" {
+		t.Errorf("Expected unset fields to fall back to service config, got %v", got)
+	}
+
+	// Overrides must not leak into the service config or later calls
+	if _, err := service.TranscribeFileWithOptions(context.Background(), audioFile, TranscribeRequest{Language: "en"}); err != nil {
+		t.Fatalf("TranscribeFileWithOptions failed: %v", err)
+	}
+	if fields[1]["language"] != "en" || fields[1]["prompt"] != "" || fields[1]["temperature"] != "0.20" {
+		t.Errorf("Expected per-call language only, got %v", fields[1])
+	}
+	if service.config.Language != "zh" || service.config.Temperature != 0.2 {
+		t.Errorf("Service config was mutated: %+v", service.config)
+	}
+}
+
+func TestServicePing(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("Expected models request, got %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	service := newSegmentTestService(t, server.URL, 1)
+	service.client.SetRetryPolicy(0, time.Millisecond)
+
+	if err := service.Ping(context.Background()); err != nil {
+		t.Errorf("Expected healthy API, got %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := service.Ping(context.Background()); err == nil {
+		t.Error("Expected Ping to report a degraded API")
+	}
+}
+
+// recordingTransport records outbound request URLs and answers 200
+type recordingTransport struct {
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestServiceCustomTransport(t *testing.T) {
+	transport := &recordingTransport{}
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.TempDir = t.TempDir()
+	config.Transport = transport
+
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create ASR service: %v", err)
+	}
+	if err := service.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if len(transport.urls) != 1 || transport.urls[0] != "https://api.openai.com/v1/models" {
+		t.Errorf("Expected request through the custom transport, got %v", transport.urls)
+	}
+}
+
+// newDownloadTestServer serves audio under /files and answers transcriptions with the uploaded filename
+func newDownloadTestServer(t *testing.T, files func(w http.ResponseWriter, r *http.Request)) (*Service, *int32) {
+	var transcriptions int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/", files)
+	mux.HandleFunc("/audio/transcriptions", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&transcriptions, 1)
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(header.Filename))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	service := newSegmentTestService(t, server.URL, 1)
+	service.client.SetRetryPolicy(0, time.Millisecond)
+	return service, &transcriptions
+}
+
+func TestTranscribeURL(t *testing.T) {
+	service, transcriptions := newDownloadTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/page.html":
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			w.Header().Set("Content-Type", "audio/mpeg")
+		}
+		w.Write(make([]byte, 200))
+	})
+	baseURL := service.config.BaseURL + "/files/"
+
+	// The extension in the URL wins, otherwise the content type picks one
+	for url, expected := range map[string]string{
+		baseURL + "speech.wav": "speech.wav",
+		baseURL + "download":   "download.mp3",
+	} {
+		filename, err := service.TranscribeURL(context.Background(), url)
+		if err != nil {
+			t.Fatalf("TranscribeURL(%q) failed: %v", url, err)
+		}
+		if filename != expected {
+			t.Errorf("TranscribeURL(%q): expected upload as %q, got %q", url, expected, filename)
+		}
+	}
+
+	// Neither the extension nor the content type is audio
+	if _, err := service.TranscribeURL(context.Background(), baseURL+"page.html"); err == nil || !strings.Contains(err.Error(), "unsupported audio format") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+
+	// Files over MaxDownloadBytes are rejected before transcription
+	service.config.MaxDownloadBytes = 100
+	if _, err := service.TranscribeURL(context.Background(), baseURL+"speech.wav"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected a size error, got %v", err)
+	}
+	if n := atomic.LoadInt32(transcriptions); n != 2 {
+		t.Errorf("Expected only the valid downloads to be transcribed, got %d requests", n)
+	}
+}
+
+func TestTranscribeURLCancelled(t *testing.T) {
+	started := make(chan struct{})
+	service, transcriptions := newDownloadTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := service.TranscribeURL(ctx, service.config.BaseURL+"/files/slow.wav")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation mid-download to stop TranscribeURL, got %v", err)
+	}
+	if n := atomic.LoadInt32(transcriptions); n != 0 {
+		t.Errorf("Expected no transcription after a cancelled download, got %d requests", n)
+	}
+}
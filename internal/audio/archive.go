@@ -0,0 +1,87 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ArchiveFormat 保存录音时使用的文件格式
+type ArchiveFormat string
+
+const (
+	ArchiveWAV  ArchiveFormat = "wav"
+	ArchiveMP3  ArchiveFormat = "mp3"
+	ArchiveOpus ArchiveFormat = "opus"
+)
+
+// archiveEncoder 压缩编码使用的外部命令（minimp3 只能解码）
+var archiveEncoder = "ffmpeg"
+
+// ErrEncoderUnavailable 系统中找不到压缩编码器，调用方应回退到 WAV
+var ErrEncoderUnavailable = errors.New("audio encoder not available")
+
+// ParseArchiveFormat 解析配置中的格式名，空字符串表示 WAV
+func ParseArchiveFormat(name string) (ArchiveFormat, error) {
+	switch format := ArchiveFormat(strings.ToLower(strings.TrimSpace(name))); format {
+	case "", ArchiveWAV:
+		return ArchiveWAV, nil
+	case ArchiveMP3, ArchiveOpus:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format: %s", name)
+	}
+}
+
+// Extension 返回格式对应的文件扩展名（含点）
+func (f ArchiveFormat) Extension() string {
+	if f == ArchiveOpus {
+		return ".ogg"
+	}
+	return "." + string(f)
+}
+
+// EncodeArchive 把单声道样本编码为指定格式
+func EncodeArchive(samples []float32, sampleRate int, format ArchiveFormat) ([]byte, error) {
+	wavData, err := EncodeWAV(samples, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeArchiveWAV(wavData, format)
+}
+
+// EncodeArchiveWAV 把已有的 WAV 数据转码为指定格式，WAV 格式原样返回
+func EncodeArchiveWAV(wavData []byte, format ArchiveFormat) ([]byte, error) {
+	var codecArgs []string
+	switch format {
+	case "", ArchiveWAV:
+		return wavData, nil
+	case ArchiveMP3:
+		codecArgs = []string{"-c:a", "libmp3lame", "-b:a", "64k", "-f", "mp3"}
+	case ArchiveOpus:
+		codecArgs = []string{"-c:a", "libopus", "-b:a", "32k", "-f", "ogg"}
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", format)
+	}
+
+	path, err := exec.LookPath(archiveEncoder)
+	if err != nil {
+		return nil, ErrEncoderUnavailable
+	}
+
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-f", "wav", "-i", "pipe:0"}, codecArgs...)
+	args = append(args, "pipe:1")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(wavData)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w: %s", format, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseArchiveFormat(t *testing.T) {
+	for name, expected := range map[string]ArchiveFormat{"": ArchiveWAV, "WAV": ArchiveWAV, "mp3": ArchiveMP3, " opus ": ArchiveOpus} {
+		format, err := ParseArchiveFormat(name)
+		if err != nil || format != expected {
+			t.Errorf("ParseArchiveFormat(%q) = %q, %v, expected %q", name, format, err, expected)
+		}
+	}
+	if _, err := ParseArchiveFormat("flac"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+	if ArchiveOpus.Extension() != ".ogg" || ArchiveMP3.Extension() != ".mp3" {
+		t.Error("Unexpected archive file extensions")
+	}
+}
+
+func TestEncodeArchiveMP3RoundTrip(t *testing.T) {
+	samples := sineWave(0.5, 440, 16000, 16000) // 1s
+
+	data, err := EncodeArchive(samples, 16000, ArchiveMP3)
+	if errors.Is(err, ErrEncoderUnavailable) {
+		t.Skipf("Skipping MP3 round trip: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("EncodeArchive failed: %v", err)
+	}
+
+	wavSize := 44 + len(samples)*2
+	if len(data) >= wavSize {
+		t.Errorf("Expected MP3 (%d bytes) to be smaller than WAV (%d bytes)", len(data), wavSize)
+	}
+
+	filename := filepath.Join(t.TempDir(), "recording.mp3")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("Failed to write recording: %v", err)
+	}
+
+	decoded, rate, err := NewAudioDecoder().DecodeAudioFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to decode MP3 recording: %v", err)
+	}
+	duration := float64(len(decoded)) / float64(rate)
+	if duration < 0.9 || duration > 1.2 {
+		t.Errorf("Expected about 1s of decoded audio, got %.2fs at %d Hz", duration, rate)
+	}
+	if peakAmplitude(decoded) < 0.3 {
+		t.Errorf("Expected decoded tone to keep its level, got peak %.2f", peakAmplitude(decoded))
+	}
+}
+
+func TestEncodeArchiveFallback(t *testing.T) {
+	saved := archiveEncoder
+	archiveEncoder = "definitely-not-an-encoder"
+	defer func() { archiveEncoder = saved }()
+
+	if _, err := EncodeArchive([]float32{0, 0.1}, 16000, ArchiveMP3); !errors.Is(err, ErrEncoderUnavailable) {
+		t.Errorf("Expected ErrEncoderUnavailable, got %v", err)
+	}
+
+	// WAV 不需要外部编码器
+	data, err := EncodeArchive([]float32{0, 0.1}, 16000, ArchiveWAV)
+	if err != nil || len(data) != 48 {
+		t.Errorf("Expected 48-byte WAV, got %d bytes, %v", len(data), err)
+	}
+}
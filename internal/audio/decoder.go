@@ -2,14 +2,31 @@ package audio
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"os/exec"
+	"strings"
+	"time"
 
+	"github.com/mewkiz/flac"
 	"github.com/tosone/minimp3"
 	"github.com/youpy/go-wav"
 )
 
+// externalDecoder 解码 Opus/AAC 使用的外部命令
+// 纯 Go 的 Opus 解码库要求更高的 Go 版本，AAC 没有可用的纯 Go 实现
+var externalDecoder = "ffmpeg"
+
+// externalDecodeTimeout 单次外部解码的最长时间，超时后终止 ffmpeg 进程
+var externalDecodeTimeout = 30 * time.Second
+
+// ErrDecoderUnavailable 系统中找不到解码 Opus/AAC 所需的外部解码器
+var ErrDecoderUnavailable = errors.New("audio decoder not available")
+
 // AudioDecoder 音频解码器
 type AudioDecoder struct{}
 
@@ -35,6 +52,18 @@ func (d *AudioDecoder) DecodeAudioData(audioData []byte) ([]float32, int, error)
 		return samples, rate, err
 	case "mp3":
 		return d.decodeMP3(audioData)
+	case "flac":
+		channels, rate, err := d.decodeFLACChannels(audioData)
+		if err != nil {
+			return nil, 0, err
+		}
+		return MixToMono(channels), rate, nil
+	case "opus", "aac":
+		channels, rate, err := d.decodeExternal(audioData, format)
+		if err != nil {
+			return nil, 0, err
+		}
+		return MixToMono(channels), rate, nil
 	default:
 		// 默认尝试健壮的 WAV 解析器，然后尝试 go-wav，最后尝试 MP3
 		samples, rate, err := d.decodeWAVRobust(audioData)
@@ -48,6 +77,32 @@ func (d *AudioDecoder) DecodeAudioData(audioData []byte) ([]float32, int, error)
 	}
 }
 
+// DecodeAudioDataMultiChannel 解码音频数据并保留各声道，返回每个声道一个切片
+// 需要单声道时使用 DecodeAudioData，它会把多声道平均混合
+func (d *AudioDecoder) DecodeAudioDataMultiChannel(audioData []byte) ([][]float32, int, error) {
+	switch format := d.detectFormat(audioData); format {
+	case "wav":
+		return parseWAVChannels(audioData, "memory")
+	case "mp3":
+		return d.decodeMP3Channels(audioData)
+	case "flac":
+		return d.decodeFLACChannels(audioData)
+	case "opus", "aac":
+		return d.decodeExternal(audioData, format)
+	default:
+		return nil, 0, fmt.Errorf("unsupported audio format for multi-channel decoding")
+	}
+}
+
+// SupportedDecodeFormats 返回 DecodeAudioData 能解码的格式，Opus/AAC 仅在找到 ffmpeg 时可用
+func SupportedDecodeFormats() []string {
+	formats := []string{"wav", "mp3", "flac"}
+	if _, err := exec.LookPath(externalDecoder); err == nil {
+		formats = append(formats, "opus", "aac")
+	}
+	return formats
+}
+
 // DecodeAudioFile 解码音频文件
 func (d *AudioDecoder) DecodeAudioFile(filename string) ([]float32, int, error) {
 	data, err := os.ReadFile(filename)
@@ -58,6 +113,16 @@ func (d *AudioDecoder) DecodeAudioFile(filename string) ([]float32, int, error)
 	return d.DecodeAudioData(data)
 }
 
+// DecodeAudioURL 下载并解码远程音频（maxBytes 限制下载大小）
+func (d *AudioDecoder) DecodeAudioURL(ctx context.Context, url string, maxBytes int64) ([]float32, int, error) {
+	data, _, err := DownloadAudio(ctx, http.DefaultClient, url, maxBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return d.DecodeAudioData(data)
+}
+
 // detectFormat 检测音频格式
 func (d *AudioDecoder) detectFormat(data []byte) string {
 	if len(data) >= 4 {
@@ -66,6 +131,21 @@ func (d *AudioDecoder) detectFormat(data []byte) string {
 			return "wav"
 		}
 
+		// 检查 FLAC 文件头
+		if bytes.Equal(data[:4], []byte("fLaC")) {
+			return "flac"
+		}
+
+		// 检查 Ogg 容器（OpenAI TTS 的 Opus 输出）
+		if bytes.Equal(data[:4], []byte("OggS")) {
+			return "opus"
+		}
+
+		// 检查 AAC ADTS 帧头（layer 固定为 0，需在 MP3 帧同步之前判断）
+		if data[0] == 0xFF && (data[1]&0xF6) == 0xF0 {
+			return "aac"
+		}
+
 		// 检查 MP3 文件头
 		if len(data) >= 3 && (data[0] == 0xFF && (data[1]&0xE0) == 0xE0) {
 			return "mp3"
@@ -216,25 +296,141 @@ func (d *AudioDecoder) decodeMP3(audioData []byte) ([]float32, int, error) {
 	return samples, decoder.SampleRate, nil
 }
 
-// decodeWAVRobust 使用健壮的 WAV 解析器（处理 OpenAI TTS 的损坏头）
-func (d *AudioDecoder) decodeWAVRobust(audioData []byte) ([]float32, int, error) {
-	// 创建临时文件
-	tempFile, err := os.CreateTemp("", "audio_decode_*.wav")
+// decodeMP3Channels 解码 MP3 并按声道拆分
+func (d *AudioDecoder) decodeMP3Channels(audioData []byte) ([][]float32, int, error) {
+	decoder, pcmData, err := minimp3.DecodeFull(audioData)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode MP3: %w", err)
 	}
-	defer func() {
-		tempFile.Close()
-		os.Remove(tempFile.Name())
-	}()
-
-	// 写入数据
-	if err := os.WriteFile(tempFile.Name(), audioData, 0644); err != nil {
-		return nil, 0, fmt.Errorf("failed to write temp file: %w", err)
+	defer decoder.Close()
+
+	return deinterleavePCM16(pcmData, decoder.Channels), decoder.SampleRate, nil
+}
+
+// decodeFLACChannels 使用开源库解码 FLAC 并按声道拆分
+func (d *AudioDecoder) decodeFLACChannels(audioData []byte) ([][]float32, int, error) {
+	stream, err := flac.New(bytes.NewReader(audioData))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode FLAC: %w", err)
+	}
+	defer stream.Close()
+
+	info := stream.Info
+	if info.NChannels == 0 || info.BitsPerSample == 0 {
+		return nil, 0, fmt.Errorf("invalid FLAC stream info: channels=%d, bits=%d", info.NChannels, info.BitsPerSample)
+	}
+
+	// 按位深归一化到 [-1, 1)
+	scale := float32(int64(1) << (info.BitsPerSample - 1))
+	channels := make([][]float32, info.NChannels)
+	for ch := range channels {
+		channels[ch] = make([]float32, 0, info.NSamples)
 	}
 
-	// 使用我们之前的健壮解析器
-	return RobustLoadFromWAV(tempFile.Name())
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode FLAC frame: %w", err)
+		}
+		for ch, subframe := range frame.Subframes {
+			if ch >= len(channels) {
+				break
+			}
+			for _, sample := range subframe.Samples {
+				channels[ch] = append(channels[ch], float32(sample)/scale)
+			}
+		}
+	}
+
+	return channels, int(info.SampleRate), nil
+}
+
+// decodeExternal 通过 ffmpeg 把 Opus/AAC 转码为 WAV 后解析，保留各声道
+func (d *AudioDecoder) decodeExternal(audioData []byte, format string) ([][]float32, int, error) {
+	path, err := exec.LookPath(externalDecoder)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot decode %s: %w", format, ErrDecoderUnavailable)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), externalDecodeTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-hide_banner", "-loglevel", "error", "-i", "pipe:0",
+		"-c:a", "pcm_s16le", "-f", "wav", "pipe:1")
+	cmd.Stdin = bytes.NewReader(audioData)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, fmt.Errorf("failed to decode %s: %w", format, ctx.Err())
+		}
+		return nil, 0, fmt.Errorf("failed to decode %s: %w: %s", format, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseWAVChannels(stdout.Bytes(), format)
+}
+
+// deinterleavePCM16 将交错的 16 位小端 PCM 拆分为各声道的 float32 样本
+func deinterleavePCM16(pcmData []byte, channels int) [][]float32 {
+	if channels < 1 {
+		channels = 1
+	}
+
+	frames := len(pcmData) / (2 * channels)
+	result := make([][]float32, channels)
+	for ch := range result {
+		result[ch] = make([]float32, frames)
+	}
+
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < channels; ch++ {
+			offset := (i*channels + ch) * 2
+			raw := int16(pcmData[offset]) | int16(pcmData[offset+1])<<8
+			result[ch][i] = float32(raw) / 32768.0
+		}
+	}
+
+	return result
+}
+
+// PCM16ToFloat32 将 16 位有符号小端单声道 PCM 数据转换为 float32
+func PCM16ToFloat32(pcmData []byte) []float32 {
+	return pcm16ToFloat32(pcmData, 1)
+}
+
+// pcm16ToFloat32 将 16 位有符号小端 PCM 数据转换为 float32，多声道混合为单声道
+func pcm16ToFloat32(pcmData []byte, channels int) []float32 {
+	if channels < 1 {
+		channels = 1
+	}
+
+	frames := len(pcmData) / (2 * channels)
+	samples := make([]float32, frames)
+
+	for i := 0; i < frames; i++ {
+		var sum float32
+		for ch := 0; ch < channels; ch++ {
+			offset := (i*channels + ch) * 2
+			raw := int16(pcmData[offset]) | int16(pcmData[offset+1])<<8
+			sum += float32(raw) / 32768.0
+		}
+		samples[i] = sum / float32(channels)
+	}
+
+	return samples
+}
+
+// decodeWAVRobust 使用健壮的 WAV 解析器（处理 OpenAI TTS 的损坏头），多声道平均混合为单声道
+func (d *AudioDecoder) decodeWAVRobust(audioData []byte) ([]float32, int, error) {
+	channels, sampleRate, err := parseWAVChannels(audioData, "memory")
+	if err != nil {
+		return nil, 0, err
+	}
+	return MixToMono(channels), sampleRate, nil
 }
 
 // ResampleAudio 使用简单线性插值重采样
@@ -0,0 +1,307 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// stereoWAV 生成左声道为正弦波、右声道为常数的双声道 WAV
+func stereoWAV(t *testing.T, frames int, opts WAVWriteOptions) ([]float32, []float32, []byte) {
+	t.Helper()
+
+	left := make([]float32, frames)
+	right := make([]float32, frames)
+	interleaved := make([]float32, 0, 2*frames)
+	for i := 0; i < frames; i++ {
+		left[i] = float32(0.5 * math.Sin(2*math.Pi*float64(i)/32))
+		right[i] = -0.25
+		interleaved = append(interleaved, left[i], right[i])
+	}
+
+	opts.Channels = 2
+	var buf bytes.Buffer
+	if err := WriteWAVOpts(&buf, interleaved, opts); err != nil {
+		t.Fatalf("Failed to write stereo WAV: %v", err)
+	}
+	return left, right, buf.Bytes()
+}
+
+func TestDecodeAudioDataMultiChannel(t *testing.T) {
+	decoder := NewAudioDecoder()
+
+	for _, opts := range []WAVWriteOptions{
+		{SampleRate: 16000, BitsPerSample: 16},
+		{SampleRate: 44100, BitsPerSample: 24},
+		{SampleRate: 48000, BitsPerSample: 32, Float: true},
+	} {
+		left, right, data := stereoWAV(t, 1000, opts)
+
+		channels, sampleRate, err := decoder.DecodeAudioDataMultiChannel(data)
+		if err != nil {
+			t.Fatalf("%+v: DecodeAudioDataMultiChannel failed: %v", opts, err)
+		}
+		if sampleRate != opts.SampleRate || len(channels) != 2 {
+			t.Fatalf("%+v: expected 2 channels at %dHz, got %d at %d", opts, opts.SampleRate, len(channels), sampleRate)
+		}
+		if len(channels[0]) != len(left) || len(channels[1]) != len(right) {
+			t.Fatalf("%+v: expected %d frames per channel, got %d and %d", opts, len(left), len(channels[0]), len(channels[1]))
+		}
+		for i := range left {
+			if math.Abs(float64(channels[0][i]-left[i])) > 1e-3 || math.Abs(float64(channels[1][i]-right[i])) > 1e-3 {
+				t.Fatalf("%+v: frame %d: expected (%.4f, %.4f), got (%.4f, %.4f)", opts, i, left[i], right[i], channels[0][i], channels[1][i])
+			}
+		}
+	}
+}
+
+func TestDecodeAudioDataMixesStereo(t *testing.T) {
+	left, right, data := stereoWAV(t, 500, WAVWriteOptions{SampleRate: 16000})
+
+	mono, sampleRate, err := NewAudioDecoder().DecodeAudioData(data)
+	if err != nil {
+		t.Fatalf("DecodeAudioData failed: %v", err)
+	}
+	if sampleRate != 16000 || len(mono) != len(left) {
+		t.Fatalf("Expected %d mono samples at 16000Hz, got %d at %d", len(left), len(mono), sampleRate)
+	}
+	for i := range mono {
+		if expected := (left[i] + right[i]) / 2; math.Abs(float64(mono[i]-expected)) > 1e-3 {
+			t.Fatalf("Sample %d: expected channel average %.4f, got %.4f", i, expected, mono[i])
+		}
+	}
+}
+
+func TestMixToMono(t *testing.T) {
+	mono := MixToMono([][]float32{{1, 0.5, 0}, {0, 0.5}})
+	if len(mono) != 2 || mono[0] != 0.5 || mono[1] != 0.5 {
+		t.Errorf("Expected averages over the shortest channel, got %v", mono)
+	}
+	if single := []float32{0.1}; &MixToMono([][]float32{single})[0] != &single[0] {
+		t.Error("Expected a single channel to be returned unchanged")
+	}
+}
+
+func TestDecodeAudioDataMultiChannelUnknownFormat(t *testing.T) {
+	if _, _, err := NewAudioDecoder().DecodeAudioDataMultiChannel([]byte("not audio")); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+// stereoFLAC 把左右声道编码为 16 位 FLAC，每帧使用未压缩的 verbatim 子帧
+func stereoFLAC(t *testing.T, left, right []float32, sampleRate int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	info := &meta.StreamInfo{
+		BlockSizeMin:  256,
+		BlockSizeMax:  256,
+		SampleRate:    uint32(sampleRate),
+		NChannels:     2,
+		BitsPerSample: 16,
+		NSamples:      uint64(len(left)),
+	}
+	enc, err := flac.NewEncoder(&buf, info)
+	if err != nil {
+		t.Fatalf("Failed to create FLAC encoder: %v", err)
+	}
+
+	for start := 0; start < len(left); start += 256 {
+		end := min(start+256, len(left))
+		f := &frame.Frame{Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(end - start),
+			SampleRate:        uint32(sampleRate),
+			Channels:          frame.ChannelsLR,
+			BitsPerSample:     16,
+		}}
+		for _, channel := range [][]float32{left[start:end], right[start:end]} {
+			samples := make([]int32, len(channel))
+			for i, v := range channel {
+				samples[i] = int32(v * 32767)
+			}
+			f.Subframes = append(f.Subframes, &frame.Subframe{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   samples,
+				NSamples:  len(samples),
+			})
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatalf("Failed to write FLAC frame: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Failed to close FLAC encoder: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeFLACRoundTrip(t *testing.T) {
+	left, right, _ := stereoWAV(t, 1000, WAVWriteOptions{SampleRate: 22050})
+	data := stereoFLAC(t, left, right, 22050)
+
+	decoder := NewAudioDecoder()
+	if format := decoder.detectFormat(data); format != "flac" {
+		t.Fatalf("Expected flac format, got %q", format)
+	}
+
+	channels, sampleRate, err := decoder.DecodeAudioDataMultiChannel(data)
+	if err != nil {
+		t.Fatalf("DecodeAudioDataMultiChannel failed: %v", err)
+	}
+	if sampleRate != 22050 || len(channels) != 2 || len(channels[0]) != len(left) {
+		t.Fatalf("Expected 2x%d frames at 22050Hz, got %d channels at %d", len(left), len(channels), sampleRate)
+	}
+	for i := range left {
+		if math.Abs(float64(channels[0][i]-left[i])) > 1e-3 || math.Abs(float64(channels[1][i]-right[i])) > 1e-3 {
+			t.Fatalf("Frame %d: expected (%.4f, %.4f), got (%.4f, %.4f)", i, left[i], right[i], channels[0][i], channels[1][i])
+		}
+	}
+
+	mono, _, err := decoder.DecodeAudioData(data)
+	if err != nil || len(mono) != len(left) {
+		t.Fatalf("Expected %d mono samples, got %d, %v", len(left), len(mono), err)
+	}
+	if expected := (left[100] + right[100]) / 2; math.Abs(float64(mono[100]-expected)) > 1e-3 {
+		t.Errorf("Expected channel average %.4f, got %.4f", expected, mono[100])
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	decoder := NewAudioDecoder()
+	for expected, header := range map[string][]byte{
+		"wav":  []byte("RIFF...."),
+		"flac": []byte("fLaC\x00\x00\x00\x22"),
+		"opus": []byte("OggS\x00\x02"),
+		"aac":  {0xFF, 0xF1, 0x50, 0x80},
+		"mp3":  {0xFF, 0xFB, 0x90, 0x64},
+	} {
+		if format := decoder.detectFormat(header); format != expected {
+			t.Errorf("detectFormat(% x) = %q, expected %q", header[:4], format, expected)
+		}
+	}
+	if format := decoder.detectFormat([]byte("ID3\x04")); format != "mp3" {
+		t.Errorf("Expected ID3 tag to be detected as mp3, got %q", format)
+	}
+}
+
+func TestDecodeOpusAndAAC(t *testing.T) {
+	samples := sineWave(0.5, 440, 16000, 16000) // 1s
+	wavData, err := EncodeWAV(samples, 16000)
+	if err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+
+	// 没有 ffmpeg 时 TestDecodeExternalCommand 仍覆盖外部解码流程，设置 REQUIRE_FFMPEG 时缺少 ffmpeg 视为失败
+	opusData, err := EncodeArchiveWAV(wavData, ArchiveOpus)
+	if errors.Is(err, ErrEncoderUnavailable) {
+		if os.Getenv("REQUIRE_FFMPEG") != "" {
+			t.Fatalf("REQUIRE_FFMPEG is set but ffmpeg is unavailable: %v", err)
+		}
+		t.Skipf("Skipping real Opus/AAC decoding, ffmpeg not found: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Failed to encode Opus: %v", err)
+	}
+
+	// EncodeArchiveWAV 不支持 AAC，直接调用 ffmpeg 生成 ADTS 流
+	var aacData bytes.Buffer
+	cmd := exec.Command(archiveEncoder, "-hide_banner", "-loglevel", "error", "-f", "wav", "-i", "pipe:0",
+		"-c:a", "aac", "-b:a", "64k", "-f", "adts", "pipe:1")
+	cmd.Stdin = bytes.NewReader(wavData)
+	cmd.Stdout = &aacData
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to encode AAC: %v", err)
+	}
+
+	decoder := NewAudioDecoder()
+	for format, data := range map[string][]byte{"opus": opusData, "aac": aacData.Bytes()} {
+		if detected := decoder.detectFormat(data); detected != format {
+			t.Errorf("Expected %s to be detected, got %q", format, detected)
+		}
+		decoded, rate, err := decoder.DecodeAudioData(data)
+		if err != nil {
+			t.Fatalf("%s: DecodeAudioData failed: %v", format, err)
+		}
+		if duration := float64(len(decoded)) / float64(rate); math.Abs(duration-1.0) > 0.1 {
+			t.Errorf("%s: expected about 1s of audio, got %.2fs", format, duration)
+		}
+	}
+}
+
+func TestDecodeExternalUnavailable(t *testing.T) {
+	original := externalDecoder
+	externalDecoder = "audio-assistant-missing-decoder"
+	defer func() { externalDecoder = original }()
+
+	_, _, err := NewAudioDecoder().DecodeAudioData([]byte("OggS\x00\x02\x00\x00"))
+	if !errors.Is(err, ErrDecoderUnavailable) {
+		t.Errorf("Expected ErrDecoderUnavailable, got %v", err)
+	}
+}
+
+// fakeDecoder 把 externalDecoder 替换为执行 script 的 shell 脚本
+func fakeDecoder(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script decoder requires a POSIX shell")
+	}
+
+	path := filepath.Join(t.TempDir(), "fake-ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write fake decoder: %v", err)
+	}
+	original := externalDecoder
+	externalDecoder = path
+	t.Cleanup(func() { externalDecoder = original })
+}
+
+func TestDecodeExternalCommand(t *testing.T) {
+	samples := sineWave(0.5, 440, 16000, 8000)
+	wavData, err := EncodeWAV(samples, 16000)
+	if err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+	wavPath := filepath.Join(t.TempDir(), "decoded.wav")
+	if err := os.WriteFile(wavPath, wavData, 0o644); err != nil {
+		t.Fatalf("Failed to write WAV: %v", err)
+	}
+
+	// 假解码器读完输入后输出固定的 WAV
+	fakeDecoder(t, "cat > /dev/null\ncat "+wavPath)
+	decoded, rate, err := NewAudioDecoder().DecodeAudioData([]byte("OggS\x00\x02\x00\x00"))
+	if err != nil {
+		t.Fatalf("DecodeAudioData failed: %v", err)
+	}
+	if rate != 16000 || len(decoded) != len(samples) {
+		t.Errorf("Expected %d samples at 16000Hz, got %d at %d", len(samples), len(decoded), rate)
+	}
+}
+
+func TestDecodeExternalTimeout(t *testing.T) {
+	original := externalDecodeTimeout
+	externalDecodeTimeout = 100 * time.Millisecond
+	defer func() { externalDecodeTimeout = original }()
+
+	// exec 让 sleep 替换 shell 进程，超时后终止的就是持有输出管道的进程
+	fakeDecoder(t, "exec sleep 10")
+	start := time.Now()
+	_, _, err := NewAudioDecoder().DecodeAudioData([]byte("OggS\x00\x02\x00\x00"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the decoder to be stopped after the timeout, took %v", elapsed)
+	}
+}
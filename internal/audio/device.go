@@ -0,0 +1,138 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// ErrDeviceNotFound 指定的设备 ID 不存在（设备已拔出或 ID 来自另一次枚举）
+var ErrDeviceNotFound = errors.New("audio device not found")
+
+// DeviceInfo 音频设备信息，ID 用于 NewInputWithDevice 和 NewAudioOutputWithDevice
+// ID 是设备在 PortAudio 设备列表中的位置，设备插拔后可能变化
+type DeviceInfo struct {
+	ID                int
+	Name              string
+	HostAPI           string
+	MaxInputChannels  int
+	MaxOutputChannels int
+	DefaultSampleRate float64
+	IsDefaultInput    bool
+	IsDefaultOutput   bool
+}
+
+// ListDevices 列出所有音频设备
+func ListDevices() ([]DeviceInfo, error) {
+	owner := new(int)
+	manager := GetManager()
+	if err := manager.Initialize(owner); err != nil {
+		return nil, fmt.Errorf("failed to initialize audio system: %w", err)
+	}
+	defer manager.Terminate(owner)
+
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio devices: %w", err)
+	}
+
+	// 没有默认设备时不算错误，只是不标记
+	defaultInput, _ := portaudio.DefaultInputDevice()
+	defaultOutput, _ := portaudio.DefaultOutputDevice()
+	return deviceInfos(devices, defaultInput, defaultOutput), nil
+}
+
+// deviceInfos 把 PortAudio 的设备列表转换为 DeviceInfo
+// PortAudio 缓存设备列表，默认设备与列表中的元素是同一个指针
+func deviceInfos(devices []*portaudio.DeviceInfo, defaultInput, defaultOutput *portaudio.DeviceInfo) []DeviceInfo {
+	infos := make([]DeviceInfo, 0, len(devices))
+	for i, device := range devices {
+		info := DeviceInfo{
+			ID:                i,
+			Name:              device.Name,
+			MaxInputChannels:  device.MaxInputChannels,
+			MaxOutputChannels: device.MaxOutputChannels,
+			DefaultSampleRate: device.DefaultSampleRate,
+			IsDefaultInput:    defaultInput != nil && device == defaultInput,
+			IsDefaultOutput:   defaultOutput != nil && device == defaultOutput,
+		}
+		if device.HostApi != nil {
+			info.HostAPI = device.HostApi.Name
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// findDevice 按 ID 查找设备（需要已初始化音频系统）
+func findDevice(deviceID int) (*portaudio.DeviceInfo, error) {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio devices: %w", err)
+	}
+	return deviceByID(devices, deviceID)
+}
+
+// deviceByID 在设备列表中查找 ID，找不到时返回包装了 ErrDeviceNotFound 的错误
+func deviceByID(devices []*portaudio.DeviceInfo, deviceID int) (*portaudio.DeviceInfo, error) {
+	if deviceID >= 0 && deviceID < len(devices) {
+		return devices[deviceID], nil
+	}
+	return nil, fmt.Errorf("%w: id %d", ErrDeviceNotFound, deviceID)
+}
+
+// checkDeviceChannels 检查设备是否支持所需的输入/输出声道数
+func checkDeviceChannels(device *portaudio.DeviceInfo, inputChannels, outputChannels int) error {
+	if inputChannels > device.MaxInputChannels {
+		return fmt.Errorf("device %q supports %d input channels, %d requested",
+			device.Name, device.MaxInputChannels, inputChannels)
+	}
+	if outputChannels > device.MaxOutputChannels {
+		return fmt.Errorf("device %q supports %d output channels, %d requested",
+			device.Name, device.MaxOutputChannels, outputChannels)
+	}
+	return nil
+}
+
+// inputStreamParameters 在设备上以低延迟录制单声道
+func inputStreamParameters(device *portaudio.DeviceInfo, sampleRate float64, framesPerBuffer int) portaudio.StreamParameters {
+	return portaudio.StreamParameters{
+		Input: portaudio.StreamDeviceParameters{
+			Device:   device,
+			Channels: channels,
+			Latency:  device.DefaultLowInputLatency,
+		},
+		SampleRate:      sampleRate,
+		FramesPerBuffer: framesPerBuffer,
+	}
+}
+
+// outputStreamParameters 在设备上以低延迟输出
+func outputStreamParameters(device *portaudio.DeviceInfo, sampleRate float64, outputChannels int) portaudio.StreamParameters {
+	return portaudio.StreamParameters{
+		Output: portaudio.StreamDeviceParameters{
+			Device:   device,
+			Channels: outputChannels,
+			Latency:  device.DefaultLowOutputLatency,
+		},
+		SampleRate:      sampleRate,
+		FramesPerBuffer: outputFramesPerBuffer,
+	}
+}
+
+// deviceOutputStream 返回在指定设备上打开输出流的 streamFactory，Reopen 时使用同一设备
+func deviceOutputStream(device *portaudio.DeviceInfo) streamFactory {
+	return func(sampleRate, channels int, callback func(out []float32)) (outputStream, error) {
+		params := outputStreamParameters(device, float64(sampleRate), channels)
+		if err := portaudio.IsFormatSupported(params, callback); err != nil {
+			return nil, fmt.Errorf("device %q does not support %d Hz with %d channels: %w",
+				device.Name, sampleRate, channels, err)
+		}
+		stream, err := portaudio.OpenStream(params, callback)
+		if err != nil {
+			return nil, err
+		}
+		return stream, nil
+	}
+}
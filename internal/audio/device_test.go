@@ -0,0 +1,87 @@
+package audio
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+func TestDeviceInfos(t *testing.T) {
+	host := &portaudio.HostApiInfo{Name: "ALSA"}
+	mic := &portaudio.DeviceInfo{Name: "USB Mic", MaxInputChannels: 1, DefaultSampleRate: 48000, HostApi: host}
+	speaker := &portaudio.DeviceInfo{Name: "Speakers", MaxOutputChannels: 2, DefaultSampleRate: 44100}
+
+	infos := deviceInfos([]*portaudio.DeviceInfo{mic, speaker}, mic, speaker)
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(infos))
+	}
+	if got := infos[0]; got.ID != 0 || got.Name != "USB Mic" || got.HostAPI != "ALSA" || got.MaxInputChannels != 1 || !got.IsDefaultInput || got.IsDefaultOutput {
+		t.Errorf("Unexpected input device info: %+v", got)
+	}
+	if got := infos[1]; got.ID != 1 || got.HostAPI != "" || got.MaxOutputChannels != 2 || got.DefaultSampleRate != 44100 || !got.IsDefaultOutput {
+		t.Errorf("Unexpected output device info: %+v", got)
+	}
+
+	// 没有默认设备时不标记
+	for _, info := range deviceInfos([]*portaudio.DeviceInfo{mic}, nil, nil) {
+		if info.IsDefaultInput || info.IsDefaultOutput {
+			t.Errorf("Expected no default flags, got %+v", info)
+		}
+	}
+}
+
+func TestDeviceLookup(t *testing.T) {
+	speaker := &portaudio.DeviceInfo{Name: "Speakers", MaxOutputChannels: 2}
+	mic := &portaudio.DeviceInfo{Name: "USB Mic", MaxInputChannels: 1}
+	devices := []*portaudio.DeviceInfo{speaker, mic}
+
+	if device, err := deviceByID(devices, 1); err != nil || device != mic {
+		t.Errorf("Expected to find device 1, got %v, %v", device, err)
+	}
+	for _, id := range []int{2, -1} {
+		if _, err := deviceByID(devices, id); !errors.Is(err, ErrDeviceNotFound) {
+			t.Errorf("Expected ErrDeviceNotFound for id %d, got %v", id, err)
+		}
+	}
+
+	if err := checkDeviceChannels(mic, 1, 0); err != nil {
+		t.Errorf("Expected mono input to be supported, got %v", err)
+	}
+	if err := checkDeviceChannels(mic, 0, 1); err == nil {
+		t.Error("Expected error for output on an input-only device")
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	devices, err := ListDevices()
+	if err != nil {
+		t.Skipf("Audio devices not available: %v", err)
+	}
+	if len(devices) == 0 {
+		t.Skip("No audio devices")
+	}
+
+	for _, device := range devices {
+		if device.Name == "" || device.MaxInputChannels+device.MaxOutputChannels == 0 {
+			t.Errorf("Unexpected device: %+v", device)
+		}
+	}
+}
+
+func TestNewWithDeviceNotFound(t *testing.T) {
+	if _, err := ListDevices(); err != nil {
+		t.Skipf("Audio system not available: %v", err)
+	}
+
+	const missing = 1 << 20
+	if _, err := NewInputWithDevice(missing); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for input, got %v", err)
+	}
+	if _, err := NewAudioOutputWithDevice(missing, 16000); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for output, got %v", err)
+	}
+	if GetManager().RefCount() != 0 {
+		t.Errorf("Expected failed opens to release the audio system, %d owners left", GetManager().RefCount())
+	}
+}
@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DownloadAudio 下载远程音频数据，返回数据与 Content-Type（超过 maxBytes 时报错）
+func DownloadAudio(ctx context.Context, client *http.Client, url string, maxBytes int64) ([]byte, string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download audio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("audio download failed with status: %d", resp.StatusCode)
+	}
+
+	// 服务器声明的大小已超过限制，直接拒绝
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("audio size %d bytes exceeds maximum allowed size of %d bytes", resp.ContentLength, maxBytes)
+	}
+
+	var reader io.Reader = resp.Body
+	if maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, maxBytes+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read audio data: %w", err)
+	}
+
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("audio size exceeds maximum allowed size of %d bytes", maxBytes)
+	}
+
+	return data, resp.Header.Get("Content-Type"), nil
+}
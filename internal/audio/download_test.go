@@ -0,0 +1,80 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadAudio(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x55}, 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clip.mp3":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(payload)
+		case "/chunked":
+			// 分块发送，不声明 Content-Length，只能在读取时限制大小
+			w.Header().Set("Content-Type", "audio/mpeg")
+			for i := 0; i < 10; i++ {
+				w.Write(payload[:100])
+				w.(http.Flusher).Flush()
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	data, contentType, err := DownloadAudio(context.Background(), nil, server.URL+"/clip.mp3", 1000)
+	if err != nil {
+		t.Fatalf("DownloadAudio failed: %v", err)
+	}
+	if !bytes.Equal(data, payload) || contentType != "audio/mpeg" {
+		t.Errorf("Expected %d bytes of audio/mpeg, got %d bytes of %q", len(payload), len(data), contentType)
+	}
+
+	// 声明的大小和实际读取的大小都受限制
+	for _, path := range []string{"/clip.mp3", "/chunked"} {
+		if _, _, err := DownloadAudio(context.Background(), nil, server.URL+path, 999); err == nil || !strings.Contains(err.Error(), "exceeds") {
+			t.Errorf("%s: expected a size error, got %v", path, err)
+		}
+	}
+
+	if _, _, err := DownloadAudio(context.Background(), nil, server.URL+"/missing.mp3", 1000); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a status error, got %v", err)
+	}
+}
+
+func TestDownloadAudioCancelled(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		close(started)
+		// 只发送一部分，直到客户端断开
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	_, _, err := DownloadAudio(ctx, nil, server.URL+"/stream.wav", 1<<20)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the download to stop with context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to stop the download promptly, took %v", elapsed)
+	}
+}
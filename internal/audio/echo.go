@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"math"
+)
+
+// echoDecimation 计算互相关前的降采样倍数（16kHz 下降到 4kHz，足够判断语音相关性）
+const echoDecimation = 4
+
+// EchoCorrelation 返回 input 与 reference 在 0..maxDelay 个样本延迟范围内的最大归一化互相关（0-1）
+// reference 为最近的播放输出，采样率与 input 相同，末尾与 input 末尾对齐（延迟为 0）
+// 值越接近 1，说明 input 越可能只是扬声器输出被麦克风拾取的回声
+func EchoCorrelation(input, reference []float32, maxDelay int) float64 {
+	in := decimate(input, echoDecimation)
+	ref := decimate(reference, echoDecimation)
+	maxLag := maxDelay / echoDecimation
+
+	inEnergy := sumSquares(in)
+	if len(in) == 0 || len(ref) < len(in) || inEnergy == 0 {
+		return 0
+	}
+
+	best := 0.0
+	base := len(ref) - len(in)
+	for lag := 0; lag <= maxLag && base-lag >= 0; lag++ {
+		segment := ref[base-lag : base-lag+len(in)]
+		refEnergy := sumSquares(segment)
+		if refEnergy == 0 {
+			continue
+		}
+
+		var dot float64
+		for i, v := range in {
+			dot += float64(v) * float64(segment[i])
+		}
+		if corr := math.Abs(dot) / math.Sqrt(inEnergy*refEnergy); corr > best {
+			best = corr
+		}
+	}
+
+	return best
+}
+
+// decimate 按块取平均降采样
+func decimate(samples []float32, factor int) []float32 {
+	if factor <= 1 {
+		return samples
+	}
+
+	result := make([]float32, len(samples)/factor)
+	for i := range result {
+		var sum float32
+		for _, v := range samples[i*factor : (i+1)*factor] {
+			sum += v
+		}
+		result[i] = sum / float32(factor)
+	}
+	return result
+}
+
+// sumSquares 返回样本平方和
+func sumSquares(samples []float32) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += float64(v) * float64(v)
+	}
+	return sum
+}
@@ -0,0 +1,49 @@
+package audio
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// noiseSignal 生成可复现的随机信号
+func noiseSignal(seed int64, amplitude float64, length int) []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]float32, length)
+	for i := range samples {
+		samples[i] = float32((rng.Float64()*2 - 1) * amplitude)
+	}
+	return samples
+}
+
+func TestEchoCorrelationDelayedCopy(t *testing.T) {
+	const maxDelay = 4800 // 300ms @ 16kHz
+	reference := noiseSignal(1, 0.5, 10240+maxDelay)
+
+	// 麦克风拾取到延迟 120ms、衰减后的输出
+	delay := 1920
+	input := make([]float32, 10240)
+	for i := range input {
+		input[i] = 0.3 * reference[maxDelay-delay+i]
+	}
+
+	if corr := EchoCorrelation(input, reference, maxDelay); corr < 0.9 {
+		t.Errorf("Expected strong correlation for echo, got %.3f", corr)
+	}
+}
+
+func TestEchoCorrelationIndependentSignal(t *testing.T) {
+	const maxDelay = 4800
+	reference := noiseSignal(1, 0.5, 10240+maxDelay)
+	input := noiseSignal(2, 0.5, 10240)
+
+	if corr := EchoCorrelation(input, reference, maxDelay); corr > 0.3 {
+		t.Errorf("Expected weak correlation for independent signal, got %.3f", corr)
+	}
+
+	if corr := EchoCorrelation(input, make([]float32, len(reference)), maxDelay); corr != 0 {
+		t.Errorf("Expected zero correlation against silent output, got %.3f", corr)
+	}
+	if corr := EchoCorrelation(input, reference[:100], maxDelay); corr != 0 {
+		t.Errorf("Expected zero correlation for short reference, got %.3f", corr)
+	}
+}
@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"math"
+)
+
+// RemoveDCOffset 减去样本均值，去除麦克风引入的直流偏置
+func RemoveDCOffset(samples []float32) []float32 {
+	result := make([]float32, len(samples))
+	if len(samples) == 0 {
+		return result
+	}
+
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s)
+	}
+	mean := sum / float64(len(samples))
+
+	for i, s := range samples {
+		result[i] = float32(float64(s) - mean)
+	}
+	return result
+}
+
+// HighPassFilter 单极点高通滤波，衰减 cutoffHz 以下的低频（包括直流）
+func HighPassFilter(samples []float32, sampleRate int, cutoffHz float64) []float32 {
+	return NewHighPass(sampleRate, cutoffHz).Process(samples)
+}
+
+// HighPass 保存滤波状态的单极点高通滤波器，用于逐块处理连续的麦克风输入
+type HighPass struct {
+	alpha      float64
+	prevInput  float64
+	prevOutput float64
+}
+
+// NewHighPass 创建高通滤波器
+func NewHighPass(sampleRate int, cutoffHz float64) *HighPass {
+	alpha := 1.0
+	if sampleRate > 0 && cutoffHz > 0 {
+		rc := 1 / (2 * math.Pi * cutoffHz)
+		dt := 1 / float64(sampleRate)
+		alpha = rc / (rc + dt)
+	}
+	return &HighPass{alpha: alpha}
+}
+
+// Process 滤波一块样本，状态在块之间延续，避免块边界产生突变
+func (f *HighPass) Process(samples []float32) []float32 {
+	result := make([]float32, len(samples))
+	for i, s := range samples {
+		x := float64(s)
+		y := f.alpha * (f.prevOutput + x - f.prevInput)
+		f.prevInput = x
+		f.prevOutput = y
+		result[i] = float32(y)
+	}
+	return result
+}
+
+// Reset 清除滤波状态
+func (f *HighPass) Reset() {
+	f.prevInput = 0
+	f.prevOutput = 0
+}
@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// meanOf 计算样本均值
+func meanOf(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s)
+	}
+	return sum / float64(len(samples))
+}
+
+// offsetSine 生成带直流偏置的正弦波
+func offsetSine(offset float64, sampleRate, length int) []float32 {
+	samples := sineWave(0.2, 440, sampleRate, length)
+	for i := range samples {
+		samples[i] += float32(offset)
+	}
+	return samples
+}
+
+func TestRemoveDCOffset(t *testing.T) {
+	samples := offsetSine(0.3, 16000, 16000)
+
+	if mean := meanOf(RemoveDCOffset(samples)); math.Abs(mean) > 1e-6 {
+		t.Errorf("Expected mean ~0 after DC removal, got %f", mean)
+	}
+
+	if result := RemoveDCOffset(nil); len(result) != 0 {
+		t.Errorf("Expected empty result for empty input, got %d samples", len(result))
+	}
+}
+
+func TestHighPassFilterRemovesOffset(t *testing.T) {
+	sampleRate := 16000
+	samples := offsetSine(0.3, sampleRate, sampleRate)
+
+	filtered := HighPassFilter(samples, sampleRate, 80)
+
+	// 跳过滤波器的建立时间（时间常数约 2ms）
+	if mean := meanOf(filtered[sampleRate/10:]); math.Abs(mean) > 1e-3 {
+		t.Errorf("Expected mean ~0 after high-pass, got %f", mean)
+	}
+
+	// 通带内的 440Hz 信号基本保留
+	if rms := rmsAmplitude(filtered[sampleRate/10:]); math.Abs(rms-0.2/math.Sqrt2) > 0.01 {
+		t.Errorf("Expected 440Hz tone preserved, RMS %f", rms)
+	}
+}
+
+func TestHighPassChunked(t *testing.T) {
+	sampleRate := 16000
+	samples := offsetSine(0.3, sampleRate, sampleRate)
+
+	whole := HighPassFilter(samples, sampleRate, 80)
+
+	// 逐块处理与整体处理结果一致
+	filter := NewHighPass(sampleRate, 80)
+	var chunked []float32
+	for start := 0; start < len(samples); start += 1024 {
+		end := start + 1024
+		if end > len(samples) {
+			end = len(samples)
+		}
+		chunked = append(chunked, filter.Process(samples[start:end])...)
+	}
+
+	for i := range whole {
+		if math.Abs(float64(whole[i]-chunked[i])) > 1e-6 {
+			t.Fatalf("Chunked output differs at sample %d: %f vs %f", i, chunked[i], whole[i])
+		}
+	}
+}
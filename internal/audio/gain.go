@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"math"
+)
+
+// maxNormalizeGain 归一化允许的最大增益（约 26dB），避免把底噪放大成"语音"
+const maxNormalizeGain = 20.0
+
+// Normalize 按峰值归一化，使最大绝对值达到 targetPeak
+// targetPeak 会被限制在 [0, 1]，静音输入原样返回
+func Normalize(samples []float32, targetPeak float32) []float32 {
+	target := clampUnit(float64(targetPeak))
+
+	peak := peakAmplitude(samples)
+	if peak == 0 {
+		return applyGain(samples, 1)
+	}
+
+	return applyGain(samples, limitGain(target/peak, peak))
+}
+
+// NormalizeRMS 按均方根能量归一化，使 RMS 达到 targetRMS
+// 增益会被限制，保证峰值不超过 1（不削波）
+func NormalizeRMS(samples []float32, targetRMS float32) []float32 {
+	target := clampUnit(float64(targetRMS))
+
+	rms := rmsAmplitude(samples)
+	if rms == 0 {
+		return applyGain(samples, 1)
+	}
+
+	return applyGain(samples, limitGain(target/rms, peakAmplitude(samples)))
+}
+
+// RMS 返回样本的均方根能量，空输入返回 0
+func RMS(samples []float32) float64 {
+	return rmsAmplitude(samples)
+}
+
+// limitGain 限制增益不超过最大值，且放大后峰值不超过 1
+func limitGain(gain, peak float64) float64 {
+	if gain > maxNormalizeGain {
+		gain = maxNormalizeGain
+	}
+	if peak > 0 && peak*gain > 1 {
+		gain = 1 / peak
+	}
+	return gain
+}
+
+// applyGain 返回乘以增益并限制在 [-1, 1] 的新切片
+func applyGain(samples []float32, gain float64) []float32 {
+	result := make([]float32, len(samples))
+	for i, s := range samples {
+		result[i] = float32(clampSample(float64(s) * gain))
+	}
+	return result
+}
+
+// peakAmplitude 返回样本的最大绝对值
+func peakAmplitude(samples []float32) float64 {
+	var peak float64
+	for _, s := range samples {
+		if a := math.Abs(float64(s)); a > peak {
+			peak = a
+		}
+	}
+	return peak
+}
+
+// rmsAmplitude 返回样本的均方根
+func rmsAmplitude(samples []float32) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// clampUnit 将值限制在 [0, 1]
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// clampSample 将样本限制在 [-1, 1]
+func clampSample(v float64) float64 {
+	return math.Max(-1, math.Min(1, v))
+}
@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// sineWave 生成指定幅度的正弦波
+func sineWave(amplitude float64, frequency float64, sampleRate, length int) []float32 {
+	samples := make([]float32, length)
+	for i := range samples {
+		samples[i] = float32(amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+func TestNormalizeSilence(t *testing.T) {
+	silence := make([]float32, 1600)
+
+	for _, result := range [][]float32{Normalize(silence, 0.9), NormalizeRMS(silence, 0.1)} {
+		if len(result) != len(silence) {
+			t.Fatalf("Expected %d samples, got %d", len(silence), len(result))
+		}
+		for i, s := range result {
+			if s != 0 || math.IsNaN(float64(s)) {
+				t.Fatalf("Expected silence to stay silent, sample %d = %f", i, s)
+			}
+		}
+	}
+
+	if result := Normalize(nil, 0.9); len(result) != 0 {
+		t.Errorf("Expected empty result for empty input, got %d samples", len(result))
+	}
+}
+
+func TestNormalizeLoudAudio(t *testing.T) {
+	loud := sineWave(0.99, 440, 16000, 16000)
+
+	if peak := peakAmplitude(Normalize(loud, 1.5)); peak > 1 {
+		t.Errorf("Expected target peak clamped to 1, got peak %f", peak)
+	}
+
+	// 方波 RMS 等于峰值，目标 RMS 过高时不能削波
+	square := make([]float32, 1600)
+	for i := range square {
+		square[i] = 0.8
+		if i%2 == 1 {
+			square[i] = -0.8
+		}
+	}
+	if peak := peakAmplitude(NormalizeRMS(square, 0.95)); peak > 1 {
+		t.Errorf("NormalizeRMS clipped loud input, peak %f", peak)
+	}
+}
+
+func TestNormalizeQuietInput(t *testing.T) {
+	quiet := sineWave(0.05, 440, 16000, 16000)
+
+	if peak := peakAmplitude(Normalize(quiet, 0.9)); math.Abs(peak-0.9) > 1e-3 {
+		t.Errorf("Expected peak 0.9 after normalization, got %f", peak)
+	}
+
+	// 正弦波 RMS = 峰值 / √2
+	if rms := rmsAmplitude(NormalizeRMS(quiet, 0.2)); math.Abs(rms-0.2) > 1e-3 {
+		t.Errorf("Expected RMS 0.2 after normalization, got %f", rms)
+	}
+
+	// 极弱的底噪最多放大 maxNormalizeGain 倍
+	noise := sineWave(0.001, 440, 16000, 16000)
+	if peak := peakAmplitude(Normalize(noise, 0.9)); peak > 0.001*maxNormalizeGain+1e-6 {
+		t.Errorf("Expected gain limited to %.0fx, got peak %f", maxNormalizeGain, peak)
+	}
+}
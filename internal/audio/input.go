@@ -1,40 +1,107 @@
 package audio
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
 )
 
 const (
-	sampleRate      = 16000
-	channels        = 1
-	framesPerBuffer = 10240
+	sampleRate = 16000
+	channels   = 1
 )
 
+// 每次 Read 返回的帧数：默认 1024（16kHz 下 64ms），上限 2 秒
+const (
+	DefaultFramesPerBuffer = 1024
+	maxFramesPerBuffer     = 2 * sampleRate
+)
+
+// InputConfig 麦克风输入配置
+type InputConfig struct {
+	// FramesPerBuffer 每次 Read 读取的帧数，越小 VAD 和打断检测响应越快，但调用更频繁
+	FramesPerBuffer int
+}
+
+// DefaultInputConfig 返回低延迟的默认输入配置
+func DefaultInputConfig() InputConfig {
+	return InputConfig{FramesPerBuffer: DefaultFramesPerBuffer}
+}
+
+// inputStream Input 使用的阻塞读取流操作，由 *portaudio.Stream 实现
+type inputStream interface {
+	Start() error
+	Stop() error
+	Close() error
+	Read() error
+	Info() *portaudio.StreamInfo
+}
+
 type Input struct {
-	stream *portaudio.Stream
+	stream inputStream
 	buffer []float32
 	mu     sync.Mutex
 	queue  [][]float32
 }
 
+// NewInput 使用默认配置在默认输入设备上创建 16kHz 单声道输入
 func NewInput() (*Input, error) {
-	// 使用统一的音频管理器
-	manager := GetManager()
-	if err := manager.Initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize audio system: %w", err)
+	return NewInputWithConfig(DefaultInputConfig())
+}
+
+// NewInputWithConfig 按配置在默认输入设备上创建 16kHz 单声道输入
+func NewInputWithConfig(config InputConfig) (*Input, error) {
+	if config.FramesPerBuffer <= 0 || config.FramesPerBuffer > maxFramesPerBuffer {
+		return nil, fmt.Errorf("invalid frames per buffer: %d (must be between 1 and %d)", config.FramesPerBuffer, maxFramesPerBuffer)
 	}
 
+	return newInput(config.FramesPerBuffer, func(buffer []float32) (*portaudio.Stream, error) {
+		return portaudio.OpenDefaultStream(channels, 0, float64(sampleRate), len(buffer), buffer)
+	})
+}
+
+// NewInputWithDevice 在指定设备上创建 16kHz 单声道输入，设备 ID 来自 ListDevices
+func NewInputWithDevice(deviceID int) (*Input, error) {
+	return newInput(DefaultFramesPerBuffer, func(buffer []float32) (*portaudio.Stream, error) {
+		device, err := findDevice(deviceID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDeviceChannels(device, channels, 0); err != nil {
+			return nil, err
+		}
+
+		params := inputStreamParameters(device, float64(sampleRate), len(buffer))
+		if err := portaudio.IsFormatSupported(params, buffer); err != nil {
+			return nil, fmt.Errorf("device %q does not support %d Hz mono input: %w", device.Name, sampleRate, err)
+		}
+		return portaudio.OpenStream(params, buffer)
+	})
+}
+
+// newInput 初始化音频系统后用 open 打开录制到 buffer 的阻塞读取流，buffer 长度为 framesPerBuffer
+func newInput(framesPerBuffer int, open func(buffer []float32) (*portaudio.Stream, error)) (*Input, error) {
 	input := &Input{
 		buffer: make([]float32, framesPerBuffer),
 		queue:  make([][]float32, 0),
 	}
 
-	stream, err := portaudio.OpenDefaultStream(channels, 0, float64(sampleRate), framesPerBuffer, input.buffer)
+	// 使用统一的音频管理器
+	manager := GetManager()
+	if err := manager.Initialize(input); err != nil {
+		return nil, fmt.Errorf("failed to initialize audio system: %w", err)
+	}
+
+	stream, err := open(input.buffer)
 	if err != nil {
-		manager.Terminate() // 清理
+		manager.Terminate(input) // 清理
+		if errors.Is(err, ErrDeviceNotFound) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to open input stream: %w", err)
 	}
 
@@ -43,6 +110,11 @@ func NewInput() (*Input, error) {
 }
 
 func (i *Input) Start() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.stream == nil {
+		return errors.New("input stream is closed")
+	}
 	return i.stream.Start()
 }
 
@@ -56,6 +128,9 @@ func (i *Input) Read() ([]float32, error) {
 		i.queue = i.queue[1:]
 		return data, nil
 	}
+	if i.stream == nil {
+		return nil, errors.New("input stream is closed")
+	}
 
 	// 否则读取新的数据
 	err := i.stream.Read()
@@ -69,15 +144,86 @@ func (i *Input) Read() ([]float32, error) {
 	return data, nil
 }
 
+// Stream 启动输入流并在后台协程中持续读取，每块 FramesPerBuffer 帧依次从 frames 送出
+// ctx 取消或读取出错时停止输入流并关闭两个通道，出错时错误先送入 errs；输入溢出只丢失该块，不会结束读取
+// 返回的通道被读取期间不要再调用 Read
+func (i *Input) Stream(ctx context.Context) (<-chan []float32, <-chan error) {
+	frames := make(chan []float32)
+	errs := make(chan error, 1)
+
+	if err := i.Start(); err != nil {
+		errs <- fmt.Errorf("failed to start input stream: %w", err)
+		close(frames)
+		close(errs)
+		return frames, errs
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(frames)
+		defer i.stopStream()
+
+		for ctx.Err() == nil {
+			data, err := i.Read()
+			if errors.Is(err, portaudio.InputOverflowed) {
+				continue
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+
+			select {
+			case frames <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return frames, errs
+}
+
+// stopStream 停止输入流，已关闭时不做任何事
+func (i *Input) stopStream() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.stream != nil {
+		i.stream.Stop()
+	}
+}
+
+// FramesPerBuffer 返回每次 Read 读取的帧数
+func (i *Input) FramesPerBuffer() int {
+	return len(i.buffer)
+}
+
+// Latency 返回一次读取引入的延迟：缓冲区时长加上设备报告的输入延迟
+func (i *Input) Latency() time.Duration {
+	latency := time.Duration(len(i.buffer)) * time.Second / sampleRate
+	if i.stream != nil {
+		if info := i.stream.Info(); info != nil {
+			latency += info.InputLatency
+		}
+	}
+	return latency
+}
+
+// Close 关闭输入流并释放音频系统，重复调用是安全的
 func (i *Input) Close() error {
 	var err error
+	i.mu.Lock()
 	if i.stream != nil {
 		err = i.stream.Close()
+		i.stream = nil
 	}
+	i.mu.Unlock()
 
 	// 使用统一的音频管理器终止
 	manager := GetManager()
-	if termErr := manager.Terminate(); termErr != nil && err == nil {
+	if termErr := manager.Terminate(i); termErr != nil && err == nil {
 		err = termErr
 	}
 
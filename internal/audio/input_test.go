@@ -0,0 +1,184 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+func TestNewInputWithConfigValidation(t *testing.T) {
+	for _, frames := range []int{0, -1, maxFramesPerBuffer + 1} {
+		if _, err := NewInputWithConfig(InputConfig{FramesPerBuffer: frames}); err == nil {
+			t.Errorf("Expected error for %d frames per buffer", frames)
+		}
+	}
+
+	if config := DefaultInputConfig(); config.FramesPerBuffer != DefaultFramesPerBuffer {
+		t.Errorf("Expected default of %d frames, got %d", DefaultFramesPerBuffer, config.FramesPerBuffer)
+	}
+}
+
+func TestNewInputWithConfig(t *testing.T) {
+	input, err := NewInputWithConfig(InputConfig{FramesPerBuffer: 512})
+	if err != nil {
+		t.Skipf("Audio input not available: %v", err)
+	}
+	defer input.Close()
+
+	if input.FramesPerBuffer() != 512 {
+		t.Errorf("Expected 512 frames per buffer, got %d", input.FramesPerBuffer())
+	}
+	if latency := input.Latency(); latency < 32*time.Millisecond {
+		t.Errorf("Expected latency of at least the 32ms buffer, got %v", latency)
+	}
+}
+
+func TestInputLatency(t *testing.T) {
+	// 未打开设备时只有缓冲区时长
+	input := &Input{buffer: make([]float32, DefaultFramesPerBuffer)}
+	if latency := input.Latency(); latency != 64*time.Millisecond {
+		t.Errorf("Expected 64ms for %d frames at 16kHz, got %v", DefaultFramesPerBuffer, latency)
+	}
+}
+
+// fakeInputStream 依次把预设的帧写入缓冲区，用完后按设备节奏返回静音
+type fakeInputStream struct {
+	mu       sync.Mutex
+	buffer   []float32
+	frames   [][]float32
+	readErrs []error // 与 frames 对应，非 nil 时该次读取返回错误
+	started  bool
+	stopped  bool
+	startErr error
+}
+
+func (s *fakeInputStream) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	return s.startErr
+}
+
+func (s *fakeInputStream) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	return nil
+}
+
+func (s *fakeInputStream) Close() error { return nil }
+
+func (s *fakeInputStream) Info() *portaudio.StreamInfo { return &portaudio.StreamInfo{} }
+
+func (s *fakeInputStream) Read() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.frames) == 0 {
+		time.Sleep(time.Millisecond)
+		for j := range s.buffer {
+			s.buffer[j] = 0
+		}
+		return nil
+	}
+
+	frame, err := s.frames[0], error(nil)
+	s.frames = s.frames[1:]
+	if len(s.readErrs) > 0 {
+		err, s.readErrs = s.readErrs[0], s.readErrs[1:]
+	}
+	copy(s.buffer, frame)
+	return err
+}
+
+func (s *fakeInputStream) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// newFakeInput 创建从 fakeInputStream 读取的输入，每块 frameSize 帧
+func newFakeInput(frameSize int, frames ...[]float32) (*Input, *fakeInputStream) {
+	input := &Input{buffer: make([]float32, frameSize)}
+	stream := &fakeInputStream{buffer: input.buffer, frames: frames}
+	input.stream = stream
+	return input, stream
+}
+
+func TestInputStream(t *testing.T) {
+	input, stream := newFakeInput(4, []float32{1, 2, 3, 4}, []float32{5, 6, 7, 8}, []float32{9, 10, 11, 12})
+	ctx, cancel := context.WithCancel(context.Background())
+	frames, errs := input.Stream(ctx)
+
+	for n := 0; n < 3; n++ {
+		frame := <-frames
+		if len(frame) != 4 {
+			t.Fatalf("Expected 4-sample frames, got %d", len(frame))
+		}
+		for j, v := range frame {
+			if expected := float32(n*4 + j + 1); v != expected {
+				t.Fatalf("Frame %d sample %d: expected %v, got %v", n, j, expected, v)
+			}
+		}
+	}
+	if !stream.started {
+		t.Error("Expected Stream to start the input stream")
+	}
+
+	// 取消后两个通道都被关闭，输入流被停止
+	cancel()
+	deadline := time.After(2 * time.Second)
+	for frames != nil || errs != nil {
+		select {
+		case _, ok := <-frames:
+			if !ok {
+				frames = nil
+			}
+		case err, ok := <-errs:
+			if ok {
+				t.Errorf("Expected no error after cancel, got %v", err)
+			}
+			errs = nil
+		case <-deadline:
+			t.Fatal("Expected channels to close after cancel")
+		}
+	}
+	if !stream.isStopped() {
+		t.Error("Expected the input stream to be stopped on cancel")
+	}
+}
+
+func TestInputStreamErrors(t *testing.T) {
+	// 输入溢出只丢失该块，其他读取错误结束读取
+	input, stream := newFakeInput(2, []float32{1, 1}, []float32{2, 2}, []float32{3, 3})
+	stream.readErrs = []error{nil, portaudio.InputOverflowed, errors.New("device lost")}
+	frames, errs := input.Stream(context.Background())
+
+	var received [][]float32
+	for frame := range frames {
+		received = append(received, frame)
+	}
+	if len(received) != 1 || received[0][0] != 1 {
+		t.Errorf("Expected only the frame before the overflow, got %v", received)
+	}
+	if err := <-errs; err == nil || err.Error() != "device lost" {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+	if !stream.isStopped() {
+		t.Error("Expected the input stream to be stopped after a read error")
+	}
+
+	// 启动失败时立即返回错误并关闭通道
+	input, stream = newFakeInput(2)
+	stream.startErr = errors.New("busy")
+	frames, errs = input.Stream(context.Background())
+	if err := <-errs; err == nil {
+		t.Error("Expected a start error")
+	}
+	if _, ok := <-frames; ok {
+		t.Error("Expected frames to be closed after a start error")
+	}
+}
@@ -12,48 +12,57 @@ var (
 	managerOnce  sync.Once
 )
 
+// PortAudio 的初始化和终止函数，测试中替换以统计调用
+var (
+	portaudioInitialize = portaudio.Initialize
+	portaudioTerminate  = portaudio.Terminate
+)
+
 // Manager 管理音频系统的初始化和终止
+// 每个使用音频系统的对象（Input、AudioOutput）作为一个 owner 持有引用，最后一个 owner 释放时才终止 PortAudio
 type Manager struct {
 	mu          sync.Mutex
 	initialized bool
-	refCount    int
+	owners      map[interface{}]struct{}
 }
 
 // GetManager 获取全局音频管理器实例
 func GetManager() *Manager {
 	managerOnce.Do(func() {
-		audioManager = &Manager{}
+		audioManager = &Manager{owners: make(map[interface{}]struct{})}
 	})
 	return audioManager
 }
 
-// Initialize 初始化音频系统
-func (m *Manager) Initialize() error {
+// Initialize 为 owner 初始化音频系统，同一个 owner 重复调用只持有一个引用
+func (m *Manager) Initialize(owner interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if !m.initialized {
-		if err := portaudio.Initialize(); err != nil {
+		if err := portaudioInitialize(); err != nil {
 			return fmt.Errorf("failed to initialize PortAudio: %w", err)
 		}
 		m.initialized = true
 	}
 
-	m.refCount++
+	m.owners[owner] = struct{}{}
 	return nil
 }
 
-// Terminate 终止音频系统
-func (m *Manager) Terminate() error {
+// Terminate 释放 owner 持有的引用，最后一个引用释放时终止音频系统
+// owner 未持有引用（从未初始化或已经释放）时不做任何事，所以重复 Close 不会提前终止其他对象正在使用的 PortAudio
+func (m *Manager) Terminate(owner interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.refCount > 0 {
-		m.refCount--
+	if _, ok := m.owners[owner]; !ok {
+		return nil
 	}
+	delete(m.owners, owner)
 
-	if m.refCount == 0 && m.initialized {
-		if err := portaudio.Terminate(); err != nil {
+	if len(m.owners) == 0 && m.initialized {
+		if err := portaudioTerminate(); err != nil {
 			return fmt.Errorf("failed to terminate PortAudio: %w", err)
 		}
 		m.initialized = false
@@ -62,6 +71,13 @@ func (m *Manager) Terminate() error {
 	return nil
 }
 
+// RefCount 返回当前持有音频系统的 owner 数
+func (m *Manager) RefCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.owners)
+}
+
 // IsInitialized 检查音频系统是否已初始化
 func (m *Manager) IsInitialized() bool {
 	m.mu.Lock()
@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// useCountingPortAudio 替换全局管理器和 PortAudio 初始化函数，返回初始化和终止的调用次数
+func useCountingPortAudio(t *testing.T) (inits, terms *int) {
+	t.Helper()
+	inits, terms = new(int), new(int)
+
+	GetManager()
+	previous := audioManager
+	audioManager = &Manager{owners: make(map[interface{}]struct{})}
+	portaudioInitialize = func() error { *inits++; return nil }
+	portaudioTerminate = func() error { *terms++; return nil }
+	t.Cleanup(func() {
+		audioManager = previous
+		portaudioInitialize = portaudio.Initialize
+		portaudioTerminate = portaudio.Terminate
+	})
+	return inits, terms
+}
+
+// openTestDevices 像 NewInput 和 NewAudioOutput 一样注册到管理器，但不打开设备
+func openTestDevices(t *testing.T) (*Input, *AudioOutput) {
+	t.Helper()
+	input := &Input{}
+	output := newTestOutput(1, nil, 1)
+	for _, owner := range []interface{}{input, output} {
+		if err := GetManager().Initialize(owner); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+	}
+	return input, output
+}
+
+func TestManagerDoubleClose(t *testing.T) {
+	inits, terms := useCountingPortAudio(t)
+	input, output := openTestDevices(t)
+	manager := GetManager()
+
+	if *inits != 1 || manager.RefCount() != 2 {
+		t.Fatalf("Expected one PortAudio initialization shared by two owners, got %d inits, %d owners", *inits, manager.RefCount())
+	}
+
+	// 重复关闭输入不会释放输出持有的引用
+	input.Close()
+	input.Close()
+	if !manager.IsInitialized() || *terms != 0 || manager.RefCount() != 1 {
+		t.Fatalf("Expected PortAudio to stay initialized while the output is open, got %d terminations, %d owners", *terms, manager.RefCount())
+	}
+
+	output.Close()
+	output.Close()
+	if manager.IsInitialized() || *terms != 1 || manager.RefCount() != 0 {
+		t.Errorf("Expected a single termination after the last owner closed, got %d", *terms)
+	}
+}
+
+func TestManagerCloseOrders(t *testing.T) {
+	orders := map[string][]string{
+		"input first":         {"input", "output"},
+		"output first":        {"output", "input"},
+		"output twice first":  {"output", "output", "input"},
+		"interleaved repeats": {"input", "output", "input", "output"},
+	}
+
+	for name, order := range orders {
+		t.Run(name, func(t *testing.T) {
+			_, terms := useCountingPortAudio(t)
+			input, output := openTestDevices(t)
+
+			for i, device := range order {
+				if device == "input" {
+					input.Close()
+				} else {
+					output.Close()
+				}
+
+				// 两个都关闭之前 PortAudio 必须保持初始化
+				closedBoth := containsBoth(order[:i+1])
+				if GetManager().IsInitialized() == closedBoth {
+					t.Fatalf("After closing %v: initialized=%v", order[:i+1], GetManager().IsInitialized())
+				}
+			}
+			if *terms != 1 {
+				t.Errorf("Expected exactly one termination, got %d", *terms)
+			}
+		})
+	}
+
+	// 从未初始化的对象关闭时不影响其他对象
+	useCountingPortAudio(t)
+	_, output := openTestDevices(t)
+	stray := &Input{}
+	stray.Close()
+	if !GetManager().IsInitialized() {
+		t.Error("Expected an unregistered owner's Close to leave PortAudio initialized")
+	}
+	output.Close()
+}
+
+func containsBoth(closed []string) bool {
+	var input, output bool
+	for _, device := range closed {
+		input = input || device == "input"
+		output = output || device == "output"
+	}
+	return input && output
+}
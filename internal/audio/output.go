@@ -2,29 +2,124 @@ package audio
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/gordonklaus/portaudio"
+	"github.com/tosone/minimp3"
 )
 
+// PlaybackDeviceError 输出设备不可用：流启动失败且重新打开设备后仍然失败
+// 调用方可以用 errors.As 判断，选择提示用户检查设备或跳过播放
+type PlaybackDeviceError struct {
+	Op  string // 失败的操作：start 或 reopen
+	Err error
+}
+
+func (e *PlaybackDeviceError) Error() string {
+	return fmt.Sprintf("failed to %s audio stream: %v", e.Op, e.Err)
+}
+
+func (e *PlaybackDeviceError) Unwrap() error {
+	return e.Err
+}
+
+// outputStream AudioOutput 使用的流操作，由 *portaudio.Stream 实现
+type outputStream interface {
+	Start() error
+	Stop() error
+	Close() error
+}
+
+// streamFactory 打开按回调输出的流，测试中可以替换以模拟设备故障
+type streamFactory func(sampleRate, channels int, callback func(out []float32)) (outputStream, error)
+
+// outputFramesPerBuffer 输出回调每次处理的帧数
+const outputFramesPerBuffer = 1024
+
+// openDefaultOutputStream 在默认输出设备上打开流
+func openDefaultOutputStream(sampleRate, channels int, callback func(out []float32)) (outputStream, error) {
+	stream, err := portaudio.OpenDefaultStream(0, channels, float64(sampleRate), outputFramesPerBuffer, callback)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
 // AudioOutput 音频输出结构
 type AudioOutput struct {
-	stream      *portaudio.Stream
+	stream      outputStream  // 输出流，重新打开失败后为 nil
+	openStream  streamFactory // 创建和重新打开输出流
 	samples     []float32
 	position    int
 	finished    bool
 	interrupted bool
+	paused      bool        // 暂停中，回调输出静音但保留播放位置
+	streaming   bool        // 流式播放中，缓冲区耗尽时等待更多数据而不是结束
+	queueing    bool        // Enqueue 队列播放中，回调从 queue 读取样本
+	queue       *ringBuffer // Enqueue 写入的单声道样本
 	mu          sync.Mutex
 	sampleRate  int
+	channels    int            // 输出流的声道数
+	srcChannels int            // 当前缓冲区内容的声道数（交错存储），单声道内容在多声道流上会被复制到各声道
+	speed       float64        // 播放速度（1.0 为原速），通过时间伸缩实现，不改变音调
+	resampler   ResampleMethod // PlayAudioData 使用的重采样算法，默认线性插值
+	volume      float64        // 目标音量（0-1）
+	gain        float64        // 当前实际增益，逐帧向 volume 平滑过渡，避免播放中调节音量产生爆音
+	history     []float32      // 最近输出的单声道样本（循环写入），用于回声抑制
+	historyPos  int            // history 的下一个写入位置
 }
 
-// NewAudioOutput 创建音频输出
+// outputHistorySeconds 保留的输出历史时长
+const outputHistorySeconds = 2
+
+// volumeRampStep 每帧增益的最大变化量（16kHz 下约 16ms 完成 0→1 的过渡）
+const volumeRampStep = 1.0 / 256
+
+// errPlaybackStopped 播放已被停止，流式读取应提前结束
+var errPlaybackStopped = errors.New("playback stopped")
+
+// NewAudioOutput 创建单声道音频输出
 func NewAudioOutput(sampleRate int) (*AudioOutput, error) {
-	if err := GetManager().Initialize(); err != nil {
+	return NewAudioOutputWithChannels(sampleRate, 1)
+}
+
+// NewAudioOutputWithChannels 创建指定声道数的音频输出（只支持立体声的设备使用 2）
+func NewAudioOutputWithChannels(sampleRate, channels int) (*AudioOutput, error) {
+	return newAudioOutput(sampleRate, channels, openDefaultOutputStream)
+}
+
+// NewAudioOutputWithDevice 在指定设备上创建单声道音频输出，设备 ID 来自 ListDevices
+func NewAudioOutputWithDevice(deviceID, sampleRate int) (*AudioOutput, error) {
+	owner := new(int)
+	manager := GetManager()
+	if err := manager.Initialize(owner); err != nil {
 		return nil, fmt.Errorf("failed to initialize audio manager: %w", err)
 	}
+	defer manager.Terminate(owner)
+
+	device, err := findDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDeviceChannels(device, 0, 1); err != nil {
+		return nil, err
+	}
+	return newAudioOutput(sampleRate, 1, deviceOutputStream(device))
+}
+
+// newAudioOutput 创建音频输出，openStream 决定使用的设备
+func newAudioOutput(sampleRate, channels int, openStream streamFactory) (*AudioOutput, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid output sample rate: %d", sampleRate)
+	}
+	if channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("unsupported output channels: %d", channels)
+	}
 
 	output := &AudioOutput{
 		samples:     make([]float32, 0),
@@ -32,11 +127,25 @@ func NewAudioOutput(sampleRate int) (*AudioOutput, error) {
 		finished:    false,
 		interrupted: false,
 		sampleRate:  sampleRate,
+		channels:    channels,
+		srcChannels: 1,
+		speed:       1.0,
+		volume:      1.0,
+		gain:        1.0,
+		resampler:   ResampleMethodLinear,
+		queue:       newRingBuffer(ringInitialCapacity),
+		history:     make([]float32, sampleRate*outputHistorySeconds),
+		openStream:  openStream,
+	}
+
+	if err := GetManager().Initialize(output); err != nil {
+		return nil, fmt.Errorf("failed to initialize audio manager: %w", err)
 	}
 
 	// 使用回调创建流
-	stream, err := portaudio.OpenDefaultStream(0, 1, float64(sampleRate), 1024, output.audioCallback)
+	stream, err := output.openStream(sampleRate, channels, output.audioCallback)
 	if err != nil {
+		GetManager().Terminate(output)
 		return nil, fmt.Errorf("failed to open output stream: %w", err)
 	}
 
@@ -44,7 +153,45 @@ func NewAudioOutput(sampleRate int) (*AudioOutput, error) {
 	return output, nil
 }
 
-// audioCallback 音频回调函数
+// Reopen 关闭并以相同的采样率和声道数重新打开输出流，用于设备断开（如拔出 USB 声卡）后恢复
+// 旧流的关闭错误会被忽略，设备消失时关闭通常也会失败
+func (ao *AudioOutput) Reopen() error {
+	if ao.stream != nil {
+		ao.stream.Close()
+		ao.stream = nil
+	}
+
+	if ao.openStream == nil {
+		return &PlaybackDeviceError{Op: "reopen", Err: errors.New("no output stream factory")}
+	}
+	stream, err := ao.openStream(ao.sampleRate, ao.channels, ao.audioCallback)
+	if err != nil {
+		return &PlaybackDeviceError{Op: "reopen", Err: err}
+	}
+	ao.stream = stream
+	return nil
+}
+
+// startStream 启动输出流，失败时重新打开设备并重试一次，仍失败返回 *PlaybackDeviceError
+func (ao *AudioOutput) startStream() error {
+	if ao.stream != nil {
+		err := ao.stream.Start()
+		if err == nil {
+			return nil
+		}
+		fmt.Printf("启动音频流失败，重新打开输出设备: %v\n", err)
+	}
+
+	if err := ao.Reopen(); err != nil {
+		return err
+	}
+	if err := ao.stream.Start(); err != nil {
+		return &PlaybackDeviceError{Op: "start", Err: err}
+	}
+	return nil
+}
+
+// audioCallback 音频回调函数，out 为按输出声道交错的帧
 func (ao *AudioOutput) audioCallback(out []float32) {
 	ao.mu.Lock()
 	defer ao.mu.Unlock()
@@ -58,19 +205,127 @@ func (ao *AudioOutput) audioCallback(out []float32) {
 		return
 	}
 
-	for i := range out {
-		if ao.position < len(ao.samples) {
-			out[i] = ao.samples[ao.position]
-			ao.position++
-		} else {
+	outChannels := ao.channels
+	if outChannels < 1 {
+		outChannels = 1
+	}
+
+	// 暂停时输出静音，不推进播放位置
+	if ao.paused {
+		for i := range out {
 			out[i] = 0.0
-			ao.finished = true
+		}
+		for i := 0; i < len(out); i += outChannels {
+			ao.recordHistory(0)
+		}
+		return
+	}
+	srcChannels := ao.srcChannels
+	if srcChannels < 1 {
+		srcChannels = 1
+	}
+
+	var queued [1]float32
+	for i := 0; i < len(out); i += outChannels {
+		frame := out[i:min(i+outChannels, len(out))]
+
+		var src []float32
+		if ao.queueing {
+			if v, ok := ao.queue.pop(); ok {
+				queued[0] = v
+				src = queued[:]
+			}
+		} else if ao.position+srcChannels <= len(ao.samples) {
+			src = ao.samples[ao.position : ao.position+srcChannels]
+			ao.position += srcChannels
+		}
+
+		if src != nil {
+			mixFrame(frame, src)
+
+			ao.gain += math.Max(-volumeRampStep, math.Min(volumeRampStep, ao.volume-ao.gain))
+			if ao.gain != 1 {
+				for c := range frame {
+					frame[c] *= float32(ao.gain)
+				}
+			}
+		} else {
+			for c := range frame {
+				frame[c] = 0.0
+			}
+			// 流式或队列播放时缓冲区暂时为空（欠载），输出静音并继续等待后续数据
+			if !ao.streaming && !ao.queueing {
+				ao.finished = true
+			}
+		}
+
+		var mono float32
+		for _, v := range frame {
+			mono += v
+		}
+		ao.recordHistory(mono / float32(len(frame)))
+	}
+}
+
+// recordHistory 记录一个已输出的单声道样本
+func (ao *AudioOutput) recordHistory(v float32) {
+	if len(ao.history) == 0 {
+		return
+	}
+	ao.history[ao.historyPos] = v
+	ao.historyPos = (ao.historyPos + 1) % len(ao.history)
+}
+
+// RecentOutput 返回最近实际输出的 n 个单声道样本（按时间顺序，采样率为 GetSampleRate()）
+// 最多保留 2 秒，尚未输出的部分以静音填充
+func (ao *AudioOutput) RecentOutput(n int) []float32 {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+
+	size := len(ao.history)
+	if n > size {
+		n = size
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	result := make([]float32, n)
+	start := (ao.historyPos - n + size) % size
+	copied := copy(result, ao.history[start:])
+	copy(result[copied:], ao.history[:n-copied])
+	return result
+}
+
+// mixFrame 将一帧源样本写入输出帧：声道数相同时直接复制，单声道复制到所有声道，多声道输出到单声道时取平均
+func mixFrame(dst, src []float32) {
+	switch {
+	case len(src) == len(dst):
+		copy(dst, src)
+	case len(src) == 1:
+		for c := range dst {
+			dst[c] = src[0]
+		}
+	case len(dst) == 1:
+		var sum float32
+		for _, v := range src {
+			sum += v
+		}
+		dst[0] = sum / float32(len(src))
+	default:
+		for c := range dst {
+			dst[c] = src[c%len(src)]
 		}
 	}
 }
 
 // PlayAudioData 播放音频数据，支持多种格式和自动重采样
+// targetSampleRate 必须与输出流的采样率一致，否则播放速度会出错
 func (ao *AudioOutput) PlayAudioData(ctx context.Context, audioData []byte, targetSampleRate int) error {
+	if targetSampleRate != ao.sampleRate {
+		return fmt.Errorf("target sample rate %d Hz does not match output stream rate %d Hz", targetSampleRate, ao.sampleRate)
+	}
+
 	// 使用解码器解码音频数据
 	decoder := NewAudioDecoder()
 	samples, sourceSampleRate, err := decoder.DecodeAudioData(audioData)
@@ -82,43 +337,70 @@ func (ao *AudioOutput) PlayAudioData(ctx context.Context, audioData []byte, targ
 
 	// 重采样到目标采样率
 	if sourceSampleRate != targetSampleRate {
-		samples, err = decoder.ResampleAudio(samples, sourceSampleRate, targetSampleRate)
-		if err != nil {
-			return fmt.Errorf("failed to resample audio: %w", err)
+		if ao.GetResampleMethod() == ResampleMethodSinc {
+			samples = ResampleSinc(samples, sourceSampleRate, targetSampleRate, defaultSincQuality)
+		} else {
+			samples, err = decoder.ResampleAudio(samples, sourceSampleRate, targetSampleRate)
+			if err != nil {
+				return fmt.Errorf("failed to resample audio: %w", err)
+			}
 		}
 	}
 
 	return ao.PlaySamples(ctx, samples)
 }
 
-// PlaySamples 播放已解码的音频样本（支持上下文取消）
+// PlaySamples 播放已解码的单声道音频样本（支持上下文取消）
 func (ao *AudioOutput) PlaySamples(ctx context.Context, samples []float32) error {
+	return ao.PlayInterleavedSamples(ctx, samples, 1)
+}
+
+// PlayInterleavedSamples 播放按声道交错存储的音频样本（支持上下文取消）
+// 单声道内容在立体声流上复制到左右声道，立体声内容在单声道流上混合为单声道
+func (ao *AudioOutput) PlayInterleavedSamples(ctx context.Context, samples []float32, channels int) error {
 	if len(samples) == 0 {
 		return fmt.Errorf("no audio samples to play")
 	}
+	if channels < 1 || channels > 2 {
+		return fmt.Errorf("unsupported sample channels: %d", channels)
+	}
+	if len(samples)%channels != 0 {
+		return fmt.Errorf("sample count %d is not a multiple of %d channels", len(samples), channels)
+	}
 
 	ao.mu.Lock()
-	ao.samples = make([]float32, len(samples))
-	copy(ao.samples, samples)
+	if ao.speed != 1.0 {
+		ao.samples = stretchInterleaved(samples, channels, ao.speed)
+	} else {
+		ao.samples = make([]float32, len(samples))
+		copy(ao.samples, samples)
+	}
+	ao.srcChannels = channels
+	ao.gain = ao.volume
 	ao.position = 0
 	ao.finished = false
 	ao.interrupted = false
+	ao.paused = false
+	ao.queueing = false
+	ao.streaming = false
 	ao.mu.Unlock()
 
 	// 开始播放
-	if err := ao.stream.Start(); err != nil {
-		return fmt.Errorf("failed to start audio stream: %w", err)
+	if err := ao.startStream(); err != nil {
+		return err
 	}
 
 	// 等待播放完成或被取消
 	ticker := time.NewTicker(10 * time.Millisecond)
 	defer ticker.Stop()
 
+waitLoop:
 	for {
 		select {
 		case <-ctx.Done():
 			// 上下文被取消，停止播放
 			ao.Stop()
+			ao.stream.Stop()
 			return ctx.Err()
 		case <-ticker.C:
 			ao.mu.Lock()
@@ -126,7 +408,8 @@ func (ao *AudioOutput) PlaySamples(ctx context.Context, samples []float32) error
 			ao.mu.Unlock()
 
 			if finished {
-				break
+				// 注意：select 中的 break 只会跳出 select，需要带标签跳出循环
+				break waitLoop
 			}
 		}
 	}
@@ -139,28 +422,387 @@ func (ao *AudioOutput) PlaySamples(ctx context.Context, samples []float32) error
 	return nil
 }
 
+// PlayStream 边接收边播放音频流（支持上下文取消）
+// format 为 "pcm"（16 位有符号小端单声道，需指定 sourceSampleRate）或 "mp3"（采样率由解码器获取）
+func (ao *AudioOutput) PlayStream(ctx context.Context, stream io.Reader, format string, sourceSampleRate int) error {
+	var mp3Decoder *minimp3.Decoder
+	pcmReader := stream
+
+	switch format {
+	case "pcm":
+		if sourceSampleRate <= 0 {
+			return fmt.Errorf("invalid source sample rate for PCM stream: %d", sourceSampleRate)
+		}
+	case "mp3":
+		decoder, err := minimp3.NewDecoder(stream)
+		if err != nil {
+			return fmt.Errorf("failed to create MP3 stream decoder: %w", err)
+		}
+		defer decoder.Close()
+		mp3Decoder = decoder
+		pcmReader = decoder
+	default:
+		return fmt.Errorf("unsupported stream format: %s", format)
+	}
+
+	ao.mu.Lock()
+	ao.samples = make([]float32, 0)
+	ao.srcChannels = 1
+	ao.gain = ao.volume
+	ao.position = 0
+	ao.finished = false
+	ao.interrupted = false
+	ao.paused = false
+	ao.queueing = false
+	ao.streaming = true
+	ao.mu.Unlock()
+
+	// 开始播放
+	if err := ao.startStream(); err != nil {
+		ao.endStream()
+		return err
+	}
+
+	// 后台读取并解码数据，逐块送入播放缓冲区
+	feedErr := make(chan error, 1)
+	go func() {
+		feedErr <- ao.feedStream(ctx, pcmReader, mp3Decoder, sourceSampleRate)
+	}()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 上下文被取消，停止播放
+			ao.Stop()
+			ao.stream.Stop()
+			return ctx.Err()
+		case err := <-feedErr:
+			feedErr = nil
+			ao.endStream()
+			if err != nil && err != errPlaybackStopped {
+				ao.Stop()
+				ao.stream.Stop()
+				return fmt.Errorf("failed to read audio stream: %w", err)
+			}
+		case <-ticker.C:
+			ao.mu.Lock()
+			finished := ao.finished || ao.interrupted
+			ao.mu.Unlock()
+
+			if finished {
+				if err := ao.stream.Stop(); err != nil {
+					return fmt.Errorf("failed to stop audio stream: %w", err)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// feedStream 从 PCM 读取器中读取 16 位样本，转换并重采样后追加到播放缓冲区
+func (ao *AudioOutput) feedStream(ctx context.Context, reader io.Reader, mp3Decoder *minimp3.Decoder, sourceSampleRate int) error {
+	buf := make([]byte, 4096)
+	var pending []byte
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		n, err := reader.Read(buf)
+		if n > 0 {
+			channels := 1
+			rate := sourceSampleRate
+			if mp3Decoder != nil {
+				channels = mp3Decoder.Channels
+				rate = mp3Decoder.SampleRate
+			}
+			if channels < 1 {
+				channels = 1
+			}
+
+			pending = append(pending, buf[:n]...)
+			frameBytes := 2 * channels
+			usable := len(pending) - len(pending)%frameBytes
+
+			samples := pcm16ToFloat32(pending[:usable], channels)
+			pending = append(pending[:0], pending[usable:]...)
+
+			if rate > 0 && rate != ao.sampleRate {
+				samples = ResampleLinear(samples, rate, ao.sampleRate)
+			}
+
+			if !ao.appendStreamSamples(samples) {
+				return errPlaybackStopped
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// appendStreamSamples 追加流式样本，已播放部分会被丢弃以限制内存。播放被停止时返回 false
+func (ao *AudioOutput) appendStreamSamples(samples []float32) bool {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+
+	if ao.interrupted {
+		return false
+	}
+
+	remaining := ao.samples[ao.position:]
+	buffer := make([]float32, 0, len(remaining)+len(samples))
+	buffer = append(buffer, remaining...)
+	ao.samples = append(buffer, samples...)
+	ao.position = 0
+	return true
+}
+
+// Enqueue 追加单声道样本到播放队列，队列未在播放时会开始播放
+// 生产者可以在播放进行中持续写入解码后的数据块，配合 Drain 等待播放完毕
+func (ao *AudioOutput) Enqueue(samples []float32) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	ao.mu.Lock()
+	start := !ao.queueing
+	if start {
+		// 开始新的队列播放
+		ao.samples = ao.samples[:0]
+		ao.srcChannels = 1
+		ao.gain = ao.volume
+		ao.position = 0
+		ao.finished = false
+		ao.interrupted = false
+		ao.paused = false
+		ao.queueing = true
+		ao.queue.reset()
+	}
+	ao.queue.write(samples)
+	ao.mu.Unlock()
+
+	if start && ao.openStream != nil {
+		if err := ao.startStream(); err != nil {
+			ao.mu.Lock()
+			ao.queueing = false
+			ao.queue.reset()
+			ao.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// Drain 阻塞直到队列中的样本全部播放完毕或播放被停止，然后结束队列播放
+// 上下文取消时停止播放并返回 ctx.Err()
+func (ao *AudioOutput) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		ao.mu.Lock()
+		if !ao.queueing {
+			ao.mu.Unlock()
+			return nil
+		}
+		done := ao.queue.len() == 0 || ao.interrupted
+		if done {
+			ao.queueing = false
+			ao.finished = true
+			ao.queue.reset()
+		}
+		ao.mu.Unlock()
+
+		if done {
+			if ao.stream != nil {
+				if err := ao.stream.Stop(); err != nil {
+					return fmt.Errorf("failed to stop audio stream: %w", err)
+				}
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			ao.Stop()
+			ao.mu.Lock()
+			ao.queueing = false
+			ao.queue.reset()
+			ao.mu.Unlock()
+			if ao.stream != nil {
+				ao.stream.Stop()
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// QueuedSamples 返回播放队列中尚未播放的样本数
+func (ao *AudioOutput) QueuedSamples() int {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	return ao.queue.len()
+}
+
+// stretchInterleaved 对交错的多声道样本逐声道做时间伸缩
+func stretchInterleaved(samples []float32, channels int, factor float64) []float32 {
+	if channels == 1 {
+		return TimeStretch(samples, factor)
+	}
+
+	frames := len(samples) / channels
+	var result []float32
+	for c := 0; c < channels; c++ {
+		channel := make([]float32, frames)
+		for i := range channel {
+			channel[i] = samples[i*channels+c]
+		}
+		stretched := TimeStretch(channel, factor)
+		if result == nil {
+			result = make([]float32, len(stretched)*channels)
+		}
+		for i, v := range stretched {
+			result[i*channels+c] = v
+		}
+	}
+	return result
+}
+
+// endStream 标记流式数据已全部到达，缓冲区播放完毕后即结束
+func (ao *AudioOutput) endStream() {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	ao.streaming = false
+}
+
+// SetPlaybackSpeed 设置本地播放速度（0.5-2.0），对已合成的音频做时间伸缩，音调不变
+// 与 TTS 的 Speed 不同，不需要重新调用 API；对下一次 PlaySamples 生效，流式播放不受影响
+func (ao *AudioOutput) SetPlaybackSpeed(speed float64) error {
+	if speed < 0.5 || speed > 2.0 {
+		return fmt.Errorf("playback speed must be between 0.5 and 2.0, got %.2f", speed)
+	}
+
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	ao.speed = speed
+	return nil
+}
+
+// GetPlaybackSpeed 获取当前播放速度
+func (ao *AudioOutput) GetPlaybackSpeed() float64 {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	return ao.speed
+}
+
+// SetResampleMethod 设置 PlayAudioData 的重采样算法
+// sinc 音质更好但 CPU 开销更高，受限设备保持默认的线性插值
+func (ao *AudioOutput) SetResampleMethod(method ResampleMethod) error {
+	if method != ResampleMethodLinear && method != ResampleMethodSinc {
+		return fmt.Errorf("unsupported resample method: %d", method)
+	}
+
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	ao.resampler = method
+	return nil
+}
+
+// GetResampleMethod 获取当前重采样算法
+func (ao *AudioOutput) GetResampleMethod() ResampleMethod {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	return ao.resampler
+}
+
+// SetVolume 设置播放音量（0.0-1.0，超出范围会被截断），播放中调节会平滑过渡
+func (ao *AudioOutput) SetVolume(v float64) {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	ao.volume = clampUnit(v)
+}
+
+// GetVolume 获取当前播放音量
+func (ao *AudioOutput) GetVolume() float64 {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	return ao.volume
+}
+
+// GetSampleRate 获取输出流的采样率
+func (ao *AudioOutput) GetSampleRate() int {
+	return ao.sampleRate
+}
+
+// GetChannels 获取输出流的声道数
+func (ao *AudioOutput) GetChannels() int {
+	return ao.channels
+}
+
 // Stop 停止当前播放
 func (ao *AudioOutput) Stop() {
 	ao.mu.Lock()
 	defer ao.mu.Unlock()
 	ao.interrupted = true
 	ao.finished = true
+	ao.paused = false
+	ao.queue.reset()
 }
 
-// IsPlaying 检查是否正在播放
+// Pause 暂停播放，保留当前位置，之后可用 Resume 继续
+// 播放调用（PlaySamples 等）在暂停期间保持阻塞
+func (ao *AudioOutput) Pause() {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	if ao.finished || ao.interrupted {
+		return
+	}
+	ao.paused = true
+}
+
+// Resume 从暂停位置继续播放
+func (ao *AudioOutput) Resume() {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	ao.paused = false
+}
+
+// IsPaused 检查是否处于暂停状态
+func (ao *AudioOutput) IsPaused() bool {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+	return ao.paused
+}
+
+// IsPlaying 检查是否正在播放（暂停时返回 false）
 func (ao *AudioOutput) IsPlaying() bool {
 	ao.mu.Lock()
 	defer ao.mu.Unlock()
-	return !ao.finished && !ao.interrupted && ao.position < len(ao.samples)
+	return !ao.finished && !ao.interrupted && !ao.paused && (ao.queueing || ao.position < len(ao.samples))
 }
 
-// Close 关闭音频输出
+// Close 关闭音频输出并释放音频系统，重复调用是安全的
 func (ao *AudioOutput) Close() error {
+	var err error
 	if ao.stream != nil {
-		if err := ao.stream.Close(); err != nil {
-			return fmt.Errorf("failed to close audio stream: %w", err)
+		if closeErr := ao.stream.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close audio stream: %w", closeErr)
 		}
+		ao.stream = nil
 	}
 
-	return GetManager().Terminate()
+	if termErr := GetManager().Terminate(ao); termErr != nil && err == nil {
+		err = termErr
+	}
+	return err
 }
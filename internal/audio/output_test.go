@@ -0,0 +1,645 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tosone/minimp3"
+)
+
+func TestPlaySamplesReturns(t *testing.T) {
+	output, err := NewAudioOutput(16000)
+	if err != nil {
+		t.Skipf("Audio output not available: %v", err)
+	}
+	defer output.Close()
+
+	// 0.1 秒的静音
+	samples := make([]float32, 1600)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- output.PlaySamples(ctx, samples)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("PlaySamples failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("PlaySamples did not return after playback finished")
+	}
+
+	if output.IsPlaying() {
+		t.Error("Expected playback to be finished")
+	}
+}
+
+// newTestOutput 创建不依赖音频设备的输出，用于直接驱动回调
+func newTestOutput(channels int, samples []float32, srcChannels int) *AudioOutput {
+	return &AudioOutput{
+		samples:     samples,
+		channels:    channels,
+		srcChannels: srcChannels,
+		speed:       1.0,
+		volume:      1.0,
+		gain:        1.0,
+		queue:       newRingBuffer(16),
+	}
+}
+
+func TestAudioCallbackMonoToStereo(t *testing.T) {
+	output := newTestOutput(2, []float32{0.1, 0.2, 0.3}, 1)
+
+	out := make([]float32, 8)
+	output.audioCallback(out)
+
+	expected := []float32{0.1, 0.1, 0.2, 0.2, 0.3, 0.3, 0, 0}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, out)
+		}
+	}
+	if !output.finished {
+		t.Error("Expected playback finished after buffer was exhausted")
+	}
+}
+
+func TestAudioCallbackStereo(t *testing.T) {
+	interleaved := []float32{0.1, -0.1, 0.2, -0.2}
+
+	// 立体声内容在立体声流上原样输出
+	stereo := newTestOutput(2, interleaved, 2)
+	out := make([]float32, 4)
+	stereo.audioCallback(out)
+	for i := range interleaved {
+		if out[i] != interleaved[i] {
+			t.Fatalf("Expected %v, got %v", interleaved, out)
+		}
+	}
+
+	// 立体声内容在单声道流上混合为单声道
+	mono := newTestOutput(1, []float32{0.2, 0.4, 0.6, 0.8}, 2)
+	out = make([]float32, 2)
+	mono.audioCallback(out)
+	if math.Abs(float64(out[0]-0.3)) > 1e-6 || math.Abs(float64(out[1]-0.7)) > 1e-6 {
+		t.Errorf("Expected downmixed [0.3 0.7], got %v", out)
+	}
+}
+
+func TestStretchInterleavedKeepsChannels(t *testing.T) {
+	left := sineWave(0.5, 440, 16000, 16000)
+	samples := make([]float32, len(left)*2)
+	for i, v := range left {
+		samples[i*2] = v
+		samples[i*2+1] = -v
+	}
+
+	stretched := stretchInterleaved(samples, 2, 1.5)
+	if len(stretched)%2 != 0 || len(stretched)/2 != int(float64(len(left))/1.5) {
+		t.Fatalf("Unexpected stretched length %d", len(stretched))
+	}
+	for i := 0; i < len(stretched); i += 2 {
+		if stretched[i] != -stretched[i+1] {
+			t.Fatalf("Channels mixed up at frame %d: %f, %f", i/2, stretched[i], stretched[i+1])
+		}
+	}
+}
+
+func TestAudioCallbackVolume(t *testing.T) {
+	samples := make([]float32, 2048)
+	for i := range samples {
+		samples[i] = 0.8
+	}
+	output := newTestOutput(1, samples, 1)
+	output.SetVolume(0.5)
+
+	out := make([]float32, 1024)
+	output.audioCallback(out)
+
+	// 增益平滑过渡，相邻帧的变化不超过 volumeRampStep
+	for i := 1; i < len(out); i++ {
+		if math.Abs(float64(out[i]-out[i-1])) > 0.8*volumeRampStep+1e-6 {
+			t.Fatalf("Volume jumped at frame %d: %f -> %f", i, out[i-1], out[i])
+		}
+	}
+
+	// 过渡完成后输出减半
+	for i := 256; i < len(out); i++ {
+		if math.Abs(float64(out[i]-0.4)) > 1e-6 {
+			t.Fatalf("Expected halved sample 0.4 at frame %d, got %f", i, out[i])
+		}
+	}
+
+	output.SetVolume(1.7)
+	if v := output.GetVolume(); v != 1 {
+		t.Errorf("Expected volume clamped to 1, got %f", v)
+	}
+	output.SetVolume(-1)
+	if v := output.GetVolume(); v != 0 {
+		t.Errorf("Expected volume clamped to 0, got %f", v)
+	}
+}
+
+func TestAudioCallbackPauseResume(t *testing.T) {
+	output := newTestOutput(1, []float32{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}, 1)
+
+	out := make([]float32, 2)
+	output.audioCallback(out)
+	if out[0] != 0.1 || out[1] != 0.2 {
+		t.Fatalf("Expected [0.1 0.2], got %v", out)
+	}
+
+	output.Pause()
+	if !output.IsPaused() || output.IsPlaying() {
+		t.Error("Expected paused state to be reported distinctly from playing")
+	}
+
+	// 暂停期间输出静音，位置不变，也不会结束播放
+	for i := 0; i < 3; i++ {
+		output.audioCallback(out)
+		if out[0] != 0 || out[1] != 0 {
+			t.Fatalf("Expected silence while paused, got %v", out)
+		}
+	}
+	if output.position != 2 || output.finished {
+		t.Errorf("Expected position 2 and unfinished while paused, got position %d finished %v", output.position, output.finished)
+	}
+
+	output.Resume()
+	if output.IsPaused() || !output.IsPlaying() {
+		t.Error("Expected playing state after resume")
+	}
+	output.audioCallback(out)
+	if out[0] != 0.3 || out[1] != 0.4 {
+		t.Errorf("Expected playback to continue with [0.3 0.4], got %v", out)
+	}
+
+	// 停止会清除暂停状态，之后 Pause 无效
+	output.Stop()
+	output.Pause()
+	if output.IsPaused() {
+		t.Error("Expected Pause to be ignored after Stop")
+	}
+}
+
+func TestRingBufferWrapAndGrow(t *testing.T) {
+	ring := newRingBuffer(4)
+	ring.write([]float32{1, 2, 3})
+	for _, expected := range []float32{1, 2} {
+		if v, ok := ring.pop(); !ok || v != expected {
+			t.Fatalf("Expected %f, got %f (ok=%v)", expected, v, ok)
+		}
+	}
+
+	// 写入跨越末尾并触发扩容
+	ring.write([]float32{4, 5, 6, 7, 8})
+	if ring.len() != 6 {
+		t.Fatalf("Expected 6 buffered samples, got %d", ring.len())
+	}
+	for _, expected := range []float32{3, 4, 5, 6, 7, 8} {
+		if v, ok := ring.pop(); !ok || v != expected {
+			t.Fatalf("Expected %f, got %f (ok=%v)", expected, v, ok)
+		}
+	}
+	if _, ok := ring.pop(); ok {
+		t.Error("Expected empty ring buffer")
+	}
+}
+
+func TestEnqueueWithConcurrentCallback(t *testing.T) {
+	output := newTestOutput(1, nil, 1)
+
+	const chunks, chunkSize = 50, 160
+	var received []float32
+	stop := make(chan struct{})
+	consumerDone := make(chan struct{})
+
+	// 模拟音频回调线程持续消费
+	go func() {
+		defer close(consumerDone)
+		out := make([]float32, 64)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			output.audioCallback(out)
+			for _, v := range out {
+				if v != 0 {
+					received = append(received, v)
+				}
+			}
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	// 生产者边播放边写入
+	for c := 0; c < chunks; c++ {
+		chunk := make([]float32, chunkSize)
+		for i := range chunk {
+			chunk[i] = float32(c*chunkSize+i+1) / (chunks * chunkSize)
+		}
+		if err := output.Enqueue(chunk); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		time.Sleep(200 * time.Microsecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := output.Drain(ctx); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	close(stop)
+	<-consumerDone
+
+	if len(received) != chunks*chunkSize {
+		t.Fatalf("Expected %d samples played, got %d", chunks*chunkSize, len(received))
+	}
+	for i, v := range received {
+		if expected := float32(i+1) / (chunks * chunkSize); v != expected {
+			t.Fatalf("Sample %d out of order: expected %f, got %f", i, expected, v)
+		}
+	}
+	if output.IsPlaying() {
+		t.Error("Expected queue playback finished after Drain")
+	}
+}
+
+func TestEnqueueUnderrun(t *testing.T) {
+	output := newTestOutput(1, nil, 1)
+	output.Enqueue([]float32{0.5, 0.5})
+
+	out := make([]float32, 4)
+	output.audioCallback(out)
+	if out[0] != 0.5 || out[1] != 0.5 || out[2] != 0 || out[3] != 0 {
+		t.Errorf("Expected samples followed by silence, got %v", out)
+	}
+	if output.finished || !output.IsPlaying() {
+		t.Error("Expected underrun to keep the queue playing")
+	}
+}
+
+func TestDrainContextCancel(t *testing.T) {
+	output := newTestOutput(1, nil, 1)
+	output.Enqueue(make([]float32, 1600))
+
+	// 没有回调消费，Drain 只能因上下文取消返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := output.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if output.QueuedSamples() != 0 || output.IsPlaying() {
+		t.Error("Expected queue cleared after cancellation")
+	}
+}
+
+func TestPlayAudioDataRateMismatch(t *testing.T) {
+	output := newTestOutput(1, nil, 1)
+	output.sampleRate = 24000
+
+	wavData, err := EncodeWAV(make([]float32, 2400), 24000)
+	if err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+
+	if err := output.PlayAudioData(context.Background(), wavData, 16000); err == nil {
+		t.Error("Expected error when target rate differs from the output stream rate")
+	}
+}
+
+func TestRecentOutput(t *testing.T) {
+	ao := newTestOutput(2, []float32{0.1, 0.2, 0.3, 0.4, 0.5}, 1)
+	ao.history = make([]float32, 4)
+
+	out := make([]float32, 6) // 3 帧立体声
+	ao.audioCallback(out)
+
+	recent := ao.RecentOutput(3)
+	expected := []float32{0.1, 0.2, 0.3}
+	for i := range expected {
+		if math.Abs(float64(recent[i]-expected[i])) > 1e-6 {
+			t.Fatalf("Expected recent output %v, got %v", expected, recent)
+		}
+	}
+
+	// 历史为循环缓冲区，超出容量后只保留最近的样本
+	ao.audioCallback(out)
+	recent = ao.RecentOutput(10)
+	expected = []float32{0.3, 0.4, 0.5, 0}
+	if len(recent) != len(expected) {
+		t.Fatalf("Expected %d samples, got %d", len(expected), len(recent))
+	}
+	for i := range expected {
+		if math.Abs(float64(recent[i]-expected[i])) > 1e-6 {
+			t.Fatalf("Expected recent output %v, got %v", expected, recent)
+		}
+	}
+}
+
+// fakeStream 模拟输出流：Start 成功后在后台驱动回调，startErr 非空时启动失败
+type fakeStream struct {
+	startErr error
+	callback func(out []float32)
+	stop     chan struct{}
+	closed   bool
+}
+
+func (s *fakeStream) Start() error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	s.stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		out := make([]float32, 256)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.callback(out)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}(s.stop)
+	return nil
+}
+
+func (s *fakeStream) Stop() error {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	return nil
+}
+
+func (s *fakeStream) Close() error {
+	s.Stop()
+	s.closed = true
+	return nil
+}
+
+// newFakeDeviceOutput 创建使用 fakeStream 的输出，factory 依次返回 reopened 中的流，用完后打开失败
+func newFakeDeviceOutput(current *fakeStream, reopened ...*fakeStream) (*AudioOutput, *int) {
+	output := newTestOutput(1, nil, 1)
+	output.sampleRate = 16000
+	current.callback = output.audioCallback
+	output.stream = current
+
+	opens := 0
+	output.openStream = func(sampleRate, channels int, callback func(out []float32)) (outputStream, error) {
+		if sampleRate != 16000 || channels != 1 {
+			return nil, errors.New("unexpected stream parameters")
+		}
+		if opens >= len(reopened) {
+			return nil, errors.New("device not found")
+		}
+		stream := reopened[opens]
+		opens++
+		stream.callback = callback
+		return stream, nil
+	}
+	return output, &opens
+}
+
+func TestPlaySamplesReopensOnStartFailure(t *testing.T) {
+	unplugged := &fakeStream{startErr: errors.New("device unavailable")}
+	replugged := &fakeStream{}
+	output, opens := newFakeDeviceOutput(unplugged, replugged)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := output.PlaySamples(ctx, make([]float32, 800)); err != nil {
+		t.Fatalf("Expected playback to recover after reopening, got %v", err)
+	}
+	if *opens != 1 || !unplugged.closed {
+		t.Errorf("Expected the failed stream closed and one reopen, got %d reopens, closed=%v", *opens, unplugged.closed)
+	}
+
+	// 之后的播放直接使用重新打开的流
+	if err := output.PlaySamples(ctx, make([]float32, 800)); err != nil || *opens != 1 {
+		t.Errorf("Expected the reopened stream to be reused, got %v after %d reopens", err, *opens)
+	}
+}
+
+func TestPlaySamplesDeviceError(t *testing.T) {
+	ctx := context.Background()
+
+	// 设备彻底消失：重新打开失败
+	output, _ := newFakeDeviceOutput(&fakeStream{startErr: errors.New("device unavailable")})
+	err := output.PlaySamples(ctx, make([]float32, 800))
+	var deviceErr *PlaybackDeviceError
+	if !errors.As(err, &deviceErr) || deviceErr.Op != "reopen" {
+		t.Fatalf("Expected a reopen PlaybackDeviceError, got %v", err)
+	}
+
+	// 重新打开成功但仍无法启动，只重试一次
+	stillBroken := &fakeStream{startErr: errors.New("still unavailable")}
+	output, opens := newFakeDeviceOutput(&fakeStream{startErr: errors.New("device unavailable")}, stillBroken, &fakeStream{})
+	err = output.PlayAudioData(ctx, mustEncodeWAV(t, make([]float32, 800), 16000), 16000)
+	if !errors.As(err, &deviceErr) || deviceErr.Op != "start" || *opens != 1 {
+		t.Errorf("Expected a start PlaybackDeviceError after one reopen, got %v after %d reopens", err, *opens)
+	}
+
+	// 放弃的流可以由调用方稍后手动重新打开
+	if err := output.Reopen(); err != nil || *opens != 2 {
+		t.Errorf("Expected Reopen to open a new stream, got %v", err)
+	}
+	if err := output.PlaySamples(ctx, make([]float32, 800)); err != nil {
+		t.Errorf("Expected playback after a manual reopen, got %v", err)
+	}
+}
+
+func mustEncodeWAV(t *testing.T, samples []float32, sampleRate int) []byte {
+	t.Helper()
+	data, err := EncodeWAV(samples, sampleRate)
+	if err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+	return data
+}
+
+// silentMP3 生成 n 帧静音 MP3（MPEG-1 Layer III，128kbps，44.1kHz 单声道），边信息全为零，每帧解码为 1152 个零样本
+func silentMP3(n int) []byte {
+	const frameBytes = 417 // 144 * 128000 / 44100
+	data := make([]byte, 0, n*frameBytes)
+	for i := 0; i < n; i++ {
+		frame := make([]byte, frameBytes)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0xC0})
+		data = append(data, frame...)
+	}
+	return data
+}
+
+// pcmRamp 生成 n 个互不相同的非零 16 位样本，便于检查字节是否错位
+func pcmRamp(n int) ([]byte, []float32) {
+	data := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(100*(i+1))))
+	}
+	return data, pcm16ToFloat32(data, 1)
+}
+
+// chunkedReader 按给定的长度依次返回数据，用于制造奇数字节的块边界
+type chunkedReader struct {
+	data  []byte
+	sizes []int
+	next  int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	size := r.sizes[r.next%len(r.sizes)]
+	r.next++
+	size = min(size, len(r.data), len(p))
+	n := copy(p, r.data[:size])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// waitForStreamSamples 等待流式数据进入播放缓冲区
+func waitForStreamSamples(t *testing.T, output *AudioOutput) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		output.mu.Lock()
+		buffered := len(output.samples)
+		output.mu.Unlock()
+		if buffered > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Stream samples never reached the playback buffer")
+}
+
+// streamSources 按格式返回流式播放的测试数据
+var streamSources = []struct {
+	format string
+	chunk  func() []byte
+}{
+	{"pcm", func() []byte { data, _ := pcmRamp(1600); return data }},
+	{"mp3", func() []byte { return silentMP3(20) }},
+}
+
+func TestFeedStreamStop(t *testing.T) {
+	for _, source := range streamSources {
+		t.Run(source.format, func(t *testing.T) {
+			output := newTestOutput(1, nil, 1)
+			output.sampleRate = 16000
+			output.streaming = true
+
+			pr, pw := io.Pipe()
+			defer pw.Close()
+
+			var reader io.Reader = pr
+			var decoder *minimp3.Decoder
+			if source.format == "mp3" {
+				var err error
+				decoder, err = minimp3.NewDecoder(pr)
+				if err != nil {
+					t.Fatalf("Failed to create MP3 decoder: %v", err)
+				}
+				defer decoder.Close()
+				reader = decoder
+			}
+
+			feedErr := make(chan error, 1)
+			go func() {
+				feedErr <- output.feedStream(context.Background(), reader, decoder, 16000)
+			}()
+
+			go pw.Write(source.chunk())
+			waitForStreamSamples(t, output)
+
+			// 播放中途停止，之后到达的数据不再进入缓冲区
+			output.Stop()
+			go pw.Write(source.chunk())
+
+			select {
+			case err := <-feedErr:
+				if !errors.Is(err, errPlaybackStopped) {
+					t.Errorf("Expected feedStream to exit with errPlaybackStopped, got %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("feedStream did not exit after Stop")
+			}
+		})
+	}
+}
+
+func TestPlayStreamStop(t *testing.T) {
+	for _, source := range streamSources {
+		t.Run(source.format, func(t *testing.T) {
+			output, _ := newFakeDeviceOutput(&fakeStream{})
+			pr, pw := io.Pipe()
+			defer pw.Close()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- output.PlayStream(context.Background(), pr, source.format, 16000)
+			}()
+
+			go pw.Write(source.chunk())
+			waitForStreamSamples(t, output)
+			output.Stop()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Expected a stopped stream to end without error, got %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("PlayStream did not return after Stop")
+			}
+			if output.IsPlaying() {
+				t.Error("Expected playback to be stopped")
+			}
+		})
+	}
+}
+
+func TestPlayStreamOddChunks(t *testing.T) {
+	output, _ := newFakeDeviceOutput(&fakeStream{})
+	output.history = make([]float32, 16000*outputHistorySeconds)
+
+	// 块长度为奇数时半个样本留在 pending 中，与下一块拼接
+	data, expected := pcmRamp(300)
+	reader := &chunkedReader{data: data, sizes: []int{1, 3, 2, 7, 5}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := output.PlayStream(ctx, reader, "pcm", 16000); err != nil {
+		t.Fatalf("PlayStream failed: %v", err)
+	}
+
+	// 欠载时输出的静音夹在样本之间，只比较非零样本
+	var played []float32
+	for _, v := range output.RecentOutput(len(output.history)) {
+		if v != 0 {
+			played = append(played, v)
+		}
+	}
+	if len(played) != len(expected) {
+		t.Fatalf("Expected %d samples played, got %d", len(expected), len(played))
+	}
+	for i := range expected {
+		if played[i] != expected[i] {
+			t.Fatalf("Sample %d: expected %f, got %f", i, expected[i], played[i])
+		}
+	}
+}
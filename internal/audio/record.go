@@ -0,0 +1,42 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxRecordSeconds RecordAudio 单次录音的上限
+const maxRecordSeconds = 600
+
+// RecordAudio 在默认输入设备上录制 seconds 秒 16kHz 单声道音频，返回 16 位 PCM WAV 数据
+// 上下文取消时停止录音并返回 ctx.Err()
+func RecordAudio(ctx context.Context, seconds float64) ([]byte, error) {
+	if seconds <= 0 || seconds > maxRecordSeconds {
+		return nil, fmt.Errorf("invalid recording duration: %.2fs (must be between 0 and %d)", seconds, maxRecordSeconds)
+	}
+
+	input, err := NewInput()
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	if err := input.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start input stream: %w", err)
+	}
+
+	total := int(seconds * sampleRate)
+	samples := make([]float32, 0, total+input.FramesPerBuffer())
+	for len(samples) < total {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		chunk, err := input.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audio: %w", err)
+		}
+		samples = append(samples, chunk...)
+	}
+
+	return EncodeWAV(samples[:total], sampleRate)
+}
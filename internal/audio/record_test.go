@@ -0,0 +1,38 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+)
+
+func TestRecordAudio(t *testing.T) {
+	wavData, err := RecordAudio(context.Background(), 0.2)
+	if err != nil {
+		t.Skipf("Audio input not available: %v", err)
+	}
+
+	if len(wavData) < 44 || !bytes.Equal(wavData[:4], []byte("RIFF")) || !bytes.Equal(wavData[8:12], []byte("WAVE")) {
+		t.Fatalf("Expected a RIFF/WAVE header, got %q", wavData[:min(len(wavData), 12)])
+	}
+	if size := binary.LittleEndian.Uint32(wavData[4:8]); int(size) != len(wavData)-8 {
+		t.Errorf("Expected RIFF size %d, got %d", len(wavData)-8, size)
+	}
+
+	channels, rate, err := parseWAVChannels(wavData, "memory")
+	if err != nil {
+		t.Fatalf("Failed to parse recording: %v", err)
+	}
+	if rate != 16000 || len(channels) != 1 || len(channels[0]) != 3200 {
+		t.Errorf("Expected 3200 mono samples at 16000 Hz, got %d channels at %d Hz", len(channels), rate)
+	}
+}
+
+func TestRecordAudioInvalidDuration(t *testing.T) {
+	for _, seconds := range []float64{0, -1, maxRecordSeconds + 1} {
+		if _, err := RecordAudio(context.Background(), seconds); err == nil {
+			t.Errorf("Expected error for %.0f seconds", seconds)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package audio
 
 import (
 	"fmt"
+	"math"
 )
 
 // Resample performs simple linear interpolation resampling
@@ -21,12 +22,22 @@ func Resample(inputSamples []float32, inputSampleRate, outputSampleRate int) ([]
 		return result, nil
 	}
 
+	outputSamples := ResampleLinear(inputSamples, inputSampleRate, outputSampleRate)
+
+	fmt.Printf("重采样: %d Hz (%d 样本) -> %d Hz (%d 样本)\n",
+		inputSampleRate, len(inputSamples), outputSampleRate, len(outputSamples))
+
+	return outputSamples, nil
+}
+
+// ResampleLinear 线性插值重采样（不打印日志，适用于流式分块处理）
+func ResampleLinear(inputSamples []float32, inputSampleRate, outputSampleRate int) []float32 {
 	// 计算重采样比率
 	ratio := float64(inputSampleRate) / float64(outputSampleRate)
 	outputLength := int(float64(len(inputSamples)) / ratio)
 
 	if outputLength <= 0 {
-		return []float32{}, nil
+		return []float32{}
 	}
 
 	outputSamples := make([]float32, outputLength)
@@ -50,10 +61,116 @@ func Resample(inputSamples []float32, inputSampleRate, outputSampleRate int) ([]
 		outputSamples[i] = sample1 + float32(fraction)*(sample2-sample1)
 	}
 
-	fmt.Printf("重采样: %d Hz (%d 样本) -> %d Hz (%d 样本)\n",
-		inputSampleRate, len(inputSamples), outputSampleRate, len(outputSamples))
+	return outputSamples
+}
 
-	return outputSamples, nil
+// ResampleMethod 重采样算法
+type ResampleMethod int
+
+const (
+	// ResampleMethodLinear 线性插值，开销最小，降采样时会有混叠
+	ResampleMethodLinear ResampleMethod = iota
+	// ResampleMethodSinc 加窗 sinc 插值，降采样前做抗混叠低通
+	ResampleMethodSinc
+)
+
+// sinc 重采样参数
+const (
+	// 默认的 sinc 核半宽（过零点数）
+	defaultSincQuality = 16
+	// 允许的最大核半宽
+	maxSincQuality = 64
+)
+
+// String 返回重采样算法名称
+func (m ResampleMethod) String() string {
+	switch m {
+	case ResampleMethodLinear:
+		return "linear"
+	case ResampleMethodSinc:
+		return "sinc"
+	default:
+		return "unknown"
+	}
+}
+
+// ResampleSinc 使用 Blackman 加窗 sinc 核重采样
+// quality 为核的半宽（过零点数），越大越接近理想低通但越慢，<=0 时使用默认值
+// 降采样时截止频率取输出采样率的奈奎斯特频率，抑制混叠
+func ResampleSinc(in []float32, inRate, outRate, quality int) []float32 {
+	if len(in) == 0 || inRate <= 0 || outRate <= 0 {
+		return []float32{}
+	}
+	if inRate == outRate {
+		result := make([]float32, len(in))
+		copy(result, in)
+		return result
+	}
+
+	if quality <= 0 {
+		quality = defaultSincQuality
+	}
+	if quality > maxSincQuality {
+		quality = maxSincQuality
+	}
+
+	ratio := float64(inRate) / float64(outRate)
+	outputLength := int(float64(len(in)) / ratio)
+	if outputLength <= 0 {
+		return []float32{}
+	}
+
+	// 截止频率（相对输入奈奎斯特频率），升采样时不需要额外低通
+	cutoff := 1.0
+	if ratio > 1 {
+		cutoff = 1 / ratio
+	}
+	// 核半宽（输入样本数）
+	halfWidth := float64(quality) / cutoff
+
+	output := make([]float32, outputLength)
+	for i := range output {
+		center := float64(i) * ratio
+		start := int(math.Ceil(center - halfWidth))
+		end := int(math.Floor(center + halfWidth))
+		if start < 0 {
+			start = 0
+		}
+		if end > len(in)-1 {
+			end = len(in) - 1
+		}
+
+		var sum, weightSum float64
+		for j := start; j <= end; j++ {
+			offset := float64(j) - center
+			weight := sincValue(offset*cutoff) * blackmanWindow(offset/halfWidth)
+			sum += float64(in[j]) * weight
+			weightSum += weight
+		}
+
+		// 按权重和归一化，保证直流增益为 1（边缘处核被截断时也成立）
+		if weightSum != 0 {
+			output[i] = float32(sum / weightSum)
+		}
+	}
+
+	return output
+}
+
+// sincValue 归一化 sinc 函数 sin(πx)/(πx)
+func sincValue(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackmanWindow 返回 t∈[-1, 1] 处的 Blackman 窗值，范围外为 0
+func blackmanWindow(t float64) float64 {
+	if t < -1 || t > 1 {
+		return 0
+	}
+	return 0.42 + 0.5*math.Cos(math.Pi*t) + 0.08*math.Cos(2*math.Pi*t)
 }
 
 // GetTargetSampleRate returns the target sample rate for the audio system
@@ -0,0 +1,44 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResampleSincReducesAliasing(t *testing.T) {
+	inRate, outRate := 44100, 16000
+
+	// 12kHz 高于输出奈奎斯特频率（8kHz），降采样后残留的能量全部是混叠
+	tone := sineWave(0.5, 12000, inRate, inRate)
+
+	// 去掉首尾边缘后比较
+	edge := outRate / 100
+	linear := ResampleLinear(tone, inRate, outRate)
+	sinc := ResampleSinc(tone, inRate, outRate, defaultSincQuality)
+	linearAlias := rmsAmplitude(linear[edge : len(linear)-edge])
+	sincAlias := rmsAmplitude(sinc[edge : len(sinc)-edge])
+
+	if sincAlias > linearAlias*0.1 {
+		t.Errorf("Expected sinc aliasing well below linear, got sinc RMS %f vs linear RMS %f", sincAlias, linearAlias)
+	}
+
+	// 通带内的 1kHz 信号应基本保留
+	passband := ResampleSinc(sineWave(0.5, 1000, inRate, inRate), inRate, outRate, defaultSincQuality)
+	if rms := rmsAmplitude(passband[edge : len(passband)-edge]); math.Abs(rms-0.5/math.Sqrt2) > 0.01 {
+		t.Errorf("Expected 1kHz tone preserved, RMS %f", rms)
+	}
+}
+
+func TestResampleSincLength(t *testing.T) {
+	in := sineWave(0.5, 440, 24000, 24000)
+
+	if out := ResampleSinc(in, 24000, 16000, 0); len(out) != 16000 {
+		t.Errorf("Expected 16000 samples, got %d", len(out))
+	}
+	if out := ResampleSinc(in, 24000, 48000, 8); len(out) != 48000 {
+		t.Errorf("Expected 48000 samples, got %d", len(out))
+	}
+	if out := ResampleSinc(nil, 24000, 16000, 8); len(out) != 0 {
+		t.Errorf("Expected empty output for empty input, got %d", len(out))
+	}
+}
@@ -0,0 +1,68 @@
+package audio
+
+// ringInitialCapacity 环形缓冲区初始容量（16kHz 下约 2 秒）
+const ringInitialCapacity = 32768
+
+// ringBuffer 单声道样本环形缓冲区，写满时自动扩容
+// 本身不加锁，由 AudioOutput.mu 保护
+type ringBuffer struct {
+	data []float32
+	head int // 下一个读取位置
+	size int // 已缓冲的样本数
+}
+
+// newRingBuffer 创建环形缓冲区
+func newRingBuffer(capacity int) *ringBuffer {
+	if capacity <= 0 {
+		capacity = ringInitialCapacity
+	}
+	return &ringBuffer{data: make([]float32, capacity)}
+}
+
+// write 追加样本，容量不足时按倍数扩容
+func (r *ringBuffer) write(samples []float32) {
+	if r.size+len(samples) > len(r.data) {
+		r.grow(r.size + len(samples))
+	}
+
+	tail := (r.head + r.size) % len(r.data)
+	n := copy(r.data[tail:], samples)
+	copy(r.data, samples[n:])
+	r.size += len(samples)
+}
+
+// pop 取出一个样本，缓冲区为空时返回 false
+func (r *ringBuffer) pop() (float32, bool) {
+	if r.size == 0 {
+		return 0, false
+	}
+	v := r.data[r.head]
+	r.head = (r.head + 1) % len(r.data)
+	r.size--
+	return v, true
+}
+
+// len 返回已缓冲的样本数
+func (r *ringBuffer) len() int {
+	return r.size
+}
+
+// reset 清空缓冲区
+func (r *ringBuffer) reset() {
+	r.head = 0
+	r.size = 0
+}
+
+// grow 扩容到至少 minCapacity，并把数据整理到起始位置
+func (r *ringBuffer) grow(minCapacity int) {
+	capacity := len(r.data) * 2
+	for capacity < minCapacity {
+		capacity *= 2
+	}
+
+	data := make([]float32, capacity)
+	n := copy(data, r.data[r.head:min(r.head+r.size, len(r.data))])
+	copy(data[n:], r.data[:r.size-n])
+	r.data = data
+	r.head = 0
+}
@@ -0,0 +1,99 @@
+package audio
+
+import (
+	"math"
+)
+
+// 时间伸缩参数（WSOLA）
+const (
+	// 分析/合成帧长度
+	stretchFrameSize = 1024
+	// 合成帧移（50% 重叠）
+	stretchHopSize = stretchFrameSize / 2
+	// 相似度搜索范围（样本数）
+	stretchTolerance = 256
+)
+
+// TimeStretch 使用 WSOLA（波形相似重叠相加）改变播放速度而不改变音调
+// factor > 1 加速（输出变短），factor < 1 减速（输出变长）
+func TimeStretch(samples []float32, factor float64) []float32 {
+	if factor <= 0 || factor == 1 || len(samples) < stretchFrameSize {
+		result := make([]float32, len(samples))
+		copy(result, samples)
+		return result
+	}
+
+	outputLength := int(float64(len(samples)) / factor)
+	output := make([]float64, outputLength+stretchFrameSize)
+	weights := make([]float64, outputLength+stretchFrameSize)
+
+	// Hann 窗，50% 重叠时窗口之和恒定
+	window := make([]float64, stretchFrameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(stretchFrameSize))
+	}
+
+	maxPos := len(samples) - stretchFrameSize
+	prevPos := 0
+	for outPos := 0; outPos < outputLength; outPos += stretchHopSize {
+		pos := int(float64(outPos) * factor)
+		if outPos > 0 {
+			// 在理想位置附近寻找与上一帧自然延续最相似的片段
+			pos = findBestOverlap(samples, prevPos+stretchHopSize, pos)
+		}
+		if pos > maxPos {
+			pos = maxPos
+		}
+
+		for i := 0; i < stretchFrameSize; i++ {
+			output[outPos+i] += float64(samples[pos+i]) * window[i]
+			weights[outPos+i] += window[i]
+		}
+		prevPos = pos
+	}
+
+	result := make([]float32, outputLength)
+	for i := range result {
+		if weights[i] > 1e-6 {
+			result[i] = float32(output[i] / weights[i])
+		}
+	}
+
+	return result
+}
+
+// findBestOverlap 在 target 附近搜索与 natural 处波形互相关最大的位置
+func findBestOverlap(samples []float32, natural, target int) int {
+	maxPos := len(samples) - stretchFrameSize
+	if natural > maxPos {
+		natural = maxPos
+	}
+
+	start := target - stretchTolerance
+	if start < 0 {
+		start = 0
+	}
+	end := target + stretchTolerance
+	if end > maxPos {
+		end = maxPos
+	}
+	if start > end {
+		return end
+	}
+
+	bestPos := start
+	bestCorr := math.Inf(-1)
+	for candidate := start; candidate <= end; candidate++ {
+		// 只比较重叠区域
+		var corr float64
+		for i := 0; i < stretchHopSize; i++ {
+			corr += float64(samples[natural+i]) * float64(samples[candidate+i])
+		}
+		if corr > bestCorr {
+			bestCorr = corr
+			bestPos = candidate
+		}
+	}
+
+	return bestPos
+}
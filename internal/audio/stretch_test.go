@@ -0,0 +1,52 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// zeroCrossingRate 计算每个样本的过零次数，用于估计音调
+func zeroCrossingRate(samples []float32) float64 {
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(samples))
+}
+
+func TestTimeStretchPreservesPitch(t *testing.T) {
+	sampleRate := 16000
+	samples := make([]float32, sampleRate*2)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)))
+	}
+
+	for _, factor := range []float64{0.75, 1.5, 2.0} {
+		stretched := TimeStretch(samples, factor)
+
+		expectedLength := int(float64(len(samples)) / factor)
+		if len(stretched) != expectedLength {
+			t.Errorf("factor %.2f: expected %d samples, got %d", factor, expectedLength, len(stretched))
+		}
+
+		// 去掉首尾边缘后比较过零率
+		original := zeroCrossingRate(samples[stretchFrameSize : len(samples)-stretchFrameSize])
+		got := zeroCrossingRate(stretched[stretchFrameSize : len(stretched)-stretchFrameSize])
+		if math.Abs(got-original)/original > 0.05 {
+			t.Errorf("factor %.2f: pitch changed, zero crossing rate %.4f vs %.4f", factor, got, original)
+		}
+	}
+}
+
+func TestTimeStretchPassthrough(t *testing.T) {
+	samples := []float32{0.1, 0.2, 0.3}
+
+	if got := TimeStretch(samples, 1.0); len(got) != len(samples) {
+		t.Errorf("Expected unchanged length for factor 1.0, got %d", len(got))
+	}
+	if got := TimeStretch(samples, 2.0); len(got) != len(samples) {
+		t.Errorf("Expected short input to be returned unchanged, got %d samples", len(got))
+	}
+}
@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"math"
+)
+
+// 静音裁剪参数
+const (
+	// 能量分析帧长
+	trimFrameMs = 10
+	// 保留在语音两侧的余量，避免切掉词首辅音和尾音
+	trimGuardMs = 100
+)
+
+// TrimSilence 裁剪首尾能量低于 thresholdDB（dBFS，如 -45）的静音段，两侧各保留 100ms 余量
+// 整段都低于阈值时返回空切片，返回值总是新切片
+func TrimSilence(samples []float32, sampleRate int, thresholdDB float64) []float32 {
+	if sampleRate <= 0 || len(samples) == 0 {
+		return []float32{}
+	}
+
+	frameSize := max(sampleRate*trimFrameMs/1000, 1)
+	threshold := math.Pow(10, thresholdDB/20)
+
+	first, last := -1, -1
+	for start := 0; start < len(samples); start += frameSize {
+		end := min(start+frameSize, len(samples))
+		if rmsAmplitude(samples[start:end]) >= threshold {
+			if first < 0 {
+				first = start
+			}
+			last = end
+		}
+	}
+	if first < 0 {
+		return []float32{}
+	}
+
+	guard := sampleRate * trimGuardMs / 1000
+	first = max(first-guard, 0)
+	last = min(last+guard, len(samples))
+
+	result := make([]float32, last-first)
+	copy(result, samples[first:last])
+	return result
+}
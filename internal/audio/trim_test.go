@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"testing"
+)
+
+func TestTrimSilence(t *testing.T) {
+	const sampleRate = 16000
+	tone := sineWave(0.3, 440, sampleRate, sampleRate/2) // 500ms
+
+	var samples []float32
+	samples = append(samples, make([]float32, sampleRate)...) // 1s 前导静音
+	samples = append(samples, tone...)
+	samples = append(samples, make([]float32, sampleRate)...) // 1s 尾部静音
+
+	trimmed := TrimSilence(samples, sampleRate, -45)
+
+	// 语音 500ms + 两侧各 100ms 余量，允许一帧误差
+	guard := sampleRate * trimGuardMs / 1000
+	frame := sampleRate * trimFrameMs / 1000
+	expected := len(tone) + 2*guard
+	if len(trimmed) < expected-frame || len(trimmed) > expected+frame {
+		t.Errorf("Expected about %d samples after trimming, got %d", expected, len(trimmed))
+	}
+
+	// 语音本身不能被裁掉
+	if peakAmplitude(trimmed[guard:guard+frame]) < 0.2 {
+		t.Error("Expected tone to start right after the leading guard margin")
+	}
+}
+
+func TestTrimSilenceEdges(t *testing.T) {
+	if trimmed := TrimSilence(make([]float32, 16000), 16000, -45); len(trimmed) != 0 {
+		t.Errorf("Expected all-silent input to be trimmed to nothing, got %d samples", len(trimmed))
+	}
+
+	// 没有静音可裁时保持原长度，且返回新切片
+	tone := sineWave(0.3, 440, 16000, 8000)
+	trimmed := TrimSilence(tone, 16000, -45)
+	if len(trimmed) != len(tone) {
+		t.Errorf("Expected untouched length %d, got %d", len(tone), len(trimmed))
+	}
+	trimmed[0] = 1
+	if tone[0] == 1 {
+		t.Error("Expected TrimSilence to return a copy")
+	}
+
+	// 低于阈值的微弱底噪视为静音
+	quiet := sineWave(0.001, 440, 16000, 8000) // 约 -63 dBFS
+	if trimmed := TrimSilence(quiet, 16000, -45); len(trimmed) != 0 {
+		t.Errorf("Expected low-level noise to be trimmed, got %d samples", len(trimmed))
+	}
+}
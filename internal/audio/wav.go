@@ -1,11 +1,20 @@
 package audio
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
+	"math"
 	"os"
 )
 
+// WAV audio format codes
+const (
+	wavFormatPCM       = 1 // Integer PCM
+	wavFormatIEEEFloat = 3 // IEEE 754 float
+)
+
 // WAV file header structure
 type WAVHeader struct {
 	ChunkID       [4]byte // "RIFF"
@@ -13,7 +22,7 @@ type WAVHeader struct {
 	Format        [4]byte // "WAVE"
 	Subchunk1ID   [4]byte // "fmt "
 	Subchunk1Size uint32  // 16 for PCM
-	AudioFormat   uint16  // 1 for PCM
+	AudioFormat   uint16  // 1 for PCM, 3 for IEEE float
 	NumChannels   uint16  // Number of channels
 	SampleRate    uint32  // Sample rate
 	ByteRate      uint32  // SampleRate * NumChannels * BitsPerSample/8
@@ -23,19 +32,73 @@ type WAVHeader struct {
 	Subchunk2Size uint32  // Data size
 }
 
-// SaveToWAV saves float32 audio data to a WAV file
+// WAVWriteOptions describes the output format of SaveToWAVOpts
+type WAVWriteOptions struct {
+	SampleRate    int
+	Channels      int  // Number of interleaved channels in samples, defaults to 1
+	BitsPerSample int  // 8, 16, 24 or 32, defaults to 16
+	Float         bool // Write 32-bit IEEE float instead of integer PCM
+}
+
+// SaveToWAV saves float32 audio data to a mono 16-bit WAV file
 func SaveToWAV(filename string, audioData []float32, sampleRate int) error {
+	return SaveToWAVOpts(filename, audioData, WAVWriteOptions{SampleRate: sampleRate})
+}
+
+// SaveToWAVOpts saves float32 audio data (interleaved when Channels > 1) to a WAV file
+func SaveToWAVOpts(filename string, samples []float32, opts WAVWriteOptions) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create WAV file: %w", err)
 	}
 	defer file.Close()
 
-	numChannels := uint16(1)
-	bitsPerSample := uint16(16)
-	byteRate := uint32(sampleRate) * uint32(numChannels) * uint32(bitsPerSample) / 8
+	return WriteWAVOpts(file, samples, opts)
+}
+
+// EncodeWAV encodes float32 audio data as an in-memory 16-bit PCM WAV file
+func EncodeWAV(audioData []float32, sampleRate int) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(44 + len(audioData)*2)
+	if err := WriteWAV(&buf, audioData, sampleRate); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteWAV writes float32 audio data as a mono 16-bit PCM WAV stream
+func WriteWAV(w io.Writer, audioData []float32, sampleRate int) error {
+	return WriteWAVOpts(w, audioData, WAVWriteOptions{SampleRate: sampleRate})
+}
+
+// WriteWAVOpts writes float32 audio data as a WAV stream in the given format
+func WriteWAVOpts(w io.Writer, samples []float32, opts WAVWriteOptions) error {
+	if opts.Channels <= 0 {
+		opts.Channels = 1
+	}
+	if opts.BitsPerSample <= 0 {
+		opts.BitsPerSample = 16
+	}
+	audioFormat := uint16(wavFormatPCM)
+	if opts.Float {
+		audioFormat = wavFormatIEEEFloat
+	}
+	if err := validateWAVFormat(audioFormat, uint16(opts.BitsPerSample)); err != nil {
+		return err
+	}
+	if opts.SampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate: %d", opts.SampleRate)
+	}
+	if len(samples)%opts.Channels != 0 {
+		return fmt.Errorf("sample count %d is not a multiple of %d channels", len(samples), opts.Channels)
+	}
+
+	numChannels := uint16(opts.Channels)
+	bitsPerSample := uint16(opts.BitsPerSample)
+	bytesPerSample := opts.BitsPerSample / 8
+	byteRate := uint32(opts.SampleRate) * uint32(numChannels) * uint32(bitsPerSample) / 8
 	blockAlign := numChannels * bitsPerSample / 8
-	dataSize := uint32(len(audioData) * 2) // 16-bit samples = 2 bytes each
+	dataSize := uint32(len(samples) * bytesPerSample)
 
 	// Create WAV header
 	header := WAVHeader{
@@ -44,9 +107,9 @@ func SaveToWAV(filename string, audioData []float32, sampleRate int) error {
 		Format:        [4]byte{'W', 'A', 'V', 'E'},
 		Subchunk1ID:   [4]byte{'f', 'm', 't', ' '},
 		Subchunk1Size: 16,
-		AudioFormat:   1, // PCM
+		AudioFormat:   audioFormat,
 		NumChannels:   numChannels,
-		SampleRate:    uint32(sampleRate),
+		SampleRate:    uint32(opts.SampleRate),
 		ByteRate:      byteRate,
 		BlockAlign:    blockAlign,
 		BitsPerSample: bitsPerSample,
@@ -55,29 +118,70 @@ func SaveToWAV(filename string, audioData []float32, sampleRate int) error {
 	}
 
 	// Write header
-	if err := binary.Write(file, binary.LittleEndian, header); err != nil {
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
 		return fmt.Errorf("failed to write WAV header: %w", err)
 	}
 
-	// Convert float32 to int16 and write data
-	for _, sample := range audioData {
-		// Clamp sample to [-1.0, 1.0] range
-		if sample > 1.0 {
-			sample = 1.0
-		} else if sample < -1.0 {
-			sample = -1.0
-		}
+	// Convert samples in one buffer instead of a write per sample
+	data := make([]byte, dataSize)
+	for i, sample := range samples {
+		encodeWAVSample(data[i*bytesPerSample:], sample, audioFormat, bitsPerSample)
+	}
 
-		// Convert to 16-bit signed integer
-		int16Sample := int16(sample * 32767)
-		if err := binary.Write(file, binary.LittleEndian, int16Sample); err != nil {
-			return fmt.Errorf("failed to write audio data: %w", err)
-		}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write audio data: %w", err)
 	}
 
 	return nil
 }
 
+// encodeWAVSample writes one sample, integer formats are clamped to [-1.0, 1.0]
+func encodeWAVSample(dst []byte, sample float32, audioFormat, bitsPerSample uint16) {
+	if audioFormat == wavFormatIEEEFloat {
+		binary.LittleEndian.PutUint32(dst, math.Float32bits(sample))
+		return
+	}
+
+	if sample > 1.0 {
+		sample = 1.0
+	} else if sample < -1.0 {
+		sample = -1.0
+	}
+
+	switch bitsPerSample {
+	case 8:
+		// 8-bit WAV is unsigned with 128 as silence
+		dst[0] = uint8(int16(sample*127) + 128)
+	case 16:
+		binary.LittleEndian.PutUint16(dst, uint16(int16(sample*32767)))
+	case 24:
+		v := int32(float64(sample) * 8388607)
+		dst[0], dst[1], dst[2] = byte(v), byte(v>>8), byte(v>>16)
+	case 32:
+		binary.LittleEndian.PutUint32(dst, uint32(int32(float64(sample)*2147483647)))
+	}
+}
+
+// decodeWAVSample reads one sample and converts it to float32 in [-1.0, 1.0]
+func decodeWAVSample(src []byte, audioFormat, bitsPerSample uint16) float32 {
+	if audioFormat == wavFormatIEEEFloat {
+		return math.Float32frombits(binary.LittleEndian.Uint32(src))
+	}
+
+	switch bitsPerSample {
+	case 8:
+		return float32(int16(src[0])-128) / 127.0
+	case 16:
+		return float32(int16(binary.LittleEndian.Uint16(src))) / 32767.0
+	case 24:
+		v := int32(uint32(src[0])<<8|uint32(src[1])<<16|uint32(src[2])<<24) >> 8
+		return float32(float64(v) / 8388607)
+	case 32:
+		return float32(float64(int32(binary.LittleEndian.Uint32(src))) / 2147483647)
+	}
+	return 0
+}
+
 // LoadFromWAV loads audio data from a WAV file
 func LoadFromWAV(filename string) ([]float32, int, error) {
 	file, err := os.Open(filename)
@@ -105,12 +209,8 @@ func LoadFromWAV(filename string) ([]float32, int, error) {
 			string(header.ChunkID[:]), string(header.Format[:]))
 	}
 
-	if header.AudioFormat != 1 {
-		return nil, 0, fmt.Errorf("unsupported audio format: %d (only PCM is supported)", header.AudioFormat)
-	}
-
-	if header.BitsPerSample != 16 {
-		return nil, 0, fmt.Errorf("unsupported bits per sample: %d (only 16-bit is supported)", header.BitsPerSample)
+	if err := validateWAVFormat(header.AudioFormat, header.BitsPerSample); err != nil {
+		return nil, 0, err
 	}
 
 	// Validate data chunk size
@@ -141,10 +241,14 @@ func LoadFromWAV(filename string) ([]float32, int, error) {
 	audioData := make([]float32, numSamples)
 
 	// Read audio data with better error handling
+	buf := make([]byte, bytesPerSample)
 	for i := 0; i < numSamples; i++ {
-		var sample int16
-		if err := binary.Read(file, binary.LittleEndian, &sample); err != nil {
-			if err.Error() == "EOF" {
+		_, err := io.ReadFull(file, buf)
+		if err == nil {
+			audioData[i] = decodeWAVSample(buf, header.AudioFormat, header.BitsPerSample)
+		}
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
 				// Handle EOF gracefully - truncate to actual samples read
 				fmt.Printf("Warning: EOF encountered at sample %d of %d. Truncating audio data.\n", i, numSamples)
 				audioData = audioData[:i]
@@ -152,10 +256,26 @@ func LoadFromWAV(filename string) ([]float32, int, error) {
 			}
 			return nil, 0, fmt.Errorf("failed to read audio sample %d: %w", i, err)
 		}
-
-		// Convert to float32 in range [-1.0, 1.0]
-		audioData[i] = float32(sample) / 32767.0
 	}
 
 	return audioData, int(header.SampleRate), nil
 }
+
+// validateWAVFormat checks that the format is 8/16/24/32-bit PCM or 32-bit IEEE float
+func validateWAVFormat(audioFormat, bitsPerSample uint16) error {
+	switch audioFormat {
+	case wavFormatPCM:
+		switch bitsPerSample {
+		case 8, 16, 24, 32:
+		default:
+			return fmt.Errorf("unsupported bits per sample: %d (only 8/16/24/32-bit PCM is supported)", bitsPerSample)
+		}
+	case wavFormatIEEEFloat:
+		if bitsPerSample != 32 {
+			return fmt.Errorf("unsupported bits per sample: %d (only 32-bit float is supported)", bitsPerSample)
+		}
+	default:
+		return fmt.Errorf("unsupported audio format: %d (only PCM and IEEE float are supported)", audioFormat)
+	}
+	return nil
+}
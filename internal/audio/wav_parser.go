@@ -38,8 +38,17 @@ func RobustLoadFromWAV(filename string) ([]float32, int, error) {
 	return parseWAVContent(fileContent, filename)
 }
 
-// parseWAVContent parses WAV content from bytes
+// parseWAVContent parses WAV content from bytes, multi-channel samples stay interleaved like LoadFromWAV
 func parseWAVContent(content []byte, filename string) ([]float32, int, error) {
+	channels, sampleRate, err := parseWAVChannels(content, filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	return interleave(channels), sampleRate, nil
+}
+
+// parseWAVChannels parses WAV content from bytes and returns one slice per channel
+func parseWAVChannels(content []byte, filename string) ([][]float32, int, error) {
 	if len(content) < 12 {
 		return nil, 0, fmt.Errorf("file too small to be a valid WAV file")
 	}
@@ -124,7 +133,7 @@ func parseWAVContent(content []byte, filename string) ([]float32, int, error) {
 		return nil, 0, fmt.Errorf("data chunk not found")
 	}
 
-	fmt.Printf("  Audio format: %d (PCM=%d)\n", fmtChunk.AudioFormat, 1)
+	fmt.Printf("  Audio format: %d (PCM=%d, Float=%d)\n", fmtChunk.AudioFormat, wavFormatPCM, wavFormatIEEEFloat)
 	fmt.Printf("  Channels: %d\n", fmtChunk.NumChannels)
 	fmt.Printf("  Sample rate: %d\n", fmtChunk.SampleRate)
 	fmt.Printf("  Bits per sample: %d\n", fmtChunk.BitsPerSample)
@@ -132,12 +141,8 @@ func parseWAVContent(content []byte, filename string) ([]float32, int, error) {
 	fmt.Printf("  Data size: %d\n", dataSize)
 
 	// Validate format
-	if fmtChunk.AudioFormat != 1 {
-		return nil, 0, fmt.Errorf("unsupported audio format: %d (only PCM is supported)", fmtChunk.AudioFormat)
-	}
-
-	if fmtChunk.BitsPerSample != 16 {
-		return nil, 0, fmt.Errorf("unsupported bits per sample: %d (only 16-bit is supported)", fmtChunk.BitsPerSample)
+	if err := validateWAVFormat(fmtChunk.AudioFormat, fmtChunk.BitsPerSample); err != nil {
+		return nil, 0, err
 	}
 
 	// Extract audio data
@@ -170,8 +175,8 @@ func parseFmtChunk(data []byte) (*FmtChunk, error) {
 	return &fmtData, nil
 }
 
-// extractAudioData extracts audio samples from the data chunk
-func extractAudioData(content []byte, dataOffset int64, dataSize uint32, fmtChunk *FmtChunk) ([]float32, int, error) {
+// extractAudioData extracts audio samples from the data chunk and de-interleaves them per channel
+func extractAudioData(content []byte, dataOffset int64, dataSize uint32, fmtChunk *FmtChunk) ([][]float32, int, error) {
 	// Validate data bounds
 	if dataOffset+int64(dataSize) > int64(len(content)) {
 		actualDataSize := int64(len(content)) - dataOffset
@@ -180,36 +185,75 @@ func extractAudioData(content []byte, dataOffset int64, dataSize uint32, fmtChun
 		dataSize = uint32(actualDataSize)
 	}
 
-	// Calculate number of samples
+	// Calculate number of frames (one sample per channel)
+	numChannels := int(fmtChunk.NumChannels)
+	if numChannels < 1 {
+		numChannels = 1
+	}
 	bytesPerSample := int(fmtChunk.BitsPerSample) / 8
-	numSamples := int(dataSize) / bytesPerSample
+	numFrames := int(dataSize) / (bytesPerSample * numChannels)
 
-	fmt.Printf("  Calculated samples: %d\n", numSamples)
+	fmt.Printf("  Calculated frames: %d\n", numFrames)
 
-	if numSamples <= 0 {
+	if numFrames <= 0 {
 		return nil, 0, fmt.Errorf("no audio samples found")
 	}
 
-	// Extract samples
-	audioData := make([]float32, numSamples)
+	// Extract samples, a trailing partial frame is dropped
+	channels := make([][]float32, numChannels)
+	for ch := range channels {
+		channels[ch] = make([]float32, numFrames)
+	}
 	dataBytes := content[dataOffset : dataOffset+int64(dataSize)]
-	reader := bytes.NewReader(dataBytes)
+	frameBytes := bytesPerSample * numChannels
+	for i := 0; i < numFrames; i++ {
+		frame := dataBytes[i*frameBytes : (i+1)*frameBytes]
+		for ch := 0; ch < numChannels; ch++ {
+			channels[ch][i] = decodeWAVSample(frame[ch*bytesPerSample:], fmtChunk.AudioFormat, fmtChunk.BitsPerSample)
+		}
+	}
 
-	for i := 0; i < numSamples; i++ {
-		var sample int16
-		if err := binary.Read(reader, binary.LittleEndian, &sample); err != nil {
-			if err == io.EOF {
-				fmt.Printf("  Warning: EOF at sample %d of %d. Truncating.\n", i, numSamples)
-				audioData = audioData[:i]
-				break
-			}
-			return nil, 0, fmt.Errorf("failed to read sample %d: %w", i, err)
+	fmt.Printf("  Successfully loaded %d frames x %d channels\n", numFrames, numChannels)
+	return channels, int(fmtChunk.SampleRate), nil
+}
+
+// interleave merges per-channel slices back into frame order
+func interleave(channels [][]float32) []float32 {
+	if len(channels) == 1 {
+		return channels[0]
+	}
+
+	frames := len(channels[0])
+	samples := make([]float32, 0, frames*len(channels))
+	for i := 0; i < frames; i++ {
+		for _, ch := range channels {
+			samples = append(samples, ch[i])
 		}
+	}
+	return samples
+}
+
+// MixToMono averages per-channel slices into a single channel
+func MixToMono(channels [][]float32) []float32 {
+	if len(channels) == 1 {
+		return channels[0]
+	}
+	if len(channels) == 0 {
+		return nil
+	}
 
-		// Convert to float32 in range [-1.0, 1.0]
-		audioData[i] = float32(sample) / 32767.0
+	frames := len(channels[0])
+	for _, ch := range channels[1:] {
+		frames = min(frames, len(ch))
 	}
 
-	fmt.Printf("  Successfully loaded %d samples\n", len(audioData))
-	return audioData, int(fmtChunk.SampleRate), nil
+	mono := make([]float32, frames)
+	for i := range mono {
+		var sum float32
+		for _, ch := range channels {
+			sum += ch[i]
+		}
+		mono[i] = sum / float32(len(channels))
+	}
+	return mono
 }
@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFloatWAV 按 cmd/voice_assistant saveAudioToTempFile 的格式写入 32 位浮点 WAV
+func writeFloatWAV(t *testing.T, filename string, audioData []float32, sampleRate int) {
+	t.Helper()
+
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("Failed to create WAV file: %v", err)
+	}
+	defer file.Close()
+
+	numChannels := 1
+	bitsPerSample := 32
+	dataSize := len(audioData) * 4
+	fileSize := 36 + dataSize
+
+	header := make([]byte, 44)
+	copy(header[0:4], []byte("RIFF"))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(fileSize))
+	copy(header[8:12], []byte("WAVE"))
+	copy(header[12:16], []byte("fmt "))
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 3) // IEEE float
+	binary.LittleEndian.PutUint16(header[22:24], uint16(numChannels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*numChannels*bitsPerSample/8))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(numChannels*bitsPerSample/8))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(bitsPerSample))
+	copy(header[36:40], []byte("data"))
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+
+	if _, err := file.Write(header); err != nil {
+		t.Fatalf("Failed to write WAV header: %v", err)
+	}
+	for _, sample := range audioData {
+		if err := binary.Write(file, binary.LittleEndian, sample); err != nil {
+			t.Fatalf("Failed to write sample: %v", err)
+		}
+	}
+}
+
+func TestLoadFloatWAV(t *testing.T) {
+	samples := []float32{0, 0.25, -0.5, 0.999, -1, 0.123456}
+	filename := filepath.Join(t.TempDir(), "float.wav")
+	writeFloatWAV(t, filename, samples, 16000)
+
+	loaders := map[string]func(string) ([]float32, int, error){
+		"LoadFromWAV":       LoadFromWAV,
+		"RobustLoadFromWAV": RobustLoadFromWAV,
+	}
+
+	for name, load := range loaders {
+		loaded, sampleRate, err := load(filename)
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		if sampleRate != 16000 {
+			t.Errorf("%s: expected sample rate 16000, got %d", name, sampleRate)
+		}
+		if len(loaded) != len(samples) {
+			t.Fatalf("%s: expected %d samples, got %d", name, len(samples), len(loaded))
+		}
+		for i := range samples {
+			if loaded[i] != samples[i] {
+				t.Errorf("%s: sample %d mismatch: got %v, expected %v", name, i, loaded[i], samples[i])
+			}
+		}
+	}
+}
+
+func TestSaveToWAVOptsRoundTrip(t *testing.T) {
+	// 立体声交错样本（左右声道不同）
+	samples := []float32{0, 0, 0.5, -0.5, -0.25, 0.25, 0.999, -0.999, 0.1, -0.1, 1, -1}
+
+	tests := []struct {
+		name      string
+		opts      WAVWriteOptions
+		tolerance float64
+	}{
+		{"8-bit", WAVWriteOptions{SampleRate: 16000, Channels: 2, BitsPerSample: 8}, 1.0 / 64},
+		{"16-bit", WAVWriteOptions{SampleRate: 16000, Channels: 2, BitsPerSample: 16}, 1.0 / 16384},
+		{"24-bit", WAVWriteOptions{SampleRate: 44100, Channels: 2, BitsPerSample: 24}, 1e-6},
+		{"32-bit", WAVWriteOptions{SampleRate: 48000, Channels: 2, BitsPerSample: 32}, 1e-6},
+		{"32-bit float", WAVWriteOptions{SampleRate: 48000, Channels: 2, BitsPerSample: 32, Float: true}, 0},
+	}
+
+	loaders := map[string]func(string) ([]float32, int, error){
+		"LoadFromWAV":       LoadFromWAV,
+		"RobustLoadFromWAV": RobustLoadFromWAV,
+	}
+
+	for _, tt := range tests {
+		filename := filepath.Join(t.TempDir(), "out.wav")
+		if err := SaveToWAVOpts(filename, samples, tt.opts); err != nil {
+			t.Fatalf("%s: SaveToWAVOpts failed: %v", tt.name, err)
+		}
+
+		header := make([]byte, 44)
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("%s: failed to open: %v", tt.name, err)
+		}
+		file.Read(header)
+		file.Close()
+		if channels := binary.LittleEndian.Uint16(header[22:24]); channels != 2 {
+			t.Errorf("%s: expected 2 channels in header, got %d", tt.name, channels)
+		}
+		if bits := binary.LittleEndian.Uint16(header[34:36]); int(bits) != tt.opts.BitsPerSample {
+			t.Errorf("%s: expected %d bits in header, got %d", tt.name, tt.opts.BitsPerSample, bits)
+		}
+
+		for loaderName, load := range loaders {
+			loaded, rate, err := load(filename)
+			if err != nil {
+				t.Fatalf("%s/%s: load failed: %v", tt.name, loaderName, err)
+			}
+			if rate != tt.opts.SampleRate || len(loaded) != len(samples) {
+				t.Fatalf("%s/%s: expected %d samples at %d Hz, got %d at %d Hz",
+					tt.name, loaderName, len(samples), tt.opts.SampleRate, len(loaded), rate)
+			}
+			for i := range samples {
+				if diff := float64(loaded[i] - samples[i]); diff > tt.tolerance || diff < -tt.tolerance {
+					t.Errorf("%s/%s: sample %d = %f, expected %f", tt.name, loaderName, i, loaded[i], samples[i])
+				}
+			}
+		}
+	}
+}
+
+func TestSaveToWAVOptsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := SaveToWAVOpts(filepath.Join(dir, "a.wav"), []float32{0}, WAVWriteOptions{SampleRate: 16000, BitsPerSample: 12}); err == nil {
+		t.Error("Expected error for unsupported bit depth")
+	}
+	if err := SaveToWAVOpts(filepath.Join(dir, "b.wav"), []float32{0, 0, 0}, WAVWriteOptions{SampleRate: 16000, Channels: 2}); err == nil {
+		t.Error("Expected error for partial frame")
+	}
+
+	// 默认值与 SaveToWAV 相同：单声道 16 位
+	data, err := EncodeWAV([]float32{0.5}, 16000)
+	if err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+	if len(data) != 46 || binary.LittleEndian.Uint16(data[34:36]) != 16 || binary.LittleEndian.Uint16(data[22:24]) != 1 {
+		t.Errorf("Expected mono 16-bit default output, got %d bytes", len(data))
+	}
+}
@@ -0,0 +1,187 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"audio-assistant/internal/retry"
+)
+
+// State is the position of a circuit breaker
+type State int
+
+// Circuit breaker states
+const (
+	Closed   State = iota // Calls pass through, consecutive failures are counted
+	Open                  // Calls fail fast until the cooldown has elapsed
+	HalfOpen              // One probe call is let through to test the upstream
+)
+
+// Default policy shared by the upstream clients
+const (
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+// ErrOpen is returned without calling the upstream while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// String returns the state name used in logs and health checks
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Config configures when a breaker opens and how long it stays open
+type Config struct {
+	FailureThreshold int           // Consecutive failures that open the breaker, 0 disables it
+	Cooldown         time.Duration // Time spent open before a probe call is allowed
+}
+
+// DefaultConfig returns the default breaker configuration
+func DefaultConfig() Config {
+	return Config{
+		FailureThreshold: DefaultFailureThreshold,
+		Cooldown:         DefaultCooldown,
+	}
+}
+
+// Breaker fails calls fast after repeated upstream failures
+// After FailureThreshold consecutive failures it opens for Cooldown, then lets a single probe through:
+// a successful probe closes it again, a failed one reopens it for another cooldown
+// Only transport errors, timeouts and 429/5xx responses are failures. Other HTTP errors are answers from
+// a healthy upstream and count as successes, while cancellations and errors without a status
+// (e.g. request validation) are neither
+type Breaker struct {
+	mu       sync.Mutex
+	config   Config
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// New creates a closed breaker
+func New(config Config) *Breaker {
+	return &Breaker{config: config, now: time.Now}
+}
+
+// Do runs fn unless the breaker is open and records its result
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// Allow reports whether a call may proceed, every allowed call must report its result through done
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config.FailureThreshold <= 0 {
+		return func(error) {}, nil
+	}
+
+	probe := false
+	switch b.currentState() {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probing {
+			return nil, ErrOpen
+		}
+		b.state = HalfOpen
+		b.probing = true
+		probe = true
+	}
+	return func(err error) { b.record(err, probe) }, nil
+}
+
+// record updates the breaker with the result of an allowed call
+// Only the probe decides a half-open breaker, calls started before the breaker opened are ignored there
+func (b *Breaker) record(err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	} else if b.state != Closed {
+		return
+	}
+
+	switch outcome(err) {
+	case success:
+		b.state = Closed
+		b.failures = 0
+	case failure:
+		b.failures++
+		if probe || b.failures >= b.config.FailureThreshold {
+			b.state = Open
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// result is what a call says about the health of the upstream
+type result int
+
+const (
+	neutral result = iota // Says nothing, e.g. the caller gave up
+	success               // The upstream answered
+	failure               // The upstream is unreachable, too slow or failing
+)
+
+// outcome classifies the error of a call
+func outcome(err error) result {
+	var statusErr *retry.StatusError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return success
+	case errors.Is(err, context.Canceled):
+		return neutral
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError {
+			return failure
+		}
+		return success
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &netErr):
+		return failure
+	default:
+		return neutral
+	}
+}
+
+// State returns the current state, an open breaker whose cooldown has elapsed reports HalfOpen
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// currentState resolves the cooldown, the caller must hold b.mu
+func (b *Breaker) currentState() State {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		return HalfOpen
+	}
+	return b.state
+}
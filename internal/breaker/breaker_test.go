@@ -0,0 +1,202 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/retry"
+)
+
+// errUnavailable is what an upstream returns while it is down
+var errUnavailable = retry.NewStatusError(http.StatusServiceUnavailable, errors.New("upstream unavailable"))
+
+// fakeClock is advanced by hand so cooldowns do not need real waiting
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// flakyUpstream fails until healthy is set, counting the calls that reach it
+type flakyUpstream struct {
+	healthy bool
+	calls   int
+}
+
+func (u *flakyUpstream) call() error {
+	u.calls++
+	if !u.healthy {
+		return errUnavailable
+	}
+	return nil
+}
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	b := New(Config{FailureThreshold: threshold, Cooldown: cooldown})
+	b.now = clock.Now
+	return b, clock
+}
+
+func TestBreakerStateTransitions(t *testing.T) {
+	b, clock := newTestBreaker(3, 10*time.Second)
+	upstream := &flakyUpstream{}
+
+	// Closed: failures below the threshold still reach the upstream
+	for i := 0; i < 2; i++ {
+		if err := b.Do(upstream.call); err == nil || errors.Is(err, ErrOpen) {
+			t.Fatalf("Expected upstream error, got %v", err)
+		}
+	}
+	if b.State() != Closed {
+		t.Fatalf("Expected closed below the threshold, got %v", b.State())
+	}
+
+	// The third consecutive failure opens the breaker, later calls fail fast
+	b.Do(upstream.call)
+	if b.State() != Open {
+		t.Fatalf("Expected open after 3 failures, got %v", b.State())
+	}
+	if err := b.Do(upstream.call); !errors.Is(err, ErrOpen) || upstream.calls != 3 {
+		t.Errorf("Expected fail-fast without calling the upstream, got %v after %d calls", err, upstream.calls)
+	}
+
+	// After the cooldown a failed probe reopens it for another cooldown
+	clock.now = clock.now.Add(10 * time.Second)
+	if b.State() != HalfOpen {
+		t.Fatalf("Expected half-open after the cooldown, got %v", b.State())
+	}
+	b.Do(upstream.call)
+	if b.State() != Open || upstream.calls != 4 {
+		t.Fatalf("Expected a failed probe to reopen, got %v after %d calls", b.State(), upstream.calls)
+	}
+
+	// Once the upstream recovers the probe closes the breaker
+	upstream.healthy = true
+	clock.now = clock.now.Add(10 * time.Second)
+	if err := b.Do(upstream.call); err != nil {
+		t.Fatalf("Expected probe to succeed, got %v", err)
+	}
+	if b.State() != Closed {
+		t.Errorf("Expected closed after a successful probe, got %v", b.State())
+	}
+
+	// A success resets the failure count
+	upstream.healthy = false
+	b.Do(upstream.call)
+	b.Do(upstream.call)
+	upstream.healthy = true
+	b.Do(upstream.call)
+	upstream.healthy = false
+	b.Do(upstream.call)
+	if b.State() != Closed {
+		t.Errorf("Expected closed when failures are not consecutive, got %v", b.State())
+	}
+}
+
+func TestBreakerHalfOpenSingleProbe(t *testing.T) {
+	b, clock := newTestBreaker(1, time.Second)
+	straggler, _ := b.Allow() // Started before the breaker opened
+	b.Do(func() error { return errUnavailable })
+	clock.now = clock.now.Add(time.Second)
+
+	// Only one probe is in flight at a time
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected the probe to be allowed, got %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected a second call during the probe to fail fast, got %v", err)
+	}
+
+	// A call that started before the breaker opened does not end the probe
+	straggler(nil)
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) || b.State() != HalfOpen {
+		t.Errorf("Expected the probe still in flight after a straggler finished, got %v, %v", err, b.State())
+	}
+
+	// A probe cancelled by its caller lets the next call probe instead
+	probe(context.Canceled)
+	if b.State() != HalfOpen {
+		t.Errorf("Expected cancellation to leave the breaker half-open, got %v", b.State())
+	}
+	probe, err = b.Allow()
+	if err != nil {
+		t.Fatalf("Expected a new probe after cancellation, got %v", err)
+	}
+	probe(nil)
+	if b.State() != Closed {
+		t.Errorf("Expected closed after the probe succeeded, got %v", b.State())
+	}
+}
+
+func TestBreakerIgnoresCancellation(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Minute)
+	for i := 0; i < 5; i++ {
+		b.Do(func() error { return context.Canceled })
+	}
+	if b.State() != Closed {
+		t.Errorf("Expected cancelled calls not to open the breaker, got %v", b.State())
+	}
+
+	// Timeouts do count, an unresponsive upstream is what the breaker is for
+	b.Do(func() error { return context.DeadlineExceeded })
+	b.Do(func() error { return context.DeadlineExceeded })
+	if b.State() != Open {
+		t.Errorf("Expected timeouts to open the breaker, got %v", b.State())
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b, _ := newTestBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.Do(func() error { return errUnavailable })
+	}
+	if _, err := b.Allow(); err != nil || b.State() != Closed {
+		t.Errorf("Expected a zero threshold to disable the breaker, got %v, %v", err, b.State())
+	}
+}
+
+func TestBreakerCountsOnlyUpstreamFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		opens bool
+	}{
+		{"server error", retry.NewStatusError(http.StatusInternalServerError, errors.New("internal error")), true},
+		{"rate limited", retry.NewStatusError(http.StatusTooManyRequests, errors.New("slow down")), true},
+		{"timeout", context.DeadlineExceeded, true},
+		{"transport error", &url.Error{Op: "Post", URL: "http://upstream", Err: errors.New("connection refused")}, true},
+		{"bad request", retry.NewStatusError(http.StatusBadRequest, errors.New("invalid voice")), false},
+		{"unauthorized", retry.NewStatusError(http.StatusUnauthorized, errors.New("invalid key")), false},
+		{"validation error", errors.New("text is empty"), false},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBreaker(2, time.Minute)
+		b.Do(func() error { return tt.err })
+		b.Do(func() error { return tt.err })
+		if opened := b.State() == Open; opened != tt.opens {
+			t.Errorf("%s: expected open=%v, got %v", tt.name, tt.opens, b.State())
+		}
+	}
+
+	// A 4xx answer shows the upstream is reachable and resets the failure count
+	b, _ := newTestBreaker(2, time.Minute)
+	b.Do(func() error { return errUnavailable })
+	b.Do(func() error { return retry.NewStatusError(http.StatusBadRequest, errors.New("invalid voice")) })
+	b.Do(func() error { return errUnavailable })
+	if b.State() != Closed {
+		t.Errorf("Expected a 4xx answer to reset the failure count, got %v", b.State())
+	}
+
+	// An error without a status says nothing about the upstream
+	b.Do(func() error { return errors.New("text is empty") })
+	b.Do(func() error { return errUnavailable })
+	if b.State() != Open {
+		t.Errorf("Expected a validation error not to reset the failure count, got %v", b.State())
+	}
+}
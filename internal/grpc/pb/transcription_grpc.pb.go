@@ -0,0 +1,123 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pb/transcription.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Transcription_StreamTranscribe_FullMethodName = "/audioassistant.transcription.v1.Transcription/StreamTranscribe"
+)
+
+// TranscriptionClient is the client API for Transcription service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Transcription streams microphone audio in and transcripts out
+type TranscriptionClient interface {
+	// StreamTranscribe receives audio chunks and returns transcripts as speech segments complete
+	// Interim transcripts cover the segment heard so far and may change, a final transcript closes the segment
+	StreamTranscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AudioChunk, Transcript], error)
+}
+
+type transcriptionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTranscriptionClient(cc grpc.ClientConnInterface) TranscriptionClient {
+	return &transcriptionClient{cc}
+}
+
+func (c *transcriptionClient) StreamTranscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AudioChunk, Transcript], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Transcription_ServiceDesc.Streams[0], Transcription_StreamTranscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AudioChunk, Transcript]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Transcription_StreamTranscribeClient = grpc.BidiStreamingClient[AudioChunk, Transcript]
+
+// TranscriptionServer is the server API for Transcription service.
+// All implementations must embed UnimplementedTranscriptionServer
+// for forward compatibility.
+//
+// Transcription streams microphone audio in and transcripts out
+type TranscriptionServer interface {
+	// StreamTranscribe receives audio chunks and returns transcripts as speech segments complete
+	// Interim transcripts cover the segment heard so far and may change, a final transcript closes the segment
+	StreamTranscribe(grpc.BidiStreamingServer[AudioChunk, Transcript]) error
+	mustEmbedUnimplementedTranscriptionServer()
+}
+
+// UnimplementedTranscriptionServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTranscriptionServer struct{}
+
+func (UnimplementedTranscriptionServer) StreamTranscribe(grpc.BidiStreamingServer[AudioChunk, Transcript]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTranscribe not implemented")
+}
+func (UnimplementedTranscriptionServer) mustEmbedUnimplementedTranscriptionServer() {}
+func (UnimplementedTranscriptionServer) testEmbeddedByValue()                       {}
+
+// UnsafeTranscriptionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TranscriptionServer will
+// result in compilation errors.
+type UnsafeTranscriptionServer interface {
+	mustEmbedUnimplementedTranscriptionServer()
+}
+
+func RegisterTranscriptionServer(s grpc.ServiceRegistrar, srv TranscriptionServer) {
+	// If the following call pancis, it indicates UnimplementedTranscriptionServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Transcription_ServiceDesc, srv)
+}
+
+func _Transcription_StreamTranscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TranscriptionServer).StreamTranscribe(&grpc.GenericServerStream[AudioChunk, Transcript]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Transcription_StreamTranscribeServer = grpc.BidiStreamingServer[AudioChunk, Transcript]
+
+// Transcription_ServiceDesc is the grpc.ServiceDesc for Transcription service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Transcription_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "audioassistant.transcription.v1.Transcription",
+	HandlerType: (*TranscriptionServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTranscribe",
+			Handler:       _Transcription_StreamTranscribe_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pb/transcription.proto",
+}
@@ -0,0 +1,228 @@
+// Package grpc serves real-time transcription over a bidirectional gRPC stream
+//
+// Stubs in pb are generated from pb/transcription.proto, see the go:generate directive below.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pb/transcription.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/grpc/pb"
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/vad"
+)
+
+// Transcriber converts a speech segment to text, implemented by *asr.Service
+// An empty language lets the API detect it
+type Transcriber interface {
+	TranscribeWithLanguageHint(ctx context.Context, audioData []float32, sampleRate int, language string) (string, error)
+}
+
+// Segmenter finds speech segments in buffered audio, implemented by *vad.LocalDetector
+type Segmenter interface {
+	Detect(samples []float32, sampleRate int) []vad.SpeechSegment
+}
+
+// Config configures the streaming transcription server
+type Config struct {
+	SampleRate         int            // Sample rate assumed when the first chunk does not set one
+	SegmentSilenceSec  float64        // Silence that closes a segment, shorter pauses stay in the same segment
+	InterimIntervalSec float64        // Speech added before an interim transcript is sent again (0 disables interim results)
+	MaxSegmentSec      float64        // Segments are closed at this length even without a pause (0 = unlimited)
+	Segmenter          Segmenter      // Speech detector, nil uses vad.NewLocalDetector with default settings
+	Logger             logging.Logger // Receives server logs, nil uses the standard log package
+}
+
+// DefaultConfig returns the default server configuration
+func DefaultConfig() Config {
+	return Config{
+		SampleRate:         audio.GetTargetSampleRate(),
+		SegmentSilenceSec:  0.5,
+		InterimIntervalSec: 1.0,
+		MaxSegmentSec:      25,
+	}
+}
+
+// Server implements pb.TranscriptionServer on top of a Transcriber
+type Server struct {
+	pb.UnimplementedTranscriptionServer
+
+	transcriber Transcriber
+	segmenter   Segmenter
+	config      Config
+	logger      logging.Logger
+}
+
+// NewServer creates a streaming transcription server
+func NewServer(transcriber Transcriber, config Config) *Server {
+	if config.SampleRate <= 0 {
+		config.SampleRate = audio.GetTargetSampleRate()
+	}
+	segmenter := config.Segmenter
+	if segmenter == nil {
+		segmenter = vad.NewLocalDetector(vad.DefaultLocalDetectorConfig())
+	}
+
+	return &Server{
+		transcriber: transcriber,
+		segmenter:   segmenter,
+		config:      config,
+		logger:      logging.OrStd(config.Logger),
+	}
+}
+
+// Register adds the service to a gRPC server
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	pb.RegisterTranscriptionServer(registrar, s)
+}
+
+// StreamTranscribe implements pb.TranscriptionServer
+func (s *Server) StreamTranscribe(stream pb.Transcription_StreamTranscribeServer) error {
+	ctx := stream.Context()
+	st := &streamState{server: s, stream: stream, sampleRate: s.config.SampleRate}
+
+	for first := true; ; first = false {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			// The client is done sending, close whatever speech is still open
+			return st.process(ctx, true)
+		}
+		if err != nil {
+			return err
+		}
+
+		if first {
+			if chunk.SampleRate > 0 {
+				st.sampleRate = int(chunk.SampleRate)
+			}
+			st.language = chunk.Language
+		}
+		if len(chunk.Pcm)%2 != 0 {
+			return status.Errorf(codes.InvalidArgument, "PCM chunk has odd length %d", len(chunk.Pcm))
+		}
+
+		st.pending = append(st.pending, audio.PCM16ToFloat32(chunk.Pcm)...)
+		if err := st.process(ctx, false); err != nil {
+			return err
+		}
+	}
+}
+
+// streamState holds the audio of one stream that has not been closed into a final segment yet
+type streamState struct {
+	server     *Server
+	stream     pb.Transcription_StreamTranscribeServer
+	sampleRate int
+	language   string
+
+	pending    []float32 // Audio after the last final segment
+	offset     float64   // Stream time of pending[0] in seconds
+	segment    int32     // Index of the next segment
+	interimLen float64   // Duration of the open segment when its last interim transcript was sent
+}
+
+// process closes completed segments and sends an interim transcript for the open one
+// With final set every detected segment is closed
+func (st *streamState) process(ctx context.Context, final bool) error {
+	config := st.server.config
+	rate := float64(st.sampleRate)
+	duration := float64(len(st.pending)) / rate
+
+	segments := mergeSegments(st.server.segmenter.Detect(st.pending, st.sampleRate), config.SegmentSilenceSec)
+	if len(segments) == 0 {
+		// Keep a little audio so speech that has only just started is not cut off
+		if keep := int(rate); !final && len(st.pending) > keep {
+			st.drop(len(st.pending) - keep)
+		}
+		return nil
+	}
+
+	cut := 0
+	for i, seg := range segments {
+		open := i == len(segments)-1 && duration-seg.End < config.SegmentSilenceSec
+		tooLong := config.MaxSegmentSec > 0 && seg.End-seg.Start >= config.MaxSegmentSec
+		if open && !final && !tooLong {
+			if err := st.sendInterim(ctx, seg); err != nil {
+				return err
+			}
+			break
+		}
+
+		if err := st.send(ctx, seg, true); err != nil {
+			return err
+		}
+		st.segment++
+		st.interimLen = 0
+		cut = min(int(seg.End*rate), len(st.pending))
+	}
+
+	st.drop(cut)
+	return nil
+}
+
+// sendInterim transcribes the open segment once enough new speech has arrived
+func (st *streamState) sendInterim(ctx context.Context, seg vad.SpeechSegment) error {
+	interval := st.server.config.InterimIntervalSec
+	length := seg.End - seg.Start
+	if interval <= 0 || length-st.interimLen < interval {
+		return nil
+	}
+
+	st.interimLen = length
+	return st.send(ctx, seg, false)
+}
+
+// send transcribes a segment of the pending audio and streams the result, empty results are skipped
+func (st *streamState) send(ctx context.Context, seg vad.SpeechSegment, final bool) error {
+	rate := float64(st.sampleRate)
+	start := int(seg.Start * rate)
+	end := min(int(seg.End*rate), len(st.pending))
+
+	text, err := st.server.transcriber.TranscribeWithLanguageHint(ctx, st.pending[start:end], st.sampleRate, st.language)
+	if err != nil {
+		st.server.logger.Warn("Streaming transcription failed: %v", err)
+		return status.Errorf(codes.Unavailable, "transcription failed: %v", err)
+	}
+	if text == "" {
+		return nil
+	}
+
+	return st.stream.Send(&pb.Transcript{
+		Text:    text,
+		IsFinal: final,
+		Segment: st.segment,
+		Start:   st.offset + seg.Start,
+		End:     st.offset + seg.End,
+	})
+}
+
+// drop removes the first n pending samples
+func (st *streamState) drop(n int) {
+	if n <= 0 {
+		return
+	}
+	st.pending = append(st.pending[:0], st.pending[n:]...)
+	st.offset += float64(n) / float64(st.sampleRate)
+}
+
+// mergeSegments joins segments separated by less than minGap seconds
+func mergeSegments(segments []vad.SpeechSegment, minGap float64) []vad.SpeechSegment {
+	var merged []vad.SpeechSegment
+	for _, seg := range segments {
+		if n := len(merged); n > 0 && seg.Start-merged[n-1].End < minGap {
+			merged[n-1].End = seg.End
+			merged[n-1].Duration = merged[n-1].End - merged[n-1].Start
+			continue
+		}
+		merged = append(merged, seg)
+	}
+	return merged
+}
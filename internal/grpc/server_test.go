@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"audio-assistant/internal/grpc/pb"
+	"audio-assistant/internal/logging"
+)
+
+// stubTranscriber reports the duration of each segment it is asked to transcribe
+type stubTranscriber struct {
+	err       error
+	languages []string
+}
+
+func (s *stubTranscriber) TranscribeWithLanguageHint(ctx context.Context, audioData []float32, sampleRate int, language string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.languages = append(s.languages, language)
+	return fmt.Sprintf("%.1fs", float64(len(audioData))/float64(sampleRate)), nil
+}
+
+// startServer serves transcriber over an in-memory connection and returns a client
+func startServer(t *testing.T, transcriber Transcriber) pb.TranscriptionClient {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Logger = logging.Discard()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	NewServer(transcriber, config).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewTranscriptionClient(conn)
+}
+
+// speechPCM generates alternating silence and voiced tone as 16-bit PCM, starting with silence
+func speechPCM(sampleRate int, durations ...float64) []byte {
+	var pcm []byte
+	for i, d := range durations {
+		n := int(d * float64(sampleRate))
+		for j := 0; j < n; j++ {
+			var v float64
+			if i%2 == 1 {
+				t := float64(j) / float64(sampleRate)
+				v = 0.3*math.Sin(2*math.Pi*150*t) + 0.15*math.Sin(2*math.Pi*300*t)
+			}
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(v*32767)))
+		}
+	}
+	return pcm
+}
+
+// streamAudio sends pcm in 100ms chunks, closes the send side and collects every transcript
+func streamAudio(t *testing.T, client pb.TranscriptionClient, pcm []byte, sampleRate int) ([]*pb.Transcript, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := client.StreamTranscribe(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	chunkBytes := sampleRate / 10 * 2
+	for i := 0; i < len(pcm); i += chunkBytes {
+		chunk := &pb.AudioChunk{Pcm: pcm[i:min(i+chunkBytes, len(pcm))]}
+		if i == 0 {
+			chunk.SampleRate = int32(sampleRate)
+			chunk.Language = "zh"
+		}
+		if err := stream.Send(chunk); err != nil {
+			t.Fatalf("Failed to send chunk: %v", err)
+		}
+	}
+	stream.CloseSend()
+
+	var transcripts []*pb.Transcript
+	for {
+		transcript, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return transcripts, nil
+		}
+		if err != nil {
+			return transcripts, err
+		}
+		transcripts = append(transcripts, transcript)
+	}
+}
+
+func TestStreamTranscribe(t *testing.T) {
+	transcriber := &stubTranscriber{}
+	client := startServer(t, transcriber)
+
+	// silence 0.3s, speech 2.2s, silence 0.8s, speech 0.6s
+	transcripts, err := streamAudio(t, client, speechPCM(16000, 0.3, 2.2, 0.8, 0.6), 16000)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	var kinds []string
+	for _, tr := range transcripts {
+		kinds = append(kinds, fmt.Sprintf("%d:%v", tr.Segment, tr.IsFinal))
+	}
+	// Two interim results while the first segment grows past 1s and 2s, then one final per segment
+	expected := []string{"0:false", "0:false", "0:true", "1:true"}
+	if fmt.Sprint(kinds) != fmt.Sprint(expected) {
+		t.Fatalf("Expected transcripts %v, got %v", expected, kinds)
+	}
+
+	first, second := transcripts[2], transcripts[3]
+	if first.Text != "2.2s" || math.Abs(first.Start-0.3) > 0.05 || math.Abs(first.End-2.5) > 0.05 {
+		t.Errorf("Unexpected first segment: %q %.2f-%.2f", first.Text, first.Start, first.End)
+	}
+	if second.Text != "0.6s" || math.Abs(second.Start-3.3) > 0.05 || math.Abs(second.End-3.9) > 0.05 {
+		t.Errorf("Unexpected second segment: %q %.2f-%.2f", second.Text, second.Start, second.End)
+	}
+	if transcripts[0].End >= transcripts[1].End {
+		t.Errorf("Expected interim transcripts to grow, got %.2f then %.2f", transcripts[0].End, transcripts[1].End)
+	}
+	for _, language := range transcriber.languages {
+		if language != "zh" {
+			t.Errorf("Expected language hint from the first chunk, got %q", language)
+		}
+	}
+}
+
+func TestStreamTranscribeErrors(t *testing.T) {
+	client := startServer(t, &stubTranscriber{err: errors.New("upstream down")})
+
+	_, err := streamAudio(t, client, speechPCM(16000, 0.2, 0.6), 16000)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable on transcription failure, got %v", err)
+	}
+
+	_, err = streamAudio(t, client, []byte{1, 2, 3}, 16000)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for odd PCM chunk, got %v", err)
+	}
+
+	// Silence alone produces no transcripts
+	transcripts, err := streamAudio(t, startServer(t, &stubTranscriber{}), speechPCM(16000, 2.0), 16000)
+	if err != nil || len(transcripts) != 0 {
+		t.Errorf("Expected no transcripts for silence, got %v, %v", transcripts, err)
+	}
+}
@@ -2,7 +2,6 @@ package llm
 
 import (
 	"context"
-	"fmt"
 )
 
 // Client接口定义了LLM客户端必须实现的方法
@@ -13,28 +12,98 @@ type Client interface {
 	EstimateTokens(text string) int
 }
 
+// StreamingClient is implemented by clients that support streaming chat completions
+type StreamingClient interface {
+	// ChatCompletionStream sends content deltas as they arrive, both channels are closed when the stream ends
+	ChatCompletionStream(ctx context.Context, req *ChatRequest) (<-chan string, <-chan error)
+}
+
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"`    // system, user, assistant
-	Content string `json:"content"` // message content
+	Role       string     `json:"role"`                   // system, user, assistant, tool
+	Content    string     `json:"content"`                // message content
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // tool calls requested by the assistant
+	ToolCallID string     `json:"tool_call_id,omitempty"` // id of the call a tool message answers
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string             `json:"type"` // always "function"
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a callable function and its JSON Schema parameters
+type FunctionDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // always "function"
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall holds the function name and its JSON-encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool choice values, any other value forces a call to the function with that name
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// NewFunctionTool creates a function tool definition
+func NewFunctionTool(name, description string, parameters map[string]interface{}) Tool {
+	return Tool{
+		Type: "function",
+		Function: FunctionDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
 }
 
 // ChatRequest represents the request parameters for chat completion
 type ChatRequest struct {
-	Model            string    `json:"model"`                       // gpt-3.5-turbo, gpt-4, etc.
-	Messages         []Message `json:"messages"`                    // conversation messages
-	MaxTokens        int       `json:"max_tokens,omitempty"`        // maximum tokens to generate
-	Temperature      float32   `json:"temperature,omitempty"`       // sampling temperature (0-2)
-	TopP             float32   `json:"top_p,omitempty"`             // nucleus sampling (0-1)
-	N                int       `json:"n,omitempty"`                 // number of completions
-	Stream           bool      `json:"stream,omitempty"`            // whether to stream responses
-	Stop             []string  `json:"stop,omitempty"`              // stop sequences
-	PresencePenalty  float32   `json:"presence_penalty,omitempty"`  // presence penalty (-2 to 2)
-	FrequencyPenalty float32   `json:"frequency_penalty,omitempty"` // frequency penalty (-2 to 2)
-	User             string    `json:"user,omitempty"`              // user identifier
-	EnableThinking   *bool     `json:"enable_thinking,omitempty"`   // for DashScope API compatibility
+	Model            string          `json:"model"`                       // gpt-3.5-turbo, gpt-4, etc.
+	Messages         []Message       `json:"messages"`                    // conversation messages
+	MaxTokens        int             `json:"max_tokens,omitempty"`        // maximum tokens to generate
+	Temperature      float32         `json:"temperature,omitempty"`       // sampling temperature (0-2)
+	TopP             float32         `json:"top_p,omitempty"`             // nucleus sampling (0-1)
+	N                int             `json:"n,omitempty"`                 // number of completions
+	Stream           bool            `json:"stream,omitempty"`            // whether to stream responses
+	Stop             []string        `json:"stop,omitempty"`              // stop sequences
+	PresencePenalty  float32         `json:"presence_penalty,omitempty"`  // presence penalty (-2 to 2)
+	FrequencyPenalty float32         `json:"frequency_penalty,omitempty"` // frequency penalty (-2 to 2)
+	User             string          `json:"user,omitempty"`              // user identifier
+	EnableThinking   *bool           `json:"enable_thinking,omitempty"`   // for DashScope API compatibility
+	Tools            []Tool          `json:"tools,omitempty"`             // functions the model may call
+	ToolChoice       string          `json:"tool_choice,omitempty"`       // auto, none, required or a function name
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`   // output format, e.g. JSON object mode
+
+	// IncrementalOutput set to false asks DashScope to send the full text so far in every stream event
+	IncrementalOutput *bool `json:"incremental_output,omitempty"`
 }
 
+// ResponseFormat selects the output format of the model
+type ResponseFormat struct {
+	Type string `json:"type"` // text or json_object
+}
+
+// Response format types
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+)
+
 // ChatResponse represents the response from chat completion
 type ChatResponse struct {
 	ID      string   `json:"id"`
@@ -43,6 +112,9 @@ type ChatResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+
+	// ToolCalls are the tool calls of the first choice, empty when the model answered directly
+	ToolCalls []ToolCall `json:"-"`
 }
 
 // Choice represents a completion choice
@@ -70,31 +142,13 @@ type ErrorResponse struct {
 
 // CreateSystemMessage creates a system message for voice assistant
 func CreateVoiceAssistantSystemMessage() Message {
-	return Message{
-		Role: "system",
-		Content: `你是一个智能语音助手。请遵循以下规则：
-
-1. 用简洁、自然的中文回复用户
-2. 回复长度控制在50字以内，适合语音播放
-3. 语气友好、礼貌，像朋友一样交流
-4. 如果用户问题不清楚，礼貌地请求澄清
-5. 避免使用过于技术性的词汇
-6. 回复应该适合口语表达，避免复杂的标点符号
-
-记住：你的回复将被转换为语音，所以要确保内容适合听觉理解。`,
-	}
+	return Message{Role: "system", Content: mustRenderPrompt(systemPromptTemplate, PromptOptions{})}
 }
 
 // CreateConversationContext creates context for ongoing conversation
 func CreateConversationContext(userName string) Message {
-	content := fmt.Sprintf(`当前对话上下文：
-- 用户名：%s
-- 对话类型：语音交互
-- 回复要求：简洁、自然、适合语音播放
-- 字数限制：50字以内`, userName)
-
 	return Message{
 		Role:    "system",
-		Content: content,
+		Content: mustRenderPrompt(conversationContextTemplate, PromptOptions{UserName: userName}),
 	}
 }
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxDocumentTokens is the default token budget for ChatWithDocuments context
+const DefaultMaxDocumentTokens = 1500
+
+// documentsHeader introduces the retrieved documents and asks the model to cite them
+const documentsHeader = `以下是检索到的参考资料，请优先依据资料回答，并在回答中用 [编号] 注明引用的来源。资料中没有的信息请如实说明，不要编造。`
+
+// truncationMarker is appended to a document cut to fit the budget
+const truncationMarker = "…"
+
+// Document is a retrieved snippet passed to ChatWithDocuments
+type Document struct {
+	Title   string
+	Content string
+	Source  string // URL, file path or other reference the assistant cites
+}
+
+// ChatWithDocuments runs a chat turn with docs formatted into a numbered context block
+// The block is sent just before the user message but not stored in history, so later
+// turns are not charged for it. Documents beyond MaxDocumentTokens are cut or dropped
+// in order, earlier documents are assumed to be more relevant
+func (s *Service) ChatWithDocuments(ctx context.Context, userMessage string, docs []Document) (string, error) {
+	if len(docs) == 0 {
+		return s.Chat(ctx, userMessage)
+	}
+
+	block, included := s.formatDocuments(docs)
+	if included < len(docs) {
+		s.logger.Warn("Document budget of %d tokens exceeded, using %d of %d documents", s.config.MaxDocumentTokens, included, len(docs))
+	}
+
+	response, _, err := s.chatWithContext(ctx, userMessage, &Message{Role: "system", Content: block})
+	return response, err
+}
+
+// formatDocuments renders docs within the token budget and returns the block and how many documents it holds
+// The last document that does not fit whole is truncated when some of its content still fits
+func (s *Service) formatDocuments(docs []Document) (string, int) {
+	budget := s.config.MaxDocumentTokens
+
+	var sb strings.Builder
+	sb.WriteString(documentsHeader)
+	used := s.EstimateTokens(documentsHeader)
+
+	for i, doc := range docs {
+		entry := formatDocument(i+1, doc, doc.Content)
+		cost := s.EstimateTokens(entry)
+		if budget <= 0 || used+cost <= budget {
+			sb.WriteString(entry)
+			used += cost
+			continue
+		}
+
+		// Keep as much of this document as fits, then stop
+		remaining := budget - used - s.EstimateTokens(formatDocument(i+1, doc, truncationMarker))
+		content := s.truncateToTokens(doc.Content, remaining)
+		if content == "" {
+			return sb.String(), i
+		}
+		sb.WriteString(formatDocument(i+1, doc, content+truncationMarker))
+		return sb.String(), i + 1
+	}
+
+	return sb.String(), len(docs)
+}
+
+// formatDocument renders one numbered document, omitting empty title and source lines
+func formatDocument(number int, doc Document, content string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n\n[%d]", number)
+	if doc.Title != "" {
+		sb.WriteString(" " + doc.Title)
+	}
+	if doc.Source != "" {
+		sb.WriteString("\n来源：" + doc.Source)
+	}
+	sb.WriteString("\n" + strings.TrimSpace(content))
+	return sb.String()
+}
+
+// truncateToTokens returns the longest rune prefix of text estimated within maxTokens
+func (s *Service) truncateToTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	// Token estimates grow with length, so binary search the rune count
+	low, high := 0, utf8.RuneCountInString(text)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if s.EstimateTokens(truncateRunes(text, mid)) <= maxTokens {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return truncateRunes(text, low)
+}
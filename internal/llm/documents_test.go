@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func newDocumentService(t *testing.T, budget int) (*Service, *capturingClient) {
+	t.Helper()
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.MaxDocumentTokens = budget
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	client := &capturingClient{}
+	service.client = client
+	service.isRunning = true
+	return service, client
+}
+
+var testDocuments = []Document{
+	{Title: "退货政策", Content: "购买后七天内可无理由退货。", Source: "https://example.com/returns"},
+	{Title: "配送时间", Content: "普通快递三到五天送达。", Source: "https://example.com/shipping"},
+	{Content: "客服电话工作日九点到十八点接听。", Source: "faq.md"},
+}
+
+func TestChatWithDocumentsWithinBudget(t *testing.T) {
+	service, client := newDocumentService(t, 0)
+
+	if _, err := service.ChatWithDocuments(context.Background(), "可以退货吗", testDocuments); err != nil {
+		t.Fatalf("ChatWithDocuments failed: %v", err)
+	}
+
+	// The context block sits between the system prompt and the user message
+	messages := client.req.Messages
+	if len(messages) != 3 || messages[1].Role != "system" || messages[2].Content != "可以退货吗" {
+		t.Fatalf("Unexpected request messages: %+v", messages)
+	}
+	block := messages[1].Content
+	for i, doc := range testDocuments {
+		for _, want := range []string{doc.Content, "来源：" + doc.Source, "[" + string(rune('1'+i)) + "]"} {
+			if !strings.Contains(block, want) {
+				t.Errorf("Expected %q in the context block:\n%s", want, block)
+			}
+		}
+	}
+	if !strings.Contains(block, "[1] 退货政策") || !strings.Contains(block, "注明引用的来源") {
+		t.Errorf("Expected titles and a citation instruction:\n%s", block)
+	}
+
+	// History keeps only the plain exchange
+	history := service.GetConversationHistory()
+	if len(history) != 3 || history[1].Content != "可以退货吗" {
+		t.Errorf("Expected documents to stay out of history, got %+v", history)
+	}
+}
+
+func TestChatWithDocumentsTruncation(t *testing.T) {
+	// fakeClient estimates one token per byte, so the budget fits the header,
+	// the first document and part of the second
+	full, _ := newDocumentService(t, 0)
+	first, _ := full.formatDocuments(testDocuments[:1])
+	budget := len(first) + len(formatDocument(2, testDocuments[1], "普通"+truncationMarker))
+	service, client := newDocumentService(t, budget)
+
+	if _, err := service.ChatWithDocuments(context.Background(), "多久到货", testDocuments); err != nil {
+		t.Fatalf("ChatWithDocuments failed: %v", err)
+	}
+	block := client.req.Messages[1].Content
+	if len(block) > budget {
+		t.Errorf("Expected the block within %d tokens, got %d", budget, len(block))
+	}
+	if !strings.Contains(block, testDocuments[0].Content) {
+		t.Errorf("Expected the first document whole:\n%s", block)
+	}
+	if !strings.Contains(block, "[2] 配送时间") || !strings.Contains(block, "普通"+truncationMarker) || strings.Contains(block, testDocuments[1].Content) {
+		t.Errorf("Expected the second document truncated:\n%s", block)
+	}
+	if strings.Contains(block, "[3]") {
+		t.Errorf("Expected the third document dropped:\n%s", block)
+	}
+
+	// Documents are dropped when not even their heading fits
+	service.config.MaxDocumentTokens = len(documentsHeader) + 5
+	if block, included := service.formatDocuments(testDocuments); included != 0 || block != documentsHeader {
+		t.Errorf("Expected only the header, got %d documents:\n%s", included, block)
+	}
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonInstruction is sent with JSON mode requests, the API requires the prompt to mention JSON
+const jsonInstruction = "Respond with a single valid JSON object and nothing else."
+
+// ChatJSON sends user input in JSON mode and unmarshals the reply into v
+// If the reply is not valid JSON, the model is asked once to correct it
+func (s *Service) ChatJSON(ctx context.Context, userMessage string, v interface{}) error {
+	if !s.isRunning {
+		return fmt.Errorf("LLM service is not running")
+	}
+
+	if strings.TrimSpace(userMessage) == "" {
+		return fmt.Errorf("user message cannot be empty")
+	}
+
+	s.appendHistory("user", userMessage)
+
+	messages := make([]Message, 0, len(s.conversationHist)+3)
+	messages = append(messages, s.conversationHist...)
+	messages = append(messages, Message{Role: "system", Content: jsonInstruction})
+
+	reply, err := s.completeJSON(ctx, messages)
+	if err != nil {
+		return err
+	}
+
+	if parseErr := unmarshalJSONReply(reply, v); parseErr != nil {
+		// Retry once, showing the model its invalid reply
+		messages = append(messages,
+			Message{Role: "assistant", Content: reply},
+			Message{Role: "user", Content: fmt.Sprintf("Your previous reply was not valid JSON (%v). %s", parseErr, jsonInstruction)},
+		)
+		if reply, err = s.completeJSON(ctx, messages); err != nil {
+			return err
+		}
+		if err := unmarshalJSONReply(reply, v); err != nil {
+			return fmt.Errorf("invalid JSON response after retry: %w", err)
+		}
+	}
+
+	s.appendHistory("assistant", reply)
+	s.trimHistory(ctx)
+
+	return nil
+}
+
+// completeJSON runs one JSON mode completion and returns the reply text
+func (s *Service) completeJSON(ctx context.Context, messages []Message) (string, error) {
+	req := &ChatRequest{
+		Model:          s.config.Model,
+		Messages:       messages,
+		MaxTokens:      s.config.MaxTokens,
+		Temperature:    s.config.Temperature,
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject},
+	}
+
+	response, err := s.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("chat completion failed: %w", err)
+	}
+	s.recordUsage(req.Model, response.Usage)
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned")
+	}
+
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
+
+// unmarshalJSONReply decodes a reply, tolerating a surrounding markdown code fence
+func unmarshalJSONReply(reply string, v interface{}) error {
+	reply = strings.TrimSpace(reply)
+	if strings.HasPrefix(reply, "```") {
+		reply = strings.TrimPrefix(reply, "```json")
+		reply = strings.TrimPrefix(reply, "```")
+		reply = strings.TrimSuffix(strings.TrimSpace(reply), "```")
+	}
+	return json.Unmarshal([]byte(reply), v)
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scriptedClient returns the given replies in order
+type scriptedClient struct {
+	fakeClient
+	replies  []string
+	requests []*ChatRequest
+}
+
+func (c *scriptedClient) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	c.requests = append(c.requests, req)
+	reply := c.replies[min(len(c.requests)-1, len(c.replies)-1)]
+	return &ChatResponse{
+		Model:   req.Model,
+		Choices: []Choice{{Message: Message{Role: "assistant", Content: reply}}},
+	}, nil
+}
+
+type alarmIntent struct {
+	Intent string `json:"intent"`
+	Time   string `json:"time"`
+}
+
+func newJSONService(t *testing.T, client Client) *Service {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = client
+	service.isRunning = true
+	return service
+}
+
+func TestChatJSONValid(t *testing.T) {
+	client := &scriptedClient{replies: []string{`{"intent":"set_alarm","time":"08:00"}`}}
+	service := newJSONService(t, client)
+
+	var intent alarmIntent
+	if err := service.ChatJSON(context.Background(), "设置明天早上八点的闹钟", &intent); err != nil {
+		t.Fatalf("ChatJSON failed: %v", err)
+	}
+	if intent.Intent != "set_alarm" || intent.Time != "08:00" {
+		t.Errorf("Unexpected intent: %+v", intent)
+	}
+
+	if len(client.requests) != 1 {
+		t.Fatalf("Expected a single request, got %d", len(client.requests))
+	}
+	req := client.requests[0]
+	if req.ResponseFormat == nil || req.ResponseFormat.Type != ResponseFormatJSONObject {
+		t.Errorf("Expected JSON response format, got %+v", req.ResponseFormat)
+	}
+
+	// The JSON instruction is sent with the request but not stored in history
+	for _, msg := range service.GetConversationHistory() {
+		if msg.Content == jsonInstruction {
+			t.Error("Expected JSON instruction to stay out of history")
+		}
+	}
+}
+
+func TestChatJSONRetriesMalformed(t *testing.T) {
+	client := &scriptedClient{replies: []string{`{"intent": "set_alarm",`, "```json\n{\"intent\":\"set_alarm\",\"time\":\"08:00\"}\n```"}}
+	service := newJSONService(t, client)
+
+	var intent alarmIntent
+	if err := service.ChatJSON(context.Background(), "设置闹钟", &intent); err != nil {
+		t.Fatalf("ChatJSON failed: %v", err)
+	}
+	if intent.Time != "08:00" {
+		t.Errorf("Unexpected intent after retry: %+v", intent)
+	}
+	if len(client.requests) != 2 {
+		t.Fatalf("Expected one retry, got %d requests", len(client.requests))
+	}
+
+	retry := client.requests[1].Messages
+	if last := retry[len(retry)-1]; last.Role != "user" || !strings.Contains(last.Content, "not valid JSON") {
+		t.Errorf("Expected corrective message in retry, got %+v", last)
+	}
+}
+
+func TestChatJSONFailsAfterRetry(t *testing.T) {
+	client := &scriptedClient{replies: []string{"好的，已设置"}}
+	service := newJSONService(t, client)
+
+	var intent alarmIntent
+	if err := service.ChatJSON(context.Background(), "设置闹钟", &intent); err == nil {
+		t.Error("Expected error for persistently invalid JSON")
+	}
+	if len(client.requests) != 2 {
+		t.Errorf("Expected exactly one retry, got %d requests", len(client.requests))
+	}
+}
+
+func TestResponseFormatRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		format, _ := body["response_format"].(map[string]interface{})
+		if format["type"] != "json_object" {
+			t.Errorf("Expected response_format json_object, got %v", body["response_format"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","created":0,"model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"{}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = server.URL
+	client := NewClient(config)
+
+	_, err := client.ChatCompletion(context.Background(), &ChatRequest{
+		Model:          "gpt-4o-mini",
+		Messages:       []Message{{Role: "user", Content: "JSON please"}},
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+}
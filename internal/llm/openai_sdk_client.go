@@ -2,12 +2,14 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+
+	"audio-assistant/internal/retry"
 )
 
 // OpenAISDKClient represents a client using the official OpenAI Go SDK
@@ -26,9 +28,15 @@ func NewClient(config *Config) *OpenAISDKClient {
 		opts = append(opts, option.WithBaseURL(config.BaseURL))
 	}
 
-	if config.Timeout > 0 {
-		opts = append(opts, option.WithHTTPClient(&http.Client{Timeout: config.Timeout}))
+	// Retries are handled by our transport so the backoff policy is the same as the other clients
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = retry.NewClientWithTransport(config.Timeout, config.MaxRetries, config.RetryBaseDelay, config.Transport)
 	}
+	opts = append(opts,
+		option.WithHTTPClient(httpClient),
+		option.WithMaxRetries(0),
+	)
 
 	client := openai.NewClient(opts...)
 
@@ -39,6 +47,116 @@ func NewClient(config *Config) *OpenAISDKClient {
 
 // ChatCompletion creates a chat completion using OpenAI SDK
 func (c *OpenAISDKClient) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	params := buildChatParams(req)
+
+	// Make the API call
+	completion, err := c.client.Chat.Completions.New(ctx, params, chatRequestOptions(req)...)
+	if err != nil {
+		return nil, fmt.Errorf("chat completion failed: %w", withStatus(err))
+	}
+
+	// Convert response to our format
+	choices := make([]Choice, len(completion.Choices))
+	for i, choice := range completion.Choices {
+		choices[i] = Choice{
+			Index: i,
+			Message: Message{
+				Role:      string(choice.Message.Role),
+				Content:   choice.Message.Content,
+				ToolCalls: convertToolCalls(choice.Message.ToolCalls),
+			},
+			FinishReason: string(choice.FinishReason),
+		}
+	}
+
+	response := &ChatResponse{
+		ID:      completion.ID,
+		Object:  string(completion.Object),
+		Created: completion.Created,
+		Model:   completion.Model,
+		Choices: choices,
+		Usage: Usage{
+			PromptTokens:     int(completion.Usage.PromptTokens),
+			CompletionTokens: int(completion.Usage.CompletionTokens),
+			TotalTokens:      int(completion.Usage.TotalTokens),
+		},
+	}
+	if len(choices) > 0 {
+		response.ToolCalls = choices[0].Message.ToolCalls
+	}
+
+	return response, nil
+}
+
+// convertToolCalls converts SDK tool calls to our format
+func convertToolCalls(calls []openai.ChatCompletionMessageToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	result := make([]ToolCall, len(calls))
+	for i, call := range calls {
+		result[i] = ToolCall{
+			ID:   call.ID,
+			Type: string(call.Type),
+			Function: FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		}
+	}
+	return result
+}
+
+// ChatCompletionStream creates a streaming chat completion using OpenAI SDK
+// The SDK parses the SSE "data:" lines, each content delta is sent on the returned channel
+// Both channels are closed when the stream ends, the error channel receives at most one error
+func (c *OpenAISDKClient) ChatCompletionStream(ctx context.Context, req *ChatRequest) (<-chan string, <-chan error) {
+	deltas := make(chan string)
+	errs := make(chan error, 1)
+
+	params := buildChatParams(req)
+
+	go func() {
+		defer close(errs)
+		defer close(deltas)
+
+		stream := c.client.Chat.Completions.NewStreaming(ctx, params, chatRequestOptions(req)...)
+		defer stream.Close()
+
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+
+			select {
+			case deltas <- chunk.Choices[0].Delta.Content:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			errs <- fmt.Errorf("chat completion stream failed: %w", withStatus(err))
+		}
+	}()
+
+	return deltas, errs
+}
+
+// withStatus exposes the HTTP status of SDK API errors as a retry.StatusError
+func withStatus(err error) error {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return retry.NewStatusError(apiErr.StatusCode, err)
+	}
+	return err
+}
+
+// buildChatParams converts our request format to OpenAI SDK parameters
+func buildChatParams(req *ChatRequest) openai.ChatCompletionNewParams {
 	// Convert our format to OpenAI SDK format
 	messages := make([]openai.ChatCompletionMessageParamUnion, len(req.Messages))
 	for i, msg := range req.Messages {
@@ -48,7 +166,9 @@ func (c *OpenAISDKClient) ChatCompletion(ctx context.Context, req *ChatRequest)
 		case "user":
 			messages[i] = openai.UserMessage(msg.Content)
 		case "assistant":
-			messages[i] = openai.AssistantMessage(msg.Content)
+			messages[i] = assistantMessageParam(msg)
+		case "tool":
+			messages[i] = openai.ToolMessage(msg.Content, msg.ToolCallID)
 		default:
 			messages[i] = openai.UserMessage(msg.Content)
 		}
@@ -60,11 +180,6 @@ func (c *OpenAISDKClient) ChatCompletion(ctx context.Context, req *ChatRequest)
 		model = "gpt-3.5-turbo"
 	}
 
-	temperature := req.Temperature
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
 		maxTokens = 1000
@@ -80,9 +195,8 @@ func (c *OpenAISDKClient) ChatCompletion(ctx context.Context, req *ChatRequest)
 	if maxTokens > 0 {
 		params.MaxTokens = openai.Int(int64(maxTokens))
 	}
-	if temperature > 0 {
-		params.Temperature = openai.Float(float64(temperature))
-	}
+	// Temperature is always sent, 0 asks for deterministic output rather than the server default
+	params.Temperature = openai.Float(float64(req.Temperature))
 	if req.TopP > 0 {
 		params.TopP = openai.Float(float64(req.TopP))
 	}
@@ -102,40 +216,83 @@ func (c *OpenAISDKClient) ChatCompletion(ctx context.Context, req *ChatRequest)
 	if req.User != "" {
 		params.User = openai.String(req.User)
 	}
+	if len(req.Tools) > 0 {
+		params.Tools = buildToolParams(req.Tools)
+	}
+	if req.ToolChoice != "" {
+		params.ToolChoice = buildToolChoice(req.ToolChoice)
+	}
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case ResponseFormatJSONObject:
+			params.ResponseFormat.OfJSONObject = &openai.ResponseFormatJSONObjectParam{}
+		case ResponseFormatText:
+			params.ResponseFormat.OfText = &openai.ResponseFormatTextParam{}
+		}
+	}
 
-	// Make the API call
-	completion, err := c.client.Chat.Completions.New(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("chat completion failed: %w", err)
+	return params
+}
+
+// assistantMessageParam converts an assistant message, keeping any tool calls it made
+func assistantMessageParam(msg Message) openai.ChatCompletionMessageParamUnion {
+	if len(msg.ToolCalls) == 0 {
+		return openai.AssistantMessage(msg.Content)
 	}
 
-	// Convert response to our format
-	choices := make([]Choice, len(completion.Choices))
-	for i, choice := range completion.Choices {
-		choices[i] = Choice{
-			Index: i,
-			Message: Message{
-				Role:    string(choice.Message.Role),
-				Content: choice.Message.Content,
+	assistant := openai.ChatCompletionAssistantMessageParam{}
+	if msg.Content != "" {
+		assistant.Content.OfString = openai.String(msg.Content)
+	}
+	for _, call := range msg.ToolCalls {
+		assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+			ID: call.ID,
+			Function: openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
 			},
-			FinishReason: string(choice.FinishReason),
+		})
+	}
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
+// buildToolParams converts tool definitions to SDK parameters
+func buildToolParams(tools []Tool) []openai.ChatCompletionToolParam {
+	params := make([]openai.ChatCompletionToolParam, len(tools))
+	for i, tool := range tools {
+		definition := openai.FunctionDefinitionParam{Name: tool.Function.Name}
+		if tool.Function.Description != "" {
+			definition.Description = openai.String(tool.Function.Description)
+		}
+		if tool.Function.Parameters != nil {
+			definition.Parameters = openai.FunctionParameters(tool.Function.Parameters)
 		}
+		params[i] = openai.ChatCompletionToolParam{Function: definition}
 	}
+	return params
+}
 
-	response := &ChatResponse{
-		ID:      completion.ID,
-		Object:  string(completion.Object),
-		Created: completion.Created,
-		Model:   completion.Model,
-		Choices: choices,
-		Usage: Usage{
-			PromptTokens:     int(completion.Usage.PromptTokens),
-			CompletionTokens: int(completion.Usage.CompletionTokens),
-			TotalTokens:      int(completion.Usage.TotalTokens),
-		},
+// buildToolChoice converts a tool choice string, unknown values name a function to force
+func buildToolChoice(choice string) openai.ChatCompletionToolChoiceOptionUnionParam {
+	switch choice {
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(choice)}
+	default:
+		return openai.ChatCompletionToolChoiceOptionParamOfChatCompletionNamedToolChoice(
+			openai.ChatCompletionNamedToolChoiceFunctionParam{Name: choice})
 	}
+}
 
-	return response, nil
+// chatRequestOptions returns per-request options for fields the SDK params don't cover
+func chatRequestOptions(req *ChatRequest) []option.RequestOption {
+	var opts []option.RequestOption
+	if req.EnableThinking != nil {
+		opts = append(opts, option.WithJSONSet("enable_thinking", *req.EnableThinking))
+	}
+	if req.IncrementalOutput != nil {
+		opts = append(opts, option.WithJSONSet("incremental_output", *req.IncrementalOutput))
+	}
+	return opts
 }
 
 // SimpleChat provides a simple interface for single-turn conversations
@@ -212,15 +369,9 @@ func (c *OpenAISDKClient) ValidateAPIKey(ctx context.Context) error {
 	return nil
 }
 
-// EstimateTokens provides a rough estimate of token count
+// EstimateTokens estimates token count with the shared llm tokenizer
 func (c *OpenAISDKClient) EstimateTokens(text string) int {
-	// Rough estimation: 1 token ≈ 0.75 words for English
-	words := len(strings.Fields(text))
-	tokens := int(float64(words) * 1.33)
-	if tokens < 1 {
-		tokens = 1
-	}
-	return tokens
+	return EstimateTokens(text)
 }
 
 // TruncateToTokenLimit truncates text to fit within token limit
@@ -229,14 +380,17 @@ func (c *OpenAISDKClient) TruncateToTokenLimit(text string, maxTokens int) strin
 		return text
 	}
 
-	// Simple truncation - could be improved with proper tokenization
-	words := strings.Fields(text)
-	estimatedWordsPerToken := 0.75
-	maxWords := int(float64(maxTokens) * estimatedWordsPerToken)
-
-	if len(words) <= maxWords {
-		return text
+	// Binary search the longest rune prefix that fits, works for text without spaces (e.g. Chinese)
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if c.EstimateTokens(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
 	}
 
-	return strings.Join(words[:maxWords], " ") + "..."
+	return string(runes[:lo]) + "..."
 }
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PromptOptions holds the variables rendered into the system prompt
+// Zero values fall back to the defaults used by CreateVoiceAssistantSystemMessage
+type PromptOptions struct {
+	Persona       string    // Opening sentence describing the assistant
+	MaxReplyChars int       // Reply length limit in characters
+	Language      string    // Reply language, e.g. "中文" or "English"
+	UserName      string    // Added to the prompt when set
+	IncludeTime   bool      // Add the current time to the prompt
+	Now           time.Time // Time shown when IncludeTime is set, defaults to time.Now()
+}
+
+// Defaults for PromptOptions
+const (
+	defaultPersona       = "你是一个智能语音助手。"
+	defaultMaxReplyChars = 50
+	defaultLanguage      = "中文"
+)
+
+// DefaultVoiceSystemMessage is the system prompt GenerateVoiceResponse uses when
+// Config.VoiceSystemMessage is empty
+const DefaultVoiceSystemMessage = `你是一个智能语音助手。请遵循以下规则：
+
+1. 回复必须简洁明了，控制在30字以内
+2. 使用自然的口语化表达，避免书面语
+3. 语气要友好亲切，像朋友聊天一样
+4. 避免使用复杂的标点符号和特殊字符
+5. 如果需要列举，用简单的语言描述，不要用编号
+6. 回复要适合语音播放，听起来自然流畅
+
+记住：你的回复将直接转换为语音播放给用户。`
+
+var systemPromptTemplate = template.Must(template.New("system").Parse(`{{.Persona}}请遵循以下规则：
+
+1. 用简洁、自然的{{.Language}}回复用户
+2. 回复长度控制在{{.MaxReplyChars}}字以内，适合语音播放
+3. 语气友好、礼貌，像朋友一样交流
+4. 如果用户问题不清楚，礼貌地请求澄清
+5. 避免使用过于技术性的词汇
+6. 回复应该适合口语表达，避免复杂的标点符号
+
+记住：你的回复将被转换为语音，所以要确保内容适合听觉理解。
+{{- if or .UserName .IncludeTime}}
+{{if .UserName}}
+用户名：{{.UserName}}{{end}}{{if .IncludeTime}}
+当前时间：{{.Time}}{{end}}{{end}}`))
+
+var conversationContextTemplate = template.Must(template.New("context").Parse(`当前对话上下文：
+- 用户名：{{.UserName}}
+- 对话类型：语音交互
+- 回复要求：简洁、自然、适合语音播放
+- 字数限制：{{.MaxReplyChars}}字以内`))
+
+// promptData is the template input, with defaults applied and the time formatted
+type promptData struct {
+	PromptOptions
+	Time string
+}
+
+// BuildSystemPrompt renders the voice assistant system prompt with the given options
+func BuildSystemPrompt(opts PromptOptions) (Message, error) {
+	content, err := renderPrompt(systemPromptTemplate, opts)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Role: "system", Content: content}, nil
+}
+
+// mustRenderPrompt renders a template with built-in options, which only fails on a broken template
+func mustRenderPrompt(tmpl *template.Template, opts PromptOptions) string {
+	content, err := renderPrompt(tmpl, opts)
+	if err != nil {
+		panic(err)
+	}
+	return content
+}
+
+// renderPrompt applies defaults and executes a prompt template
+func renderPrompt(tmpl *template.Template, opts PromptOptions) (string, error) {
+	if opts.Persona == "" {
+		opts.Persona = defaultPersona
+	}
+	if opts.MaxReplyChars <= 0 {
+		opts.MaxReplyChars = defaultMaxReplyChars
+	}
+	if opts.Language == "" {
+		opts.Language = defaultLanguage
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	data := promptData{PromptOptions: opts, Time: opts.Now.Format("2006-01-02 15:04 Monday")}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestBuildSystemPromptDefault(t *testing.T) {
+	expected := `你是一个智能语音助手。请遵循以下规则：
+
+1. 用简洁、自然的中文回复用户
+2. 回复长度控制在50字以内，适合语音播放
+3. 语气友好、礼貌，像朋友一样交流
+4. 如果用户问题不清楚，礼貌地请求澄清
+5. 避免使用过于技术性的词汇
+6. 回复应该适合口语表达，避免复杂的标点符号
+
+记住：你的回复将被转换为语音，所以要确保内容适合听觉理解。`
+
+	msg, err := BuildSystemPrompt(PromptOptions{})
+	if err != nil {
+		t.Fatalf("BuildSystemPrompt failed: %v", err)
+	}
+	if msg.Role != "system" || msg.Content != expected {
+		t.Errorf("Unexpected default prompt:\n%s", msg.Content)
+	}
+	if CreateVoiceAssistantSystemMessage().Content != expected {
+		t.Error("Expected CreateVoiceAssistantSystemMessage to match the default prompt")
+	}
+
+	context := `当前对话上下文：
+- 用户名：小明
+- 对话类型：语音交互
+- 回复要求：简洁、自然、适合语音播放
+- 字数限制：50字以内`
+	if got := CreateConversationContext("小明").Content; got != context {
+		t.Errorf("Unexpected conversation context:\n%s", got)
+	}
+}
+
+func TestBuildSystemPromptVariables(t *testing.T) {
+	now := time.Date(2024, 6, 10, 8, 30, 0, 0, time.UTC)
+	msg, err := BuildSystemPrompt(PromptOptions{
+		Persona:       "你是一位耐心的英语老师。",
+		MaxReplyChars: 80,
+		Language:      "英文",
+		UserName:      "小红",
+		IncludeTime:   true,
+		Now:           now,
+	})
+	if err != nil {
+		t.Fatalf("BuildSystemPrompt failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"你是一位耐心的英语老师。请遵循以下规则",
+		"用简洁、自然的英文回复用户",
+		"回复长度控制在80字以内",
+		"用户名：小红",
+		"当前时间：2024-06-10 08:30 Monday",
+	} {
+		if !strings.Contains(msg.Content, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, msg.Content)
+		}
+	}
+	if strings.Contains(msg.Content, defaultPersona) {
+		t.Error("Expected custom persona to replace the default")
+	}
+
+	withoutTime, err := BuildSystemPrompt(PromptOptions{UserName: "小红"})
+	if err != nil {
+		t.Fatalf("BuildSystemPrompt failed: %v", err)
+	}
+	if strings.Contains(withoutTime.Content, "当前时间") {
+		t.Error("Expected no time when IncludeTime is false")
+	}
+}
+
+func TestRenderPromptError(t *testing.T) {
+	broken := template.Must(template.New("broken").Parse(`{{.Missing}}`))
+	if _, err := renderPrompt(broken, PromptOptions{}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected a render error naming the template, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"fmt"
+)
+
+// Supported LLM providers
+const (
+	ProviderOpenAI    = "openai"
+	ProviderOpenAISDK = "openai-sdk"
+	ProviderQwen      = "qwen"
+)
+
+// Default chat models of each provider, used when no model is configured
+const (
+	DefaultOpenAIModel = "gpt-4o-mini"
+	DefaultQwenModel   = "qwen-plus"
+)
+
+// DefaultModelFor returns the default chat model of provider, an empty or unknown name gets the OpenAI default
+func DefaultModelFor(provider string) string {
+	if provider == ProviderQwen {
+		return DefaultQwenModel
+	}
+	return DefaultOpenAIModel
+}
+
+// NewProviderClient creates the client for the named provider
+// "openai" and "openai-sdk" both use the official SDK client, an empty name selects "openai"
+func NewProviderClient(provider string, config *Config) (Client, error) {
+	switch provider {
+	case "", ProviderOpenAI, ProviderOpenAISDK:
+		return NewClient(config), nil
+	case ProviderQwen:
+		return NewQwenClient(config), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (supported: %s, %s, %s)",
+			provider, ProviderOpenAI, ProviderOpenAISDK, ProviderQwen)
+	}
+}
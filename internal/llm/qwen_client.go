@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultQwenBaseURL is the OpenAI-compatible endpoint of DashScope
+const DefaultQwenBaseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+
+// QwenClient represents a client for Qwen models served through the DashScope compatible API
+type QwenClient struct {
+	*OpenAISDKClient
+}
+
+// qwenChatPath is appended to the base URL by the SDK
+const qwenChatPath = "/chat/completions"
+
+// NewQwenClient creates a new Qwen client, the DashScope endpoint is used unless BaseURL points elsewhere
+// A BaseURL copied with the full chat endpoint is trimmed to its base, since the SDK appends the path itself
+func NewQwenClient(config *Config) *QwenClient {
+	qwenConfig := *config
+	if qwenConfig.BaseURL == "" || qwenConfig.BaseURL == DefaultConfig().BaseURL {
+		qwenConfig.BaseURL = DefaultQwenBaseURL
+	}
+	qwenConfig.BaseURL = strings.TrimSuffix(strings.TrimSuffix(qwenConfig.BaseURL, "/"), qwenChatPath)
+
+	return &QwenClient{
+		OpenAISDKClient: NewClient(&qwenConfig),
+	}
+}
+
+// ChatCompletion creates a chat completion, thinking is disabled unless requested since DashScope rejects it for non-streaming calls
+func (c *QwenClient) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if req.EnableThinking == nil {
+		qwenReq := *req
+		enableThinking := false
+		qwenReq.EnableThinking = &enableThinking
+		req = &qwenReq
+	}
+	return c.OpenAISDKClient.ChatCompletion(ctx, req)
+}
+
+// ChatCompletionStream streams a chat completion over DashScope SSE
+// The compatible-mode endpoint sends incremental deltas by default. Requests that set
+// IncrementalOutput to false get the full text so far in every event, which is converted to deltas
+// so callers get the same contract as OpenAISDKClient. The mode is never guessed from the content,
+// since a delta that happens to extend the previous one ("好" then "好的") looks like full text
+func (c *QwenClient) ChatCompletionStream(ctx context.Context, req *ChatRequest) (<-chan string, <-chan error) {
+	events, eventErrs := c.OpenAISDKClient.ChatCompletionStream(ctx, req)
+	if req.IncrementalOutput == nil || *req.IncrementalOutput {
+		return events, eventErrs
+	}
+	return fullTextToDeltas(ctx, events, eventErrs)
+}
+
+// fullTextToDeltas converts a stream of text events that each hold the full text so far into deltas
+func fullTextToDeltas(ctx context.Context, events <-chan string, eventErrs <-chan error) (<-chan string, <-chan error) {
+	deltas := make(chan string)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(deltas)
+
+		var text string
+		for event := range events {
+			// An event that doesn't extend the previous one restarts the text
+			delta := event
+			if strings.HasPrefix(event, text) {
+				delta = event[len(text):]
+			}
+			text = event
+			if delta == "" {
+				continue
+			}
+
+			select {
+			case deltas <- delta:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				// Let the upstream goroutine observe the cancellation and close
+				for range events {
+				}
+				return
+			}
+		}
+
+		if err, ok := <-eventErrs; ok {
+			errs <- err
+		}
+	}()
+
+	return deltas, errs
+}
+
+// GetAvailableModels returns available Qwen models
+func (c *QwenClient) GetAvailableModels() []string {
+	return []string{
+		"qwen-max",
+		"qwen-plus",
+		"qwen-turbo",
+		"qwen-long",
+	}
+}
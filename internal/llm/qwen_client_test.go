@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQwenClientDisablesThinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if body["enable_thinking"] != false {
+			t.Errorf("Expected enable_thinking=false, got %v", body["enable_thinking"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","created":0,"model":"qwen-plus","choices":[{"index":0,"message":{"role":"assistant","content":"你好"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = server.URL
+	client := NewQwenClient(config)
+
+	resp, err := client.ChatCompletion(context.Background(), &ChatRequest{
+		Model:    "qwen-plus",
+		Messages: []Message{{Role: "user", Content: "你好"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "你好" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+// Captured DashScope compatible-mode SSE frames, trimmed to the fields the client reads
+const (
+	qwenIncrementalFrames = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"今天"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"天气"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"晴朗。"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+	qwenFullTextFrames = `data: {"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"今天"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"今天天气"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"今天天气晴朗。"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+	// Incremental deltas where each one extends the previous, which looks like full text
+	qwenRepeatedFrames = `data: {"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"哈"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"哈哈"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[{"index":0,"delta":{"content":"，好的"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+)
+
+// newQwenStreamServer replays frames, incrementalOutput is the expected incremental_output field (nil when absent)
+func newQwenStreamServer(t *testing.T, frames string, incrementalOutput interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if body["stream"] != true {
+			t.Errorf("Expected stream=true, got %v", body["stream"])
+		}
+		if body["incremental_output"] != incrementalOutput {
+			t.Errorf("Expected incremental_output=%v, got %v", incrementalOutput, body["incremental_output"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(frames))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func collectStream(t *testing.T, deltas <-chan string, errs <-chan error) []string {
+	t.Helper()
+	var got []string
+	for delta := range deltas {
+		got = append(got, delta)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	return got
+}
+
+func TestQwenClientChatCompletionStream(t *testing.T) {
+	fullText := false
+	tests := []struct {
+		name              string
+		frames            string
+		incrementalOutput *bool
+		expected          []string
+	}{
+		{"incremental", qwenIncrementalFrames, nil, []string{"今天", "天气", "晴朗。"}},
+		{"full text", qwenFullTextFrames, &fullText, []string{"今天", "天气", "晴朗。"}},
+		// Incremental deltas are passed through even when one extends the previous
+		{"repeated characters", qwenRepeatedFrames, nil, []string{"哈", "哈哈", "，好的"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expectedField interface{}
+			if tt.incrementalOutput != nil {
+				expectedField = *tt.incrementalOutput
+			}
+			config := DefaultConfig()
+			config.APIKey = "test-key"
+			config.BaseURL = newQwenStreamServer(t, tt.frames, expectedField).URL
+			client := NewQwenClient(config)
+
+			deltas, errs := client.ChatCompletionStream(context.Background(), &ChatRequest{
+				Model:             "qwen-plus",
+				Messages:          []Message{{Role: "user", Content: "今天天气怎么样"}},
+				IncrementalOutput: tt.incrementalOutput,
+			})
+			got := collectStream(t, deltas, errs)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected deltas %q, got %q", tt.expected, got)
+			}
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected deltas %q, got %q", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestQwenClientChatCompletionStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"invalid model","type":"invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = server.URL
+	config.MaxRetries = 0
+	deltas, errs := NewQwenClient(config).ChatCompletionStream(context.Background(), &ChatRequest{
+		Model:    "qwen-unknown",
+		Messages: []Message{{Role: "user", Content: "你好"}},
+	})
+	for range deltas {
+		t.Error("Expected no deltas")
+	}
+	if err := <-errs; err == nil {
+		t.Error("Expected stream error")
+	}
+}
+
+func TestQwenClientEndpoint(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","created":0,"model":"qwen-plus","choices":[{"index":0,"message":{"role":"assistant","content":"你好"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	// Both the base URL and the full endpoint resolve to the compatible-mode chat path
+	for _, baseURL := range []string{
+		server.URL + "/compatible-mode/v1",
+		server.URL + "/compatible-mode/v1/chat/completions",
+		server.URL + "/compatible-mode/v1/",
+	} {
+		config := DefaultConfig()
+		config.APIKey = "test-key"
+		config.BaseURL = baseURL
+		_, err := NewQwenClient(config).ChatCompletion(context.Background(), &ChatRequest{
+			Model:    "qwen-plus",
+			Messages: []Message{{Role: "system", Content: "你是助手"}, {Role: "user", Content: "你好"}},
+		})
+		if err != nil {
+			t.Fatalf("ChatCompletion with %q failed: %v", baseURL, err)
+		}
+		if path != "/compatible-mode/v1/chat/completions" {
+			t.Errorf("Base URL %q: expected the compatible-mode chat path, got %q", baseURL, path)
+		}
+
+		// OpenAI-style body: top-level model and messages, no native DashScope input/parameters
+		messages, _ := body["messages"].([]interface{})
+		if body["model"] != "qwen-plus" || len(messages) != 2 || body["input"] != nil || body["parameters"] != nil {
+			t.Errorf("Base URL %q: expected an OpenAI-style body, got %v", baseURL, body)
+		}
+	}
+}
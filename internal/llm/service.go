@@ -3,9 +3,14 @@ package llm
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/retry"
 )
 
 // Service manages LLM operations and conversation context
@@ -15,6 +20,17 @@ type Service struct {
 	isRunning        bool
 	conversationHist []Message
 	maxHistoryLength int
+
+	// Conversation checkpoints for in-process experimentation
+	checkpoints      map[int][]Message
+	checkpointOrder  []int
+	nextCheckpointID int
+
+	// Accumulated token usage per model
+	usageMu      sync.Mutex
+	usageByModel map[string]Usage
+
+	logger logging.Logger
 }
 
 // Config represents LLM service configuration
@@ -25,11 +41,37 @@ type Config struct {
 	Temperature      float32
 	MaxTokens        int
 	MaxHistoryLength int
+	MaxHistoryTokens int  // Estimated token budget for the whole history (0 = unlimited)
+	SummarizeOnTrim  bool // Summarize dropped turns into a system note instead of discarding them
 	SystemMessage    string
 	UserName         string
 	Timeout          time.Duration
+	MaxCheckpoints   int           // Maximum number of conversation checkpoints kept in memory
+	MaxMessageRunes  int           // Maximum runes of a single message stored in history (0 = unlimited)
+	MaxRetries       int           // Retries for transient API failures (429/5xx)
+	RetryBaseDelay   time.Duration // Initial backoff delay, doubled on each retry
+	// Transport carries requests for the default retrying client (proxy, custom TLS), nil uses http.DefaultTransport
+	Transport http.RoundTripper
+	// HTTPClient replaces the default client entirely, Timeout, MaxRetries and Transport are then ignored
+	HTTPClient *http.Client
+	// Logger receives service logs, nil uses the standard log package
+	Logger logging.Logger
+
+	// Estimated token budget for the documents passed to ChatWithDocuments (0 = unlimited)
+	MaxDocumentTokens int
+
+	// GenerateVoiceResponse settings, a zero token cap or empty prompt uses the defaults below
+	VoiceMaxTokens     int     // Completion token cap for one-shot voice replies
+	VoiceTemperature   float32 // Sampling temperature for one-shot voice replies (0-2, 0 is deterministic)
+	VoiceSystemMessage string  // System prompt for one-shot voice replies
 }
 
+// Defaults for GenerateVoiceResponse
+const (
+	DefaultVoiceMaxTokens   = 100 // Even shorter than MaxTokens for voice
+	DefaultVoiceTemperature = 0.7
+)
+
 // DefaultConfig returns default LLM configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -38,9 +80,20 @@ func DefaultConfig() *Config {
 		Temperature:      0.7,
 		MaxTokens:        150, // Shorter responses for voice
 		MaxHistoryLength: 10,  // Keep last 10 exchanges
+		MaxHistoryTokens: 3000,
 		SystemMessage:    CreateVoiceAssistantSystemMessage().Content,
 		UserName:         "用户",
 		Timeout:          30 * time.Second,
+		MaxCheckpoints:   10,
+		MaxMessageRunes:  2000,
+		MaxRetries:       retry.DefaultMaxRetries,
+		RetryBaseDelay:   retry.DefaultBaseDelay,
+
+		MaxDocumentTokens: DefaultMaxDocumentTokens,
+
+		VoiceMaxTokens:     DefaultVoiceMaxTokens,
+		VoiceTemperature:   DefaultVoiceTemperature,
+		VoiceSystemMessage: DefaultVoiceSystemMessage,
 	}
 }
 
@@ -54,7 +107,33 @@ func NewService(config *Config) (*Service, error) {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
-	// Initialize conversation history with system message
+	client := NewClient(config)
+	return &Service{
+		client:           client,
+		config:           config,
+		conversationHist: initialHistory(config),
+		maxHistoryLength: config.MaxHistoryLength,
+		checkpoints:      make(map[int][]Message),
+		logger:           logging.OrStd(config.Logger),
+	}, nil
+}
+
+// NewConversation returns a service with a fresh history that shares this service's client and config
+// A Service holds one conversation and is not safe for concurrent chats, so concurrent users each need their own
+func (s *Service) NewConversation() *Service {
+	return &Service{
+		client:           s.client,
+		config:           s.config,
+		isRunning:        s.isRunning,
+		conversationHist: initialHistory(s.config),
+		maxHistoryLength: s.maxHistoryLength,
+		checkpoints:      make(map[int][]Message),
+		logger:           s.logger,
+	}
+}
+
+// initialHistory starts a conversation with the configured system message
+func initialHistory(config *Config) []Message {
 	conversationHist := []Message{}
 	if config.SystemMessage != "" {
 		conversationHist = append(conversationHist, Message{
@@ -62,14 +141,7 @@ func NewService(config *Config) (*Service, error) {
 			Content: config.SystemMessage,
 		})
 	}
-
-	client := NewClient(config)
-	return &Service{
-		client:           client,
-		config:           config,
-		conversationHist: conversationHist,
-		maxHistoryLength: config.MaxHistoryLength,
-	}, nil
+	return conversationHist
 }
 
 // Start starts the LLM service
@@ -84,7 +156,7 @@ func (s *Service) Start(ctx context.Context) error {
 	}
 
 	s.isRunning = true
-	log.Println("LLM service started successfully")
+	s.logger.Info("LLM service started successfully")
 
 	return nil
 }
@@ -96,7 +168,7 @@ func (s *Service) Stop() {
 	}
 
 	s.isRunning = false
-	log.Println("LLM service stopped")
+	s.logger.Info("LLM service stopped")
 }
 
 // IsRunning returns whether the service is running
@@ -104,26 +176,48 @@ func (s *Service) IsRunning() bool {
 	return s.isRunning
 }
 
+// Ping checks that the LLM provider is still reachable, for periodic liveness checks
+func (s *Service) Ping(ctx context.Context) error {
+	return s.client.ValidateAPIKey(ctx)
+}
+
 // Chat processes user input and returns assistant response
 func (s *Service) Chat(ctx context.Context, userMessage string) (string, error) {
+	response, _, err := s.ChatWithUsage(ctx, userMessage)
+	return response, err
+}
+
+// ChatWithUsage processes user input and returns assistant response with the token usage of this turn
+func (s *Service) ChatWithUsage(ctx context.Context, userMessage string) (string, Usage, error) {
+	return s.chatWithContext(ctx, userMessage, nil)
+}
+
+// chatWithContext runs a chat turn, sending extra just before the user message
+// extra is only part of this request, history keeps the plain user message
+func (s *Service) chatWithContext(ctx context.Context, userMessage string, extra *Message) (string, Usage, error) {
 	if !s.isRunning {
-		return "", fmt.Errorf("LLM service is not running")
+		return "", Usage{}, fmt.Errorf("LLM service is not running")
 	}
 
 	if strings.TrimSpace(userMessage) == "" {
-		return "", fmt.Errorf("user message cannot be empty")
+		return "", Usage{}, fmt.Errorf("user message cannot be empty")
 	}
 
 	// Add user message to history
-	s.conversationHist = append(s.conversationHist, Message{
-		Role:    "user",
-		Content: userMessage,
-	})
+	s.appendHistory("user", userMessage)
+
+	messages := s.conversationHist
+	if extra != nil {
+		last := len(messages) - 1
+		messages = make([]Message, 0, len(s.conversationHist)+1)
+		messages = append(messages, s.conversationHist[:last]...)
+		messages = append(messages, *extra, s.conversationHist[last])
+	}
 
 	// Create chat request
 	req := &ChatRequest{
 		Model:       s.config.Model,
-		Messages:    s.conversationHist,
+		Messages:    messages,
 		MaxTokens:   s.config.MaxTokens,
 		Temperature: s.config.Temperature,
 	}
@@ -137,27 +231,98 @@ func (s *Service) Chat(ctx context.Context, userMessage string) (string, error)
 	// Get response from LLM
 	response, err := s.client.ChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("chat completion failed: %w", err)
+		return "", Usage{}, fmt.Errorf("chat completion failed: %w", err)
 	}
+	s.recordUsage(req.Model, response.Usage)
 
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return "", response.Usage, fmt.Errorf("no response choices returned")
 	}
 
 	assistantMessage := strings.TrimSpace(response.Choices[0].Message.Content)
 
 	// Add assistant response to history
-	s.conversationHist = append(s.conversationHist, Message{
-		Role:    "assistant",
-		Content: assistantMessage,
-	})
+	s.appendHistory("assistant", assistantMessage)
 
 	// Trim history if too long
-	s.trimHistory()
+	s.trimHistory(ctx)
+
+	s.logger.Debug("LLM response: %q (tokens: %d)", assistantMessage, response.Usage.TotalTokens)
+
+	return assistantMessage, response.Usage, nil
+}
+
+// ChatStream processes user input and streams the assistant response as token deltas
+// The conversation history is updated with the full assistant message after the stream closes
+func (s *Service) ChatStream(ctx context.Context, userMessage string) (<-chan string, <-chan error) {
+	deltas := make(chan string)
+	errs := make(chan error, 1)
+
+	fail := func(err error) (<-chan string, <-chan error) {
+		errs <- err
+		close(errs)
+		close(deltas)
+		return deltas, errs
+	}
+
+	if !s.isRunning {
+		return fail(fmt.Errorf("LLM service is not running"))
+	}
+
+	if strings.TrimSpace(userMessage) == "" {
+		return fail(fmt.Errorf("user message cannot be empty"))
+	}
+
+	streamer, ok := s.client.(StreamingClient)
+	if !ok {
+		return fail(fmt.Errorf("LLM client does not support streaming"))
+	}
+
+	// Add user message to history
+	s.appendHistory("user", userMessage)
+
+	req := &ChatRequest{
+		Model:       s.config.Model,
+		Messages:    s.conversationHist,
+		MaxTokens:   s.config.MaxTokens,
+		Temperature: s.config.Temperature,
+		Stream:      true,
+	}
 
-	log.Printf("LLM response: %q (tokens: %d)", assistantMessage, response.Usage.TotalTokens)
+	clientDeltas, clientErrs := streamer.ChatCompletionStream(ctx, req)
+
+	go func() {
+		defer close(errs)
+		defer close(deltas)
+
+		var full strings.Builder
+		for delta := range clientDeltas {
+			full.WriteString(delta)
+			select {
+			case deltas <- delta:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-clientErrs; err != nil {
+			errs <- fmt.Errorf("chat completion failed: %w", err)
+			return
+		}
+
+		assistantMessage := strings.TrimSpace(full.String())
+
+		// Add assistant response to history
+		s.appendHistory("assistant", assistantMessage)
 
-	return assistantMessage, nil
+		// Trim history if too long
+		s.trimHistory(ctx)
+
+		s.logger.Debug("LLM streamed response: %q", assistantMessage)
+	}()
+
+	return deltas, errs
 }
 
 // GetConversationHistory returns the current conversation history
@@ -170,16 +335,64 @@ func (s *Service) GetConversationHistory() []Message {
 
 // ClearHistory clears the conversation history (keeps system message)
 func (s *Service) ClearHistory() {
-	// Keep only system message
+	// Keep only system message, the summary of trimmed turns goes with the history
 	systemMessages := []Message{}
 	for _, msg := range s.conversationHist {
-		if msg.Role == "system" {
+		if msg.Role == "system" && !strings.HasPrefix(msg.Content, summaryPrefix) {
 			systemMessages = append(systemMessages, msg)
 		}
 	}
 	s.conversationHist = systemMessages
 
-	log.Println("Conversation history cleared")
+	s.logger.Info("Conversation history cleared")
+}
+
+// Checkpoint saves a snapshot of the current conversation history and returns its id
+// When the number of checkpoints exceeds MaxCheckpoints, the oldest one is discarded
+func (s *Service) Checkpoint() int {
+	s.nextCheckpointID++
+	id := s.nextCheckpointID
+
+	snapshot := make([]Message, len(s.conversationHist))
+	copy(snapshot, s.conversationHist)
+	s.checkpoints[id] = snapshot
+	s.checkpointOrder = append(s.checkpointOrder, id)
+
+	maxCheckpoints := s.config.MaxCheckpoints
+	if maxCheckpoints <= 0 {
+		maxCheckpoints = 1
+	}
+	for len(s.checkpointOrder) > maxCheckpoints {
+		oldest := s.checkpointOrder[0]
+		s.checkpointOrder = s.checkpointOrder[1:]
+		delete(s.checkpoints, oldest)
+	}
+
+	s.logger.Info("Conversation checkpoint %d saved (%d messages)", id, len(snapshot))
+	return id
+}
+
+// Restore restores the conversation history saved by Checkpoint
+// The checkpoint is kept, so it can be restored again
+func (s *Service) Restore(id int) error {
+	snapshot, ok := s.checkpoints[id]
+	if !ok {
+		return fmt.Errorf("checkpoint %d not found", id)
+	}
+
+	history := make([]Message, len(snapshot))
+	copy(history, snapshot)
+	s.conversationHist = history
+
+	s.logger.Info("Conversation restored to checkpoint %d (%d messages)", id, len(history))
+	return nil
+}
+
+// ListCheckpoints returns the ids of the available checkpoints, oldest first
+func (s *Service) ListCheckpoints() []int {
+	ids := make([]int, len(s.checkpointOrder))
+	copy(ids, s.checkpointOrder)
+	return ids
 }
 
 // UpdateConfig updates LLM configuration
@@ -188,7 +401,7 @@ func (s *Service) UpdateConfig(model string, temperature float32, maxTokens int)
 	s.config.Temperature = temperature
 	s.config.MaxTokens = maxTokens
 
-	log.Printf("LLM config updated: model=%s, temperature=%.2f, maxTokens=%d",
+	s.logger.Info("LLM config updated: model=%s, temperature=%.2f, maxTokens=%d",
 		model, temperature, maxTokens)
 }
 
@@ -201,9 +414,24 @@ func (s *Service) GetConfig() *Config {
 		Temperature:      s.config.Temperature,
 		MaxTokens:        s.config.MaxTokens,
 		MaxHistoryLength: s.config.MaxHistoryLength,
+		MaxHistoryTokens: s.config.MaxHistoryTokens,
+		SummarizeOnTrim:  s.config.SummarizeOnTrim,
 		SystemMessage:    s.config.SystemMessage,
 		UserName:         s.config.UserName,
 		Timeout:          s.config.Timeout,
+		MaxCheckpoints:   s.config.MaxCheckpoints,
+		MaxMessageRunes:  s.config.MaxMessageRunes,
+		MaxRetries:       s.config.MaxRetries,
+		RetryBaseDelay:   s.config.RetryBaseDelay,
+		Transport:        s.config.Transport,
+		HTTPClient:       s.config.HTTPClient,
+		Logger:           s.config.Logger,
+
+		MaxDocumentTokens: s.config.MaxDocumentTokens,
+
+		VoiceMaxTokens:     s.config.VoiceMaxTokens,
+		VoiceTemperature:   s.config.VoiceTemperature,
+		VoiceSystemMessage: s.config.VoiceSystemMessage,
 	}
 }
 
@@ -219,43 +447,189 @@ func (s *Service) EstimateTokens(text string) int {
 
 // GetHistoryTokenCount estimates total tokens in conversation history
 func (s *Service) GetHistoryTokenCount() int {
-	totalTokens := 0
-	for _, msg := range s.conversationHist {
-		totalTokens += s.EstimateTokens(msg.Content)
+	return s.countTokens(s.conversationHist)
+}
+
+// appendHistory adds a message to history, truncating it if it exceeds MaxMessageRunes
+func (s *Service) appendHistory(role, content string) {
+	s.appendMessage(Message{Role: role, Content: content})
+}
+
+// appendMessage adds a full message (e.g. with tool calls) to history, truncating its content like appendHistory
+func (s *Service) appendMessage(msg Message) {
+	if limit := s.config.MaxMessageRunes; limit > 0 && utf8.RuneCountInString(msg.Content) > limit {
+		s.logger.Warn("Truncating %s message from %d to %d runes", msg.Role, utf8.RuneCountInString(msg.Content), limit)
+		msg.Content = truncateRunes(msg.Content, limit)
+	}
+
+	s.conversationHist = append(s.conversationHist, msg)
+}
+
+// truncateRunes truncates text to at most maxRunes runes without splitting a character
+func truncateRunes(text string, maxRunes int) string {
+	if maxRunes <= 0 {
+		return ""
 	}
-	return totalTokens
+
+	count := 0
+	for i := range text {
+		if count == maxRunes {
+			return text[:i]
+		}
+		count++
+	}
+	return text
 }
 
 // trimHistory trims conversation history to stay within limits
-func (s *Service) trimHistory() {
-	if len(s.conversationHist) <= s.maxHistoryLength {
+// Whole turns (a user message and the replies that follow it) are dropped, so the
+// remaining conversation always starts with a user message
+func (s *Service) trimHistory(ctx context.Context) {
+	tokenBudget := s.config.MaxHistoryTokens
+	overLength := len(s.conversationHist) > s.maxHistoryLength
+	overTokens := tokenBudget > 0 && s.GetHistoryTokenCount() > tokenBudget
+	if !overLength && !overTokens {
 		return
 	}
 
 	// Keep system messages and trim user/assistant pairs
 	systemMessages := []Message{}
 	conversationMessages := []Message{}
+	var summary *Message
 
 	for _, msg := range s.conversationHist {
-		if msg.Role == "system" {
+		switch {
+		case msg.Role == "system" && strings.HasPrefix(msg.Content, summaryPrefix):
+			summary = &Message{Role: msg.Role, Content: msg.Content}
+		case msg.Role == "system":
 			systemMessages = append(systemMessages, msg)
-		} else {
+		default:
 			conversationMessages = append(conversationMessages, msg)
 		}
 	}
+	allConversation := conversationMessages
 
-	// Keep only the most recent conversation messages
+	// Keep only the most recent turns that fit
 	maxConversationMessages := s.maxHistoryLength - len(systemMessages)
 	if maxConversationMessages > 0 && len(conversationMessages) > maxConversationMessages {
-		// Keep the most recent messages
-		startIndex := len(conversationMessages) - maxConversationMessages
+		startIndex := turnBoundary(conversationMessages, len(conversationMessages)-maxConversationMessages)
 		conversationMessages = conversationMessages[startIndex:]
 	}
 
+	// Drop oldest turns until the token budget is met, the latest turn is always kept
+	if tokenBudget > 0 {
+		systemTokens := s.countTokens(systemMessages)
+		if summary != nil {
+			systemTokens += s.EstimateTokens(summary.Content)
+		}
+		if systemTokens > tokenBudget {
+			s.logger.Warn("system messages use %d tokens, exceeding history budget of %d", systemTokens, tokenBudget)
+		}
+
+		tokens := systemTokens + s.countTokens(conversationMessages)
+		for tokens > tokenBudget {
+			next := turnBoundary(conversationMessages, 1)
+			if next <= 0 || next >= len(conversationMessages) {
+				break
+			}
+			tokens -= s.countTokens(conversationMessages[:next])
+			conversationMessages = conversationMessages[next:]
+		}
+	}
+
+	// Condense the dropped turns (and any earlier summary) into a single note
+	dropped := allConversation[:len(allConversation)-len(conversationMessages)]
+	if s.config.SummarizeOnTrim && len(dropped) > 0 {
+		if next, err := s.summarize(ctx, summary, dropped); err != nil {
+			s.logger.Warn("failed to summarize trimmed history: %v", err)
+		} else {
+			summary = next
+		}
+	}
+
 	// Rebuild history
+	if summary != nil {
+		systemMessages = append(systemMessages, *summary)
+	}
 	s.conversationHist = append(systemMessages, conversationMessages...)
+	if len(dropped) == 0 {
+		return
+	}
+
+	s.logger.Info("Conversation history trimmed to %d messages (~%d tokens)", len(s.conversationHist), s.GetHistoryTokenCount())
+}
+
+// summaryPrefix marks the system note holding the summary of trimmed turns
+const summaryPrefix = "Summary of the earlier conversation: "
+
+// summaryMaxTokens bounds the summarization call
+const summaryMaxTokens = 200
+
+// summarize asks the model to condense dropped messages, folding in the previous summary
+func (s *Service) summarize(ctx context.Context, previous *Message, dropped []Message) (*Message, error) {
+	var transcript strings.Builder
+	if previous != nil {
+		transcript.WriteString(strings.TrimPrefix(previous.Content, summaryPrefix))
+		transcript.WriteString("\n")
+	}
+	for _, msg := range dropped {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+
+	req := &ChatRequest{
+		Model: s.config.Model,
+		Messages: []Message{
+			{Role: "system", Content: "Summarize the conversation below in a few sentences. Keep names, facts and preferences the user mentioned. Reply in the language of the conversation."},
+			{Role: "user", Content: transcript.String()},
+		},
+		MaxTokens:   summaryMaxTokens,
+		Temperature: 0.3,
+	}
+
+	response, err := s.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.recordUsage(req.Model, response.Usage)
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned")
+	}
+	text := strings.TrimSpace(response.Choices[0].Message.Content)
+	if text == "" {
+		return nil, fmt.Errorf("empty summary")
+	}
+
+	return &Message{Role: "system", Content: summaryPrefix + text}, nil
+}
+
+// countTokens estimates total tokens of messages
+func (s *Service) countTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += s.EstimateTokens(msg.Content)
+	}
+	return total
+}
+
+// turnBoundary returns the first user message index at or after minIndex
+// If no turn starts there, the last turn is kept whole even if it exceeds the limit
+func turnBoundary(messages []Message, minIndex int) int {
+	last := -1
+	for i, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		if i >= minIndex {
+			return i
+		}
+		last = i
+	}
 
-	log.Printf("Conversation history trimmed to %d messages", len(s.conversationHist))
+	if last >= 0 {
+		return last
+	}
+	return minIndex
 }
 
 // ValidateConfiguration validates the current configuration
@@ -272,6 +646,10 @@ func (s *Service) ValidateConfiguration(ctx context.Context) error {
 		return fmt.Errorf("temperature must be between 0 and 2")
 	}
 
+	if s.config.VoiceTemperature < 0 || s.config.VoiceTemperature > 2 {
+		return fmt.Errorf("voice temperature must be between 0 and 2")
+	}
+
 	if s.config.MaxTokens <= 0 {
 		return fmt.Errorf("max tokens must be positive")
 	}
@@ -304,35 +682,16 @@ func (s *Service) GenerateVoiceResponse(ctx context.Context, userInput string) (
 		return "", fmt.Errorf("LLM service is not running")
 	}
 
-	// Use voice-optimized system message
-	voiceSystemMsg := Message{
-		Role: "system",
-		Content: `你是一个智能语音助手。请遵循以下规则：
-
-1. 回复必须简洁明了，控制在30字以内
-2. 使用自然的口语化表达，避免书面语
-3. 语气要友好亲切，像朋友聊天一样
-4. 避免使用复杂的标点符号和特殊字符
-5. 如果需要列举，用简单的语言描述，不要用编号
-6. 回复要适合语音播放，听起来自然流畅
-
-记住：你的回复将直接转换为语音播放给用户。`,
-	}
-
-	req := &ChatRequest{
-		Model: s.config.Model,
-		Messages: []Message{
-			voiceSystemMsg,
-			{Role: "user", Content: userInput},
-		},
-		MaxTokens:   100, // Even shorter for voice
-		Temperature: 0.7,
+	if s.config.VoiceTemperature < 0 {
+		return "", fmt.Errorf("voice temperature must not be negative")
 	}
+	req := s.voiceRequest(userInput)
 
 	response, err := s.client.ChatCompletion(ctx, req)
 	if err != nil {
 		return "", fmt.Errorf("voice response generation failed: %w", err)
 	}
+	s.recordUsage(req.Model, response.Usage)
 
 	if len(response.Choices) == 0 {
 		return "", fmt.Errorf("no response choices returned")
@@ -340,3 +699,25 @@ func (s *Service) GenerateVoiceResponse(ctx context.Context, userInput string) (
 
 	return strings.TrimSpace(response.Choices[0].Message.Content), nil
 }
+
+// voiceRequest builds the GenerateVoiceResponse request from the voice settings in the config
+func (s *Service) voiceRequest(userInput string) *ChatRequest {
+	maxTokens := s.config.VoiceMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultVoiceMaxTokens
+	}
+	systemMessage := s.config.VoiceSystemMessage
+	if systemMessage == "" {
+		systemMessage = DefaultVoiceSystemMessage
+	}
+
+	return &ChatRequest{
+		Model: s.config.Model,
+		Messages: []Message{
+			{Role: "system", Content: systemMessage},
+			{Role: "user", Content: userInput},
+		},
+		MaxTokens:   maxTokens,
+		Temperature: s.config.VoiceTemperature,
+	}
+}
@@ -0,0 +1,737 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/logging"
+)
+
+func TestChatStream(t *testing.T) {
+	deltas := []string{"你好", "，", "我是助手"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if body["stream"] != true {
+			t.Errorf("Expected stream=true in request, got %v", body["stream"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range deltas {
+			chunk := fmt.Sprintf(`{"id":"1","object":"chat.completion.chunk","created":0,"model":"test","choices":[{"index":0,"delta":{"content":%q},"finish_reason":null}]}`, delta)
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = server.URL
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.isRunning = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	deltaChan, errChan := service.ChatStream(ctx, "你好")
+
+	var received []string
+	for delta := range deltaChan {
+		received = append(received, delta)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+
+	if strings.Join(received, "|") != strings.Join(deltas, "|") {
+		t.Errorf("Expected deltas %v, got %v", deltas, received)
+	}
+
+	history := service.GetConversationHistory()
+	last := history[len(history)-1]
+	if last.Role != "assistant" || last.Content != strings.Join(deltas, "") {
+		t.Errorf("Expected assembled assistant message in history, got %+v", last)
+	}
+}
+
+func TestChatStreamNotRunning(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	deltaChan, errChan := service.ChatStream(context.Background(), "你好")
+	for range deltaChan {
+		t.Error("Expected no deltas when service is not running")
+	}
+	if err := <-errChan; err == nil {
+		t.Error("Expected error when service is not running")
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxRunes int
+		expected string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"你好世界", 2, "你好"},
+		{"你好世界", 4, "你好世界"},
+		{"你好", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncateRunes(tt.text, tt.maxRunes); got != tt.expected {
+			t.Errorf("truncateRunes(%q, %d) = %q, expected %q", tt.text, tt.maxRunes, got, tt.expected)
+		}
+	}
+}
+
+func TestMaxMessageRunes(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.MaxMessageRunes = 5
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	service.appendHistory("user", strings.Repeat("长", 100))
+
+	history := service.GetConversationHistory()
+	last := history[len(history)-1]
+	if last.Content != strings.Repeat("长", 5) {
+		t.Errorf("Expected message truncated to 5 runes, got %q", last.Content)
+	}
+}
+
+// fakeClient returns a fixed response and usage for every completion
+type fakeClient struct {
+	usage Usage
+}
+
+func (c *fakeClient) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return &ChatResponse{
+		Model:   req.Model,
+		Choices: []Choice{{Message: Message{Role: "assistant", Content: "好的"}}},
+		Usage:   c.usage,
+	}, nil
+}
+
+func (c *fakeClient) ValidateAPIKey(ctx context.Context) error { return nil }
+func (c *fakeClient) GetAvailableModels() []string             { return nil }
+func (c *fakeClient) EstimateTokens(text string) int           { return len(text) }
+
+func TestUsageAccumulation(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.Model = "gpt-4o-mini"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = &fakeClient{usage: Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}}
+	service.isRunning = true
+
+	for i := 0; i < 3; i++ {
+		_, usage, err := service.ChatWithUsage(context.Background(), "你好")
+		if err != nil {
+			t.Fatalf("ChatWithUsage failed: %v", err)
+		}
+		if usage.TotalTokens != 120 {
+			t.Errorf("Expected per-turn usage of 120 tokens, got %d", usage.TotalTokens)
+		}
+	}
+
+	total := service.TotalUsage()
+	if total.PromptTokens != 300 || total.CompletionTokens != 60 || total.TotalTokens != 360 {
+		t.Errorf("Unexpected accumulated usage: %+v", total)
+	}
+
+	expectedCost := 300/1e6*0.15 + 60/1e6*0.60
+	if cost := service.EstimatedCostUSD(); cost < expectedCost*0.999 || cost > expectedCost*1.001 {
+		t.Errorf("Expected cost %.8f, got %.8f", expectedCost, cost)
+	}
+
+	service.ResetUsage()
+	if total := service.TotalUsage(); total.TotalTokens != 0 {
+		t.Errorf("Expected usage reset, got %+v", total)
+	}
+}
+
+func TestUsageConcurrentRecord(t *testing.T) {
+	service := &Service{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.recordUsage("gpt-4o", Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2})
+		}()
+	}
+	wg.Wait()
+
+	if total := service.TotalUsage(); total.TotalTokens != 100 {
+		t.Errorf("Expected 100 total tokens, got %d", total.TotalTokens)
+	}
+}
+
+func TestTrimHistoryKeepsPairs(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.MaxHistoryLength = 4 // system + 3 conversation messages
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		service.appendHistory("user", fmt.Sprintf("问题%d", i))
+		service.appendHistory("assistant", fmt.Sprintf("回答%d", i))
+	}
+	service.trimHistory(context.Background())
+
+	history := service.GetConversationHistory()
+	if history[0].Role != "system" {
+		t.Fatalf("Expected system message first, got %+v", history[0])
+	}
+	if history[1].Role != "user" {
+		t.Errorf("Expected conversation to start with a user message, got %+v", history[1])
+	}
+	// 奇数预算只能保留一整轮，不能留下没有问题的回答
+	if len(history) != 3 || history[1].Content != "问题2" || history[2].Content != "回答2" {
+		t.Errorf("Expected only the last complete turn, got %+v", history)
+	}
+}
+
+func TestTrimHistoryOddLength(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.MaxHistoryLength = 5
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// 奇数长度：最新的用户消息还没有回答
+	for i := 0; i < 3; i++ {
+		service.appendHistory("user", fmt.Sprintf("问题%d", i))
+		service.appendHistory("assistant", fmt.Sprintf("回答%d", i))
+	}
+	service.appendHistory("user", "问题3")
+	service.trimHistory(context.Background())
+
+	history := service.GetConversationHistory()
+	conversation := history[1:]
+	if conversation[0].Role != "user" {
+		t.Fatalf("Expected no orphaned assistant message, got %+v", conversation)
+	}
+	for i, msg := range conversation {
+		if msg.Role == "assistant" && (i == 0 || conversation[i-1].Role != "user") {
+			t.Errorf("Orphaned assistant message at %d: %+v", i, msg)
+		}
+	}
+	if last := conversation[len(conversation)-1]; last.Content != "问题3" {
+		t.Errorf("Expected latest user message kept, got %+v", last)
+	}
+	if len(history) > config.MaxHistoryLength {
+		t.Errorf("Expected at most %d messages, got %d", config.MaxHistoryLength, len(history))
+	}
+}
+
+func TestTrimHistoryTokenBudget(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.SystemMessage = "你是助手"
+	config.MaxHistoryLength = 100
+	config.MaxHistoryTokens = 60
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	service.appendHistory("user", "你好")
+	service.appendHistory("assistant", "你好！")
+	service.appendHistory("user", "讲个故事")
+	service.appendHistory("assistant", strings.Repeat("很久很久以前", 20)) // about 120 tokens
+	service.appendHistory("user", "谢谢")
+	service.appendHistory("assistant", "不客气")
+	service.trimHistory(context.Background())
+
+	if tokens := service.GetHistoryTokenCount(); tokens > config.MaxHistoryTokens {
+		t.Errorf("Expected history within %d tokens, got %d", config.MaxHistoryTokens, tokens)
+	}
+
+	history := service.GetConversationHistory()
+	if history[0].Role != "system" || history[1].Role != "user" {
+		t.Fatalf("Expected system message followed by a user message, got %+v", history)
+	}
+	if len(history) != 3 || history[1].Content != "谢谢" {
+		t.Errorf("Expected only the last short turn to remain, got %+v", history)
+	}
+}
+
+func TestTrimHistoryTokenBudgetKeepsSystem(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.SystemMessage = strings.Repeat("请用简短的中文回答", 10)
+	config.MaxHistoryLength = 100
+	config.MaxHistoryTokens = 20
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	service.appendHistory("user", "问题一")
+	service.appendHistory("assistant", "回答一")
+	service.appendHistory("user", "问题二")
+	service.trimHistory(context.Background())
+
+	// 系统消息本身超出预算时仍然保留，并且最新一轮不会被丢弃
+	history := service.GetConversationHistory()
+	if history[0].Role != "system" || history[0].Content != config.SystemMessage {
+		t.Fatalf("Expected system message retained, got %+v", history[0])
+	}
+	if len(history) != 2 || history[1].Content != "问题二" {
+		t.Errorf("Expected only system message and latest turn, got %+v", history)
+	}
+}
+
+// summaryClient answers summarization requests, optionally failing
+type summaryClient struct {
+	fakeClient
+	err      error
+	requests []*ChatRequest
+}
+
+func (c *summaryClient) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &ChatResponse{
+		Model:   req.Model,
+		Choices: []Choice{{Message: Message{Role: "assistant", Content: "用户叫小明，喜欢爬山"}}},
+	}, nil
+}
+
+func newSummarizingService(t *testing.T, client Client) *Service {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.SystemMessage = "你是助手"
+	config.MaxHistoryLength = 3
+	config.SummarizeOnTrim = true
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = client
+
+	service.appendHistory("user", "我叫小明")
+	service.appendHistory("assistant", "你好小明")
+	service.appendHistory("user", "我喜欢爬山")
+	service.appendHistory("assistant", "爬山很健康")
+	return service
+}
+
+func TestTrimHistorySummarizes(t *testing.T) {
+	client := &summaryClient{}
+	service := newSummarizingService(t, client)
+	service.trimHistory(context.Background())
+
+	history := service.GetConversationHistory()
+	if len(history) != 4 {
+		t.Fatalf("Expected system, summary and last turn, got %+v", history)
+	}
+	if history[1].Role != "system" || !strings.Contains(history[1].Content, "小明") {
+		t.Errorf("Expected summary note after system message, got %+v", history[1])
+	}
+	if history[2].Content != "我喜欢爬山" {
+		t.Errorf("Expected old turn removed, got %+v", history[2:])
+	}
+
+	if len(client.requests) != 1 {
+		t.Fatalf("Expected one summarization request, got %d", len(client.requests))
+	}
+	req := client.requests[0]
+	if req.MaxTokens != summaryMaxTokens || !strings.Contains(req.Messages[1].Content, "我叫小明") {
+		t.Errorf("Unexpected summarization request: %+v", req)
+	}
+
+	service.ClearHistory()
+	if history := service.GetConversationHistory(); len(history) != 1 {
+		t.Errorf("Expected summary cleared with history, got %+v", history)
+	}
+}
+
+func TestTrimHistorySummarizeError(t *testing.T) {
+	service := newSummarizingService(t, &summaryClient{err: fmt.Errorf("unavailable")})
+	service.trimHistory(context.Background())
+
+	// 摘要失败时照常丢弃旧轮次
+	history := service.GetConversationHistory()
+	if len(history) != 3 || history[1].Content != "我喜欢爬山" {
+		t.Errorf("Expected old turn dropped without summary, got %+v", history)
+	}
+}
+
+// unreachableClient fails every validation, as if the provider were down
+type unreachableClient struct {
+	fakeClient
+}
+
+func (c *unreachableClient) ValidateAPIKey(ctx context.Context) error {
+	return fmt.Errorf("API key validation failed: connection refused")
+}
+
+func TestServicePing(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	service.client = &fakeClient{}
+	if err := service.Ping(context.Background()); err != nil {
+		t.Errorf("Expected healthy provider, got %v", err)
+	}
+
+	service.client = &unreachableClient{}
+	if err := service.Ping(context.Background()); err == nil {
+		t.Error("Expected Ping to report an unreachable provider")
+	}
+}
+
+// recordingTransport records outbound request URLs and replies with a fixed chat completion
+type recordingTransport struct {
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(toolAnswerPayload)),
+		Request:    req,
+	}, nil
+}
+
+func TestClientCustomTransport(t *testing.T) {
+	transport := &recordingTransport{}
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = "https://llm.internal.example/v1"
+	config.Transport = transport
+
+	client := NewClient(config)
+	if _, err := client.ChatCompletion(context.Background(), &ChatRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "你好"}},
+	}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	if len(transport.urls) != 1 || transport.urls[0] != "https://llm.internal.example/v1/chat/completions" {
+		t.Errorf("Expected request through the custom transport, got %v", transport.urls)
+	}
+
+	// A full client takes precedence over Transport
+	other := &recordingTransport{}
+	config.HTTPClient = &http.Client{Transport: other}
+	NewQwenClient(config).ChatCompletion(context.Background(), &ChatRequest{Model: "qwen-plus", Messages: []Message{{Role: "user", Content: "你好"}}})
+	if len(other.urls) != 1 || len(transport.urls) != 1 {
+		t.Errorf("Expected HTTPClient to be used, got %v / %v", other.urls, transport.urls)
+	}
+}
+
+func TestServiceLogger(t *testing.T) {
+	recorder := logging.NewRecorder()
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.MaxMessageRunes = 5
+	config.Logger = recorder
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = &fakeClient{}
+
+	if err := service.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	service.appendHistory("user", strings.Repeat("长", 100))
+	if _, err := service.Chat(context.Background(), "你好"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if !recorder.Contains(logging.LevelInfo, "LLM service started") {
+		t.Errorf("Expected start info line, got %+v", recorder.Entries())
+	}
+	if !recorder.Contains(logging.LevelWarn, "Truncating user message") {
+		t.Errorf("Expected truncation warning, got %+v", recorder.Entries())
+	}
+	if !recorder.Contains(logging.LevelDebug, "LLM response") {
+		t.Errorf("Expected response at debug level, got %+v", recorder.Entries())
+	}
+}
+
+// capturingClient records the last completion request
+type capturingClient struct {
+	fakeClient
+	req *ChatRequest
+}
+
+func (c *capturingClient) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	c.req = req
+	return c.fakeClient.ChatCompletion(ctx, req)
+}
+
+func TestGenerateVoiceResponseConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.VoiceMaxTokens = 200
+	config.VoiceTemperature = 0.2
+	config.VoiceSystemMessage = "你是一个车载助手。"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	client := &capturingClient{}
+	service.client = client
+	service.isRunning = true
+
+	if _, err := service.GenerateVoiceResponse(context.Background(), "导航回家"); err != nil {
+		t.Fatalf("GenerateVoiceResponse failed: %v", err)
+	}
+	req := client.req
+	if req.MaxTokens != 200 || req.Temperature != 0.2 || req.Model != config.Model {
+		t.Errorf("Expected configured voice settings, got max tokens %d, temperature %v, model %q", req.MaxTokens, req.Temperature, req.Model)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != "你是一个车载助手。" || req.Messages[1].Content != "导航回家" {
+		t.Errorf("Unexpected messages: %+v", req.Messages)
+	}
+
+	// A zero token cap and empty prompt fall back to the defaults, temperature 0 is kept
+	service.config = &Config{Model: "gpt-4o-mini"}
+	if _, err := service.GenerateVoiceResponse(context.Background(), "你好"); err != nil {
+		t.Fatalf("GenerateVoiceResponse failed: %v", err)
+	}
+	req = client.req
+	if req.MaxTokens != DefaultVoiceMaxTokens || req.Temperature != 0 || req.Messages[0].Content != DefaultVoiceSystemMessage {
+		t.Errorf("Expected default voice settings with temperature 0, got %d, %v, %q", req.MaxTokens, req.Temperature, req.Messages[0].Content)
+	}
+
+	service.config.VoiceTemperature = -0.1
+	if _, err := service.GenerateVoiceResponse(context.Background(), "你好"); err == nil {
+		t.Error("Expected a negative voice temperature to be rejected")
+	}
+}
+
+func TestGenerateVoiceResponseZeroTemperature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if temperature, ok := body["temperature"]; !ok || temperature != float64(0) {
+			t.Errorf("Expected temperature=0 in request, got %v", body["temperature"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"message":{"role":"assistant","content":"好的"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = server.URL
+	config.VoiceTemperature = 0
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.isRunning = true
+
+	response, err := service.GenerateVoiceResponse(context.Background(), "你好")
+	if err != nil {
+		t.Fatalf("GenerateVoiceResponse failed: %v", err)
+	}
+	if response != "好的" {
+		t.Errorf("Unexpected response %q", response)
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.VoiceMaxTokens = 200
+	config.VoiceTemperature = 0
+	config.VoiceSystemMessage = "你是一个车载助手。"
+	config.MaxDocumentTokens = 4000
+	config.Transport = &recordingTransport{}
+	config.HTTPClient = &http.Client{Timeout: time.Second}
+	config.Logger = logging.NewRecorder()
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	got := service.GetConfig()
+	if got == config {
+		t.Fatal("Expected GetConfig to return a copy")
+	}
+	if got.APIKey != config.APIKey || got.Model != config.Model || got.MaxHistoryLength != config.MaxHistoryLength {
+		t.Errorf("Expected the base settings copied, got %+v", got)
+	}
+	if got.MaxDocumentTokens != 4000 {
+		t.Errorf("Expected MaxDocumentTokens 4000, got %d", got.MaxDocumentTokens)
+	}
+	if got.Transport != config.Transport || got.HTTPClient != config.HTTPClient {
+		t.Errorf("Expected Transport and HTTPClient copied, got %v, %v", got.Transport, got.HTTPClient)
+	}
+	if got.Logger != config.Logger {
+		t.Errorf("Expected Logger copied, got %v", got.Logger)
+	}
+	if got.VoiceMaxTokens != 200 || got.VoiceTemperature != 0 || got.VoiceSystemMessage != "你是一个车载助手。" {
+		t.Errorf("Expected the voice settings copied, got %d, %v, %q", got.VoiceMaxTokens, got.VoiceTemperature, got.VoiceSystemMessage)
+	}
+}
+
+func TestNewConversation(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = &fakeClient{}
+	service.isRunning = true
+
+	if _, err := service.Chat(context.Background(), "你好"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	conversation := service.NewConversation()
+	if history := conversation.GetConversationHistory(); len(history) != 1 || history[0].Role != "system" {
+		t.Fatalf("Expected a fresh history with the system message, got %+v", history)
+	}
+	if _, err := conversation.Chat(context.Background(), "第二个对话"); err != nil {
+		t.Fatalf("Chat on the new conversation failed: %v", err)
+	}
+	if got := len(service.GetConversationHistory()); got != 3 {
+		t.Errorf("Expected the original history untouched at 3 messages, got %d", got)
+	}
+	if got := len(conversation.GetConversationHistory()); got != 3 {
+		t.Errorf("Expected 3 messages in the new conversation, got %d", got)
+	}
+}
+
+func newCheckpointTestService(t *testing.T, maxCheckpoints int) *Service {
+	t.Helper()
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.MaxCheckpoints = maxCheckpoints
+	config.Logger = logging.Discard()
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = &fakeClient{}
+	service.isRunning = true
+	return service
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	service := newCheckpointTestService(t, 5)
+	if _, err := service.Chat(context.Background(), "你好"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	saved := service.GetConversationHistory()
+	id := service.Checkpoint()
+
+	// Later changes, including edits in place, must not reach the snapshot
+	service.conversationHist[len(saved)-1].Content = "改过的回复"
+	if _, err := service.Chat(context.Background(), "今天天气怎么样"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.Restore(id); err != nil {
+			t.Fatalf("Restore %d failed: %v", i+1, err)
+		}
+		history := service.GetConversationHistory()
+		if len(history) != len(saved) || history[len(saved)-1].Content != "好的" {
+			t.Fatalf("Restore %d: expected the %d checkpointed messages, got %+v", i+1, len(saved), history)
+		}
+		// Editing the restored history must not change the checkpoint for the next restore
+		service.conversationHist[len(saved)-1].Content = "又改了"
+	}
+}
+
+func TestRestoreUnknownCheckpoint(t *testing.T) {
+	service := newCheckpointTestService(t, 5)
+	if _, err := service.Chat(context.Background(), "你好"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	before := service.GetConversationHistory()
+
+	if err := service.Restore(42); err == nil {
+		t.Fatal("Expected an error for an unknown checkpoint")
+	}
+	after := service.GetConversationHistory()
+	if len(after) != len(before) {
+		t.Fatalf("Expected history untouched, had %d messages and now %d", len(before), len(after))
+	}
+	for i := range before {
+		if after[i].Role != before[i].Role || after[i].Content != before[i].Content {
+			t.Errorf("Message %d changed from %+v to %+v", i, before[i], after[i])
+		}
+	}
+}
+
+func TestCheckpointEviction(t *testing.T) {
+	service := newCheckpointTestService(t, 2)
+	first := service.Checkpoint()
+	second := service.Checkpoint()
+	third := service.Checkpoint()
+
+	if ids := service.ListCheckpoints(); len(ids) != 2 || ids[0] != second || ids[1] != third {
+		t.Errorf("Expected checkpoints [%d %d], got %v", second, third, ids)
+	}
+	if err := service.Restore(first); err == nil {
+		t.Error("Expected the oldest checkpoint to be evicted")
+	}
+
+	// Zero and negative limits keep just the latest checkpoint
+	for _, limit := range []int{0, -3} {
+		service := newCheckpointTestService(t, limit)
+		service.Checkpoint()
+		latest := service.Checkpoint()
+		if ids := service.ListCheckpoints(); len(ids) != 1 || ids[0] != latest {
+			t.Errorf("MaxCheckpoints %d: expected only checkpoint %d, got %v", limit, latest, ids)
+		}
+	}
+}
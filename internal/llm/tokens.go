@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// DefaultEncoding is the BPE encoding EstimateTokens uses unless another tokenizer is registered
+const DefaultEncoding = "cl100k_base"
+
+// Tokenizer counts tokens with a real encoding such as cl100k_base
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+var (
+	tokenizerMu sync.RWMutex
+	tokenizer   Tokenizer
+)
+
+// SetTokenizer replaces the encoding used by EstimateTokens, nil restores cl100k_base
+func SetTokenizer(t Tokenizer) {
+	tokenizerMu.Lock()
+	defer tokenizerMu.Unlock()
+	tokenizer = t
+}
+
+// EstimateTokens counts tokens with the registered tokenizer, cl100k_base by default
+func EstimateTokens(text string) int {
+	tokenizerMu.RLock()
+	t := tokenizer
+	tokenizerMu.RUnlock()
+
+	if t != nil {
+		return t.CountTokens(text)
+	}
+	return defaultTokenizer().CountTokens(text)
+}
+
+// bpeTokenizer counts tokens with a tiktoken encoding
+type bpeTokenizer struct {
+	encoding *tiktoken.Tiktoken
+}
+
+// CountTokens encodes text as ordinary text, special token markers are counted like any other text
+func (b bpeTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(b.encoding.EncodeOrdinary(text))
+}
+
+// runeTokenizer counts one token per rune, only used if the embedded encoding cannot be loaded
+type runeTokenizer struct{}
+
+func (runeTokenizer) CountTokens(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+var (
+	defaultTokenizerOnce sync.Once
+	defaultTokenizerImpl Tokenizer
+)
+
+// defaultTokenizer loads cl100k_base from the ranks embedded in the binary on first use, so no download is needed
+func defaultTokenizer() Tokenizer {
+	defaultTokenizerOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+		encoding, err := tiktoken.GetEncoding(DefaultEncoding)
+		if err != nil {
+			defaultTokenizerImpl = runeTokenizer{}
+			return
+		}
+		defaultTokenizerImpl = bpeTokenizer{encoding: encoding}
+	})
+	return defaultTokenizerImpl
+}
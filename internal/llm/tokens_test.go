@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokensEnglish(t *testing.T) {
+	// Reference counts from the cl100k_base encoding
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"Hello, world!", 4},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"How are you today?", 5},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.expected {
+			t.Errorf("EstimateTokens(%q) = %d, expected %d", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestEstimateTokensChinese(t *testing.T) {
+	// Reference counts from the cl100k_base encoding
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"你好", 2},
+		{"今天天气怎么样？", 10},
+		{"请帮我查一下明天北京的天气，谢谢。", 20},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.expected {
+			t.Errorf("EstimateTokens(%q) = %d, expected %d", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestEstimateTokensSpecialMarkers(t *testing.T) {
+	// Special token markers in user text are counted as ordinary text instead of failing the encoder
+	if got := EstimateTokens("<|endoftext|>"); got != 7 {
+		t.Errorf("Expected the marker to be encoded as ordinary text, got %d tokens", got)
+	}
+}
+
+// fixedTokenizer counts every byte as a token
+type fixedTokenizer struct{}
+
+func (fixedTokenizer) CountTokens(text string) int { return len(text) }
+
+func TestSetTokenizer(t *testing.T) {
+	SetTokenizer(fixedTokenizer{})
+	defer SetTokenizer(nil)
+
+	if got := EstimateTokens("abc"); got != 3 {
+		t.Errorf("Expected registered tokenizer to be used, got %d", got)
+	}
+
+	client := &OpenAISDKClient{}
+	if got := client.EstimateTokens("abcd"); got != 4 {
+		t.Errorf("Expected client to share the registered tokenizer, got %d", got)
+	}
+
+	SetTokenizer(nil)
+	if got := EstimateTokens("Hello, world!"); got != 4 {
+		t.Errorf("Expected cl100k_base after reset, got %d", got)
+	}
+}
+
+func TestTruncateToTokenLimitChinese(t *testing.T) {
+	client := &OpenAISDKClient{}
+	text := strings.Repeat("你好世界", 10)
+
+	truncated := client.TruncateToTokenLimit(text, 8)
+	if !strings.HasSuffix(truncated, "...") {
+		t.Fatalf("Expected truncated text to end with ellipsis, got %q", truncated)
+	}
+	if got := EstimateTokens(strings.TrimSuffix(truncated, "...")); got > 8 {
+		t.Errorf("Expected at most 8 tokens after truncation, got %d", got)
+	}
+	if client.TruncateToTokenLimit("short", 8) != "short" {
+		t.Error("Expected text within the limit to be unchanged")
+	}
+}
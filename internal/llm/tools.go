@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ChatWithTools sends user input with the given tools and returns either a reply or the requested tool calls
+// When tool calls are returned, run them, report each result with AddToolResult and call
+// ChatWithTools again with an empty message to let the model answer using the results
+func (s *Service) ChatWithTools(ctx context.Context, userMessage string, tools []Tool) (string, []ToolCall, error) {
+	if !s.isRunning {
+		return "", nil, fmt.Errorf("LLM service is not running")
+	}
+
+	if strings.TrimSpace(userMessage) != "" {
+		s.appendHistory("user", userMessage)
+	} else if n := len(s.conversationHist); n == 0 || s.conversationHist[n-1].Role != "tool" {
+		return "", nil, fmt.Errorf("user message cannot be empty")
+	}
+
+	req := &ChatRequest{
+		Model:       s.config.Model,
+		Messages:    s.conversationHist,
+		MaxTokens:   s.config.MaxTokens,
+		Temperature: s.config.Temperature,
+		Tools:       tools,
+	}
+	if len(tools) > 0 {
+		req.ToolChoice = ToolChoiceAuto
+	}
+
+	response, err := s.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return "", nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+	s.recordUsage(req.Model, response.Usage)
+
+	if len(response.Choices) == 0 {
+		return "", nil, fmt.Errorf("no response choices returned")
+	}
+
+	message := response.Choices[0].Message
+	message.Role = "assistant"
+	message.Content = strings.TrimSpace(message.Content)
+	if len(message.ToolCalls) == 0 {
+		message.ToolCalls = response.ToolCalls
+	}
+	s.appendMessage(message)
+
+	// Keep the pending tool round trip intact, trim only once the model has answered
+	if len(message.ToolCalls) > 0 {
+		return message.Content, message.ToolCalls, nil
+	}
+	s.trimHistory(ctx)
+
+	return message.Content, nil, nil
+}
+
+// AddToolResult records the result of a tool call requested by ChatWithTools
+func (s *Service) AddToolResult(toolCallID, result string) {
+	s.appendMessage(Message{Role: "tool", Content: result, ToolCallID: toolCallID})
+}
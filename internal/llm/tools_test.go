@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// toolCallPayload is a captured chat completion response requesting a tool call
+const toolCallPayload = `{
+  "id": "chatcmpl-tool",
+  "object": "chat.completion",
+  "created": 1718000000,
+  "model": "gpt-4o-mini",
+  "choices": [{
+    "index": 0,
+    "message": {
+      "role": "assistant",
+      "content": null,
+      "tool_calls": [{
+        "id": "call_abc123",
+        "type": "function",
+        "function": {"name": "set_alarm", "arguments": "{\"time\":\"2024-06-11T08:00:00\"}"}
+      }]
+    },
+    "finish_reason": "tool_calls"
+  }],
+  "usage": {"prompt_tokens": 80, "completion_tokens": 20, "total_tokens": 100}
+}`
+
+const toolAnswerPayload = `{"id":"chatcmpl-answer","object":"chat.completion","created":0,"model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"好的，已为你设置明天早上八点的闹钟"},"finish_reason":"stop"}]}`
+
+var alarmTool = NewFunctionTool("set_alarm", "设置闹钟", map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"time": map[string]interface{}{"type": "string", "description": "ISO 8601 时间"},
+	},
+	"required": []string{"time"},
+})
+
+func TestChatWithTools(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 1 {
+			w.Write([]byte(toolCallPayload))
+		} else {
+			w.Write([]byte(toolAnswerPayload))
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = server.URL
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.isRunning = true
+
+	reply, calls, err := service.ChatWithTools(context.Background(), "设置明天早上八点的闹钟", []Tool{alarmTool})
+	if err != nil {
+		t.Fatalf("ChatWithTools failed: %v", err)
+	}
+	if reply != "" || len(calls) != 1 {
+		t.Fatalf("Expected one tool call and no reply, got %q, %+v", reply, calls)
+	}
+	if calls[0].ID != "call_abc123" || calls[0].Function.Name != "set_alarm" || calls[0].Function.Arguments != `{"time":"2024-06-11T08:00:00"}` {
+		t.Errorf("Unexpected tool call: %+v", calls[0])
+	}
+
+	tools, _ := requests[0]["tools"].([]interface{})
+	if len(tools) != 1 || requests[0]["tool_choice"] != "auto" {
+		t.Errorf("Expected tools and tool_choice in request, got %v / %v", requests[0]["tools"], requests[0]["tool_choice"])
+	}
+
+	service.AddToolResult(calls[0].ID, `{"ok":true}`)
+	reply, calls, err = service.ChatWithTools(context.Background(), "", []Tool{alarmTool})
+	if err != nil {
+		t.Fatalf("ChatWithTools follow-up failed: %v", err)
+	}
+	if len(calls) != 0 || reply != "好的，已为你设置明天早上八点的闹钟" {
+		t.Errorf("Unexpected final answer: %q, %+v", reply, calls)
+	}
+
+	// The follow-up must replay the assistant tool call and the tool result
+	messages, _ := requests[1]["messages"].([]interface{})
+	if len(messages) < 3 {
+		t.Fatalf("Expected tool round trip in follow-up request, got %v", messages)
+	}
+	assistant, _ := messages[len(messages)-2].(map[string]interface{})
+	tool, _ := messages[len(messages)-1].(map[string]interface{})
+	if _, ok := assistant["tool_calls"]; !ok {
+		t.Errorf("Expected assistant message with tool_calls, got %v", assistant)
+	}
+	if tool["role"] != "tool" || tool["tool_call_id"] != "call_abc123" {
+		t.Errorf("Expected tool result message, got %v", tool)
+	}
+}
+
+func TestChatWithToolsEmptyMessage(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "test-key"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.isRunning = true
+
+	if _, _, err := service.ChatWithTools(context.Background(), "", nil); err == nil {
+		t.Error("Expected error for empty message without pending tool result")
+	}
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"strings"
+)
+
+// ModelPricing represents per-token prices of a model in USD per 1M tokens
+type ModelPricing struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// modelPricing lists approximate public prices, used only for cost estimates
+var modelPricing = map[string]ModelPricing{
+	"gpt-4o":            {PromptPerMillion: 2.50, CompletionPerMillion: 10.00},
+	"gpt-4o-mini":       {PromptPerMillion: 0.15, CompletionPerMillion: 0.60},
+	"gpt-4-turbo":       {PromptPerMillion: 10.00, CompletionPerMillion: 30.00},
+	"gpt-4":             {PromptPerMillion: 30.00, CompletionPerMillion: 60.00},
+	"gpt-3.5-turbo":     {PromptPerMillion: 0.50, CompletionPerMillion: 1.50},
+	"gpt-3.5-turbo-16k": {PromptPerMillion: 3.00, CompletionPerMillion: 4.00},
+}
+
+// pricingForModel returns pricing for a model, matching dated variants like "gpt-4o-2024-08-06"
+func pricingForModel(model string) (ModelPricing, bool) {
+	if pricing, ok := modelPricing[model]; ok {
+		return pricing, true
+	}
+
+	// Prefer the longest matching prefix so "gpt-4o-mini-..." doesn't match "gpt-4o"
+	best := ""
+	for name := range modelPricing {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPricing{}, false
+	}
+	return modelPricing[best], true
+}
+
+// add returns the sum of two usages
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// recordUsage adds usage from one completion to the running totals
+func (s *Service) recordUsage(model string, usage Usage) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	if s.usageByModel == nil {
+		s.usageByModel = make(map[string]Usage)
+	}
+	s.usageByModel[model] = s.usageByModel[model].add(usage)
+}
+
+// TotalUsage returns the accumulated token usage since creation or the last ResetUsage
+func (s *Service) TotalUsage() Usage {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	var total Usage
+	for _, usage := range s.usageByModel {
+		total = total.add(usage)
+	}
+	return total
+}
+
+// ResetUsage clears the accumulated token usage
+func (s *Service) ResetUsage() {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	s.usageByModel = make(map[string]Usage)
+}
+
+// EstimatedCostUSD estimates the cost of the accumulated usage
+// Models without known pricing are not counted
+func (s *Service) EstimatedCostUSD() float64 {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	cost := 0.0
+	for model, usage := range s.usageByModel {
+		pricing, ok := pricingForModel(model)
+		if !ok {
+			continue
+		}
+		cost += float64(usage.PromptTokens) / 1e6 * pricing.PromptPerMillion
+		cost += float64(usage.CompletionTokens) / 1e6 * pricing.CompletionPerMillion
+	}
+	return cost
+}
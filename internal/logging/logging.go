@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Logger receives leveled, printf-style log output from the services
+// Embedding applications implement it to redirect, filter or silence the output
+type Logger interface {
+	Debug(format string, args ...interface{})
+	Info(format string, args ...interface{})
+	Warn(format string, args ...interface{})
+	Error(format string, args ...interface{})
+}
+
+// stdLogger writes every level to the standard log package
+type stdLogger struct{}
+
+// Std returns a Logger backed by the standard log package
+// Debug and Info lines are printed as is, Warn and Error lines are prefixed with "Warning: " and "Error: "
+func Std() Logger {
+	return stdLogger{}
+}
+
+func (stdLogger) Debug(format string, args ...interface{}) { log.Printf(format, args...) }
+func (stdLogger) Info(format string, args ...interface{})  { log.Printf(format, args...) }
+func (stdLogger) Warn(format string, args ...interface{})  { log.Printf("Warning: "+format, args...) }
+func (stdLogger) Error(format string, args ...interface{}) { log.Printf("Error: "+format, args...) }
+
+// discardLogger drops everything
+type discardLogger struct{}
+
+// Discard returns a Logger that drops all output
+func Discard() Logger {
+	return discardLogger{}
+}
+
+func (discardLogger) Debug(format string, args ...interface{}) {}
+func (discardLogger) Info(format string, args ...interface{})  {}
+func (discardLogger) Warn(format string, args ...interface{})  {}
+func (discardLogger) Error(format string, args ...interface{}) {}
+
+// OrStd returns l, or the standard logger when l is nil
+func OrStd(l Logger) Logger {
+	if l == nil {
+		return Std()
+	}
+	return l
+}
+
+// Level identifies the severity of a log line
+type Level string
+
+const (
+	LevelDebug Level = "debug"
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+)
+
+// Entry is a formatted log line captured by a Recorder
+type Entry struct {
+	Level   Level
+	Message string
+}
+
+// Recorder is a Logger that keeps every line in memory, for tests and diagnostics
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Debug(format string, args ...interface{}) { r.record(LevelDebug, format, args) }
+func (r *Recorder) Info(format string, args ...interface{})  { r.record(LevelInfo, format, args) }
+func (r *Recorder) Warn(format string, args ...interface{})  { r.record(LevelWarn, format, args) }
+func (r *Recorder) Error(format string, args ...interface{}) { r.record(LevelError, format, args) }
+
+func (r *Recorder) record(level Level, format string, args []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, Entry{Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+// Entries returns a copy of the captured lines in order
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Contains reports whether a line at level contains substr
+func (r *Recorder) Contains(level Level, substr string) bool {
+	for _, entry := range r.Entries() {
+		if entry.Level == level && strings.Contains(entry.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStdLoggerPrefixes(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+
+	logger := Std()
+	logger.Info("service started")
+	logger.Warn("cache disabled: %v", "read-only")
+	logger.Error("failed to read audio")
+
+	expected := "service started\nWarning: cache disabled: read-only\nError: failed to read audio\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	var logger Logger = recorder
+	logger.Debug("chunk %d", 1)
+	logger.Warn("slow response")
+
+	entries := recorder.Entries()
+	if len(entries) != 2 || entries[0] != (Entry{LevelDebug, "chunk 1"}) {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	if !recorder.Contains(LevelWarn, "slow") || recorder.Contains(LevelError, "slow") {
+		t.Error("Expected Contains to match on level and substring")
+	}
+
+	if OrStd(nil) != Std() || OrStd(recorder) != Logger(recorder) {
+		t.Error("Expected OrStd to fall back only for nil")
+	}
+	Discard().Error("dropped %s", strings.Repeat("x", 3))
+}
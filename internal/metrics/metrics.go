@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stage labels reported by the voice pipeline
+const (
+	StageVAD = "vad"
+	StageASR = "asr"
+	StageLLM = "llm"
+	StageTTS = "tts"
+)
+
+// Metrics receives per-stage latency and error observations
+// Implementations typically forward to a Prometheus histogram and counter labelled by stage
+type Metrics interface {
+	ObserveLatency(stage string, d time.Duration)
+	IncError(stage string)
+}
+
+// nopMetrics drops every observation
+type nopMetrics struct{}
+
+// Nop returns a Metrics that records nothing
+func Nop() Metrics {
+	return nopMetrics{}
+}
+
+func (nopMetrics) ObserveLatency(stage string, d time.Duration) {}
+func (nopMetrics) IncError(stage string)                        {}
+
+// Memory keeps observations in memory, for tests and simple status pages
+type Memory struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+// NewMemory creates an empty in-memory Metrics
+func NewMemory() *Memory {
+	return &Memory{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+	}
+}
+
+// ObserveLatency implements Metrics
+func (m *Memory) ObserveLatency(stage string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies[stage] = append(m.latencies[stage], d)
+}
+
+// IncError implements Metrics
+func (m *Memory) IncError(stage string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[stage]++
+}
+
+// Count returns how many latencies were observed for stage
+func (m *Memory) Count(stage string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.latencies[stage])
+}
+
+// Errors returns the error count for stage
+func (m *Memory) Errors(stage string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.errors[stage]
+}
+
+// Latencies returns a copy of the latencies observed for stage
+func (m *Memory) Latencies(stage string) []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.latencies[stage]...)
+}
+
+// Stages returns every stage that has at least one observation or error
+func (m *Memory) Stages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stages []string
+	for stage := range m.latencies {
+		stages = append(stages, stage)
+	}
+	for stage := range m.errors {
+		if _, ok := m.latencies[stage]; !ok {
+			stages = append(stages, stage)
+		}
+	}
+	sort.Strings(stages)
+	return stages
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	m.ObserveLatency(StageASR, 120*time.Millisecond)
+	m.ObserveLatency(StageASR, 80*time.Millisecond)
+	m.ObserveLatency(StageLLM, time.Second)
+	m.IncError(StageLLM)
+	m.IncError(StageTTS)
+
+	if got := m.Count(StageASR); got != 2 {
+		t.Errorf("Expected 2 ASR observations, got %d", got)
+	}
+	if got := m.Latencies(StageASR); !reflect.DeepEqual(got, []time.Duration{120 * time.Millisecond, 80 * time.Millisecond}) {
+		t.Errorf("Unexpected ASR latencies: %v", got)
+	}
+	if m.Errors(StageASR) != 0 || m.Errors(StageLLM) != 1 || m.Errors(StageTTS) != 1 {
+		t.Errorf("Unexpected error counts: asr=%d llm=%d tts=%d", m.Errors(StageASR), m.Errors(StageLLM), m.Errors(StageTTS))
+	}
+	if got := m.Stages(); !reflect.DeepEqual(got, []string{StageASR, StageLLM, StageTTS}) {
+		t.Errorf("Unexpected stages: %v", got)
+	}
+
+	// Callers must not be able to mutate the recorded latencies
+	m.Latencies(StageASR)[0] = 0
+	if m.Latencies(StageASR)[0] != 120*time.Millisecond {
+		t.Error("Expected Latencies to return a copy")
+	}
+}
+
+func TestNop(t *testing.T) {
+	m := Nop()
+	m.ObserveLatency(StageVAD, time.Millisecond)
+	m.IncError(StageVAD)
+}
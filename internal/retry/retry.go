@@ -0,0 +1,202 @@
+package retry
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default retry policy shared by the API clients
+const (
+	DefaultMaxRetries = 3
+	DefaultBaseDelay  = 500 * time.Millisecond
+	DefaultMaxDelay   = 30 * time.Second
+)
+
+// Transport is an http.RoundTripper that retries transient failures
+// Requests are retried on 429/500/502/503/504 responses with jittered exponential backoff
+// The Retry-After header is honored, and no retry is attempted if it would exceed the request context deadline
+type Transport struct {
+	Base       http.RoundTripper // Underlying transport, http.DefaultTransport when nil
+	MaxRetries int               // Number of retries after the first attempt (0 disables retries)
+	BaseDelay  time.Duration     // Delay before the first retry, doubled on each attempt
+	MaxDelay   time.Duration     // Upper bound for a single delay
+}
+
+// NewTransport creates a retrying transport on top of http.DefaultTransport
+func NewTransport(maxRetries int, baseDelay time.Duration) *Transport {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if baseDelay <= 0 {
+		baseDelay = DefaultBaseDelay
+	}
+
+	return &Transport{
+		MaxRetries: maxRetries,
+		BaseDelay:  baseDelay,
+		MaxDelay:   DefaultMaxDelay,
+	}
+}
+
+// NewClient creates an http.Client with the given timeout and retry policy
+func NewClient(timeout time.Duration, maxRetries int, baseDelay time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewTransport(maxRetries, baseDelay),
+	}
+}
+
+// NewClientWithTransport creates an http.Client whose retrying transport sends requests through base
+// A nil base means http.DefaultTransport, which honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+func NewClientWithTransport(timeout time.Duration, maxRetries int, baseDelay time.Duration, base http.RoundTripper) *http.Client {
+	client := NewClient(timeout, maxRetries, baseDelay)
+	client.Transport.(*Transport).Base = base
+	return client
+}
+
+// SetPolicy changes the retry policy of client while keeping its underlying transport
+// A client without a retrying transport gets one wrapped around its current transport
+func SetPolicy(client *http.Client, maxRetries int, baseDelay time.Duration) {
+	transport := NewTransport(maxRetries, baseDelay)
+	if current, ok := client.Transport.(*Transport); ok {
+		transport.Base = current.Base
+	} else {
+		transport.Base = client.Transport
+	}
+	client.Transport = transport
+}
+
+// SetBaseTransport routes client's requests through base (proxy, custom TLS), keeping the retry policy
+func SetBaseTransport(client *http.Client, base http.RoundTripper) {
+	if current, ok := client.Transport.(*Transport); ok {
+		current.Base = base
+		return
+	}
+	client.Transport = base
+}
+
+// IsRetryableStatus reports whether an HTTP status code indicates a transient failure
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// StatusError is an API error caused by an HTTP response status
+// It keeps the message of the wrapped error and lets callers such as circuit breakers tell 4xx from 5xx failures
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+// NewStatusError wraps err with the response status that caused it
+func NewStatusError(statusCode int, err error) error {
+	return &StatusError{StatusCode: statusCode, Err: err}
+}
+
+// Error returns the message of the wrapped error
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// A body that cannot be replayed can only be sent once
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := base.RoundTrip(attemptReq)
+		if err != nil || attempt >= t.MaxRetries || !IsRetryableStatus(resp.StatusCode) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			delay = retryAfter
+		}
+
+		// Give up if waiting would run past the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the jittered exponential delay for the given attempt
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := t.BaseDelay << uint(attempt)
+	if t.MaxDelay > 0 && (delay > t.MaxDelay || delay <= 0) {
+		delay = t.MaxDelay
+	}
+
+	// Jitter in [delay/2, delay)
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,192 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer returns 429 for the first failures requests, then 200
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method == http.MethodPost && string(body) != "payload" {
+			t.Errorf("Expected replayed body %q, got %q", "payload", body)
+		}
+
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	return server, &calls
+}
+
+func TestRetryOn429ThenSuccess(t *testing.T) {
+	server, calls := flakyServer(t, 2, http.StatusTooManyRequests)
+	defer server.Close()
+
+	client := NewClient(5*time.Second, 3, time.Millisecond)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized} {
+		server, calls := flakyServer(t, 10, status)
+
+		client := NewClient(5*time.Second, 3, time.Millisecond)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Errorf("Expected status %d, got %d", status, resp.StatusCode)
+		}
+		if got := atomic.LoadInt32(calls); got != 1 {
+			t.Errorf("Expected a single attempt for status %d, got %d", status, got)
+		}
+		server.Close()
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	server, calls := flakyServer(t, 10, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	client := NewClient(5*time.Second, 2, time.Millisecond)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected last status 503, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryHonorsDeadline(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	client := NewClient(0, 3, time.Millisecond)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Expected to give up immediately when Retry-After exceeds the deadline")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected a single attempt, got %d", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("2"); !ok || d != 2*time.Second {
+		t.Errorf("Expected 2s, got %v (ok=%v)", d, ok)
+	}
+	if _, ok := parseRetryAfter(""); ok {
+		t.Error("Expected empty header to be ignored")
+	}
+	date := time.Now().Add(3 * time.Second).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d <= 0 || d > 3*time.Second {
+		t.Errorf("Expected HTTP date to parse to ~3s, got %v (ok=%v)", d, ok)
+	}
+}
+
+// recordingTransport records outbound request URLs and answers with the queued status codes (200 when empty)
+type recordingTransport struct {
+	urls     []string
+	statuses []int
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	status := http.StatusOK
+	if len(t.statuses) > 0 {
+		status, t.statuses = t.statuses[0], t.statuses[1:]
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("ok")),
+		Request:    req,
+	}, nil
+}
+
+func TestCustomBaseTransport(t *testing.T) {
+	base := &recordingTransport{statuses: []int{http.StatusServiceUnavailable}}
+	client := NewClientWithTransport(time.Second, 2, time.Millisecond, base)
+
+	resp, err := client.Get("https://api.example.com/v1/models")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(base.urls) != 2 || base.urls[0] != "https://api.example.com/v1/models" {
+		t.Errorf("Expected the retry to go through the custom transport, got %v", base.urls)
+	}
+
+	// Changing the retry policy must keep the custom transport
+	SetPolicy(client, 0, time.Millisecond)
+	base.statuses = []int{http.StatusServiceUnavailable}
+	resp, err = client.Get("https://api.example.com/v1/health")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(base.urls) != 3 || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected a single attempt through the custom transport, got %v", base.urls)
+	}
+
+	replaced := &recordingTransport{}
+	SetBaseTransport(client, replaced)
+	if resp, err := client.Get("https://api.example.com/v1/other"); err == nil {
+		resp.Body.Close()
+	}
+	if len(replaced.urls) != 1 || len(base.urls) != 3 {
+		t.Errorf("Expected requests to move to the new transport, got %v / %v", replaced.urls, base.urls)
+	}
+	if _, ok := client.Transport.(*Transport); !ok {
+		t.Error("Expected the retrying transport to stay in place")
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/llm"
+	"audio-assistant/internal/tts"
+	"audio-assistant/internal/vad"
+)
+
+// Pipeline runs one utterance through the VAD→ASR→LLM→TTS stages
+type Pipeline interface {
+	// HasSpeech reports whether the buffered audio contains speech worth transcribing
+	HasSpeech(ctx context.Context, samples []float32, sampleRate int) (bool, error)
+	// Transcribe converts speech to text
+	Transcribe(ctx context.Context, samples []float32, sampleRate int) (string, error)
+	// Reply generates the assistant's answer to the user's text
+	Reply(ctx context.Context, text string) (string, error)
+	// Synthesize converts the reply to audio, returning the encoded bytes and their format
+	Synthesize(ctx context.Context, text string) ([]byte, string, error)
+}
+
+// SessionPipeline is implemented by pipelines that keep per-connection state such as conversation history
+type SessionPipeline interface {
+	Pipeline
+	// NewSession returns the pipeline serving one connection
+	NewSession() Pipeline
+}
+
+// ServicePipeline implements Pipeline with the existing services
+// VAD may be nil, in which case every utterance is treated as speech
+// LLM holds a single conversation, the handler gives each connection its own through NewSession
+type ServicePipeline struct {
+	VAD *vad.Service
+	ASR *asr.Service
+	LLM *llm.Service
+	TTS *tts.TTSService
+}
+
+// NewSession implements SessionPipeline, the copy has its own LLM conversation and shares the other services
+func (p *ServicePipeline) NewSession() Pipeline {
+	session := *p
+	session.LLM = p.LLM.NewConversation()
+	return &session
+}
+
+// HasSpeech implements Pipeline
+func (p *ServicePipeline) HasSpeech(ctx context.Context, samples []float32, sampleRate int) (bool, error) {
+	if p.VAD == nil {
+		return len(samples) > 0, nil
+	}
+
+	response, err := p.VAD.DetectFromAudioDataContext(ctx, samples, sampleRate)
+	if err != nil {
+		return false, fmt.Errorf("VAD detection failed: %w", err)
+	}
+	return len(response.SpeechSegments) > 0, nil
+}
+
+// Transcribe implements Pipeline
+func (p *ServicePipeline) Transcribe(ctx context.Context, samples []float32, sampleRate int) (string, error) {
+	return p.ASR.TranscribeAudioData(ctx, samples, sampleRate)
+}
+
+// Reply implements Pipeline
+func (p *ServicePipeline) Reply(ctx context.Context, text string) (string, error) {
+	return p.LLM.Chat(ctx, text)
+}
+
+// Synthesize implements Pipeline
+func (p *ServicePipeline) Synthesize(ctx context.Context, text string) ([]byte, string, error) {
+	audioData, err := p.TTS.SynthesizeText(ctx, text)
+	if err != nil {
+		return nil, "", err
+	}
+	return audioData, p.TTS.GetConfig().OutputFormat, nil
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/logging"
+)
+
+// multipartOverhead allows for form boundaries and fields on top of the audio file itself
+const multipartOverhead = 1 << 20
+
+// multipartMemory is the part of an upload kept in memory, the rest spills to temporary files
+const multipartMemory = 8 << 20
+
+// TranscriptionSegment is a timed piece of a transcription
+type TranscriptionSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// TranscriptionResult is the JSON body returned by POST /transcribe
+type TranscriptionResult struct {
+	Text     string                 `json:"text"`
+	Language string                 `json:"language,omitempty"`
+	Duration float64                `json:"duration,omitempty"`
+	Segments []TranscriptionSegment `json:"segments"`
+}
+
+// TranscribeHandler serves file transcription
+//
+// POST /transcribe takes a multipart form with the audio in the "file" field and optional
+// "language" and "model" fields, and returns a TranscriptionResult.
+// Errors are JSON {"error":"..."}: 400 for a malformed form or unknown model, 413 for uploads over
+// asr.MaxFileSize, 415 for extensions the API does not accept, 502 when transcription fails upstream.
+type TranscribeHandler struct {
+	service *asr.Service
+	logger  logging.Logger
+}
+
+// NewTranscribeHandler creates a handler backed by service, a nil logger uses the standard log package
+func NewTranscribeHandler(service *asr.Service, logger logging.Logger) *TranscribeHandler {
+	return &TranscribeHandler{service: service, logger: logging.OrStd(logger)}
+}
+
+// ServeHTTP implements http.Handler
+func (h *TranscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, asr.MaxFileSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("upload exceeds maximum allowed size of %d bytes", asr.MaxFileSize))
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart form: %w", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing audio file: %w", err))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > asr.MaxFileSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file size %d bytes exceeds maximum allowed size of %d bytes", header.Size, asr.MaxFileSize))
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if !asr.IsSupportedFormat(ext) {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported audio format: %q", ext))
+		return
+	}
+
+	opts := asr.TranscribeRequest{
+		Language: r.FormValue("language"),
+		Model:    r.FormValue("model"),
+	}
+	if opts.Model != "" && !asr.IsSupportedModel(opts.Model) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported model: %s", opts.Model))
+		return
+	}
+
+	// The client transcribes files, keep the original extension so the API can detect the format
+	tempFile, err := saveUpload(file, h.service.GetConfig().TempDir, ext)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.Remove(tempFile)
+
+	response, err := h.service.TranscribeWithDetailsOptions(r.Context(), tempFile, opts)
+	if err != nil {
+		h.logger.Warn("Transcription request failed: %v", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	result := TranscriptionResult{
+		Text:     strings.TrimSpace(response.Text),
+		Language: response.Language,
+		Duration: response.Duration,
+		Segments: make([]TranscriptionSegment, 0, len(response.Segments)),
+	}
+	for _, segment := range response.Segments {
+		result.Segments = append(result.Segments, TranscriptionSegment{
+			Start: segment.Start,
+			End:   segment.End,
+			Text:  strings.TrimSpace(segment.Text),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// saveUpload copies an uploaded file into dir and returns its path
+func saveUpload(src io.Reader, dir, ext string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	dst, err := os.CreateTemp(dir, "upload_*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("failed to save upload: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("failed to save upload: %w", err)
+	}
+
+	return dst.Name(), nil
+}
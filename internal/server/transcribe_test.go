@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"audio-assistant/internal/asr"
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/logging"
+)
+
+const verboseTranscription = `{"text":" 今天天气不错 ","language":"chinese","duration":1.5,
+"segments":[{"id":0,"start":0,"end":0.8,"text":" 今天"},{"id":1,"start":0.8,"end":1.5,"text":"天气不错"}]}`
+
+// newTranscribeHandler creates a running ASR service against a stub API and records upload fields
+func newTranscribeHandler(t *testing.T, fields *[]map[string]string) *TranscribeHandler {
+	t.Helper()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			w.Write([]byte(`{"object":"list","data":[]}`))
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		values := map[string]string{}
+		for key := range r.MultipartForm.Value {
+			values[key] = r.FormValue(key)
+		}
+		*fields = append(*fields, values)
+		w.Write([]byte(verboseTranscription))
+	}))
+	t.Cleanup(api.Close)
+
+	config := asr.DefaultConfig()
+	config.APIKey = "test-key"
+	config.BaseURL = api.URL
+	config.TempDir = t.TempDir()
+	config.MaxRetries = 0
+	config.Logger = logging.Discard()
+
+	service, err := asr.NewService(config)
+	if err != nil {
+		t.Fatalf("Failed to create ASR service: %v", err)
+	}
+	if err := service.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start ASR service: %v", err)
+	}
+	t.Cleanup(service.Stop)
+
+	return NewTranscribeHandler(service, logging.Discard())
+}
+
+// postUpload sends a multipart upload with the given file and form fields
+func postUpload(handler http.Handler, filename string, data []byte, values map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range values {
+		writer.WriteField(key, value)
+	}
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/transcribe", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestTranscribeHandler(t *testing.T) {
+	var fields []map[string]string
+	handler := newTranscribeHandler(t, &fields)
+
+	wavData, err := audio.EncodeWAV(make([]float32, 1600), 16000)
+	if err != nil {
+		t.Fatalf("Failed to encode WAV: %v", err)
+	}
+
+	response := postUpload(handler, "speech.wav", wavData, map[string]string{"language": "zh"})
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", response.Code, response.Body)
+	}
+
+	var result TranscriptionResult
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if result.Text != "今天天气不错" || result.Language != "chinese" || len(result.Segments) != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.Segments[1].Start != 0.8 || result.Segments[1].Text != "天气不错" {
+		t.Errorf("Unexpected segment: %+v", result.Segments[1])
+	}
+
+	if got := fields[0]; got["language"] != "zh" || got["response_format"] != "verbose_json" {
+		t.Errorf("Expected language override and verbose_json, got %v", got)
+	}
+}
+
+func TestTranscribeHandlerErrors(t *testing.T) {
+	var fields []map[string]string
+	handler := newTranscribeHandler(t, &fields)
+
+	if response := postUpload(handler, "notes.txt", []byte("hello"), nil); response.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for unsupported extension, got %d", response.Code)
+	}
+	if response := postUpload(handler, "big.wav", make([]byte, asr.MaxFileSize+1), nil); response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for oversized upload, got %d", response.Code)
+	}
+	if response := postUpload(handler, "speech.wav", []byte("RIFF"), map[string]string{"model": "whisper-2"}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown model, got %d", response.Code)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/transcribe", bytes.NewBufferString("not a form")))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-multipart body, got %d", recorder.Code)
+	}
+
+	if len(fields) != 0 {
+		t.Errorf("Expected rejected uploads not to reach the API, got %d calls", len(fields))
+	}
+}
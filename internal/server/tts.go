@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/tts"
+)
+
+// maxRequestBytes limits JSON request bodies
+const maxRequestBytes = 1 << 20
+
+// SpeechRequest is the body of POST /tts, empty fields keep the service configuration
+type SpeechRequest struct {
+	Text   string  `json:"text"`
+	Voice  string  `json:"voice,omitempty"`
+	Speed  float64 `json:"speed,omitempty"`
+	Format string  `json:"format,omitempty"`
+}
+
+// TTSHandler serves one-shot text-to-speech
+//
+// POST /tts with a SpeechRequest body returns the audio bytes with the matching Content-Type.
+// Errors are JSON {"error":"..."}: 400 for empty or oversized text and invalid options,
+// 405 for other methods, 502 when synthesis fails upstream.
+type TTSHandler struct {
+	service *tts.TTSService
+	logger  logging.Logger
+}
+
+// NewTTSHandler creates a handler backed by service, a nil logger uses the standard log package
+func NewTTSHandler(service *tts.TTSService, logger logging.Logger) *TTSHandler {
+	return &TTSHandler{service: service, logger: logging.OrStd(logger)}
+}
+
+// ServeHTTP implements http.Handler
+func (h *TTSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	var req SpeechRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	config := h.service.GetConfig()
+	if req.Text == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("text cannot be empty"))
+		return
+	}
+	if len(req.Text) > config.MaxTextLength {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("text too long: %d characters (max %d)", len(req.Text), config.MaxTextLength))
+		return
+	}
+
+	opts := tts.SynthesizeOptions{Voice: req.Voice, Speed: req.Speed, Format: req.Format}
+	if err := h.service.ValidateOptions(opts); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	audioData, err := h.service.SynthesizeTextWithOptions(r.Context(), req.Text, opts)
+	if err != nil {
+		h.logger.Warn("TTS request failed: %v", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = config.OutputFormat
+	}
+	w.Header().Set("Content-Type", tts.GetContentTypeForFormat(format))
+	w.Header().Set("Content-Length", strconv.Itoa(len(audioData)))
+	w.Write(audioData)
+}
+
+// writeError writes a JSON error body with the given status
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
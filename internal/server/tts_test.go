@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/tts"
+)
+
+// speechTransport stands in for the TTS API, answering with the requested voice and format
+type speechTransport struct {
+	calls  int
+	status int
+}
+
+func (t *speechTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	if t.status != 0 {
+		return &http.Response{
+			StatusCode: t.status,
+			Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"upstream down"}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	}
+
+	var request tts.TTSRequest
+	json.NewDecoder(req.Body).Decode(&request)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(request.Voice + "/" + request.ResponseFormat)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+// newTTSHandler creates a running TTS service on the stub transport
+func newTTSHandler(t *testing.T, transport http.RoundTripper) *TTSHandler {
+	t.Helper()
+
+	config := tts.DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.Transport = transport
+	config.MaxRetries = 0
+	config.Logger = logging.Discard()
+
+	service, err := tts.NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create TTS service: %v", err)
+	}
+	service.Start()
+	t.Cleanup(func() { service.Stop() })
+
+	return NewTTSHandler(service, logging.Discard())
+}
+
+// postTTS sends body to the handler and returns the recorded response
+func postTTS(handler http.Handler, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/tts", bytes.NewBufferString(body)))
+	return recorder
+}
+
+func TestTTSHandler(t *testing.T) {
+	transport := &speechTransport{}
+	handler := newTTSHandler(t, transport)
+
+	response := postTTS(handler, `{"text":"你好","voice":"nova","speed":1.25,"format":"wav"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", response.Code, response.Body)
+	}
+	if got := response.Header().Get("Content-Type"); got != "audio/wav" {
+		t.Errorf("Expected audio/wav, got %q", got)
+	}
+	if response.Body.String() != "nova/wav" {
+		t.Errorf("Unexpected audio body: %q", response.Body)
+	}
+
+	// Repeated requests are served from the service cache
+	postTTS(handler, `{"text":"你好","voice":"nova","speed":1.25,"format":"wav"}`)
+	if transport.calls != 1 {
+		t.Errorf("Expected cached response, got %d upstream calls", transport.calls)
+	}
+
+	// Omitted options use the service defaults
+	response = postTTS(handler, `{"text":"你好"}`)
+	if response.Header().Get("Content-Type") != "audio/mpeg" || response.Body.String() != "alloy/mp3" {
+		t.Errorf("Expected default mp3 response, got %q %q", response.Header().Get("Content-Type"), response.Body)
+	}
+}
+
+func TestTTSHandlerErrors(t *testing.T) {
+	handler := newTTSHandler(t, &speechTransport{})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed", `{"text":`},
+		{"empty text", `{"text":""}`},
+		{"oversized text", `{"text":"` + strings.Repeat("a", 5000) + `"}`},
+		{"unknown voice", `{"text":"你好","voice":"robot"}`},
+		{"unknown format", `{"text":"你好","format":"ogg"}`},
+		{"speed out of range", `{"text":"你好","speed":10}`},
+	}
+	for _, tt := range tests {
+		response := postTTS(handler, tt.body)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", tt.name, response.Code)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Errorf("%s: expected JSON error body, got %q", tt.name, response.Body)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tts", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", recorder.Code)
+	}
+
+	failing := newTTSHandler(t, &speechTransport{status: http.StatusInternalServerError})
+	if response := postTTS(failing, `{"text":"你好"}`); response.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 on upstream failure, got %d", response.Code)
+	}
+}
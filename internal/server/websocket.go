@@ -0,0 +1,304 @@
+// Package server exposes the assistant pipeline to remote clients
+//
+// # WebSocket protocol
+//
+// A connection carries one conversation. Client to server:
+//   - binary frames: raw 16-bit little-endian mono PCM at the session sample rate (default 16000 Hz),
+//     a frame holding more than MaxUtteranceSeconds of audio closes the connection
+//   - {"type":"start","sample_rate":16000}: optional, sets the sample rate and discards buffered audio
+//   - {"type":"end"}: the utterance is complete, run the buffered audio through the pipeline
+//   - {"type":"text","text":"..."}: skip VAD and ASR and reply to the given text
+//
+// Server to client, in order for each utterance:
+//   - {"type":"transcript","text":"..."}: the ASR result (omitted for text input)
+//   - {"type":"reply","text":"..."}: the LLM answer
+//   - {"type":"audio","format":"mp3","bytes":N}: announces the next frame, a binary frame with the synthesized audio
+//   - {"type":"done"}: the utterance is finished and the buffer has been cleared
+//
+// When VAD finds no speech the server sends {"type":"no_speech"} instead of the above.
+// Failures are reported as {"type":"error","stage":"asr","message":"..."} and the connection stays open.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/logging"
+)
+
+// Message types of the JSON control frames
+const (
+	TypeStart      = "start"
+	TypeEnd        = "end"
+	TypeText       = "text"
+	TypeTranscript = "transcript"
+	TypeReply      = "reply"
+	TypeAudio      = "audio"
+	TypeDone       = "done"
+	TypeNoSpeech   = "no_speech"
+	TypeError      = "error"
+)
+
+// Stage labels used in error messages
+const (
+	StageProtocol = "protocol"
+	StageVAD      = "vad"
+	StageASR      = "asr"
+	StageLLM      = "llm"
+	StageTTS      = "tts"
+)
+
+// Message is a JSON control frame, fields are set according to Type
+type Message struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Bytes      int    `json:"bytes,omitempty"`
+	Stage      string `json:"stage,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// minReadLimit keeps control frames readable when MaxUtteranceSeconds allows only a few PCM bytes
+const minReadLimit = 64 * 1024
+
+// HandlerConfig configures the WebSocket handler
+type HandlerConfig struct {
+	SampleRate          int                      // Default sample rate of incoming PCM
+	MaxUtteranceSeconds float64                  // Buffered audio limit per utterance, 0 = unlimited
+	StageTimeout        time.Duration            // Timeout for each utterance, 0 = none
+	CheckOrigin         func(*http.Request) bool // Origin check for the upgrade, nil accepts same-origin requests only
+	Logger              logging.Logger           // Receives handler logs, nil uses the standard log package
+}
+
+// DefaultHandlerConfig returns the default handler configuration
+func DefaultHandlerConfig() HandlerConfig {
+	return HandlerConfig{
+		SampleRate:          audio.GetTargetSampleRate(),
+		MaxUtteranceSeconds: 30,
+		StageTimeout:        60 * time.Second,
+	}
+}
+
+// Handler serves the assistant pipeline over WebSocket
+type Handler struct {
+	pipeline Pipeline
+	config   HandlerConfig
+	upgrader websocket.Upgrader
+	logger   logging.Logger
+}
+
+// NewHandler creates a WebSocket handler for the given pipeline
+func NewHandler(pipeline Pipeline, config HandlerConfig) *Handler {
+	if config.SampleRate <= 0 {
+		config.SampleRate = audio.GetTargetSampleRate()
+	}
+
+	return &Handler{
+		pipeline: pipeline,
+		config:   config,
+		upgrader: websocket.Upgrader{CheckOrigin: config.CheckOrigin},
+		logger:   logging.OrStd(config.Logger),
+	}
+}
+
+// ServeHTTP upgrades the request and serves one conversation until the client disconnects
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Warn("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	pipeline := h.pipeline
+	if sessions, ok := pipeline.(SessionPipeline); ok {
+		pipeline = sessions.NewSession()
+	}
+
+	s := &session{handler: h, pipeline: pipeline, conn: conn}
+	s.setSampleRate(h.config.SampleRate)
+	h.logger.Info("WebSocket client connected: %s", r.RemoteAddr)
+	s.run(ctx)
+	h.logger.Info("WebSocket client disconnected: %s", r.RemoteAddr)
+}
+
+// session holds the per-connection state
+type session struct {
+	handler    *Handler
+	pipeline   Pipeline
+	conn       *websocket.Conn
+	sampleRate int
+	buffer     []float32
+}
+
+// run reads frames until the connection closes
+func (s *session) run(ctx context.Context) {
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				s.handler.logger.Debug("WebSocket read ended: %v", err)
+			}
+			return
+		}
+
+		switch messageType {
+		case websocket.BinaryMessage:
+			err = s.appendAudio(data)
+		case websocket.TextMessage:
+			err = s.handleControl(ctx, data)
+		}
+		if err != nil {
+			s.handler.logger.Debug("WebSocket write failed: %v", err)
+			return
+		}
+	}
+}
+
+// setSampleRate changes the session sample rate and caps incoming frames at one full utterance
+// Without a read limit a single oversized frame would be buffered whole before appendAudio can reject it
+func (s *session) setSampleRate(sampleRate int) {
+	s.sampleRate = sampleRate
+
+	limit := s.handler.config.MaxUtteranceSeconds
+	if limit <= 0 {
+		s.conn.SetReadLimit(0)
+		return
+	}
+	s.conn.SetReadLimit(max(int64(limit*float64(sampleRate))*2, minReadLimit))
+}
+
+// appendAudio buffers a PCM frame, rejecting utterances over the configured limit
+func (s *session) appendAudio(data []byte) error {
+	if len(data)%2 != 0 {
+		return s.sendError(StageProtocol, fmt.Errorf("PCM frame has odd length %d", len(data)))
+	}
+
+	s.buffer = append(s.buffer, audio.PCM16ToFloat32(data)...)
+
+	limit := s.handler.config.MaxUtteranceSeconds
+	if limit > 0 && float64(len(s.buffer)) > limit*float64(s.sampleRate) {
+		s.buffer = nil
+		return s.sendError(StageProtocol, fmt.Errorf("utterance exceeds %.0f seconds, buffer discarded", limit))
+	}
+	return nil
+}
+
+// handleControl dispatches a JSON control frame
+func (s *session) handleControl(ctx context.Context, data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return s.sendError(StageProtocol, fmt.Errorf("invalid control message: %w", err))
+	}
+
+	switch msg.Type {
+	case TypeStart:
+		if msg.SampleRate > 0 {
+			s.setSampleRate(msg.SampleRate)
+		}
+		s.buffer = nil
+		return nil
+	case TypeEnd:
+		samples := s.buffer
+		s.buffer = nil
+		return s.processAudio(ctx, samples)
+	case TypeText:
+		return s.processText(ctx, msg.Text)
+	default:
+		return s.sendError(StageProtocol, fmt.Errorf("unknown message type %q", msg.Type))
+	}
+}
+
+// processAudio runs a buffered utterance through VAD and ASR, then replies
+func (s *session) processAudio(ctx context.Context, samples []float32) error {
+	ctx, cancel := s.stageContext(ctx)
+	defer cancel()
+
+	pipeline := s.pipeline
+	hasSpeech, err := pipeline.HasSpeech(ctx, samples, s.sampleRate)
+	if err != nil {
+		return s.sendError(StageVAD, err)
+	}
+	if !hasSpeech {
+		return s.send(Message{Type: TypeNoSpeech})
+	}
+
+	text, err := pipeline.Transcribe(ctx, samples, s.sampleRate)
+	if err != nil {
+		return s.sendError(StageASR, err)
+	}
+	if text == "" {
+		return s.send(Message{Type: TypeNoSpeech})
+	}
+	if err := s.send(Message{Type: TypeTranscript, Text: text}); err != nil {
+		return err
+	}
+
+	return s.respond(ctx, text)
+}
+
+// processText replies to text input directly
+func (s *session) processText(ctx context.Context, text string) error {
+	if text == "" {
+		return s.sendError(StageProtocol, fmt.Errorf("text message is empty"))
+	}
+
+	ctx, cancel := s.stageContext(ctx)
+	defer cancel()
+	return s.respond(ctx, text)
+}
+
+// respond sends the LLM reply and its synthesized audio, then the done marker
+func (s *session) respond(ctx context.Context, text string) error {
+	pipeline := s.pipeline
+
+	reply, err := pipeline.Reply(ctx, text)
+	if err != nil {
+		return s.sendError(StageLLM, err)
+	}
+	if err := s.send(Message{Type: TypeReply, Text: reply}); err != nil {
+		return err
+	}
+
+	audioData, format, err := pipeline.Synthesize(ctx, reply)
+	if err != nil {
+		return s.sendError(StageTTS, err)
+	}
+	if err := s.send(Message{Type: TypeAudio, Format: format, Bytes: len(audioData)}); err != nil {
+		return err
+	}
+	if err := s.conn.WriteMessage(websocket.BinaryMessage, audioData); err != nil {
+		return err
+	}
+
+	return s.send(Message{Type: TypeDone})
+}
+
+// stageContext applies the per-utterance timeout
+func (s *session) stageContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.handler.config.StageTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.handler.config.StageTimeout)
+}
+
+// send writes a JSON control frame
+func (s *session) send(msg Message) error {
+	return s.conn.WriteJSON(msg)
+}
+
+// sendError reports a stage failure to the client without closing the connection
+func (s *session) sendError(stage string, err error) error {
+	s.handler.logger.Warn("%s stage failed: %v", stage, err)
+	return s.send(Message{Type: TypeError, Stage: stage, Message: err.Error()})
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"audio-assistant/internal/logging"
+)
+
+// stubPipeline echoes its inputs so the test can check what reached each stage
+type stubPipeline struct {
+	speech     bool
+	ttsErr     error
+	sampleRate int
+	samples    int
+}
+
+func (p *stubPipeline) HasSpeech(ctx context.Context, samples []float32, sampleRate int) (bool, error) {
+	p.sampleRate = sampleRate
+	p.samples = len(samples)
+	return p.speech, nil
+}
+
+func (p *stubPipeline) Transcribe(ctx context.Context, samples []float32, sampleRate int) (string, error) {
+	return "今天天气怎么样", nil
+}
+
+func (p *stubPipeline) Reply(ctx context.Context, text string) (string, error) {
+	return "回复：" + text, nil
+}
+
+func (p *stubPipeline) Synthesize(ctx context.Context, text string) ([]byte, string, error) {
+	if p.ttsErr != nil {
+		return nil, "", p.ttsErr
+	}
+	return []byte(text), "wav", nil
+}
+
+// historyPipeline remembers the texts replied to, each session starting empty
+type historyPipeline struct {
+	stubPipeline
+	history  []string
+	sessions *atomic.Int32
+}
+
+func (p *historyPipeline) NewSession() Pipeline {
+	p.sessions.Add(1)
+	return &historyPipeline{sessions: p.sessions}
+}
+
+func (p *historyPipeline) Reply(ctx context.Context, text string) (string, error) {
+	p.history = append(p.history, text)
+	return strings.Join(p.history, "|"), nil
+}
+
+// dial starts a test server for the pipeline and connects to it
+func dial(t *testing.T, pipeline Pipeline) *websocket.Conn {
+	t.Helper()
+
+	config := DefaultHandlerConfig()
+	config.Logger = logging.Discard()
+	return dialWithConfig(t, pipeline, config)
+}
+
+// dialWithConfig starts a test server with the handler config and connects to it
+func dialWithConfig(t *testing.T, pipeline Pipeline, config HandlerConfig) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(NewHandler(pipeline, config))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// expect reads the next control frame and checks its type
+func expect(t *testing.T, conn *websocket.Conn, messageType string) Message {
+	t.Helper()
+
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read %s message: %v", messageType, err)
+	}
+	if msg.Type != messageType {
+		t.Fatalf("Expected %s message, got %+v", messageType, msg)
+	}
+	return msg
+}
+
+// pcmFrame encodes n samples of a constant value as 16-bit PCM
+func pcmFrame(n int, value int16) []byte {
+	frame := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(frame[2*i:], uint16(value))
+	}
+	return frame
+}
+
+func TestWebSocketRoundTrip(t *testing.T) {
+	pipeline := &stubPipeline{speech: true}
+	conn := dial(t, pipeline)
+
+	if err := conn.WriteJSON(Message{Type: TypeStart, SampleRate: 8000}); err != nil {
+		t.Fatalf("Failed to send start: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := conn.WriteMessage(websocket.BinaryMessage, pcmFrame(800, 1000)); err != nil {
+			t.Fatalf("Failed to send audio: %v", err)
+		}
+	}
+	if err := conn.WriteJSON(Message{Type: TypeEnd}); err != nil {
+		t.Fatalf("Failed to send end: %v", err)
+	}
+
+	if msg := expect(t, conn, TypeTranscript); msg.Text != "今天天气怎么样" {
+		t.Errorf("Unexpected transcript: %q", msg.Text)
+	}
+	reply := expect(t, conn, TypeReply)
+	if reply.Text != "回复：今天天气怎么样" {
+		t.Errorf("Unexpected reply: %q", reply.Text)
+	}
+	header := expect(t, conn, TypeAudio)
+	if header.Format != "wav" || header.Bytes != len(reply.Text) {
+		t.Errorf("Unexpected audio header: %+v", header)
+	}
+
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read audio: %v", err)
+	}
+	if messageType != websocket.BinaryMessage || string(data) != reply.Text {
+		t.Errorf("Unexpected audio frame: type %d, %q", messageType, data)
+	}
+	expect(t, conn, TypeDone)
+
+	if pipeline.sampleRate != 8000 || pipeline.samples != 1600 {
+		t.Errorf("Expected 1600 samples at 8000Hz, got %d at %d", pipeline.samples, pipeline.sampleRate)
+	}
+}
+
+func TestWebSocketTextAndErrors(t *testing.T) {
+	pipeline := &stubPipeline{ttsErr: errors.New("tts unavailable")}
+	conn := dial(t, pipeline)
+
+	// No speech in the buffer
+	conn.WriteMessage(websocket.BinaryMessage, pcmFrame(160, 0))
+	conn.WriteJSON(Message{Type: TypeEnd})
+	expect(t, conn, TypeNoSpeech)
+
+	// Text input skips ASR, a TTS failure is reported without closing the connection
+	conn.WriteJSON(Message{Type: TypeText, Text: "你好"})
+	if msg := expect(t, conn, TypeReply); msg.Text != "回复：你好" {
+		t.Errorf("Unexpected reply: %q", msg.Text)
+	}
+	if msg := expect(t, conn, TypeError); msg.Stage != StageTTS || msg.Message != "tts unavailable" {
+		t.Errorf("Unexpected error message: %+v", msg)
+	}
+
+	// Malformed frames are protocol errors
+	conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3})
+	if msg := expect(t, conn, TypeError); msg.Stage != StageProtocol {
+		t.Errorf("Expected protocol error for odd PCM frame, got %+v", msg)
+	}
+	conn.WriteJSON(Message{Type: "bogus"})
+	if msg := expect(t, conn, TypeError); msg.Stage != StageProtocol {
+		t.Errorf("Expected protocol error for unknown type, got %+v", msg)
+	}
+}
+
+func TestWebSocketSessionHistory(t *testing.T) {
+	var sessions atomic.Int32
+	pipeline := &historyPipeline{sessions: &sessions}
+	first := dial(t, pipeline)
+	second := dial(t, pipeline)
+
+	// Each connection replies from its own history
+	first.WriteJSON(Message{Type: TypeText, Text: "一"})
+	if msg := expect(t, first, TypeReply); msg.Text != "一" {
+		t.Errorf("Unexpected first reply: %q", msg.Text)
+	}
+	second.WriteJSON(Message{Type: TypeText, Text: "二"})
+	if msg := expect(t, second, TypeReply); msg.Text != "二" {
+		t.Errorf("Expected the second connection not to see the first one's history, got %q", msg.Text)
+	}
+	expect(t, first, TypeAudio)
+	first.ReadMessage()
+	expect(t, first, TypeDone)
+	first.WriteJSON(Message{Type: TypeText, Text: "三"})
+	if msg := expect(t, first, TypeReply); msg.Text != "一|三" {
+		t.Errorf("Expected the first connection to keep its history, got %q", msg.Text)
+	}
+
+	if got := sessions.Load(); got != 2 {
+		t.Errorf("Expected 2 sessions, got %d", got)
+	}
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	config := DefaultHandlerConfig()
+	config.Logger = logging.Discard()
+	config.MaxUtteranceSeconds = 10
+	conn := dialWithConfig(t, &stubPipeline{speech: true}, config)
+
+	// 10 seconds at 8000 Hz is 160000 bytes, a larger frame closes the connection
+	conn.WriteJSON(Message{Type: TypeStart, SampleRate: 8000})
+	conn.WriteMessage(websocket.BinaryMessage, pcmFrame(80000, 1))
+	conn.WriteJSON(Message{Type: TypeEnd})
+	expect(t, conn, TypeTranscript)
+	expect(t, conn, TypeReply)
+	expect(t, conn, TypeAudio)
+	conn.ReadMessage()
+	expect(t, conn, TypeDone)
+
+	conn.WriteMessage(websocket.BinaryMessage, pcmFrame(80001, 1))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("Expected the connection to close after an oversized frame")
+	}
+}
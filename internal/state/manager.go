@@ -3,14 +3,18 @@ package state
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"sync"
 	"time"
 
 	"audio-assistant/internal/audio"
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/vad"
 )
 
 type State int
@@ -32,8 +36,66 @@ const (
 	outputRateLimit = 100
 	// 临时文件目录
 	tempDir = "temp"
+	// 每个订阅者缓冲的状态变化数，缓冲满时丢弃新事件
+	subscriberBuffer = 16
+	// 默认最多缓冲的音频时长（内存加临时文件）
+	defaultMaxBufferedDuration = 5 * time.Minute
+	// 默认输入采样率，用于把样本数换算成时长
+	defaultSampleRate = 16000
+	// 默认静音持续多久判定一句话结束，与语音助手的 MinSilenceDurationMs 默认值一致
+	defaultMinSilenceDuration = time.Second
+	// 默认语音检测窗口时长，是语音助手 MinSpeechDurationMs 默认值的两倍
+	defaultDetectionWindow = time.Second
 )
 
+// ErrBufferLimit 缓冲的音频超过 MaxBufferedDuration，新数据被拒绝并强制进入 Processing
+var ErrBufferLimit = errors.New("buffered audio exceeds limit")
+
+// ManagerConfig 状态管理器配置
+type ManagerConfig struct {
+	// 内存和临时文件中合计最多缓冲的音频时长，0 表示不限制
+	MaxBufferedDuration time.Duration
+	// 输入音频采样率（单声道），0 时使用 16000
+	SampleRate int
+	// 输入队列长度（块数），0 时使用 DefaultInputQueueSize
+	InputQueueSize int
+	// 设置了语音检测器时，Listening 下静音持续超过该时长才进入 Processing，0 时使用 1 秒
+	MinSilenceDuration time.Duration
+	// 语音检测窗口时长：每块输入与之前的音频合起来送去检测，应不短于检测器最小语音时长的两倍，0 时使用 1 秒
+	DetectionWindow time.Duration
+}
+
+// PlaybackSink 播放输出接口，*audio.AudioOutput 满足该接口
+type PlaybackSink interface {
+	// Enqueue 追加单声道样本到播放队列
+	Enqueue(samples []float32) error
+	// Drain 阻塞直到队列播放完毕
+	Drain(ctx context.Context) error
+}
+
+// SpeechDetector 语音活动检测接口，vad.Service 满足该接口
+type SpeechDetector interface {
+	GetSpeechSegments(audioData []float32, sampleRate int) ([]vad.SpeechSegment, error)
+}
+
+// DefaultManagerConfig 返回默认配置
+func DefaultManagerConfig() ManagerConfig {
+	return ManagerConfig{
+		MaxBufferedDuration: defaultMaxBufferedDuration,
+		SampleRate:          defaultSampleRate,
+		InputQueueSize:      DefaultInputQueueSize,
+		MinSilenceDuration:  defaultMinSilenceDuration,
+		DetectionWindow:     defaultDetectionWindow,
+	}
+}
+
+// StateChange 一次状态变化
+type StateChange struct {
+	From State
+	To   State
+	At   time.Time
+}
+
 // 音频处理统计
 type AudioStats struct {
 	TotalInputChunks  int64
@@ -43,6 +105,8 @@ type AudioStats struct {
 	LastOutputTime    time.Time
 	TotalBytesWritten int64
 	TotalBytesRead    int64
+	RejectedChunks    int64 // 因超过缓冲上限被拒绝的音频块数
+	InputOverflows    int64 // 输入队列已满时丢弃的最旧音频块数
 }
 
 func (s State) String() string {
@@ -67,13 +131,43 @@ type Manager struct {
 	memBuffer [][]float32
 	// 临时文件
 	tempFile *os.File
+	// 自上次重置以来写入和已读出临时文件的字节数
+	tempWritten int64
+	tempRead    int64
 	// 音频处理统计
 	stats AudioStats
 	// 输出速率限制器
 	outputTicker *time.Ticker
+	// 日志输出
+	logger logging.Logger
+	// 状态变化订阅者，键为返回给调用方的只读通道
+	subscribers map[<-chan StateChange]chan StateChange
+	// 当前缓冲的样本数（内存加临时文件）
+	bufferedSamples int64
+	// 缓冲样本数上限，0 表示不限制
+	maxBufferedSamples int64
+	// 语音检测器，为 nil 时沿用按缓冲区大小切换的旧逻辑
+	detector SpeechDetector
+	// 输入采样率
+	sampleRate int
+	// 判定一句话结束所需的静音时长
+	minSilence time.Duration
+	// 输入队列长度
+	inputQueueSize int
+	// Listening 下已累计的连续静音时长，按音频块时长计算
+	silence time.Duration
+	// 语音检测窗口时长和窗口，窗口在首次检测时创建，只由处理协程使用
+	detectionWindow time.Duration
+	window          *vad.Window
 }
 
+// NewManager 使用默认配置创建状态管理器
 func NewManager() *Manager {
+	return NewManagerWithConfig(DefaultManagerConfig())
+}
+
+// NewManagerWithConfig 使用指定配置创建状态管理器
+func NewManagerWithConfig(config ManagerConfig) *Manager {
 	// 创建临时目录
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		log.Fatalf("Failed to create temp directory: %v", err)
@@ -93,20 +187,49 @@ func NewManager() *Manager {
 			LastInputTime:  time.Now(),
 			LastOutputTime: time.Now(),
 		},
-		outputTicker: time.NewTicker(time.Second / outputRateLimit),
+		outputTicker:       time.NewTicker(time.Second / outputRateLimit),
+		logger:             logging.Std(),
+		maxBufferedSamples: config.maxBufferedSamples(),
+		sampleRate:         config.SampleRate,
+		minSilence:         config.MinSilenceDuration,
+		inputQueueSize:     config.InputQueueSize,
+		detectionWindow:    config.DetectionWindow,
 	}
 }
 
+// maxBufferedSamples 把缓冲时长上限换算成样本数
+func (c ManagerConfig) maxBufferedSamples() int64 {
+	if c.MaxBufferedDuration <= 0 {
+		return 0
+	}
+	sampleRate := c.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = defaultSampleRate
+	}
+	return int64(c.MaxBufferedDuration.Seconds() * float64(sampleRate))
+}
+
+// SetLogger 设置日志输出，nil 恢复为标准 log 包
+func (m *Manager) SetLogger(logger logging.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = logging.OrStd(logger)
+}
+
 func (m *Manager) Run(ctx context.Context, input *audio.Input, output *audio.AudioOutput) error {
-	log.Println("Starting audio assistant...")
+	m.logger.Info("Starting audio assistant...")
 
 	if err := input.Start(); err != nil {
 		return err
 	}
 	// 新的 AudioOutput 不需要显式启动
 
-	// 启动音频处理循环
-	go m.processAudio(ctx, input, output)
+	// 启动音频处理循环，output 为 nil 时只清空缓冲而不播放
+	var sink PlaybackSink
+	if output != nil {
+		sink = output
+	}
+	go m.processAudio(ctx, input, sink)
 
 	// 等待上下文取消
 	<-ctx.Done()
@@ -116,12 +239,22 @@ func (m *Manager) Run(ctx context.Context, input *audio.Input, output *audio.Aud
 	return nil
 }
 
+// SetSpeechDetector 设置语音检测器，设置后 Idle 检测到语音才开始录音，Listening 持续静音后才进入 Processing
+// nil 恢复为不做检测
+func (m *Manager) SetSpeechDetector(detector SpeechDetector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.detector = detector
+	m.silence = 0
+	m.window = nil
+}
+
 // 写入音频数据到临时文件
 func (m *Manager) writeToTempFile(data []float32) error {
 	// 将 float32 切片转换为字节切片
 	bytes := make([]byte, len(data)*4)
 	for i, v := range data {
-		binary.LittleEndian.PutUint32(bytes[i*4:], uint32(v))
+		binary.LittleEndian.PutUint32(bytes[i*4:], math.Float32bits(v))
 	}
 
 	// 写入文件
@@ -130,6 +263,7 @@ func (m *Manager) writeToTempFile(data []float32) error {
 		return fmt.Errorf("failed to write to temp file: %v", err)
 	}
 
+	m.tempWritten += int64(len(bytes))
 	m.stats.TotalBytesWritten += int64(len(bytes))
 	return nil
 }
@@ -137,18 +271,16 @@ func (m *Manager) writeToTempFile(data []float32) error {
 // 从临时文件读取音频数据
 func (m *Manager) readFromTempFile(size int) ([]float32, error) {
 	// 读取字节数据
+	// 按读取偏移读，不移动写入用的文件指针
 	bytes := make([]byte, size*4)
-	n, err := m.tempFile.Read(bytes)
-	if err != nil {
-		if err == io.EOF {
-			// 如果到达文件末尾，重置文件指针并返回空数据
-			if _, err := m.tempFile.Seek(0, 0); err != nil {
-				return nil, fmt.Errorf("failed to reset temp file: %v", err)
-			}
-			return nil, nil
-		}
+	n, err := m.tempFile.ReadAt(bytes, m.tempRead)
+	if err != nil && err != io.EOF {
 		return nil, fmt.Errorf("failed to read from temp file: %v", err)
 	}
+	if n == 0 {
+		return nil, nil
+	}
+	m.tempRead += int64(n)
 
 	// 如果读取的字节数不足，调整数据大小
 	if n < size*4 {
@@ -158,7 +290,7 @@ func (m *Manager) readFromTempFile(size int) ([]float32, error) {
 	// 将字节切片转换为 float32 切片
 	data := make([]float32, n/4)
 	for i := 0; i < n/4; i++ {
-		data[i] = float32(binary.LittleEndian.Uint32(bytes[i*4:]))
+		data[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[i*4:]))
 	}
 
 	m.stats.TotalBytesRead += int64(n)
@@ -167,6 +299,16 @@ func (m *Manager) readFromTempFile(size int) ([]float32, error) {
 
 // 重置临时文件
 func (m *Manager) resetTempFile() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 临时文件中的数据随截断丢弃，只剩内存缓冲区
+	m.tempWritten, m.tempRead = 0, 0
+	m.bufferedSamples = 0
+	for _, chunk := range m.memBuffer {
+		m.bufferedSamples += int64(len(chunk))
+	}
+
 	// 截断文件
 	if err := m.tempFile.Truncate(0); err != nil {
 		return err
@@ -191,7 +333,7 @@ func (m *Manager) addAudioData(data []float32) error {
 
 	// 检查单个音频块大小
 	if len(data) > maxChunkSize {
-		log.Printf("Warning: Input chunk size %d exceeds limit %d, truncating", len(data), maxChunkSize)
+		m.logger.Warn("Input chunk size %d exceeds limit %d, truncating", len(data), maxChunkSize)
 		data = data[:maxChunkSize]
 	}
 
@@ -199,6 +341,14 @@ func (m *Manager) addAudioData(data []float32) error {
 	m.stats.TotalInputChunks++
 	m.stats.LastInputTime = time.Now()
 
+	// 缓冲超过上限时拒绝新数据并强制结束录音，避免停在 Listening 时临时文件无限增长
+	if m.maxBufferedSamples > 0 && m.bufferedSamples+int64(len(data)) > m.maxBufferedSamples {
+		m.stats.RejectedChunks++
+		m.logger.Warn("Buffered audio reached limit (%d samples), forcing %s state", m.maxBufferedSamples, StateProcessing)
+		m.setStateLocked(StateProcessing)
+		return ErrBufferLimit
+	}
+
 	// 如果内存缓冲区已满，写入临时文件
 	if len(m.memBuffer) >= memBufferSize {
 		if err := m.writeToTempFile(m.memBuffer[0]); err != nil {
@@ -210,6 +360,7 @@ func (m *Manager) addAudioData(data []float32) error {
 
 	// 添加到内存缓冲区
 	m.memBuffer = append(m.memBuffer, data)
+	m.bufferedSamples += int64(len(data))
 	return nil
 }
 
@@ -218,18 +369,27 @@ func (m *Manager) getAudioData() ([]float32, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 如果内存缓冲区为空，尝试从临时文件读取
-	if len(m.memBuffer) == 0 {
+	// 临时文件中是较早溢出的数据，先读完它才能按录音顺序输出
+	if m.tempRead < m.tempWritten {
 		data, err := m.readFromTempFile(maxChunkSize)
 		if err != nil {
 			return nil, err
 		}
-		return data, nil
+		if data != nil {
+			m.bufferedSamples = max(m.bufferedSamples-int64(len(data)), 0)
+			m.stats.TotalOutputChunks++
+			m.stats.LastOutputTime = time.Now()
+			return data, nil
+		}
+	}
+	if len(m.memBuffer) == 0 {
+		return nil, nil
 	}
 
 	// 从内存缓冲区获取数据
 	data := m.memBuffer[0]
 	m.memBuffer = m.memBuffer[1:]
+	m.bufferedSamples -= int64(len(data))
 
 	// 更新输出统计
 	m.stats.TotalOutputChunks++
@@ -245,6 +405,13 @@ func (m *Manager) getBufferSize() int {
 	return len(m.memBuffer)
 }
 
+// recordInputOverflows 记录输入队列累计丢弃的块数
+func (m *Manager) recordInputOverflows(dropped int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.InputOverflows = dropped
+}
+
 // 打印统计信息
 func (m *Manager) printStats() {
 	m.mu.Lock()
@@ -253,14 +420,14 @@ func (m *Manager) printStats() {
 	inputRate := float64(m.stats.TotalInputChunks) / time.Since(m.stats.LastInputTime).Seconds()
 	outputRate := float64(m.stats.TotalOutputChunks) / time.Since(m.stats.LastOutputTime).Seconds()
 
-	log.Printf("Stats - Memory Buffer: %d, Input Rate: %.2f/s, Output Rate: %.2f/s, Dropped: %d, Written: %d bytes, Read: %d bytes",
-		len(m.memBuffer), inputRate, outputRate, m.stats.DroppedChunks, m.stats.TotalBytesWritten, m.stats.TotalBytesRead)
+	m.logger.Debug("Stats - Memory Buffer: %d, Input Rate: %.2f/s, Output Rate: %.2f/s, Dropped: %d, Rejected: %d, Overflows: %d, Written: %d bytes, Read: %d bytes",
+		len(m.memBuffer), inputRate, outputRate, m.stats.DroppedChunks, m.stats.RejectedChunks, m.stats.InputOverflows, m.stats.TotalBytesWritten, m.stats.TotalBytesRead)
 }
 
-func (m *Manager) processAudio(ctx context.Context, input *audio.Input, output *audio.AudioOutput) {
-	// 使用更短的采样间隔
-	ticker := time.NewTicker(time.Millisecond * 10) // 100Hz 的采样率
-	defer ticker.Stop()
+func (m *Manager) processAudio(ctx context.Context, input ChunkReader, output PlaybackSink) {
+	// 独立协程按设备节奏读取输入，处理跟不上时由队列丢弃最旧的块
+	queue := NewInputQueue(input, m.inputQueueSize, m.logger)
+	go queue.Run(ctx)
 
 	// 统计信息打印定时器
 	statsTicker := time.NewTicker(time.Second)
@@ -272,80 +439,209 @@ func (m *Manager) processAudio(ctx context.Context, input *audio.Input, output *
 			return
 		case <-statsTicker.C:
 			m.printStats()
-		case <-ticker.C:
-			// 读取音频数据
-			data, err := input.Read()
-			if err != nil {
-				log.Printf("Error reading audio: %v", err)
-				continue
+		case data, ok := <-queue.Chunks():
+			if !ok {
+				return
 			}
+			m.recordInputOverflows(queue.Dropped())
 
 			// 根据当前状态处理音频数据
 			switch m.getState() {
-			case StateIdle:
-				if len(data) > 0 {
-					if err := m.addAudioData(data); err != nil {
-						log.Printf("Error adding audio data: %v", err)
-						continue
-					}
-
-					if m.getBufferSize() >= memBufferSize/2 {
-						log.Printf("Switching to Listening state, buffer size: %d", m.getBufferSize())
-						m.setState(StateListening)
-					}
-				}
-			case StateListening:
-				if err := m.addAudioData(data); err != nil {
-					log.Printf("Error adding audio data: %v", err)
-					continue
-				}
-
-				// TODO: 实现 VAD 检测
-				log.Printf("Switching to Processing state, buffer size: %d", m.getBufferSize())
-				m.setState(StateProcessing)
+			case StateIdle, StateListening:
+				m.handleInput(data)
 			case StateProcessing:
 				// TODO: 实现语音识别和 LLM 处理
-				log.Printf("Switching to Speaking state, buffer size: %d", m.getBufferSize())
+				m.logger.Debug("Switching to Speaking state, buffer size: %d", m.getBufferSize())
 				m.setState(StateSpeaking)
 			case StateSpeaking:
-				// 播放音频数据
-				log.Printf("Playing audio data")
-
-				// 持续播放直到没有更多数据
-				for {
-					// 等待输出速率限制
-					<-m.outputTicker.C
-
-					audioData, err := m.getAudioData()
-					if err != nil {
-						log.Printf("Error getting audio data: %v", err)
-						continue
-					}
-
-					// 如果没有数据，退出循环
-					if audioData == nil {
-						break
-					}
-
-					// TODO: 更新为使用新的 AudioOutput API
-					// if err := output.PlaySamples(audioData); err != nil {
-					//	log.Printf("Error playing audio chunk: %v", err)
-					// }
-					log.Printf("Audio chunk ready for playback (length: %d)", len(audioData))
-				}
-
-				// 重置临时文件
-				if err := m.resetTempFile(); err != nil {
-					log.Printf("Error resetting temp file: %v", err)
-				}
-
-				log.Printf("Switching back to Idle state")
-				m.setState(StateIdle)
+				m.playBuffered(ctx, output)
+			}
+		}
+	}
+}
+
+// playBuffered 把缓冲的音频逐块送入播放队列，等待播放完毕后回到 Idle；output 为 nil 时只清空缓冲
+func (m *Manager) playBuffered(ctx context.Context, output PlaybackSink) {
+	m.logger.Debug("Playing audio data")
+
+	// 持续送入直到没有更多数据
+	for {
+		// 等待输出速率限制
+		if m.outputTicker != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.outputTicker.C:
+			}
+		}
+
+		audioData, err := m.getAudioData()
+		if err != nil {
+			m.logger.Error("failed to get audio data: %v", err)
+			break
+		}
+
+		// 如果没有数据，退出循环
+		if audioData == nil {
+			break
+		}
+
+		if output != nil {
+			if err := output.Enqueue(audioData); err != nil {
+				m.logger.Error("failed to enqueue audio chunk: %v", err)
+				break
+			}
+		}
+	}
+
+	if output != nil {
+		if err := output.Drain(ctx); err != nil {
+			m.logger.Error("failed to drain playback: %v", err)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+
+	// 重置临时文件和未播放的缓冲
+	m.mu.Lock()
+	m.memBuffer = m.memBuffer[:0]
+	m.mu.Unlock()
+	if err := m.resetTempFile(); err != nil {
+		m.logger.Error("failed to reset temp file: %v", err)
+	}
+
+	m.logger.Debug("Switching back to Idle state")
+	m.setState(StateIdle)
+}
+
+// handleInput 处理 Idle 和 Listening 状态下读到的一块音频
+func (m *Manager) handleInput(data []float32) {
+	m.mu.Lock()
+	detector := m.detector
+	window := m.detectionWindowLocked()
+	m.mu.Unlock()
+
+	if detector == nil {
+		m.handleInputWithoutDetector(data)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	// 单块音频比检测器的最小语音时长短，在最近一段音频上检测，只看语音是否延续到这一块
+	segments, err := detector.GetSpeechSegments(window.Push(data), m.inputSampleRate())
+	if err != nil {
+		m.logger.Error("speech detection failed: %v", err)
+		return
+	}
+	hasSpeech := window.HasSpeech(segments)
+
+	switch m.getState() {
+	case StateIdle:
+		// 检测到语音才开始录音
+		if !hasSpeech {
+			return
+		}
+		m.resetSilence()
+		// 语音持续一段时间才能检测到，窗口中的音频都作为这句话的开头
+		preroll := window.Samples()
+		for len(preroll) > 0 {
+			n := min(len(preroll), maxChunkSize)
+			if err := m.addAudioData(preroll[:n]); err != nil {
+				m.logger.Error("failed to add audio data: %v", err)
+				return
 			}
+			preroll = preroll[n:]
+		}
+		m.logger.Debug("Speech detected, switching to Listening state")
+		m.setState(StateListening)
+	case StateListening:
+		// 录音中的静音也保留，直到静音足够长才结束这一句
+		if err := m.addAudioData(data); err != nil {
+			m.logger.Error("failed to add audio data: %v", err)
+			return
+		}
+		if m.updateSilence(len(data), hasSpeech) {
+			window.Reset()
+			m.logger.Debug("End of speech detected, switching to Processing state, buffer size: %d", m.getBufferSize())
+			m.setState(StateProcessing)
 		}
 	}
 }
 
+// handleInputWithoutDetector 未设置语音检测器时按缓冲区大小切换状态
+func (m *Manager) handleInputWithoutDetector(data []float32) {
+	switch m.getState() {
+	case StateIdle:
+		if len(data) > 0 {
+			if err := m.addAudioData(data); err != nil {
+				m.logger.Error("failed to add audio data: %v", err)
+				return
+			}
+
+			if m.getBufferSize() >= memBufferSize/2 {
+				m.logger.Debug("Switching to Listening state, buffer size: %d", m.getBufferSize())
+				m.setState(StateListening)
+			}
+		}
+	case StateListening:
+		if err := m.addAudioData(data); err != nil {
+			m.logger.Error("failed to add audio data: %v", err)
+			return
+		}
+
+		m.logger.Debug("Switching to Processing state, buffer size: %d", m.getBufferSize())
+		m.setState(StateProcessing)
+	}
+}
+
+// updateSilence 累计连续静音时长，返回静音是否已持续足够长
+func (m *Manager) updateSilence(samples int, hasSpeech bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hasSpeech {
+		m.silence = 0
+		return false
+	}
+	m.silence += time.Duration(samples) * time.Second / time.Duration(m.inputSampleRate())
+
+	minSilence := m.minSilence
+	if minSilence <= 0 {
+		minSilence = defaultMinSilenceDuration
+	}
+	return m.silence >= minSilence
+}
+
+// detectionWindowLocked 返回语音检测窗口，不存在时按配置创建（调用方需持有 m.mu）
+func (m *Manager) detectionWindowLocked() *vad.Window {
+	if m.window == nil {
+		duration := m.detectionWindow
+		if duration <= 0 {
+			duration = defaultDetectionWindow
+		}
+		m.window = vad.NewWindow(m.inputSampleRate(), int(duration/time.Millisecond))
+	}
+	return m.window
+}
+
+// resetSilence 新的一句话开始时清空静音计时
+func (m *Manager) resetSilence() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.silence = 0
+}
+
+// inputSampleRate 返回输入采样率，未配置时为 16000
+func (m *Manager) inputSampleRate() int {
+	if m.sampleRate <= 0 {
+		return defaultSampleRate
+	}
+	return m.sampleRate
+}
+
 func (m *Manager) getState() State {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -355,10 +651,52 @@ func (m *Manager) getState() State {
 func (m *Manager) setState(s State) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.setStateLocked(s)
+}
+
+// setStateLocked 切换状态并通知订阅者（调用方需持有 m.mu）
+func (m *Manager) setStateLocked(s State) {
 	oldState := m.currentState
 	m.currentState = s
 	if oldState != s {
-		log.Printf("State changed: %s -> %s", oldState, s)
+		m.logger.Debug("State changed: %s -> %s", oldState, s)
+		m.publish(StateChange{From: oldState, To: s, At: time.Now()})
+	}
+}
+
+// publish 非阻塞地把状态变化发给所有订阅者，缓冲已满的订阅者会错过此事件（调用方需持有 m.mu）
+func (m *Manager) publish(change StateChange) {
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- change:
+		default:
+			m.logger.Debug("State subscriber is full, dropping %s -> %s", change.From, change.To)
+		}
+	}
+}
+
+// Subscribe 订阅状态变化，只有状态真正改变时才会收到事件
+// 事件按顺序投递且不会阻塞状态切换，消费过慢时新事件被丢弃；不再需要时调用 Unsubscribe
+func (m *Manager) Subscribe() <-chan StateChange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan StateChange, subscriberBuffer)
+	if m.subscribers == nil {
+		m.subscribers = make(map[<-chan StateChange]chan StateChange)
+	}
+	m.subscribers[ch] = ch
+	return ch
+}
+
+// Unsubscribe 取消订阅并关闭通道，重复调用或传入未知通道时不做任何事
+func (m *Manager) Unsubscribe(ch <-chan StateChange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sub, ok := m.subscribers[ch]; ok {
+		delete(m.subscribers, ch)
+		close(sub)
 	}
 }
 
@@ -0,0 +1,397 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/vad"
+)
+
+func TestTempFileRoundTrip(t *testing.T) {
+	tempFile, err := os.CreateTemp(t.TempDir(), "audio_*.raw")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	m := &Manager{tempFile: tempFile}
+	defer m.cleanup()
+
+	// Ramp of fractional samples in [-1, 1]
+	samples := make([]float32, 1000)
+	for i := range samples {
+		samples[i] = -1.0 + 2.0*float32(i)/float32(len(samples)-1)
+	}
+
+	if err := m.writeToTempFile(samples); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	if _, err := tempFile.Seek(0, 0); err != nil {
+		t.Fatalf("Failed to seek temp file: %v", err)
+	}
+
+	data, err := m.readFromTempFile(len(samples))
+	if err != nil {
+		t.Fatalf("Failed to read temp file: %v", err)
+	}
+
+	if len(data) != len(samples) {
+		t.Fatalf("Expected %d samples, got %d", len(samples), len(data))
+	}
+	for i := range samples {
+		if math.Float32bits(data[i]) != math.Float32bits(samples[i]) {
+			t.Fatalf("Sample %d mismatch: expected %v, got %v", i, samples[i], data[i])
+		}
+	}
+}
+
+func TestManagerSetLogger(t *testing.T) {
+	tempFile, err := os.CreateTemp(t.TempDir(), "audio_*.raw")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer tempFile.Close()
+
+	recorder := logging.NewRecorder()
+	m := &Manager{tempFile: tempFile}
+	m.SetLogger(recorder)
+
+	m.setState(StateListening)
+	if err := m.addAudioData(make([]float32, maxChunkSize+1)); err != nil {
+		t.Fatalf("addAudioData failed: %v", err)
+	}
+
+	if !recorder.Contains(logging.LevelDebug, "State changed: Idle -> Listening") {
+		t.Errorf("Expected state change at debug level, got %+v", recorder.Entries())
+	}
+	if !recorder.Contains(logging.LevelWarn, "exceeds limit") {
+		t.Errorf("Expected oversized chunk warning, got %+v", recorder.Entries())
+	}
+}
+
+func TestManagerSubscribe(t *testing.T) {
+	m := &Manager{logger: logging.Discard()}
+	first := m.Subscribe()
+	second := m.Subscribe()
+
+	m.SetState(StateListening)
+	m.SetState(StateListening) // 状态未改变，不产生事件
+	m.SetState(StateProcessing)
+
+	for _, ch := range []<-chan StateChange{first, second} {
+		for _, expected := range []StateChange{{From: StateIdle, To: StateListening}, {From: StateListening, To: StateProcessing}} {
+			select {
+			case change := <-ch:
+				if change.From != expected.From || change.To != expected.To || change.At.IsZero() {
+					t.Errorf("Expected %s -> %s, got %+v", expected.From, expected.To, change)
+				}
+			default:
+				t.Fatalf("Expected %s -> %s to be delivered", expected.From, expected.To)
+			}
+		}
+		select {
+		case change := <-ch:
+			t.Errorf("Expected no event for an unchanged state, got %+v", change)
+		default:
+		}
+	}
+
+	m.Unsubscribe(first)
+	if _, ok := <-first; ok {
+		t.Error("Expected Unsubscribe to close the channel")
+	}
+	m.Unsubscribe(first) // 重复取消不会 panic
+
+	m.SetState(StateSpeaking)
+	if change := <-second; change.To != StateSpeaking {
+		t.Errorf("Expected remaining subscriber to receive Speaking, got %+v", change)
+	}
+}
+
+func TestManagerSlowSubscriber(t *testing.T) {
+	m := &Manager{logger: logging.Discard()}
+	slow := m.Subscribe()
+
+	// 没有人读取 slow，状态切换也不能被阻塞
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*4; i++ {
+			m.SetState(State(i%2 + 1))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected state changes not to block on a slow subscriber")
+	}
+
+	// 缓冲内保留最早的事件
+	if len(slow) != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", subscriberBuffer, len(slow))
+	}
+	if change := <-slow; change.From != StateIdle || change.To != StateListening {
+		t.Errorf("Expected the first buffered event to be Idle -> Listening, got %+v", change)
+	}
+}
+
+func TestManagerBufferLimit(t *testing.T) {
+	tempFile, err := os.CreateTemp(t.TempDir(), "audio_*.raw")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	m := &Manager{
+		tempFile:           tempFile,
+		logger:             logging.Discard(),
+		maxBufferedSamples: ManagerConfig{MaxBufferedDuration: 10 * time.Second, SampleRate: 1000}.maxBufferedSamples(),
+	}
+	defer m.cleanup()
+	changes := m.Subscribe()
+	m.SetState(StateListening)
+	<-changes
+
+	// 一共 10000 个样本，超出内存缓冲区的部分写入临时文件
+	chunk := make([]float32, 50)
+	for i := 0; i < 200; i++ {
+		if err := m.addAudioData(chunk); err != nil {
+			t.Fatalf("Chunk %d rejected before the limit: %v", i, err)
+		}
+	}
+	if m.stats.DroppedChunks == 0 {
+		t.Fatal("Expected chunks to spill to the temp file")
+	}
+
+	// 再多一个样本就超过上限
+	if err := m.addAudioData(chunk[:1]); !errors.Is(err, ErrBufferLimit) {
+		t.Fatalf("Expected ErrBufferLimit, got %v", err)
+	}
+	if m.GetState() != StateProcessing {
+		t.Errorf("Expected the guard to force Processing, got %s", m.GetState())
+	}
+	if change := <-changes; change.From != StateListening || change.To != StateProcessing {
+		t.Errorf("Expected Listening -> Processing to be published, got %+v", change)
+	}
+	info, _ := tempFile.Stat()
+	if m.stats.RejectedChunks != 1 || info.Size() != m.stats.TotalBytesWritten {
+		t.Errorf("Expected the rejected chunk not to grow the temp file, got %+v, size %d", m.stats, info.Size())
+	}
+
+	// 取出数据后重新有空间
+	if _, err := m.getAudioData(); err != nil {
+		t.Fatalf("getAudioData failed: %v", err)
+	}
+	if err := m.addAudioData(chunk); err != nil {
+		t.Errorf("Expected room after draining a chunk, got %v", err)
+	}
+
+	// 上限为 0 时不限制
+	if limit := (ManagerConfig{}).maxBufferedSamples(); limit != 0 {
+		t.Errorf("Expected no limit for a zero duration, got %d", limit)
+	}
+	if limit := (ManagerConfig{MaxBufferedDuration: time.Second}).maxBufferedSamples(); limit != defaultSampleRate {
+		t.Errorf("Expected the default sample rate, got %d", limit)
+	}
+}
+
+// fakeDetector 按顺序返回预设的检测结果
+type fakeDetector struct {
+	results []bool
+	err     error
+	calls   int
+}
+
+func (d *fakeDetector) GetSpeechSegments(audioData []float32, sampleRate int) ([]vad.SpeechSegment, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	result := d.results[d.calls%len(d.results)]
+	d.calls++
+	if !result {
+		return nil, nil
+	}
+	// 语音覆盖整个窗口
+	end := float64(len(audioData)) / float64(sampleRate)
+	return []vad.SpeechSegment{{Start: 0, End: end, Duration: end}}, nil
+}
+
+func TestManagerSpeechDetector(t *testing.T) {
+	tempFile, err := os.CreateTemp(t.TempDir(), "audio_*.raw")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	m := &Manager{tempFile: tempFile, logger: logging.Discard(), sampleRate: 1000, minSilence: 300 * time.Millisecond}
+	defer m.cleanup()
+
+	// 每块 100ms：静音、语音、语音、短暂停顿、语音，然后持续静音
+	detector := &fakeDetector{results: []bool{false, true, true, false, false, true, false, false, false, false}}
+	m.SetSpeechDetector(detector)
+	chunk := make([]float32, 100)
+
+	m.handleInput(chunk)
+	if m.GetState() != StateIdle || m.getBufferSize() != 0 {
+		t.Fatalf("Expected silence in Idle to be ignored, got %s with %d chunks", m.GetState(), m.getBufferSize())
+	}
+
+	m.handleInput(chunk)
+	if m.GetState() != StateListening {
+		t.Fatalf("Expected speech to start Listening, got %s", m.GetState())
+	}
+
+	// 短于 300ms 的停顿不结束录音
+	for i := 0; i < 4; i++ {
+		m.handleInput(chunk)
+	}
+	if m.GetState() != StateListening {
+		t.Fatalf("Expected a short pause to keep Listening, got %s", m.GetState())
+	}
+
+	m.handleInput(chunk)
+	m.handleInput(chunk)
+	if m.GetState() != StateListening {
+		t.Fatalf("Expected 200ms of silence to keep Listening, got %s", m.GetState())
+	}
+	m.handleInput(chunk)
+	if m.GetState() != StateProcessing {
+		t.Fatalf("Expected 300ms of silence to end the utterance, got %s", m.GetState())
+	}
+	if m.getBufferSize() != 8 {
+		t.Errorf("Expected every chunk after speech started to be buffered, got %d", m.getBufferSize())
+	}
+}
+
+// localSegments 让本地检测器满足 SpeechDetector
+type localSegments struct {
+	*vad.LocalDetector
+}
+
+func (d localSegments) GetSpeechSegments(audioData []float32, sampleRate int) ([]vad.SpeechSegment, error) {
+	return d.Detect(audioData, sampleRate), nil
+}
+
+func TestManagerSpeechDetectorShortChunks(t *testing.T) {
+	tempFile, err := os.CreateTemp(t.TempDir(), "audio_*.raw")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	m := &Manager{tempFile: tempFile, logger: logging.Discard(), minSilence: 500 * time.Millisecond}
+	defer m.cleanup()
+
+	// 64ms 的块比 500ms 的最小语音时长短，只有在检测窗口上才能检测到语音
+	localConfig := vad.DefaultLocalDetectorConfig()
+	localConfig.MinSpeechDurationMs = 500
+	m.SetSpeechDetector(localSegments{vad.NewLocalDetector(localConfig)})
+	silence := make([]float32, 1024)
+	speech := make([]float32, 1024)
+	for i := range speech {
+		speech[i] = 0.3 * float32(math.Sin(2*math.Pi*220*float64(i)/16000))
+	}
+
+	for i := 0; i < 5; i++ {
+		m.handleInput(silence)
+	}
+	chunks := 0
+	for m.GetState() == StateIdle && chunks < 20 {
+		m.handleInput(speech)
+		chunks++
+	}
+	if m.GetState() != StateListening || chunks < 8 {
+		t.Fatalf("Expected Listening after about 500ms of speech, got %s after %d chunks", m.GetState(), chunks)
+	}
+	// 录音开头包含检测到语音之前的窗口
+	if got := m.bufferedSamples; got < int64(chunks*1024) {
+		t.Errorf("Expected the speech onset to be buffered, got %d samples for %d speech chunks", got, chunks)
+	}
+
+	for i := 0; i < 20 && m.GetState() == StateListening; i++ {
+		m.handleInput(silence)
+	}
+	if m.GetState() != StateProcessing {
+		t.Errorf("Expected silence to end the utterance, got %s", m.GetState())
+	}
+}
+
+func TestManagerSpeechDetectorError(t *testing.T) {
+	m := &Manager{logger: logging.Discard()}
+	m.SetSpeechDetector(&fakeDetector{err: errors.New("vad unavailable")})
+	m.SetState(StateListening)
+
+	// 检测失败时保持当前状态，不丢弃录音
+	m.handleInput(make([]float32, 160))
+	if m.GetState() != StateListening || m.getBufferSize() != 0 {
+		t.Errorf("Expected detection errors to leave the state unchanged, got %s", m.GetState())
+	}
+
+	// 没有检测器时沿用旧逻辑，Listening 立即进入 Processing
+	m.SetSpeechDetector(nil)
+	m.handleInput(make([]float32, 160))
+	if m.GetState() != StateProcessing {
+		t.Errorf("Expected Processing without a detector, got %s", m.GetState())
+	}
+}
+
+// fakeSink 记录送入播放队列的样本
+type fakeSink struct {
+	samples []float32
+	drained int
+}
+
+func (s *fakeSink) Enqueue(samples []float32) error {
+	s.samples = append(s.samples, samples...)
+	return nil
+}
+
+func (s *fakeSink) Drain(ctx context.Context) error {
+	s.drained++
+	return nil
+}
+
+func TestManagerPlayBuffered(t *testing.T) {
+	tempFile, err := os.CreateTemp(t.TempDir(), "audio_*.raw")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	m := &Manager{tempFile: tempFile, logger: logging.Discard()}
+	defer m.cleanup()
+
+	// 超过内存缓冲区的块先溢出到临时文件，播放时仍要按录音顺序输出
+	const chunks, chunkSize = memBufferSize + 30, 64
+	for c := 0; c < chunks; c++ {
+		chunk := make([]float32, chunkSize)
+		for i := range chunk {
+			chunk[i] = float32(c*chunkSize + i)
+		}
+		if err := m.addAudioData(chunk); err != nil {
+			t.Fatalf("addAudioData failed: %v", err)
+		}
+	}
+	m.SetState(StateSpeaking)
+
+	sink := &fakeSink{}
+	m.playBuffered(context.Background(), sink)
+
+	if len(sink.samples) != chunks*chunkSize {
+		t.Fatalf("Expected %d samples to reach the sink, got %d", chunks*chunkSize, len(sink.samples))
+	}
+	for i, v := range sink.samples {
+		if v != float32(i) {
+			t.Fatalf("Sample %d out of order: got %v", i, v)
+		}
+	}
+	if sink.drained != 1 {
+		t.Errorf("Expected playback to be drained once, got %d", sink.drained)
+	}
+	if m.GetState() != StateIdle || m.getBufferSize() != 0 || m.bufferedSamples != 0 {
+		t.Errorf("Expected an empty buffer and Idle after playback, got %s with %d samples", m.GetState(), m.bufferedSamples)
+	}
+
+	// 下一轮只播放新录入的数据
+	m.addAudioData([]float32{1, 2, 3})
+	sink.samples = nil
+	m.playBuffered(context.Background(), sink)
+	if len(sink.samples) != 3 {
+		t.Errorf("Expected only the new chunk to play, got %d samples", len(sink.samples))
+	}
+}
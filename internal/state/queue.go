@@ -0,0 +1,96 @@
+package state
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"audio-assistant/internal/logging"
+)
+
+const (
+	// 默认输入队列长度（块数），1024 帧一块时约 2 秒
+	DefaultInputQueueSize = 32
+	// 读取失败后重试前的等待时间，避免设备异常时空转
+	inputRetryDelay = 10 * time.Millisecond
+)
+
+// ChunkReader 音频输入接口，*audio.Input 满足该接口，Read 阻塞到下一块数据就绪
+type ChunkReader interface {
+	Read() ([]float32, error)
+}
+
+// InputQueue 输入读取队列：独立协程按设备节奏阻塞读取，数据放入有界通道交给消费者
+// 消费者跟不上时丢弃最旧的块并计数，内存占用不超过 size 块
+type InputQueue struct {
+	reader  ChunkReader
+	chunks  chan []float32
+	dropped atomic.Int64
+	logger  logging.Logger
+}
+
+// NewInputQueue 创建输入读取队列，size 小于 1 时使用 DefaultInputQueueSize，logger 为 nil 时使用标准 log 包
+func NewInputQueue(reader ChunkReader, size int, logger logging.Logger) *InputQueue {
+	if size < 1 {
+		size = DefaultInputQueueSize
+	}
+	return &InputQueue{
+		reader: reader,
+		chunks: make(chan []float32, size),
+		logger: logging.OrStd(logger),
+	}
+}
+
+// Chunks 返回读取到的音频块，Run 退出后通道被关闭
+func (q *InputQueue) Chunks() <-chan []float32 {
+	return q.chunks
+}
+
+// Dropped 返回因消费者跟不上而丢弃的块数
+func (q *InputQueue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Run 持续读取输入直到 ctx 取消，应在单独的协程中调用
+// Read 没有上下文参数，取消后要等当前这次读取返回才会退出
+func (q *InputQueue) Run(ctx context.Context) {
+	defer close(q.chunks)
+
+	for ctx.Err() == nil {
+		data, err := q.reader.Read()
+		if err != nil {
+			q.logger.Error("failed to read audio: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(inputRetryDelay):
+			}
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		q.push(data)
+	}
+}
+
+// push 非阻塞地放入一块数据，队列已满时先丢弃最旧的块
+func (q *InputQueue) push(data []float32) {
+	select {
+	case q.chunks <- data:
+		return
+	default:
+	}
+
+	select {
+	case <-q.chunks:
+		q.dropped.Add(1)
+	default:
+		// 消费者刚好取走了一块，已有空位
+	}
+
+	select {
+	case q.chunks <- data:
+	default:
+		q.dropped.Add(1)
+	}
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/logging"
+)
+
+// fastReader 立即返回编号递增的音频块，读完 total 块后阻塞直到 release 被关闭
+type fastReader struct {
+	total   int64
+	reads   atomic.Int64
+	release chan struct{}
+}
+
+func (r *fastReader) Read() ([]float32, error) {
+	n := r.reads.Add(1)
+	if n > r.total {
+		<-r.release
+		return nil, errors.New("input closed")
+	}
+	return []float32{float32(n)}, nil
+}
+
+func TestInputQueueSlowConsumer(t *testing.T) {
+	const total, size = 500, 8
+	reader := &fastReader{total: total, release: make(chan struct{})}
+	queue := NewInputQueue(reader, size, logging.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+
+	// 慢速消费者，期间队列长度不能超过上限
+	var consumed [][]float32
+	for len(consumed) < 5 {
+		if pending := len(queue.chunks); pending > size {
+			t.Fatalf("Expected at most %d queued chunks, got %d", size, pending)
+		}
+		consumed = append(consumed, <-queue.Chunks())
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 等生产者读完全部数据
+	deadline := time.Now().Add(2 * time.Second)
+	for reader.reads.Load() <= total {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the producer to finish reading")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var last float32
+	for len(queue.chunks) > 0 {
+		chunk := <-queue.Chunks()
+		last = chunk[0]
+		consumed = append(consumed, chunk)
+	}
+
+	// 每一块要么被消费要么被计为丢弃
+	if dropped := queue.Dropped(); dropped != total-int64(len(consumed)) {
+		t.Errorf("Expected %d dropped chunks, got %d", total-int64(len(consumed)), dropped)
+	}
+	if queue.Dropped() == 0 {
+		t.Error("Expected a slow consumer to cause drops")
+	}
+	// 丢弃的是最旧的块，最新的块保留
+	if last != total {
+		t.Errorf("Expected the newest chunk to be kept, got %v", last)
+	}
+	for i := 1; i < len(consumed); i++ {
+		if consumed[i][0] <= consumed[i-1][0] {
+			t.Fatalf("Expected chunks in order, got %v after %v", consumed[i][0], consumed[i-1][0])
+		}
+	}
+
+	cancel()
+	close(reader.release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return after cancel")
+	}
+	if _, ok := <-queue.Chunks(); ok {
+		t.Error("Expected the channel to be closed after Run returns")
+	}
+}
+
+// errorReader 总是读取失败
+type errorReader struct {
+	reads atomic.Int64
+}
+
+func (r *errorReader) Read() ([]float32, error) {
+	r.reads.Add(1)
+	return nil, errors.New("device unavailable")
+}
+
+func TestInputQueueReadError(t *testing.T) {
+	reader := &errorReader{}
+	recorder := logging.NewRecorder()
+	queue := NewInputQueue(reader, 0, recorder)
+	if cap(queue.chunks) != DefaultInputQueueSize {
+		t.Errorf("Expected default size %d, got %d", DefaultInputQueueSize, cap(queue.chunks))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	queue.Run(ctx)
+
+	// 读取失败后等待重试，不会空转
+	if reads := reader.reads.Load(); reads == 0 || reads > 10 {
+		t.Errorf("Expected a few retries spaced by %v, got %d reads", inputRetryDelay, reads)
+	}
+	if !recorder.Contains(logging.LevelError, "device unavailable") {
+		t.Errorf("Expected read errors to be logged, got %+v", recorder.Entries())
+	}
+}
@@ -0,0 +1,228 @@
+package tts
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lruCache is a size-bounded LRU cache for synthesized audio
+// It is not safe for concurrent use, callers must hold TTSService.mu
+type lruCache struct {
+	maxBytes  int64 // 0 means unlimited
+	usedBytes int64
+	evictions int64
+	order     *list.List // front = most recently used
+	entries   map[string]*list.Element
+}
+
+// lruEntry is a single cached audio entry
+type lruEntry struct {
+	key  string
+	data []byte
+}
+
+// newLRUCache creates a new LRU cache bounded by maxBytes
+func newLRUCache(maxBytes int64) *lruCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns cached data and marks the entry as recently used
+func (c *lruCache) get(key string) ([]byte, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).data, true
+}
+
+// put adds or replaces an entry, evicting least recently used entries until it fits
+func (c *lruCache) put(key string, data []byte) {
+	size := int64(len(data))
+
+	// Entries larger than the whole cache are never stored
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+
+	for c.maxBytes > 0 && c.usedBytes+size > c.maxBytes {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeElement(oldest)
+		c.evictions++
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, data: data})
+	c.usedBytes += size
+}
+
+// remove deletes an entry if present
+func (c *lruCache) remove(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// setMaxBytes changes the size limit, evicting entries if the cache no longer fits
+func (c *lruCache) setMaxBytes(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes > 0 && c.usedBytes > c.maxBytes {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+}
+
+// clear removes all entries
+func (c *lruCache) clear() {
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.usedBytes = 0
+}
+
+// len returns the number of cached entries
+func (c *lruCache) len() int {
+	return len(c.entries)
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.usedBytes -= int64(len(entry.data))
+}
+
+// diskCacheMeta is the sidecar metadata stored next to each cached audio file
+type diskCacheMeta struct {
+	Text      string    `json:"text"`
+	Voice     string    `json:"voice"`
+	Model     string    `json:"model"`
+	Speed     float64   `json:"speed"`
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// diskCache persists synthesized audio as <key>.audio files with <key>.json sidecars
+type diskCache struct {
+	dir string
+}
+
+// diskCacheEntry is an entry found while scanning the cache directory
+type diskCacheEntry struct {
+	key     string
+	modTime time.Time
+}
+
+// newDiskCache creates a disk-backed audio cache in dir
+func newDiskCache(dir string) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+	return &diskCache{dir: dir}, nil
+}
+
+func (c *diskCache) audioPath(key string) string {
+	return filepath.Join(c.dir, key+".audio")
+}
+
+func (c *diskCache) metaPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// get reads cached audio for key, if any
+// An entry whose sidecar is missing or records another format is a miss, so the caller never
+// labels audio with the wrong format; an empty format accepts any entry
+func (c *diskCache) get(key, format string) ([]byte, bool) {
+	if format != "" {
+		metaData, err := os.ReadFile(c.metaPath(key))
+		if err != nil {
+			return nil, false
+		}
+		var meta diskCacheMeta
+		if err := json.Unmarshal(metaData, &meta); err != nil || meta.Format != format {
+			return nil, false
+		}
+	}
+
+	data, err := os.ReadFile(c.audioPath(key))
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// put writes audio and its sidecar metadata for key
+func (c *diskCache) put(key string, audioData []byte, meta diskCacheMeta) error {
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache metadata: %w", err)
+	}
+
+	// Write to temp files first so a crash never leaves a truncated entry
+	if err := writeFileAtomic(c.audioPath(key), audioData); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := writeFileAtomic(c.metaPath(key), metaData); err != nil {
+		return fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the audio file and sidecar for key
+func (c *diskCache) remove(key string) {
+	os.Remove(c.audioPath(key))
+	os.Remove(c.metaPath(key))
+}
+
+// entries lists cached entries, oldest first
+func (c *diskCache) entries() ([]diskCacheEntry, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []diskCacheEntry
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || filepath.Ext(name) != ".audio" {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, diskCacheEntry{
+			key:     strings.TrimSuffix(name, ".audio"),
+			modTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	return entries, nil
+}
+
+// writeFileAtomic writes data to a temp file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
@@ -8,8 +8,21 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"audio-assistant/internal/retry"
 )
 
+// TTSInterface is implemented by speech synthesis backends the assistant can switch between
+type TTSInterface interface {
+	SynthesizeText(ctx context.Context, text string, format string) ([]byte, error)
+	ValidateAPIKey(ctx context.Context) error
+}
+
+// VoiceSynthesizer is implemented by backends that can switch voice for a single call
+type VoiceSynthesizer interface {
+	SynthesizeTextWithVoice(ctx context.Context, text string, format string, voice string) ([]byte, error)
+}
+
 // TTSClient represents a Text-to-Speech client for OpenAI TTS API
 type TTSClient struct {
 	apiKey     string
@@ -52,6 +65,12 @@ const (
 	ModelTTS1HD = "tts-1-hd"
 )
 
+// modelPrices holds the API price of each model in USD per million characters
+var modelPrices = map[string]float64{
+	ModelTTS1:   15.00,
+	ModelTTS1HD: 30.00,
+}
+
 // Available voices
 const (
 	VoiceAlloy   = "alloy"
@@ -77,12 +96,11 @@ func NewTTSClient(apiKey string) *TTSClient {
 	return &TTSClient{
 		apiKey:  apiKey,
 		baseURL: "https://api.openai.com/v1",
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second, // TTS can take longer
-		},
-		model: ModelTTS1,
-		voice: VoiceAlloy,
-		speed: 1.0,
+		// TTS can take longer
+		httpClient: retry.NewClient(60*time.Second, retry.DefaultMaxRetries, retry.DefaultBaseDelay),
+		model:      ModelTTS1,
+		voice:      VoiceAlloy,
+		speed:      1.0,
 	}
 }
 
@@ -106,6 +124,22 @@ func (c *TTSClient) SetSpeed(speed float64) {
 	c.speed = speed
 }
 
+// SetRetryPolicy sets how transient API failures (429/5xx) are retried
+func (c *TTSClient) SetRetryPolicy(maxRetries int, baseDelay time.Duration) {
+	retry.SetPolicy(c.httpClient, maxRetries, baseDelay)
+}
+
+// SetTransport sends requests through transport (e.g. a proxy or custom TLS config), keeping the retry policy
+// By default http.DefaultTransport is used, which honors HTTP_PROXY and HTTPS_PROXY
+func (c *TTSClient) SetTransport(transport http.RoundTripper) {
+	retry.SetBaseTransport(c.httpClient, transport)
+}
+
+// SetHTTPClient replaces the HTTP client, which is used as is (its own timeout and retries apply)
+func (c *TTSClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
 // GetConfig returns current TTS configuration
 func (c *TTSClient) GetConfig() TTSConfig {
 	return TTSConfig{
@@ -126,23 +160,89 @@ func (c *TTSClient) ValidateAPIKey(ctx context.Context) error {
 	return nil
 }
 
+// Ping checks that the API is reachable and the key is accepted without synthesizing audio
+func (c *TTSClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("invalid API key")
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ping failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // SynthesizeText converts text to speech and returns audio data
 func (c *TTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
-	if text == "" {
+	return c.SynthesizeRequest(ctx, TTSRequest{Input: text, ResponseFormat: format})
+}
+
+// SynthesizeTextWithVoice converts text to speech with a per-call voice, an empty voice uses the client setting
+func (c *TTSClient) SynthesizeTextWithVoice(ctx context.Context, text string, format string, voice string) ([]byte, error) {
+	return c.SynthesizeRequest(ctx, TTSRequest{Input: text, ResponseFormat: format, Voice: voice})
+}
+
+// SynthesizeRequest sends a speech request with per-call settings
+// Empty Model and Voice and a zero Speed fall back to the client settings
+func (c *TTSClient) SynthesizeRequest(ctx context.Context, request TTSRequest) ([]byte, error) {
+	resp, err := c.sendSpeechRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, nil
+}
+
+// SynthesizeTextStream converts text to speech and returns the audio stream as it arrives
+// The caller must close the returned reader. Cancelling ctx aborts the read mid-stream.
+func (c *TTSClient) SynthesizeTextStream(ctx context.Context, text string, format string) (io.ReadCloser, error) {
+	resp, err := c.sendSpeechRequest(ctx, TTSRequest{Input: text, ResponseFormat: format})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// sendSpeechRequest sends a speech request and returns the successful HTTP response
+func (c *TTSClient) sendSpeechRequest(ctx context.Context, request TTSRequest) (*http.Response, error) {
+	if request.Input == "" {
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
 	// Validate text length (OpenAI TTS has a 4096 character limit)
-	if len(text) > 4096 {
-		return nil, fmt.Errorf("text too long: %d characters (max 4096)", len(text))
+	if len(request.Input) > 4096 {
+		return nil, fmt.Errorf("text too long: %d characters (max 4096)", len(request.Input))
 	}
 
-	request := TTSRequest{
-		Model:          c.model,
-		Input:          text,
-		Voice:          c.voice,
-		ResponseFormat: format,
-		Speed:          c.speed,
+	if request.Model == "" {
+		request.Model = c.model
+	}
+	if request.Voice == "" {
+		request.Voice = c.voice
+	}
+	if request.Speed == 0 {
+		request.Speed = c.speed
 	}
 
 	reqBody, err := json.Marshal(request)
@@ -163,24 +263,25 @@ func (c *TTSClient) SynthesizeText(ctx context.Context, text string, format stri
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
 		var errorResp ErrorResponse
 		if err := json.Unmarshal(body, &errorResp); err != nil {
-			return nil, fmt.Errorf("TTS request failed with status %d: %s",
-				resp.StatusCode, string(body))
+			return nil, retry.NewStatusError(resp.StatusCode, fmt.Errorf("TTS request failed with status %d: %s",
+				resp.StatusCode, string(body)))
 		}
-		return nil, fmt.Errorf("TTS request failed: %s (type: %s, code: %s)",
-			errorResp.Error.Message, errorResp.Error.Type, errorResp.Error.Code)
+		return nil, retry.NewStatusError(resp.StatusCode, fmt.Errorf("TTS request failed: %s (type: %s, code: %s)",
+			errorResp.Error.Message, errorResp.Error.Type, errorResp.Error.Code))
 	}
 
-	return body, nil
+	return resp, nil
 }
 
 // SynthesizeToFile converts text to speech and saves to file
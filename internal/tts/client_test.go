@@ -2,7 +2,12 @@ package tts
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -174,3 +179,166 @@ func TestTTSClientCharacterCount(t *testing.T) {
 
 	t.Log("✓ Character count tests passed")
 }
+
+func TestTTSClientRetriesRateLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+			return
+		}
+		w.Write([]byte("audio-bytes"))
+	}))
+	defer server.Close()
+
+	client := NewTTSClient("test-key")
+	client.baseURL = server.URL
+	client.SetRetryPolicy(3, time.Millisecond)
+
+	audioData, err := client.SynthesizeText(context.Background(), "你好", FormatMP3)
+	if err != nil {
+		t.Fatalf("Expected synthesis to succeed after retries: %v", err)
+	}
+	if string(audioData) != "audio-bytes" {
+		t.Errorf("Unexpected audio data: %q", audioData)
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+
+	t.Log("✓ Retry tests passed")
+}
+
+func TestTTSClientPing(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewTTSClient("test-key")
+	client.baseURL = server.URL
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Expected ping to succeed: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "GET /models" {
+		t.Errorf("Expected a single models request, got %v", paths)
+	}
+
+	client.apiKey = "wrong-key"
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected ping to fail with an invalid key")
+	}
+
+	t.Log("✓ Ping tests passed")
+}
+
+// recordingTransport records outbound request URLs and answers 200
+type recordingTransport struct {
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestTTSClientCustomTransport(t *testing.T) {
+	transport := &recordingTransport{}
+	client := NewTTSClient("test-key")
+	client.SetTransport(transport)
+	client.SetRetryPolicy(0, time.Millisecond) // must keep the custom transport
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if _, err := client.SynthesizeText(context.Background(), "你好", FormatMP3); err != nil {
+		t.Fatalf("SynthesizeText failed: %v", err)
+	}
+
+	expected := []string{"https://api.openai.com/v1/models", "https://api.openai.com/v1/audio/speech"}
+	if len(transport.urls) != 2 || transport.urls[0] != expected[0] || transport.urls[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, transport.urls)
+	}
+
+	t.Log("✓ Custom transport tests passed")
+}
+
+func TestTTSClientSynthesizeTextStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first-chunk"))
+		w.(http.Flusher).Flush()
+		// Hold the rest of the body until the test has the stream in hand
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte("second-chunk"))
+	}))
+	defer server.Close()
+
+	client := NewTTSClient("test-key")
+	client.baseURL = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.SynthesizeTextStream(ctx, "你好", FormatPCM)
+	if err != nil {
+		t.Fatalf("SynthesizeTextStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	// The stream is returned while the server is still holding the body
+	first := make([]byte, len("first-chunk"))
+	if _, err := io.ReadFull(stream, first); err != nil || string(first) != "first-chunk" {
+		t.Fatalf("Expected the first chunk before the body completes, got %q, %v", first, err)
+	}
+	close(release)
+	rest, err := io.ReadAll(stream)
+	if err != nil || string(rest) != "second-chunk" {
+		t.Errorf("Expected the rest of the body, got %q, %v", rest, err)
+	}
+}
+
+func TestTTSClientSynthesizeTextStreamCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first-chunk"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewTTSClient("test-key")
+	client.baseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.SynthesizeTextStream(ctx, "你好", FormatPCM)
+	if err != nil {
+		t.Fatalf("SynthesizeTextStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	first := make([]byte, len("first-chunk"))
+	if _, err := io.ReadFull(stream, first); err != nil {
+		t.Fatalf("Failed to read the first chunk: %v", err)
+	}
+
+	// Cancelling ctx aborts the blocked read
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = io.ReadAll(stream)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the read to stop with context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected cancellation to abort the read promptly, took %v", elapsed)
+	}
+}
@@ -0,0 +1,137 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Placeholders replaced in CommandTTSConfig.Args on every call
+const (
+	PlaceholderText  = "{text}"
+	PlaceholderVoice = "{voice}"
+)
+
+// CommandTTSConfig configures a TTS backend that runs a local program
+type CommandTTSConfig struct {
+	Command string        // Binary path or name looked up in PATH, e.g. piper or espeak-ng
+	Args    []string      // Argument template, {text} and {voice} are substituted per call
+	Voice   string        // Substituted for {voice}, typically a model file or voice name
+	Timeout time.Duration // Limit for one synthesis, 0 relies on the caller's context only
+}
+
+// DefaultCommandTTSConfig returns a configuration for piper, which reads text on stdin and writes WAV to stdout
+func DefaultCommandTTSConfig() CommandTTSConfig {
+	return CommandTTSConfig{
+		Command: "piper",
+		Args:    []string{"--model", PlaceholderVoice, "--output_file", "-"},
+		Timeout: 60 * time.Second,
+	}
+}
+
+// CommandTTSClient synthesizes speech offline by running a local command
+// The text is written to the command's stdin and WAV audio is read from its stdout
+type CommandTTSClient struct {
+	config CommandTTSConfig
+}
+
+// NewCommandTTSClient creates a command TTS client
+func NewCommandTTSClient(config CommandTTSConfig) (*CommandTTSClient, error) {
+	if config.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	return &CommandTTSClient{config: config}, nil
+}
+
+// SynthesizeText runs the command for text, only WAV output is supported
+func (c *CommandTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if format != "" && format != FormatWAV {
+		return nil, fmt.Errorf("unsupported format for command TTS: %s (only %s)", format, FormatWAV)
+	}
+
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.config.Command, c.args(text)...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Stop waiting for output held open by children of a killed command
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("%s interrupted: %w", c.config.Command, ctxErr)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s exited with code %d: %s", c.config.Command, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to run %s: %w", c.config.Command, err)
+	}
+
+	audioData := stdout.Bytes()
+	if len(audioData) < 12 || !bytes.Equal(audioData[:4], []byte("RIFF")) {
+		return nil, fmt.Errorf("%s did not produce WAV audio (%d bytes)", c.config.Command, len(audioData))
+	}
+	return audioData, nil
+}
+
+// SynthesizeTextWithVoice runs the command with voice substituted for {voice}, an empty voice uses the configured one
+func (c *CommandTTSClient) SynthesizeTextWithVoice(ctx context.Context, text string, format string, voice string) ([]byte, error) {
+	if voice == "" {
+		return c.SynthesizeText(ctx, text, format)
+	}
+	config := c.config
+	config.Voice = voice
+	return (&CommandTTSClient{config: config}).SynthesizeText(ctx, text, format)
+}
+
+// ValidateAPIKey checks that the command can be found, there is no key to validate
+func (c *CommandTTSClient) ValidateAPIKey(ctx context.Context) error {
+	if _, err := exec.LookPath(c.config.Command); err != nil {
+		return fmt.Errorf("TTS command not available: %w", err)
+	}
+	return nil
+}
+
+// GetAvailableVoices returns nil, any voice the command understands is accepted
+func (c *CommandTTSClient) GetAvailableVoices() []string {
+	return nil
+}
+
+// GetAvailableModels returns nil, the model is chosen through the voice
+func (c *CommandTTSClient) GetAvailableModels() []string {
+	return nil
+}
+
+// GetAvailableFormats returns the only format the command produces
+func (c *CommandTTSClient) GetAvailableFormats() []string {
+	return []string{FormatWAV}
+}
+
+// GetConfig returns the client configuration
+func (c *CommandTTSClient) GetConfig() CommandTTSConfig {
+	return c.config
+}
+
+// args substitutes the placeholders in the argument template
+func (c *CommandTTSClient) args(text string) []string {
+	replacer := strings.NewReplacer(PlaceholderText, text, PlaceholderVoice, c.config.Voice)
+	args := make([]string, len(c.config.Args))
+	for i, arg := range c.config.Args {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
@@ -0,0 +1,100 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"audio-assistant/internal/audio"
+)
+
+// fixtureWAV writes a short WAV file and returns its path and contents
+func fixtureWAV(t *testing.T) (string, []byte) {
+	t.Helper()
+
+	data, err := audio.EncodeWAV(make([]float32, 1600), 16000)
+	if err != nil {
+		t.Fatalf("Failed to encode fixture: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.wav")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	return path, data
+}
+
+func TestCommandTTSClient(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("Skipping command TTS test: %v", err)
+	}
+	path, expected := fixtureWAV(t)
+
+	// The fake synthesizer checks the substituted text and stdin, then prints the fixture
+	client, err := NewCommandTTSClient(CommandTTSConfig{
+		Command: "sh",
+		Args:    []string{"-c", `test "$(cat)" = "$1" && cat "$0"`, PlaceholderVoice, PlaceholderText},
+		Voice:   path,
+	})
+	if err != nil {
+		t.Fatalf("NewCommandTTSClient failed: %v", err)
+	}
+
+	audioData, err := client.SynthesizeText(context.Background(), "你好 world", FormatWAV)
+	if err != nil {
+		t.Fatalf("SynthesizeText failed: %v", err)
+	}
+	if !bytes.Equal(audioData, expected) {
+		t.Errorf("Expected the fixture WAV (%d bytes), got %d bytes", len(expected), len(audioData))
+	}
+
+	if _, err := client.SynthesizeText(context.Background(), "hello", FormatMP3); err == nil {
+		t.Error("Expected error for non-WAV format")
+	}
+	if err := client.ValidateAPIKey(context.Background()); err != nil {
+		t.Errorf("Expected sh to be found: %v", err)
+	}
+
+	t.Log("✓ Command TTS client tests passed")
+}
+
+func TestCommandTTSClientErrors(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("Skipping command TTS test: %v", err)
+	}
+
+	if _, err := NewCommandTTSClient(CommandTTSConfig{}); err == nil {
+		t.Error("Expected error for missing command")
+	}
+
+	failing, _ := NewCommandTTSClient(CommandTTSConfig{Command: "sh", Args: []string{"-c", "echo voice not found >&2; exit 3"}})
+	_, err := failing.SynthesizeText(context.Background(), "hello", "")
+	if err == nil || !strings.Contains(err.Error(), "code 3") || !strings.Contains(err.Error(), "voice not found") {
+		t.Errorf("Expected exit code and stderr in error, got %v", err)
+	}
+
+	silent, _ := NewCommandTTSClient(CommandTTSConfig{Command: "sh", Args: []string{"-c", "echo not audio"}})
+	if _, err := silent.SynthesizeText(context.Background(), "hello", ""); err == nil {
+		t.Error("Expected error for non-WAV output")
+	}
+
+	slow, _ := NewCommandTTSClient(CommandTTSConfig{Command: "sh", Args: []string{"-c", "sleep 5"}, Timeout: 100 * time.Millisecond})
+	start := time.Now()
+	if _, err := slow.SynthesizeText(context.Background(), "hello", ""); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the command to be killed on timeout, took %v", elapsed)
+	}
+
+	missing, _ := NewCommandTTSClient(CommandTTSConfig{Command: "audio-assistant-missing-tts"})
+	if err := missing.ValidateAPIKey(context.Background()); err == nil {
+		t.Error("Expected error for missing command")
+	}
+
+	t.Log("✓ Command TTS error tests passed")
+}
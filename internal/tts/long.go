@@ -0,0 +1,163 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"audio-assistant/internal/audio"
+)
+
+// longTextGap is the silence inserted between chunks joined by SynthesizeLong, about a sentence pause
+const longTextGap = 300 * time.Millisecond
+
+// SynthesizeLongText converts text of any length to speech, splitting it at sentence boundaries
+// into chunks of at most MaxTextLength and concatenating the audio of each chunk
+// Only formats whose streams can be joined byte by byte are supported: mp3, aac, opus and pcm
+func (s *TTSService) SynthesizeLongText(ctx context.Context, text string) ([]byte, error) {
+	config := s.GetConfig()
+	if !isConcatenableFormat(config.OutputFormat) {
+		return nil, fmt.Errorf("output format %s cannot be concatenated", config.OutputFormat)
+	}
+
+	chunks := SplitText(text, config.MaxTextLength)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("text validation failed: text cannot be empty")
+	}
+
+	results, err := s.synthesizeChunks(ctx, chunks, SynthesizeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var audioData []byte
+	for _, data := range results {
+		audioData = append(audioData, data...)
+	}
+
+	return audioData, nil
+}
+
+// SynthesizeLong converts text of any length to one playable 16-bit mono WAV
+// Text is split at sentence and paragraph boundaries into chunks of at most MaxTextLength, each chunk is
+// synthesized in a decodable format (reusing the cache) and decoded, then the samples are joined at the
+// sample rate of the first chunk with a short pause between chunks
+func (s *TTSService) SynthesizeLong(ctx context.Context, text string) ([]byte, error) {
+	config := s.GetConfig()
+	chunks := SplitText(text, config.MaxTextLength)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("text validation failed: text cannot be empty")
+	}
+
+	results, err := s.synthesizeChunks(ctx, chunks, SynthesizeOptions{Format: s.PlaybackFormat()})
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := audio.NewAudioDecoder()
+	var samples []float32
+	sampleRate := 0
+	for i, data := range results {
+		chunkSamples, chunkRate, err := decoder.DecodeAudioData(data)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d/%d: failed to decode audio: %w", i+1, len(chunks), err)
+		}
+
+		if sampleRate == 0 {
+			sampleRate = chunkRate
+		} else {
+			// Cached chunks may come from another format or sample rate
+			if chunkRate != sampleRate {
+				chunkSamples = audio.ResampleLinear(chunkSamples, chunkRate, sampleRate)
+			}
+			samples = append(samples, make([]float32, int(longTextGap.Seconds()*float64(sampleRate)))...)
+		}
+		samples = append(samples, chunkSamples...)
+	}
+
+	return audio.EncodeWAV(samples, sampleRate)
+}
+
+// synthesizeChunks synthesizes chunks with up to ChunkWorkers requests in flight, results keep the chunk order
+// Cached chunks are taken without a worker, the first failure cancels the requests still running
+func (s *TTSService) synthesizeChunks(ctx context.Context, chunks []string, opts SynthesizeOptions) ([][]byte, error) {
+	config := s.GetConfig()
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(),
+			time.Duration(config.DefaultTimeout)*time.Second)
+		defer cancel()
+	}
+
+	results := make([][]byte, len(chunks))
+	resolved := s.resolveOptions(opts)
+	var pending []int
+	for i, chunk := range chunks {
+		if s.cacheEnabled {
+			if audioData := s.getCachedAudioFor(chunk, resolved); audioData != nil {
+				results[i] = audioData
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	jobs := make(chan int)
+	for w := 0; w < min(max(config.ChunkWorkers, 1), len(pending)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				audioData, err := s.SynthesizeTextWithOptions(workCtx, chunks[i], opts)
+				if err != nil {
+					failOnce.Do(func() {
+						firstErr = fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+						cancel()
+					})
+					continue
+				}
+				results[i] = audioData
+			}
+		}()
+	}
+
+feed:
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-workCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("synthesis failed: %w", err)
+	}
+	return results, nil
+}
+
+// isConcatenableFormat reports whether audio in format stays playable when files are appended
+func isConcatenableFormat(format string) bool {
+	switch format {
+	case FormatMP3, FormatAAC, FormatOpus, FormatPCM:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"audio-assistant/internal/audio"
+)
+
+// wavTransport answers speech requests with WAV audio of one sample per input byte
+// Odd requests use a different sample rate to exercise resampling when joining
+type wavTransport struct {
+	requests []TTSRequest
+}
+
+func (t *wavTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var request TTSRequest
+	json.NewDecoder(req.Body).Decode(&request)
+	t.requests = append(t.requests, request)
+
+	rate := 16000
+	samples := make([]float32, len(request.Input))
+	if len(t.requests)%2 == 0 {
+		rate = 8000
+		samples = samples[:len(samples)/2]
+	}
+	for i := range samples {
+		samples[i] = 0.5
+	}
+	body, _ := audio.EncodeWAV(samples, rate)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+// longText builds about 10k characters of mixed Chinese and English paragraphs
+func longText() string {
+	var sb strings.Builder
+	for i := 0; sb.Len() < 10000; i++ {
+		sb.WriteString(strings.Repeat("这是一段比较长的中文句子，", i%5+1))
+		sb.WriteString("结束了。 ")
+		sb.WriteString(strings.Repeat("This sentence keeps going, ", i%7+1))
+		sb.WriteString("and it is done! Version 2.5 shipped? ")
+		if i%3 == 0 {
+			sb.WriteString("\n\n")
+		}
+	}
+	return sb.String()
+}
+
+func TestSynthesizeLong(t *testing.T) {
+	transport := &wavTransport{}
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.OutputFormat = FormatWAV
+	config.Transport = transport
+	config.ChunkWorkers = 1 // The sample rate alternates with the request order
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.Start()
+	defer service.Stop()
+
+	text := longText()
+	wavData, err := service.SynthesizeLong(context.Background(), text)
+	if err != nil {
+		t.Fatalf("SynthesizeLong failed: %v", err)
+	}
+
+	if len(transport.requests) < 3 {
+		t.Fatalf("Expected a 10k input to need several chunks, got %d", len(transport.requests))
+	}
+	var joined strings.Builder
+	for i, request := range transport.requests {
+		if len(request.Input) > config.MaxTextLength {
+			t.Errorf("Chunk %d exceeds the limit: %d bytes", i, len(request.Input))
+		}
+		last, _ := utf8.DecodeLastRuneInString(request.Input)
+		if !strings.ContainsRune("。.!?", last) {
+			t.Errorf("Chunk %d does not end on punctuation: %q", i, request.Input[len(request.Input)-10:])
+		}
+		joined.WriteString(request.Input)
+	}
+	if strip := func(s string) string { return strings.Join(strings.Fields(s), "") }; strip(joined.String()) != strip(text) {
+		t.Error("Expected the chunks to cover the whole text in order")
+	}
+
+	// Chunks are joined at the first chunk's rate with a pause between them
+	samples, rate, err := audio.NewAudioDecoder().DecodeAudioData(wavData)
+	if err != nil || rate != 16000 {
+		t.Fatalf("Expected a decodable WAV at 16000 Hz, got %d Hz, %v", rate, err)
+	}
+	expected := 0
+	for i, request := range transport.requests {
+		expected += len(request.Input) / (i%2 + 1) * (i%2 + 1)
+	}
+	expected += (len(transport.requests) - 1) * int(longTextGap.Seconds()*16000)
+	if diff := len(samples) - expected; diff < -len(transport.requests) || diff > len(transport.requests) {
+		t.Errorf("Expected about %d samples, got %d", expected, len(samples))
+	}
+
+	// A second call is served from the cache
+	requests := len(transport.requests)
+	if _, err := service.SynthesizeLong(context.Background(), text); err != nil || len(transport.requests) != requests {
+		t.Errorf("Expected cached chunks to be reused, got %d new requests, %v", len(transport.requests)-requests, err)
+	}
+
+	// ProcessLLMResponse no longer rejects replies over the limit
+	if wavData, err := service.ProcessLLMResponse(context.Background(), text+" 还有一句。"); err != nil || !bytes.HasPrefix(wavData, []byte("RIFF")) {
+		t.Errorf("Expected long reply to be synthesized as WAV, got %v", err)
+	}
+
+	if _, err := service.SynthesizeLong(context.Background(), "\n"); err == nil {
+		t.Error("Expected error for empty text")
+	}
+
+	t.Log("✓ Long text chunking tests passed")
+}
+
+// slowTransport answers each speech request with its input text after a delay, or fails when the request is cancelled
+type slowTransport struct {
+	delay time.Duration
+
+	mu       sync.Mutex
+	requests int
+	inFlight int
+	peak     int
+}
+
+func (t *slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var request TTSRequest
+	json.NewDecoder(req.Body).Decode(&request)
+
+	t.mu.Lock()
+	t.requests++
+	t.inFlight++
+	t.peak = max(t.peak, t.inFlight)
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.inFlight--
+		t.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(t.delay):
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(request.Input + "|")),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestSynthesizeChunksConcurrently(t *testing.T) {
+	newService := func(transport *slowTransport, workers int) *TTSService {
+		config := DefaultTTSServiceConfig()
+		config.OutputDir = t.TempDir()
+		config.Transport = transport
+		config.MaxTextLength = 25
+		config.ChunkWorkers = workers
+		config.MaxRetries = 0
+		service, err := NewTTSService("test-key", config)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		service.Start()
+		return service
+	}
+
+	text := "Sentence number one. Sentence number two. Sentence number three. Sentence number four."
+	expected := "Sentence number one.|Sentence number two.|Sentence number three.|Sentence number four.|"
+
+	sequential := newService(&slowTransport{delay: 50 * time.Millisecond}, 1)
+	defer sequential.Stop()
+	start := time.Now()
+	if audioData, err := sequential.SynthesizeLongText(context.Background(), text); err != nil || string(audioData) != expected {
+		t.Fatalf("Unexpected sequential result %q, %v", audioData, err)
+	}
+	sequentialTime := time.Since(start)
+
+	transport := &slowTransport{delay: 50 * time.Millisecond}
+	parallel := newService(transport, 4)
+	defer parallel.Stop()
+	start = time.Now()
+	audioData, err := parallel.SynthesizeLongText(context.Background(), text)
+	parallelTime := time.Since(start)
+	if err != nil || string(audioData) != expected {
+		t.Fatalf("Expected chunk order preserved, got %q, %v", audioData, err)
+	}
+	if parallelTime >= sequentialTime/2 || transport.peak < 2 {
+		t.Errorf("Expected parallel synthesis to be faster: %v parallel vs %v sequential, peak %d in flight", parallelTime, sequentialTime, transport.peak)
+	}
+
+	// Cached chunks never reach a worker
+	parallel.SynthesizeLongText(context.Background(), "Sentence number one. A brand new sentence.")
+	if transport.requests != 5 {
+		t.Errorf("Expected only the uncached chunk to be requested, got %d requests", transport.requests)
+	}
+
+	// Cancelling the context aborts the requests still in flight
+	slow := newService(&slowTransport{delay: 5 * time.Second}, 2)
+	defer slow.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := slow.SynthesizeLongText(ctx, text); err == nil {
+		t.Error("Expected error when the context is cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected outstanding requests to be aborted, took %v", elapsed)
+	}
+
+	t.Log("✓ Concurrent chunk synthesis tests passed")
+}
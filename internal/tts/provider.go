@@ -0,0 +1,70 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+)
+
+// Supported TTS providers
+const (
+	ProviderOpenAI  = "openai"
+	ProviderCommand = "command"
+)
+
+// RequestSynthesizer is implemented by backends that accept per-call model and speed as well as voice
+type RequestSynthesizer interface {
+	SynthesizeRequest(ctx context.Context, request TTSRequest) ([]byte, error)
+}
+
+// catalog is implemented by backends that list what they support, empty lists accept any value
+type catalog interface {
+	GetAvailableVoices() []string
+	GetAvailableModels() []string
+	GetAvailableFormats() []string
+}
+
+// clientSettings is implemented by backends whose defaults follow TTSService.UpdateConfig
+type clientSettings interface {
+	SetModel(model string)
+	SetVoice(voice string)
+	SetSpeed(speed float64)
+}
+
+// pinger is implemented by backends with a liveness check cheaper than ValidateAPIKey
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// NewProviderClient creates the backend named by config.Provider, an empty name selects "openai"
+// The API key is only required by the OpenAI provider
+func NewProviderClient(apiKey string, config TTSServiceConfig) (TTSInterface, error) {
+	switch config.Provider {
+	case "", ProviderOpenAI:
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key is required")
+		}
+		client := NewTTSClient(apiKey)
+		client.SetModel(config.Model)
+		client.SetVoice(config.Voice)
+		client.SetSpeed(config.Speed)
+		client.SetRetryPolicy(config.MaxRetries, config.RetryBaseDelay)
+		if config.Transport != nil {
+			client.SetTransport(config.Transport)
+		}
+		if config.HTTPClient != nil {
+			client.SetHTTPClient(config.HTTPClient)
+		}
+		return client, nil
+	case ProviderCommand:
+		commandConfig := config.Command
+		if commandConfig.Command == "" {
+			commandConfig = DefaultCommandTTSConfig()
+		}
+		// Per-call voices come from the service config, so it is the one voice setting
+		commandConfig.Voice = config.Voice
+		return NewCommandTTSClient(commandConfig)
+	default:
+		return nil, fmt.Errorf("unknown TTS provider %q (supported: %s, %s)",
+			config.Provider, ProviderOpenAI, ProviderCommand)
+	}
+}
@@ -0,0 +1,124 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+)
+
+// stubTTSClient implements only TTSInterface and records the calls it receives
+type stubTTSClient struct {
+	texts   []string
+	formats []string
+}
+
+func (c *stubTTSClient) SynthesizeText(ctx context.Context, text string, format string) ([]byte, error) {
+	c.texts = append(c.texts, text)
+	c.formats = append(c.formats, format)
+	return []byte("RIFF stub"), nil
+}
+
+func (c *stubTTSClient) ValidateAPIKey(ctx context.Context) error { return nil }
+
+func newProviderService(t *testing.T, apiKey string, config TTSServiceConfig) *TTSService {
+	t.Helper()
+	config.OutputDir = t.TempDir()
+	config.CacheEnabled = false
+	service, err := NewTTSService(apiKey, config)
+	if err != nil {
+		t.Fatalf("Failed to create %q service: %v", config.Provider, err)
+	}
+	service.Start()
+	t.Cleanup(func() { service.Stop() })
+	return service
+}
+
+func TestTTSServiceOpenAIProvider(t *testing.T) {
+	transport := &wavTransport{}
+	config := DefaultTTSServiceConfig()
+	config.Provider = ProviderOpenAI
+	config.OutputFormat = FormatWAV
+	config.Transport = transport
+	service := newProviderService(t, "test-key", config)
+
+	if _, err := service.SynthesizeTextWithOptions(context.Background(), "你好", SynthesizeOptions{Voice: VoiceNova, Speed: 1.5}); err != nil {
+		t.Fatalf("SynthesizeText failed: %v", err)
+	}
+	request := transport.requests[0]
+	if request.Input != "你好" || request.Voice != VoiceNova || request.Speed != 1.5 || request.Model != ModelTTS1 {
+		t.Errorf("Expected per-call settings in the request, got %+v", request)
+	}
+	if err := service.ValidateOptions(SynthesizeOptions{Voice: "robot"}); err == nil {
+		t.Error("Expected error for a voice the OpenAI API does not offer")
+	}
+
+	if _, err := NewTTSService("", config); err == nil {
+		t.Error("Expected error without an API key")
+	}
+}
+
+func TestTTSServiceCommandProvider(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("Skipping command TTS test: %v", err)
+	}
+	path, expected := fixtureWAV(t)
+
+	config := DefaultTTSServiceConfig()
+	config.Provider = ProviderCommand
+	config.OutputFormat = FormatWAV
+	config.Voice = path
+	config.Command = CommandTTSConfig{
+		Command: "sh",
+		Args:    []string{"-c", `test "$(cat)" = "$1" && cat "$0"`, PlaceholderVoice, PlaceholderText},
+	}
+	// No API key is needed for a local command
+	service := newProviderService(t, "", config)
+
+	audioData, err := service.SynthesizeText(context.Background(), "你好")
+	if err != nil {
+		t.Fatalf("SynthesizeText failed: %v", err)
+	}
+	if !bytes.Equal(audioData, expected) {
+		t.Errorf("Expected the fixture WAV (%d bytes), got %d bytes", len(expected), len(audioData))
+	}
+
+	// The command only produces WAV, voices are not restricted
+	if formats := service.GetAvailableFormats(); len(formats) != 1 || formats[0] != FormatWAV {
+		t.Errorf("Expected only WAV, got %v", formats)
+	}
+	if err := service.ValidateOptions(SynthesizeOptions{Voice: "zh_CN-huayan-medium.onnx"}); err != nil {
+		t.Errorf("Expected any voice to be accepted: %v", err)
+	}
+	update := service.GetConfig()
+	update.OutputFormat = FormatMP3
+	if err := service.UpdateConfig(update); err == nil {
+		t.Error("Expected error switching the command provider to MP3")
+	}
+	if err := service.Ping(context.Background()); err != nil {
+		t.Errorf("Expected Ping to find sh: %v", err)
+	}
+}
+
+func TestTTSServiceCustomClient(t *testing.T) {
+	client := &stubTTSClient{}
+	config := DefaultTTSServiceConfig()
+	config.Provider = "unused"
+	config.Client = client
+	service := newProviderService(t, "", config)
+
+	if _, err := service.SynthesizeText(context.Background(), "测试"); err != nil {
+		t.Fatalf("SynthesizeText failed: %v", err)
+	}
+	if len(client.texts) != 1 || client.texts[0] != "测试" || client.formats[0] != FormatMP3 {
+		t.Errorf("Expected the stub to synthesize with the configured format, got %v %v", client.texts, client.formats)
+	}
+	if voices := service.GetAvailableVoices(); voices != nil {
+		t.Errorf("Expected no voice list from the stub, got %v", voices)
+	}
+
+	config.Client = nil
+	if _, err := NewTTSService("test-key", config); err == nil {
+		t.Error("Expected error for an unknown provider")
+	}
+}
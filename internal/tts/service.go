@@ -2,36 +2,71 @@ package tts
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/retry"
 )
 
 // TTSService manages TTS operations and provides high-level functionality
 type TTSService struct {
-	client       *TTSClient
+	client       TTSInterface
 	config       TTSServiceConfig
 	mu           sync.RWMutex
 	isRunning    bool
 	outputDir    string
 	cacheEnabled bool
-	cache        map[string][]byte // Simple in-memory cache
+	cache        *lruCache  // Size-bounded in-memory cache
+	disk         *diskCache // Optional disk persistence, nil when CacheDir is empty
+	logger       logging.Logger
 }
 
 // TTSServiceConfig represents TTS service configuration
 type TTSServiceConfig struct {
-	Model          string  `json:"model"`
-	Voice          string  `json:"voice"`
-	Speed          float64 `json:"speed"`
-	OutputFormat   string  `json:"output_format"`
-	OutputDir      string  `json:"output_dir"`
-	CacheEnabled   bool    `json:"cache_enabled"`
-	MaxTextLength  int     `json:"max_text_length"`
-	DefaultTimeout int     `json:"default_timeout_seconds"`
+	Model string `json:"model"`
+	Voice string `json:"voice"`
+	// VoiceByLanguage maps ISO-639-1 codes to voices picked by SelectVoice, unmapped languages use Voice
+	VoiceByLanguage map[string]string `json:"voice_by_language,omitempty"`
+	Speed           float64           `json:"speed"`
+	OutputFormat    string            `json:"output_format"`
+	OutputDir       string            `json:"output_dir"`
+	CacheEnabled    bool              `json:"cache_enabled"`
+	MaxCacheBytes   int64             `json:"max_cache_bytes"`  // 0 means unlimited
+	CacheDir        string            `json:"cache_dir"`        // Persist cached audio here when set
+	MaxRetries      int               `json:"max_retries"`      // Retries for transient API failures (429/5xx)
+	RetryBaseDelay  time.Duration     `json:"retry_base_delay"` // Initial backoff delay, doubled on each retry
+	MaxTextLength   int               `json:"max_text_length"`
+	DefaultTimeout  int               `json:"default_timeout_seconds"`
+	// ChunkWorkers bounds the chunk requests SynthesizeLong and SynthesizeLongText send in parallel, 1 is sequential
+	ChunkWorkers int `json:"chunk_workers"`
+	// ExpandText makes ProcessLLMResponse run PreprocessText on replies (pause markup, dates, times, units)
+	ExpandText bool `json:"expand_text"`
+	// TextLanguage selects the spoken forms used by ExpandText, zh or en, empty detects it from the text
+	TextLanguage string `json:"text_language,omitempty"`
+	// Transport carries API requests (proxy, custom TLS), nil uses http.DefaultTransport
+	Transport http.RoundTripper `json:"-"`
+	// HTTPClient replaces the default client entirely when set
+	HTTPClient *http.Client `json:"-"`
+	// Logger receives service logs, nil uses the standard log package
+	Logger logging.Logger `json:"-"`
+
+	// Provider selects the backend, openai (default) or command
+	Provider string `json:"provider,omitempty"`
+	// Command configures the command provider, an empty Command runs piper
+	// Voice above replaces Command.Voice, e.g. the piper model file
+	Command CommandTTSConfig `json:"command"`
+	// Client replaces the provider backend when set, e.g. a custom or test implementation
+	Client TTSInterface `json:"-"`
 }
 
 // DefaultTTSServiceConfig returns default TTS service configuration
@@ -43,28 +78,34 @@ func DefaultTTSServiceConfig() TTSServiceConfig {
 		OutputFormat:   FormatMP3,
 		OutputDir:      "output/tts",
 		CacheEnabled:   true,
+		MaxCacheBytes:  50 * 1024 * 1024,
 		MaxTextLength:  4096,
 		DefaultTimeout: 60,
+		ChunkWorkers:   3,
+		MaxRetries:     retry.DefaultMaxRetries,
+		RetryBaseDelay: retry.DefaultBaseDelay,
 	}
 }
 
 // NewTTSService creates a new TTS service
+// The backend is config.Client when set, otherwise the one config.Provider names
 func NewTTSService(apiKey string, config TTSServiceConfig) (*TTSService, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("API key is required")
+	client := config.Client
+	if client == nil {
+		var err error
+		client, err = NewProviderClient(apiKey, config)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	client := NewTTSClient(apiKey)
-	client.SetModel(config.Model)
-	client.SetVoice(config.Voice)
-	client.SetSpeed(config.Speed)
-
 	service := &TTSService{
 		client:       client,
 		config:       config,
 		outputDir:    config.OutputDir,
 		cacheEnabled: config.CacheEnabled,
-		cache:        make(map[string][]byte),
+		cache:        newLRUCache(config.MaxCacheBytes),
+		logger:       logging.OrStd(config.Logger),
 	}
 
 	// Create output directory
@@ -72,6 +113,17 @@ func NewTTSService(apiKey string, config TTSServiceConfig) (*TTSService, error)
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	// Warm the in-memory cache from disk
+	if config.CacheDir != "" {
+		disk, err := newDiskCache(config.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+		service.disk = disk
+		service.warmCache()
+	}
+
+	service.warnUndecodableFormat(config.OutputFormat)
 	return service, nil
 }
 
@@ -85,7 +137,7 @@ func (s *TTSService) Start() error {
 	}
 
 	s.isRunning = true
-	log.Println("TTS service started successfully")
+	s.logger.Info("TTS service started successfully")
 	return nil
 }
 
@@ -100,7 +152,7 @@ func (s *TTSService) Stop() error {
 
 	s.isRunning = false
 	s.clearCache()
-	log.Println("TTS service stopped")
+	s.logger.Info("TTS service stopped")
 	return nil
 }
 
@@ -111,8 +163,22 @@ func (s *TTSService) IsRunning() bool {
 	return s.isRunning
 }
 
+// SynthesizeOptions overrides the configured voice, speed and output format for a single call
+// Zero values keep the service configuration
+type SynthesizeOptions struct {
+	Voice  string
+	Speed  float64
+	Format string
+}
+
 // SynthesizeText converts text to speech and returns audio data
 func (s *TTSService) SynthesizeText(ctx context.Context, text string) ([]byte, error) {
+	return s.SynthesizeTextWithOptions(ctx, text, SynthesizeOptions{})
+}
+
+// SynthesizeTextWithOptions converts text to speech with per-call voice, speed and format
+// Results are cached per option set, like SynthesizeText
+func (s *TTSService) SynthesizeTextWithOptions(ctx context.Context, text string, opts SynthesizeOptions) ([]byte, error) {
 	if !s.IsRunning() {
 		return nil, fmt.Errorf("TTS service is not running")
 	}
@@ -122,9 +188,14 @@ func (s *TTSService) SynthesizeText(ctx context.Context, text string) ([]byte, e
 		return nil, fmt.Errorf("text validation failed: %w", err)
 	}
 
+	opts = s.resolveOptions(opts)
+	if err := s.ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
 	// Check cache first
 	if s.cacheEnabled {
-		if audioData := s.getCachedAudio(text); audioData != nil {
+		if audioData := s.getCachedAudioFor(text, opts); audioData != nil {
 			return audioData, nil
 		}
 	}
@@ -138,14 +209,14 @@ func (s *TTSService) SynthesizeText(ctx context.Context, text string) ([]byte, e
 	}
 
 	// Synthesize text
-	audioData, err := s.client.SynthesizeText(ctx, text, s.config.OutputFormat)
+	audioData, err := s.synthesize(ctx, text, opts)
 	if err != nil {
 		return nil, fmt.Errorf("synthesis failed: %w", err)
 	}
 
 	// Cache the result
 	if s.cacheEnabled {
-		s.cacheAudio(text, audioData)
+		s.cacheAudioFor(text, opts, audioData)
 	}
 
 	return audioData, nil
@@ -178,7 +249,7 @@ func (s *TTSService) SynthesizeToFile(ctx context.Context, text string, filename
 		return fmt.Errorf("failed to save audio file: %w", err)
 	}
 
-	log.Printf("TTS audio saved to: %s", filename)
+	s.logger.Info("TTS audio saved to: %s", filename)
 	return nil
 }
 
@@ -200,6 +271,28 @@ func (s *TTSService) SynthesizeWithAutoFilename(ctx context.Context, text string
 	return fullPath, nil
 }
 
+// EstimateRequest reports the billable characters and estimated cost of synthesizing text without calling the API
+// Text over MaxTextLength is counted as the chunks SynthesizeLongText would send
+func (s *TTSService) EstimateRequest(text string) (chars int, estCostUSD float64, err error) {
+	config := s.GetConfig()
+	price, ok := modelPrices[config.Model]
+	if !ok {
+		return 0, 0, fmt.Errorf("no price for model: %s", config.Model)
+	}
+
+	chunks := SplitText(text, config.MaxTextLength)
+	if len(chunks) == 0 {
+		return 0, 0, fmt.Errorf("text validation failed: text cannot be empty")
+	}
+
+	// The API bills characters, not bytes
+	for _, chunk := range chunks {
+		chars += utf8.RuneCountInString(chunk)
+	}
+
+	return chars, float64(chars) * price / 1e6, nil
+}
+
 // ProcessLLMResponse processes LLM response text for TTS
 // This method optimizes text for voice synthesis
 func (s *TTSService) ProcessLLMResponse(ctx context.Context, llmResponse string) ([]byte, error) {
@@ -210,8 +303,13 @@ func (s *TTSService) ProcessLLMResponse(ctx context.Context, llmResponse string)
 	// Optimize text for voice synthesis
 	optimizedText := s.optimizeTextForVoice(llmResponse)
 
-	// Synthesize optimized text
-	return s.SynthesizeText(ctx, optimizedText)
+	// Replies over the API limit are synthesized in chunks and joined as WAV
+	if len(optimizedText) > s.GetConfig().MaxTextLength {
+		return s.SynthesizeLong(ctx, optimizedText)
+	}
+
+	// Synthesize optimized text in a format the playback decoder can read
+	return s.SynthesizeTextWithOptions(ctx, optimizedText, SynthesizeOptions{Format: s.PlaybackFormat()})
 }
 
 // UpdateConfig updates TTS service configuration
@@ -225,14 +323,18 @@ func (s *TTSService) UpdateConfig(config TTSServiceConfig) error {
 	}
 
 	// Update client configuration
-	s.client.SetModel(config.Model)
-	s.client.SetVoice(config.Voice)
-	s.client.SetSpeed(config.Speed)
+	if settings, ok := s.client.(clientSettings); ok {
+		settings.SetModel(config.Model)
+		settings.SetVoice(config.Voice)
+		settings.SetSpeed(config.Speed)
+	}
 
 	// Update service configuration
 	s.config = config
 	s.outputDir = config.OutputDir
 	s.cacheEnabled = config.CacheEnabled
+	s.cache.setMaxBytes(config.MaxCacheBytes)
+	s.logger = logging.OrStd(config.Logger)
 
 	// Clear cache if caching is disabled
 	if !config.CacheEnabled {
@@ -244,7 +346,8 @@ func (s *TTSService) UpdateConfig(config TTSServiceConfig) error {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	log.Printf("TTS config updated: model=%s, voice=%s, speed=%.2f, format=%s",
+	s.warnUndecodableFormat(config.OutputFormat)
+	s.logger.Info("TTS config updated: model=%s, voice=%s, speed=%.2f, format=%s",
 		config.Model, config.Voice, config.Speed, config.OutputFormat)
 	return nil
 }
@@ -256,19 +359,45 @@ func (s *TTSService) GetConfig() TTSServiceConfig {
 	return s.config
 }
 
-// GetAvailableVoices returns list of available voices
+// SelectVoice returns the voice configured for language, or the default voice when it is not mapped
+func (s *TTSService) SelectVoice(language string) string {
+	config := s.GetConfig()
+	return VoiceForLanguage(config.VoiceByLanguage, language, config.Voice)
+}
+
+// GetAvailableVoices returns list of available voices, nil when the backend accepts any voice
 func (s *TTSService) GetAvailableVoices() []string {
-	return s.client.GetAvailableVoices()
+	if c, ok := s.client.(catalog); ok {
+		return c.GetAvailableVoices()
+	}
+	return nil
 }
 
-// GetAvailableModels returns list of available models
+// GetAvailableModels returns list of available models, nil when the backend has no model choice
 func (s *TTSService) GetAvailableModels() []string {
-	return s.client.GetAvailableModels()
+	if c, ok := s.client.(catalog); ok {
+		return c.GetAvailableModels()
+	}
+	return nil
 }
 
-// GetAvailableFormats returns list of available formats
+// GetAvailableFormats returns list of available formats, nil when the backend does not list them
 func (s *TTSService) GetAvailableFormats() []string {
-	return s.client.GetAvailableFormats()
+	if c, ok := s.client.(catalog); ok {
+		return c.GetAvailableFormats()
+	}
+	return nil
+}
+
+// SupportedPlaybackFormats returns the output formats that audio.AudioDecoder can decode for playback
+func (s *TTSService) SupportedPlaybackFormats() []string {
+	return playbackFormats(s.GetAvailableFormats(), audio.SupportedDecodeFormats())
+}
+
+// PlaybackFormat returns the configured output format if it can be played, otherwise MP3
+func (s *TTSService) PlaybackFormat() string {
+	format, _ := ResolvePlaybackFormat(s.GetConfig().OutputFormat, FormatMP3)
+	return format
 }
 
 // GetCacheStats returns cache statistics
@@ -276,24 +405,51 @@ func (s *TTSService) GetCacheStats() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	totalSize := 0
-	for _, data := range s.cache {
-		totalSize += len(data)
-	}
-
 	return map[string]interface{}{
 		"enabled":     s.cacheEnabled,
-		"entries":     len(s.cache),
-		"total_bytes": totalSize,
+		"entries":     s.cache.len(),
+		"total_bytes": s.cache.usedBytes,
+		"max_bytes":   s.cache.maxBytes,
+		"evictions":   s.cache.evictions,
 	}
 }
 
-// ClearCache clears the audio cache
+// ClearCache clears the in-memory audio cache, entries persisted in CacheDir are kept
 func (s *TTSService) ClearCache() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.clearCache()
-	log.Println("TTS cache cleared")
+	s.logger.Info("TTS cache cleared")
+}
+
+// PruneCache removes cached entries older than maxAge from disk and memory
+// Returns the number of entries removed
+func (s *TTSService) PruneCache(maxAge time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disk == nil {
+		return 0, nil
+	}
+
+	entries, err := s.disk.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		if entry.modTime.After(cutoff) {
+			break
+		}
+		s.disk.remove(entry.key)
+		s.cache.remove(entry.key)
+		removed++
+	}
+
+	s.logger.Info("TTS cache pruned: %d entries older than %v removed", removed, maxAge)
+	return removed, nil
 }
 
 // ValidateAPIKey validates the API key
@@ -301,8 +457,87 @@ func (s *TTSService) ValidateAPIKey(ctx context.Context) error {
 	return s.client.ValidateAPIKey(ctx)
 }
 
+// Ping checks that the TTS API is still reachable, for periodic liveness checks
+// Backends without a dedicated check fall back to ValidateAPIKey
+func (s *TTSService) Ping(ctx context.Context) error {
+	if p, ok := s.client.(pinger); ok {
+		return p.Ping(ctx)
+	}
+	return s.client.ValidateAPIKey(ctx)
+}
+
+// ValidateOptions checks per-call options, zero values are accepted since they keep the configured settings
+func (s *TTSService) ValidateOptions(opts SynthesizeOptions) error {
+	if opts.Voice != "" {
+		if err := validateChoice("voice", opts.Voice, s.GetAvailableVoices()); err != nil {
+			return err
+		}
+	}
+	if opts.Format != "" {
+		if err := validateChoice("format", opts.Format, s.GetAvailableFormats()); err != nil {
+			return err
+		}
+	}
+	if opts.Speed != 0 && (opts.Speed < 0.25 || opts.Speed > 4.0) {
+		return fmt.Errorf("invalid speed: %.2f (must be between 0.25 and 4.0)", opts.Speed)
+	}
+	return nil
+}
+
 // Private methods
 
+// synthesize sends one request with the resolved options, using the richest call the backend supports
+func (s *TTSService) synthesize(ctx context.Context, text string, opts SynthesizeOptions) ([]byte, error) {
+	switch client := s.client.(type) {
+	case RequestSynthesizer:
+		return client.SynthesizeRequest(ctx, TTSRequest{
+			Model:          s.config.Model,
+			Input:          text,
+			Voice:          opts.Voice,
+			ResponseFormat: opts.Format,
+			Speed:          opts.Speed,
+		})
+	case VoiceSynthesizer:
+		return client.SynthesizeTextWithVoice(ctx, text, opts.Format, opts.Voice)
+	default:
+		return s.client.SynthesizeText(ctx, text, opts.Format)
+	}
+}
+
+// validateChoice checks value against the backend's list, an empty list accepts any value
+func validateChoice(kind, value string, available []string) error {
+	if len(available) == 0 {
+		return nil
+	}
+	for _, v := range available {
+		if v == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported %s: %s", kind, value)
+}
+
+// resolveOptions fills zero option values from the service configuration
+func (s *TTSService) resolveOptions(opts SynthesizeOptions) SynthesizeOptions {
+	if opts.Voice == "" {
+		opts.Voice = s.config.Voice
+	}
+	if opts.Speed == 0 {
+		opts.Speed = s.config.Speed
+	}
+	if opts.Format == "" {
+		opts.Format = s.config.OutputFormat
+	}
+	return opts
+}
+
+// warnUndecodableFormat logs when audio in format cannot be decoded for playback
+func (s *TTSService) warnUndecodableFormat(format string) {
+	if playable, ok := ResolvePlaybackFormat(format, FormatMP3); !ok {
+		s.logger.Warn("Output format %s cannot be decoded for playback, ProcessLLMResponse will use %s", format, playable)
+	}
+}
+
 func (s *TTSService) validateText(text string) error {
 	if text == "" {
 		return fmt.Errorf("text cannot be empty")
@@ -317,18 +552,24 @@ func (s *TTSService) validateText(text string) error {
 }
 
 func (s *TTSService) validateConfig(config TTSServiceConfig) error {
-	if err := s.client.ValidateModel(config.Model); err != nil {
+	if err := validateChoice("model", config.Model, s.GetAvailableModels()); err != nil {
 		return err
 	}
 
-	if err := s.client.ValidateVoice(config.Voice); err != nil {
+	if err := validateChoice("voice", config.Voice, s.GetAvailableVoices()); err != nil {
 		return err
 	}
 
-	if err := s.client.ValidateFormat(config.OutputFormat); err != nil {
+	if err := validateChoice("format", config.OutputFormat, s.GetAvailableFormats()); err != nil {
 		return err
 	}
 
+	for language, voice := range config.VoiceByLanguage {
+		if err := validateChoice("voice", voice, s.GetAvailableVoices()); err != nil {
+			return fmt.Errorf("voice for language %s: %w", language, err)
+		}
+	}
+
 	if config.Speed < 0.25 || config.Speed > 4.0 {
 		return fmt.Errorf("invalid speed: %.2f (must be between 0.25 and 4.0)", config.Speed)
 	}
@@ -342,31 +583,100 @@ func (s *TTSService) validateConfig(config TTSServiceConfig) error {
 }
 
 func (s *TTSService) getCachedAudio(text string) []byte {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.getCachedAudioFor(text, s.resolveOptions(SynthesizeOptions{}))
+}
+
+func (s *TTSService) getCachedAudioFor(text string, opts SynthesizeOptions) []byte {
+	// Write lock: a hit updates the LRU order
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cacheKey := s.optionsCacheKey(text, opts)
+	if audioData, ok := s.cache.get(cacheKey); ok {
+		return audioData
+	}
 
-	cacheKey := s.generateCacheKey(text)
-	return s.cache[cacheKey]
+	// Fall back to disk when the entry was evicted from memory
+	if s.disk != nil {
+		if audioData, ok := s.disk.get(cacheKey, opts.Format); ok {
+			s.cache.put(cacheKey, audioData)
+			return audioData
+		}
+	}
+	return nil
 }
 
 func (s *TTSService) cacheAudio(text string, audioData []byte) {
+	s.cacheAudioFor(text, s.resolveOptions(SynthesizeOptions{}), audioData)
+}
+
+func (s *TTSService) cacheAudioFor(text string, opts SynthesizeOptions, audioData []byte) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	cacheKey := s.generateCacheKey(text)
-	s.cache[cacheKey] = audioData
+	cacheKey := s.optionsCacheKey(text, opts)
+	s.cache.put(cacheKey, audioData)
+
+	// Write through to disk
+	if s.disk != nil {
+		meta := diskCacheMeta{
+			Text:      text,
+			Voice:     opts.Voice,
+			Model:     s.config.Model,
+			Speed:     opts.Speed,
+			Format:    opts.Format,
+			CreatedAt: time.Now(),
+		}
+		if err := s.disk.put(cacheKey, audioData, meta); err != nil {
+			s.logger.Warn("failed to persist TTS cache entry: %v", err)
+		}
+	}
 }
 
+// warmCache loads persisted entries into memory, most recent entries win when the limit is hit
+func (s *TTSService) warmCache() {
+	entries, err := s.disk.entries()
+	if err != nil {
+		s.logger.Warn("failed to warm TTS cache: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		// Keys include the format, so entries are only ever served for the format they hold
+		if audioData, ok := s.disk.get(entry.key, ""); ok {
+			s.cache.put(entry.key, audioData)
+		}
+	}
+	// Evictions while warming are not interesting to callers
+	s.cache.evictions = 0
+
+	s.logger.Info("TTS cache warmed from %s: %d entries", s.disk.dir, s.cache.len())
+}
+
+// generateCacheKey returns a SHA-256 hex digest of the (model, voice, speed, format, text) tuple
 func (s *TTSService) generateCacheKey(text string) string {
-	return fmt.Sprintf("%s_%s_%.2f_%s",
-		s.config.Model, s.config.Voice, s.config.Speed, text)
+	return s.optionsCacheKey(text, s.resolveOptions(SynthesizeOptions{}))
+}
+
+// optionsCacheKey is generateCacheKey for resolved per-call options
+// The format is always part of the key, a change of OutputFormat must not serve audio cached in the old one
+func (s *TTSService) optionsCacheKey(text string, opts SynthesizeOptions) string {
+	canonical := fmt.Sprintf("%s\x00%s\x00%.2f\x00%s\x00%s",
+		s.config.Model, opts.Voice, opts.Speed, opts.Format, text)
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:])
 }
 
 func (s *TTSService) clearCache() {
-	s.cache = make(map[string][]byte)
+	s.cache.clear()
 }
 
 func (s *TTSService) optimizeTextForVoice(text string) string {
+	// Expand markup, times and units before punctuation is normalized
+	if config := s.GetConfig(); config.ExpandText {
+		text = PreprocessText(text, config.TextLanguage)
+	}
+
 	// Remove excessive whitespace
 	text = strings.TrimSpace(text)
 
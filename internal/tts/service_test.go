@@ -0,0 +1,427 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictionOrder(t *testing.T) {
+	cache := newLRUCache(30)
+
+	cache.put("a", make([]byte, 10))
+	cache.put("b", make([]byte, 10))
+	cache.put("c", make([]byte, 10))
+
+	// Touch "a" so "b" becomes least recently used
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("Expected entry a to be cached")
+	}
+
+	cache.put("d", make([]byte, 10))
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected least recently used entry b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("Expected entry %s to be cached", key)
+		}
+	}
+
+	// A large entry evicts as many entries as needed
+	cache.put("e", make([]byte, 25))
+	if cache.len() != 1 || cache.usedBytes != 25 {
+		t.Errorf("Expected only e to remain, got %d entries, %d bytes", cache.len(), cache.usedBytes)
+	}
+	if cache.evictions != 4 {
+		t.Errorf("Expected 4 evictions, got %d", cache.evictions)
+	}
+
+	// Entries larger than the limit are not cached
+	cache.put("huge", make([]byte, 31))
+	if _, ok := cache.get("huge"); ok {
+		t.Error("Expected oversized entry to be rejected")
+	}
+
+	t.Log("✓ LRU eviction order tests passed")
+}
+
+func TestTTSServiceCacheStats(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.MaxCacheBytes = 20
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	service.cacheAudio("你好", make([]byte, 10))
+	service.cacheAudio("世界", make([]byte, 10))
+	service.getCachedAudio("你好")
+	service.cacheAudio("再见", make([]byte, 10))
+
+	if service.getCachedAudio("世界") != nil {
+		t.Error("Expected least recently used text to be evicted")
+	}
+	if service.getCachedAudio("你好") == nil {
+		t.Error("Expected recently used text to stay cached")
+	}
+
+	stats := service.GetCacheStats()
+	if stats["evictions"] != int64(1) {
+		t.Errorf("Expected 1 eviction, got %v", stats["evictions"])
+	}
+	if stats["total_bytes"] != int64(20) {
+		t.Errorf("Expected 20 bytes in use, got %v", stats["total_bytes"])
+	}
+
+	service.ClearCache()
+	stats = service.GetCacheStats()
+	if stats["entries"] != 0 || stats["total_bytes"] != int64(0) {
+		t.Errorf("Expected empty cache after ClearCache, got %v", stats)
+	}
+
+	t.Log("✓ Cache stats tests passed")
+}
+
+func TestGenerateCacheKey(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	key := service.generateCacheKey("你好")
+	if len(key) != 64 {
+		t.Errorf("Expected 64-char hex SHA-256 key, got %q", key)
+	}
+	if service.generateCacheKey("你好") != key {
+		t.Error("Expected identical inputs to produce the same key")
+	}
+	if service.generateCacheKey("再见") == key {
+		t.Error("Expected different texts to produce different keys")
+	}
+
+	service.config.Voice = VoiceNova
+	if service.generateCacheKey("你好") == key {
+		t.Error("Expected different voices to produce different keys")
+	}
+
+	voiceKey := service.generateCacheKey("你好")
+	service.config.OutputFormat = FormatWAV
+	if service.generateCacheKey("你好") == voiceKey {
+		t.Error("Expected different formats to produce different keys")
+	}
+
+	t.Log("✓ Cache key tests passed")
+}
+
+func TestTTSServiceDiskCacheWarmStart(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.CacheDir = t.TempDir()
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	audioData := []byte("cached-audio")
+	service.cacheAudio("你好", audioData)
+
+	// A new service with the same CacheDir starts warm
+	restarted, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create restarted service: %v", err)
+	}
+	if stats := restarted.GetCacheStats(); stats["entries"] != 1 {
+		t.Errorf("Expected 1 warmed entry, got %v", stats["entries"])
+	}
+	if got := restarted.getCachedAudio("你好"); string(got) != string(audioData) {
+		t.Errorf("Expected warmed audio %q, got %q", audioData, got)
+	}
+
+	// Clearing memory keeps the disk copy
+	restarted.ClearCache()
+	if got := restarted.getCachedAudio("你好"); string(got) != string(audioData) {
+		t.Errorf("Expected audio to be reloaded from disk, got %q", got)
+	}
+
+	t.Log("✓ Disk cache warm start tests passed")
+}
+
+func TestTTSServiceCacheFormat(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.CacheDir = t.TempDir()
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.cacheAudio("你好", []byte("mp3-audio"))
+
+	// Changing the output format must not serve the cached MP3
+	update := service.GetConfig()
+	update.OutputFormat = FormatWAV
+	if err := service.UpdateConfig(update); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if got := service.getCachedAudio("你好"); got != nil {
+		t.Errorf("Expected a miss after switching to WAV, got %q", got)
+	}
+
+	// Neither does a restart with another format
+	config.OutputFormat = FormatWAV
+	restarted, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create restarted service: %v", err)
+	}
+	if got := restarted.getCachedAudio("你好"); got != nil {
+		t.Errorf("Expected a miss for WAV after restarting, got %q", got)
+	}
+
+	// A disk entry is only served for the format its sidecar records
+	mp3Key := service.optionsCacheKey("你好", SynthesizeOptions{Voice: config.Voice, Speed: config.Speed, Format: FormatMP3})
+	if _, ok := service.disk.get(mp3Key, FormatMP3); !ok {
+		t.Error("Expected the MP3 entry on disk")
+	}
+	if _, ok := service.disk.get(mp3Key, FormatWAV); ok {
+		t.Error("Expected a format mismatch to be a miss")
+	}
+	os.Remove(service.disk.metaPath(mp3Key))
+	if _, ok := service.disk.get(mp3Key, FormatMP3); ok {
+		t.Error("Expected an entry without metadata to be a miss")
+	}
+}
+
+func TestTTSServicePruneCache(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.CacheDir = t.TempDir()
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.cacheAudio("旧的", []byte("old"))
+	service.cacheAudio("新的", []byte("new"))
+
+	// Age the first entry
+	oldKey := service.generateCacheKey("旧的")
+	oldTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(service.disk.audioPath(oldKey), oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to age cache entry: %v", err)
+	}
+
+	removed, err := service.PruneCache(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PruneCache failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 entry pruned, got %d", removed)
+	}
+	if service.getCachedAudio("旧的") != nil {
+		t.Error("Expected old entry to be pruned from memory and disk")
+	}
+	if service.getCachedAudio("新的") == nil {
+		t.Error("Expected recent entry to survive pruning")
+	}
+
+	if _, err := os.Stat(filepath.Join(config.CacheDir, oldKey+".json")); !os.IsNotExist(err) {
+		t.Error("Expected sidecar metadata of pruned entry to be removed")
+	}
+
+	t.Log("✓ Prune cache tests passed")
+}
+
+// speechTransport answers speech requests with the requested voice and format, recording each request
+type speechTransport struct {
+	requests []TTSRequest
+}
+
+func (t *speechTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var request TTSRequest
+	json.NewDecoder(req.Body).Decode(&request)
+	t.requests = append(t.requests, request)
+
+	body := request.Voice + "/" + request.ResponseFormat
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestSynthesizeTextWithOptions(t *testing.T) {
+	transport := &speechTransport{}
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.Transport = transport
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.Start()
+	defer service.Stop()
+
+	audioData, err := service.SynthesizeTextWithOptions(context.Background(), "你好", SynthesizeOptions{Voice: VoiceNova, Speed: 1.5, Format: FormatWAV})
+	if err != nil {
+		t.Fatalf("SynthesizeTextWithOptions failed: %v", err)
+	}
+	if string(audioData) != "nova/wav" {
+		t.Errorf("Unexpected audio data: %q", audioData)
+	}
+	if got := transport.requests[0]; got.Speed != 1.5 || got.Model != ModelTTS1 {
+		t.Errorf("Expected speed and configured model in request, got %+v", got)
+	}
+
+	// The same options hit the cache, the configured defaults are a different entry
+	service.SynthesizeTextWithOptions(context.Background(), "你好", SynthesizeOptions{Voice: VoiceNova, Speed: 1.5, Format: FormatWAV})
+	if len(transport.requests) != 1 {
+		t.Errorf("Expected cached result for identical options, got %d requests", len(transport.requests))
+	}
+	audioData, err = service.SynthesizeText(context.Background(), "你好")
+	if err != nil || string(audioData) != "alloy/mp3" {
+		t.Errorf("Expected default voice and format, got %q, %v", audioData, err)
+	}
+
+	// Options differing only in format must not share a cache entry
+	audioData, _ = service.SynthesizeTextWithOptions(context.Background(), "你好", SynthesizeOptions{Format: FormatOpus})
+	if string(audioData) != "alloy/opus" {
+		t.Errorf("Expected a separate entry per format, got %q", audioData)
+	}
+
+	for _, opts := range []SynthesizeOptions{{Voice: "robot"}, {Format: "ogg"}, {Speed: 5}} {
+		if _, err := service.SynthesizeTextWithOptions(context.Background(), "你好", opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+
+	t.Log("✓ Per-call options tests passed")
+}
+
+func TestTTSServiceSelectVoice(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.VoiceByLanguage = map[string]string{"zh": VoiceNova}
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	if voice := service.SelectVoice("zh"); voice != VoiceNova {
+		t.Errorf("Expected mapped voice for zh, got %q", voice)
+	}
+	if voice := service.SelectVoice("fr"); voice != VoiceAlloy {
+		t.Errorf("Expected default voice for unmapped language, got %q", voice)
+	}
+	if voice := service.SelectVoice(""); voice != VoiceAlloy {
+		t.Errorf("Expected default voice for empty language, got %q", voice)
+	}
+
+	config.VoiceByLanguage = map[string]string{"en": "robot"}
+	if err := service.UpdateConfig(config); err == nil {
+		t.Error("Expected error for an unknown mapped voice")
+	}
+
+	t.Log("✓ Service voice selection tests passed")
+}
+
+func TestEstimateRequest(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// Characters are counted, not bytes
+	chars, cost, err := service.EstimateRequest("你好，世界")
+	if err != nil || chars != 5 {
+		t.Fatalf("Expected 5 characters, got %d, %v", chars, err)
+	}
+	if cost != 5*15.0/1e6 {
+		t.Errorf("Unexpected cost for %s: %v", ModelTTS1, cost)
+	}
+
+	// Long text is counted as the chunks that would be sent, the space at each chunk boundary is not billed
+	text := strings.TrimSpace(strings.Repeat(strings.Repeat("a", 99)+". ", 100))
+	chunks := SplitText(text, config.MaxTextLength)
+	chars, _, err = service.EstimateRequest(text)
+	if err != nil || len(chunks) < 3 || chars != len(text)-(len(chunks)-1) {
+		t.Errorf("Expected %d characters in %d chunks, got %d, %v", len(text)-(len(chunks)-1), len(chunks), chars, err)
+	}
+
+	config.Model = ModelTTS1HD
+	service.UpdateConfig(config)
+	if _, cost, _ := service.EstimateRequest("hello"); cost != 5*30.0/1e6 {
+		t.Errorf("Unexpected cost for %s: %v", ModelTTS1HD, cost)
+	}
+
+	if _, _, err := service.EstimateRequest(" "); err == nil {
+		t.Error("Expected error for empty text")
+	}
+
+	t.Log("✓ Request estimate tests passed")
+}
+
+func TestSynthesizeLongText(t *testing.T) {
+	transport := &speechTransport{}
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+	config.Transport = transport
+	config.CacheEnabled = false
+	config.ChunkWorkers = 1 // The transport records requests in order
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.Start()
+	defer service.Stop()
+
+	text := strings.Repeat(strings.Repeat("字", 100)+"。", 30)
+	audioData, err := service.SynthesizeLongText(context.Background(), text)
+	if err != nil {
+		t.Fatalf("SynthesizeLongText failed: %v", err)
+	}
+
+	if len(transport.requests) < 3 {
+		t.Fatalf("Expected the text to be split, got %d requests", len(transport.requests))
+	}
+	var joined strings.Builder
+	for _, request := range transport.requests {
+		if len(request.Input) > config.MaxTextLength || !strings.HasSuffix(request.Input, "。") {
+			t.Errorf("Expected chunks within the limit ending on a sentence, got %d bytes", len(request.Input))
+		}
+		joined.WriteString(request.Input)
+	}
+	if joined.String() != text {
+		t.Error("Expected the chunks to cover the whole text in order")
+	}
+	if string(audioData) != strings.Repeat("alloy/mp3", len(transport.requests)) {
+		t.Errorf("Expected the audio of every chunk concatenated, got %q", audioData)
+	}
+
+	config.OutputFormat = FormatWAV
+	service.UpdateConfig(config)
+	if _, err := service.SynthesizeLongText(context.Background(), text); err == nil {
+		t.Error("Expected error for a format that cannot be concatenated")
+	}
+
+	t.Log("✓ Long text synthesis tests passed")
+}
@@ -0,0 +1,312 @@
+package tts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Languages understood by PreprocessText
+const (
+	TextLanguageChinese = "zh"
+	TextLanguageEnglish = "en"
+)
+
+// longPause is the shortest <break> rendered as a sentence break instead of a comma
+const longPause = 500 * time.Millisecond
+
+var (
+	breakPattern = regexp.MustCompile(`<break(?:\s+time="([0-9.]+)(ms|s)")?\s*/?>`)
+	tagPattern   = regexp.MustCompile(`</?(?:speak|emphasis|prosody|p|s)(?:\s[^>]*)?>`)
+	datePattern  = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	timePattern  = regexp.MustCompile(`\b([01]?\d|2[0-3]):([0-5]\d)\b`)
+	unitPattern  = regexp.MustCompile(`(-?)(\d+(?:\.\d+)?)\s*(km/h|°C|℃|°F|%|km|kg|cm|mm|ml)`)
+)
+
+// unitNames holds the spoken form of each unit per language
+var unitNames = map[string]map[string]string{
+	TextLanguageChinese: {
+		"km/h": "公里每小时", "°C": "摄氏度", "℃": "摄氏度", "°F": "华氏度",
+		"km": "公里", "kg": "公斤", "cm": "厘米", "mm": "毫米", "ml": "毫升",
+	},
+	TextLanguageEnglish: {
+		"km/h": "kilometers per hour", "°C": "degrees Celsius", "℃": "degrees Celsius", "°F": "degrees Fahrenheit",
+		"%": "percent", "km": "kilometers", "kg": "kilograms", "cm": "centimeters", "mm": "millimeters", "ml": "milliliters",
+	},
+}
+
+// PreprocessText rewrites markup, dates, times and units into forms that read naturally
+// Supported markup is a minimal SSML subset: <break/> and <break time="300ms"/> become commas or
+// sentence breaks, and <speak>, <emphasis>, <prosody>, <p> and <s> tags are removed keeping their text
+// An empty language is detected from the text, Chinese when it contains Han characters
+func PreprocessText(text, language string) string {
+	if language == "" {
+		language = detectTextLanguage(text)
+	}
+	if language != TextLanguageChinese {
+		language = TextLanguageEnglish
+	}
+
+	text = breakPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return pauseFor(breakPattern.FindStringSubmatch(match), language)
+	})
+	text = tagPattern.ReplaceAllString(text, "")
+	text = datePattern.ReplaceAllStringFunc(text, func(match string) string {
+		return expandDate(datePattern.FindStringSubmatch(match), language)
+	})
+	text = timePattern.ReplaceAllStringFunc(text, func(match string) string {
+		return expandTime(timePattern.FindStringSubmatch(match), language)
+	})
+	return expandUnits(text, language)
+}
+
+// detectTextLanguage guesses the language of text from its script
+func detectTextLanguage(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return TextLanguageChinese
+		}
+	}
+	return TextLanguageEnglish
+}
+
+// pauseFor renders a <break> as punctuation, long pauses end the sentence
+func pauseFor(match []string, language string) string {
+	long := true
+	if match[1] != "" {
+		value, _ := strconv.ParseFloat(match[1], 64)
+		if match[2] == "s" {
+			value *= 1000
+		}
+		long = time.Duration(value*float64(time.Millisecond)) >= longPause
+	}
+
+	switch {
+	case language == TextLanguageChinese && long:
+		return "。"
+	case language == TextLanguageChinese:
+		return "，"
+	case long:
+		return ". "
+	default:
+		return ", "
+	}
+}
+
+// expandDate speaks an ISO date, invalid dates are left unchanged
+func expandDate(match []string, language string) string {
+	year, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	day, _ := strconv.Atoi(match[3])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return match[0]
+	}
+
+	if language == TextLanguageChinese {
+		return fmt.Sprintf("%d年%d月%d日", year, month, day)
+	}
+	return fmt.Sprintf("%s %d, %d", time.Month(month), day, year)
+}
+
+// expandTime speaks a clock time such as 12:30
+func expandTime(match []string, language string) string {
+	hour, _ := strconv.Atoi(match[1])
+	minute, _ := strconv.Atoi(match[2])
+
+	if language == TextLanguageChinese {
+		switch {
+		case minute == 0:
+			return fmt.Sprintf("%d点", hour)
+		case minute < 10:
+			return fmt.Sprintf("%d点零%d分", hour, minute)
+		default:
+			return fmt.Sprintf("%d点%d分", hour, minute)
+		}
+	}
+
+	switch {
+	case minute == 0:
+		return fmt.Sprintf("%d o'clock", hour)
+	case minute < 10:
+		return fmt.Sprintf("%d oh %d", hour, minute)
+	default:
+		return fmt.Sprintf("%d %d", hour, minute)
+	}
+}
+
+// expandUnits speaks numbers followed by a unit, skipping matches that are part of a longer word like "5kmh"
+// A minus sign only counts when it does not follow a word, so ranges like 10-20km keep their dash
+func expandUnits(text, language string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range unitPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if end < len(text) && isASCIILetter(text[end]) {
+			continue
+		}
+
+		negative := loc[3] > loc[2]
+		if negative && start > 0 && isWordByte(text[start-1]) {
+			// Keep the dash and expand only the number after it
+			start++
+			negative = false
+		}
+
+		sb.WriteString(text[last:start])
+		sb.WriteString(expandUnit(negative, text[loc[4]:loc[5]], text[loc[6]:loc[7]], language))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isWordByte(b byte) bool {
+	return isASCIILetter(b) || (b >= '0' && b <= '9')
+}
+
+// expandUnit speaks one number and unit, temperatures below zero get their own wording
+func expandUnit(negative bool, number, unit, language string) string {
+	temperature := unit == "°C" || unit == "℃" || unit == "°F"
+
+	if language == TextLanguageChinese {
+		sign := ""
+		if negative && temperature {
+			sign = "零下"
+		} else if negative {
+			sign = "负"
+		}
+		if unit == "%" {
+			return sign + "百分之" + number
+		}
+		return sign + number + unitNames[language][unit]
+	}
+
+	sign := ""
+	if negative {
+		sign = "minus "
+	}
+	return sign + number + " " + unitNames[language][unit]
+}
+
+// SplitText splits text into chunks of at most maxLen bytes, breaking after sentences where possible
+// Sentences longer than maxLen break at commas or spaces, and at any character as a last resort
+func SplitText(text string, maxLen int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if len(text) <= maxLen {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+	}
+
+	for _, sentence := range splitSentences(text) {
+		if current.Len()+len(sentence) > maxLen {
+			flush()
+		}
+		if current.Len() == 0 {
+			// Whitespace between sentences does not count against the next chunk
+			sentence = strings.TrimLeftFunc(sentence, unicode.IsSpace)
+		}
+		if len(sentence) > maxLen {
+			chunks = append(chunks, splitLongSentence(sentence, maxLen)...)
+			continue
+		}
+		current.WriteString(sentence)
+	}
+	flush()
+
+	return chunks
+}
+
+// SplitSentences cuts text into sentences, keeping every byte so the pieces join back to text
+// The last piece may be an unfinished sentence, which streaming callers hold until more text arrives
+func SplitSentences(text string) []string {
+	return splitSentences(text)
+}
+
+// splitSentences cuts text after sentence terminators and line breaks, keeping every byte
+// ASCII terminators only count before whitespace so numbers like 3.5 stay whole
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		end := i + utf8.RuneLen(r)
+		switch r {
+		case '。', '！', '？', '；', '…', '\n':
+		case '.', '!', '?', ';':
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if end < len(text) && !unicode.IsSpace(next) {
+				continue
+			}
+		default:
+			continue
+		}
+		// Closing quotes and brackets belong to the sentence they end
+		for end < len(text) {
+			next, size := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(`"')”’」』）`, next) {
+				break
+			}
+			end += size
+		}
+		if end > start {
+			sentences = append(sentences, text[start:end])
+			start = end
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// splitLongSentence cuts a sentence longer than maxLen at the last comma or space that fits
+func splitLongSentence(sentence string, maxLen int) []string {
+	var pieces []string
+	for len(sentence) > maxLen {
+		cut, fallback := 0, 0
+		for i, r := range sentence {
+			end := i + utf8.RuneLen(r)
+			if end > maxLen {
+				break
+			}
+			fallback = end
+			if unicode.IsSpace(r) || strings.ContainsRune(",，、:：", r) {
+				cut = end
+			}
+		}
+		if cut == 0 {
+			cut = fallback
+		}
+		if cut == 0 {
+			// maxLen is smaller than one character, take it anyway to make progress
+			_, cut = utf8.DecodeRuneInString(sentence)
+		}
+
+		if piece := strings.TrimSpace(sentence[:cut]); piece != "" {
+			pieces = append(pieces, piece)
+		}
+		sentence = sentence[cut:]
+	}
+	if piece := strings.TrimSpace(sentence); piece != "" {
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
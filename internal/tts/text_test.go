@@ -0,0 +1,140 @@
+package tts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreprocessTextChinese(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"会议在12:30开始", "会议在12点30分开始"},
+		{"闹钟定在8:05", "闹钟定在8点零5分"},
+		{"现在是9:00", "现在是9点"},
+		{"日期是2024-03-15", "日期是2024年3月15日"},
+		{"今天25℃，明天-5°C", "今天25摄氏度，明天零下5摄氏度"},
+		{"湿度80%", "湿度百分之80"},
+		{"全程10-20km", "全程10-20公里"},
+		{"限速 60 km/h", "限速 60公里每小时"},
+		{"5kmh 不是单位", "5kmh 不是单位"},
+		{"先想想<break/>好的", "先想想。好的"},
+		{`嗯<break time="300ms"/>可以`, "嗯，可以"},
+		{"<speak><emphasis>注意</emphasis>安全</speak>", "注意安全"},
+	}
+
+	for _, test := range tests {
+		if result := PreprocessText(test.input, TextLanguageChinese); result != test.expected {
+			t.Errorf("PreprocessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	t.Log("✓ Chinese text preprocessing tests passed")
+}
+
+func TestPreprocessTextEnglish(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"The meeting starts at 12:30", "The meeting starts at 12 30"},
+		{"Wake me at 8:05", "Wake me at 8 oh 5"},
+		{"See you at 9:00", "See you at 9 o'clock"},
+		{"Due 2024-03-15", "Due March 15, 2024"},
+		{"It is 25°C, tonight -5°C", "It is 25 degrees Celsius, tonight minus 5 degrees Celsius"},
+		{"Battery at 3.5%", "Battery at 3.5 percent"},
+		{"Run 10-20km", "Run 10-20 kilometers"},
+		{"Invalid 2024-13-40 stays", "Invalid 2024-13-40 stays"},
+		{"Wait<break time=\"1s\"/>done", "Wait. done"},
+		{"<prosody rate=\"slow\">Slowly</prosody>", "Slowly"},
+	}
+
+	for _, test := range tests {
+		if result := PreprocessText(test.input, TextLanguageEnglish); result != test.expected {
+			t.Errorf("PreprocessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// The language is detected from the text when not configured
+	if result := PreprocessText("现在12:30", ""); result != "现在12点30分" {
+		t.Errorf("Expected Chinese forms for detected language, got %q", result)
+	}
+	if result := PreprocessText("Now 12:30", ""); result != "Now 12 30" {
+		t.Errorf("Expected English forms for detected language, got %q", result)
+	}
+
+	t.Log("✓ English text preprocessing tests passed")
+}
+
+func TestOptimizeTextForVoiceExpandText(t *testing.T) {
+	config := DefaultTTSServiceConfig()
+	config.OutputDir = t.TempDir()
+
+	service, err := NewTTSService("test-key", config)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// Preprocessing is opt-in
+	if result := service.optimizeTextForVoice("It is 25°C"); result != "It is 25°C." {
+		t.Errorf("Expected text unchanged without ExpandText, got %q", result)
+	}
+
+	config.ExpandText = true
+	config.TextLanguage = TextLanguageEnglish
+	if err := service.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if result := service.optimizeTextForVoice("It is **25°C** at 9:00"); result != "It is 25 degrees Celsius at 9 o'clock." {
+		t.Errorf("Expected expanded text, got %q", result)
+	}
+
+	t.Log("✓ Voice text expansion tests passed")
+}
+
+func TestSplitText(t *testing.T) {
+	if chunks := SplitText("  短句。  ", 100); len(chunks) != 1 || chunks[0] != "短句。" {
+		t.Errorf("Expected short text as a single trimmed chunk, got %q", chunks)
+	}
+	if chunks := SplitText("   ", 100); len(chunks) != 0 {
+		t.Errorf("Expected no chunks for blank text, got %q", chunks)
+	}
+
+	// Chunks break after sentences, numbers with a decimal point stay whole
+	text := "First sentence is here. Pi is 3.14 exactly! 第三句话。第四句？"
+	chunks := SplitText(text, 30)
+	expected := []string{"First sentence is here.", "Pi is 3.14 exactly!", "第三句话。第四句？"}
+	if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, chunks)
+	}
+
+	// Whitespace before a sentence does not push it over the limit
+	chunks = SplitText("Exactly twenty bytes. Exactly twenty bytes.", 21)
+	if len(chunks) != 2 || chunks[1] != "Exactly twenty bytes." {
+		t.Errorf("Expected whole sentences per chunk, got %q", chunks)
+	}
+
+	// Closing quotes stay with their sentence
+	chunks = SplitText(`他说“好的。”然后离开了。`, 30)
+	if len(chunks) != 2 || chunks[0] != `他说“好的。”` {
+		t.Errorf("Expected closing quote kept with the sentence, got %q", chunks)
+	}
+
+	// A sentence over the limit breaks at commas or spaces, then anywhere
+	chunks = SplitText("one two three four five six", 10)
+	for _, chunk := range chunks {
+		if len(chunk) > 10 {
+			t.Errorf("Chunk %q exceeds the limit", chunk)
+		}
+	}
+	if strings.Join(chunks, " ") != "one two three four five six" {
+		t.Errorf("Expected words kept whole, got %q", chunks)
+	}
+	chunks = SplitText(strings.Repeat("长", 10), 9)
+	if len(chunks) != 4 || chunks[0] != "长长长" {
+		t.Errorf("Expected breaks on character boundaries, got %q", chunks)
+	}
+
+	t.Log("✓ Text splitting tests passed")
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"audio-assistant/internal/audio"
 )
 
 // saveAudioToFile saves audio data to a file
@@ -28,6 +31,54 @@ func saveAudioToFile(audioData []byte, filename string) error {
 	return nil
 }
 
+// ResolvePlaybackFormat returns requested when the playback decoder can read it
+// An empty request selects fallback, an undecodable one selects fallback and reports false
+func ResolvePlaybackFormat(requested, fallback string) (string, bool) {
+	if requested == "" {
+		return fallback, true
+	}
+	for _, format := range audio.SupportedDecodeFormats() {
+		if format == requested {
+			return requested, true
+		}
+	}
+	return fallback, false
+}
+
+// playbackFormats returns the output formats that are also decodable, keeping the output order
+func playbackFormats(outputs, decodable []string) []string {
+	supported := make(map[string]bool, len(decodable))
+	for _, format := range decodable {
+		supported[format] = true
+	}
+
+	var formats []string
+	for _, format := range outputs {
+		if supported[format] {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// VoiceForLanguage looks up the voice for a language code, region suffixes like zh-CN match zh
+// Empty or unmapped languages return fallback
+func VoiceForLanguage(voices map[string]string, language, fallback string) string {
+	language = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(language)), "_", "-")
+	if language == "" {
+		return fallback
+	}
+	if voice, ok := voices[language]; ok && voice != "" {
+		return voice
+	}
+	if base, _, found := strings.Cut(language, "-"); found {
+		if voice, ok := voices[base]; ok && voice != "" {
+			return voice
+		}
+	}
+	return fallback
+}
+
 // GetFileExtensionForFormat returns the appropriate file extension for a format
 func GetFileExtensionForFormat(format string) string {
 	switch format {
@@ -48,6 +99,26 @@ func GetFileExtensionForFormat(format string) string {
 	}
 }
 
+// GetContentTypeForFormat returns the MIME type for a format
+func GetContentTypeForFormat(format string) string {
+	switch format {
+	case FormatMP3:
+		return "audio/mpeg"
+	case FormatOpus:
+		return "audio/ogg"
+	case FormatAAC:
+		return "audio/aac"
+	case FormatFLAC:
+		return "audio/flac"
+	case FormatWAV:
+		return "audio/wav"
+	case FormatPCM:
+		return "audio/pcm"
+	default:
+		return "application/octet-stream"
+	}
+}
+
 // GenerateFilename generates a filename with timestamp and format
 func GenerateFilename(prefix string, format string) string {
 	timestamp := fmt.Sprintf("%d", getCurrentTimestamp())
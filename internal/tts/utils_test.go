@@ -0,0 +1,72 @@
+package tts
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPlaybackFormats(t *testing.T) {
+	outputs := []string{FormatMP3, FormatOpus, FormatAAC, FormatFLAC, FormatWAV, FormatPCM}
+
+	formats := playbackFormats(outputs, []string{"wav", "mp3", "flac"})
+	if expected := []string{FormatMP3, FormatFLAC, FormatWAV}; fmt.Sprint(formats) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, formats)
+	}
+
+	formats = playbackFormats(outputs, []string{"wav", "mp3", "flac", "opus", "aac"})
+	if len(formats) != 5 || formats[1] != FormatOpus {
+		t.Errorf("Expected every format but PCM in output order, got %v", formats)
+	}
+
+	if formats := playbackFormats(outputs, nil); len(formats) != 0 {
+		t.Errorf("Expected no formats without a decoder, got %v", formats)
+	}
+
+	t.Log("✓ Playback format intersection tests passed")
+}
+
+func TestResolvePlaybackFormat(t *testing.T) {
+	for _, tc := range []struct {
+		requested string
+		expected  string
+		ok        bool
+	}{
+		{"", FormatWAV, true},
+		{FormatMP3, FormatMP3, true},
+		{FormatFLAC, FormatFLAC, true},
+		{FormatPCM, FormatWAV, false},
+	} {
+		format, ok := ResolvePlaybackFormat(tc.requested, FormatWAV)
+		if format != tc.expected || ok != tc.ok {
+			t.Errorf("ResolvePlaybackFormat(%q) = %q, %v, expected %q, %v", tc.requested, format, ok, tc.expected, tc.ok)
+		}
+	}
+
+	t.Log("✓ Playback format resolution tests passed")
+}
+
+func TestVoiceForLanguage(t *testing.T) {
+	voices := map[string]string{"zh": VoiceNova, "en": VoiceEcho}
+
+	for _, tc := range []struct {
+		language string
+		expected string
+	}{
+		{"zh", VoiceNova},
+		{"EN", VoiceEcho},
+		{"zh-CN", VoiceNova},
+		{"zh_TW", VoiceNova},
+		{"ja", VoiceAlloy},
+		{"", VoiceAlloy},
+	} {
+		if voice := VoiceForLanguage(voices, tc.language, VoiceAlloy); voice != tc.expected {
+			t.Errorf("VoiceForLanguage(%q) = %q, expected %q", tc.language, voice, tc.expected)
+		}
+	}
+
+	if voice := VoiceForLanguage(nil, "zh", VoiceAlloy); voice != VoiceAlloy {
+		t.Errorf("Expected fallback without a mapping, got %q", voice)
+	}
+
+	t.Log("✓ Voice selection tests passed")
+}
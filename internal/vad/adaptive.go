@@ -0,0 +1,119 @@
+package vad
+
+import (
+	"math"
+)
+
+// energyMargin is how far above the ambient RMS the local detector's energy threshold is placed
+const energyMargin = 3.0
+
+// AdaptiveThresholdConfig represents ambient-noise based threshold configuration
+type AdaptiveThresholdConfig struct {
+	MinThreshold  float64 // Threshold used in a quiet room
+	MaxThreshold  float64 // Threshold used in a noisy room
+	QuietDB       float64 // Ambient level (dBFS) at or below which MinThreshold applies
+	NoisyDB       float64 // Ambient level (dBFS) at or above which MaxThreshold applies
+	CalibrationMs int     // Ambient audio averaged before the first adjustment
+	Smoothing     float64 // Weight of each later silent chunk in the running noise estimate (0-1), 0 keeps the calibrated level
+}
+
+// DefaultAdaptiveThresholdConfig returns default adaptive threshold configuration
+func DefaultAdaptiveThresholdConfig() AdaptiveThresholdConfig {
+	return AdaptiveThresholdConfig{
+		MinThreshold:  0.3,
+		MaxThreshold:  0.8,
+		QuietDB:       -60,
+		NoisyDB:       -30,
+		CalibrationMs: 1000,
+		Smoothing:     0.05,
+	}
+}
+
+// AdaptiveThreshold estimates ambient noise from audio without speech and derives a VAD threshold from it
+// Louder rooms get a higher threshold so background noise isn't taken for speech, it is not safe for concurrent use
+type AdaptiveThreshold struct {
+	config     AdaptiveThresholdConfig
+	sumSquares float64 // Energy collected during calibration
+	collected  int     // Samples collected during calibration
+	collectMs  float64 // Duration collected during calibration
+	noiseRMS   float64
+	calibrated bool
+}
+
+// NewAdaptiveThreshold creates an adaptive threshold, swapped or out of range bounds are corrected
+func NewAdaptiveThreshold(config AdaptiveThresholdConfig) *AdaptiveThreshold {
+	defaults := DefaultAdaptiveThresholdConfig()
+	if config.MinThreshold > config.MaxThreshold {
+		config.MinThreshold, config.MaxThreshold = config.MaxThreshold, config.MinThreshold
+	}
+	config.MinThreshold = math.Max(config.MinThreshold, 0)
+	config.MaxThreshold = math.Min(config.MaxThreshold, 1)
+	if config.NoisyDB <= config.QuietDB {
+		config.QuietDB, config.NoisyDB = defaults.QuietDB, defaults.NoisyDB
+	}
+	if config.CalibrationMs <= 0 {
+		config.CalibrationMs = defaults.CalibrationMs
+	}
+	config.Smoothing = math.Min(math.Max(config.Smoothing, 0), 1)
+
+	return &AdaptiveThreshold{config: config}
+}
+
+// Observe feeds audio known to contain no speech
+// Until CalibrationMs of audio has been seen the energy is averaged, afterwards the estimate follows slowly with Smoothing
+func (a *AdaptiveThreshold) Observe(samples []float32, sampleRate int) {
+	if len(samples) == 0 || sampleRate <= 0 {
+		return
+	}
+
+	if !a.calibrated {
+		for _, s := range samples {
+			a.sumSquares += float64(s) * float64(s)
+		}
+		a.collected += len(samples)
+		a.collectMs += float64(len(samples)) * 1000 / float64(sampleRate)
+		a.noiseRMS = math.Sqrt(a.sumSquares / float64(a.collected))
+		a.calibrated = a.collectMs >= float64(a.config.CalibrationMs)
+		return
+	}
+
+	a.noiseRMS += a.config.Smoothing * (frameRMS(samples) - a.noiseRMS)
+}
+
+// Calibrated reports whether the calibration window has been filled
+func (a *AdaptiveThreshold) Calibrated() bool {
+	return a.calibrated
+}
+
+// NoiseRMS returns the estimated ambient RMS
+func (a *AdaptiveThreshold) NoiseRMS() float64 {
+	return a.noiseRMS
+}
+
+// Threshold returns the VAD threshold for the current ambient level, ok is false until calibrated
+func (a *AdaptiveThreshold) Threshold() (threshold float64, ok bool) {
+	if !a.calibrated {
+		return 0, false
+	}
+	return a.thresholdForNoise(a.noiseRMS), true
+}
+
+// EnergyThreshold returns the local detector energy threshold: a margin above the ambient RMS, never below base
+func (a *AdaptiveThreshold) EnergyThreshold(base float64) float64 {
+	if !a.calibrated {
+		return base
+	}
+	return math.Max(base, a.noiseRMS*energyMargin)
+}
+
+// thresholdForNoise maps an ambient RMS linearly in dBFS from [QuietDB, NoisyDB] onto [MinThreshold, MaxThreshold]
+func (a *AdaptiveThreshold) thresholdForNoise(rms float64) float64 {
+	if rms <= 0 {
+		return a.config.MinThreshold
+	}
+
+	db := 20 * math.Log10(rms)
+	position := (db - a.config.QuietDB) / (a.config.NoisyDB - a.config.QuietDB)
+	position = math.Min(math.Max(position, 0), 1)
+	return a.config.MinThreshold + position*(a.config.MaxThreshold-a.config.MinThreshold)
+}
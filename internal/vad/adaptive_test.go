@@ -0,0 +1,87 @@
+package vad
+
+import (
+	"math"
+	"testing"
+)
+
+// calibrate feeds one second of a tone with the given amplitude in 100 ms chunks
+func calibrate(a *AdaptiveThreshold, amplitude float32) {
+	for i := 0; i < 10; i++ {
+		a.Observe(generateTone(200, amplitude, 16000, 1600), 16000)
+	}
+}
+
+func TestAdaptiveThresholdCalibration(t *testing.T) {
+	// Quiet room: tone RMS about -69 dBFS, below QuietDB
+	quiet := NewAdaptiveThreshold(DefaultAdaptiveThresholdConfig())
+	if _, ok := quiet.Threshold(); ok {
+		t.Error("Expected no threshold before calibration")
+	}
+	quiet.Observe(generateTone(200, 0.0005, 16000, 8000), 16000)
+	if quiet.Calibrated() {
+		t.Error("Expected 500ms to be shorter than the calibration window")
+	}
+	calibrate(quiet, 0.0005)
+	threshold, ok := quiet.Threshold()
+	if !ok || threshold != 0.3 {
+		t.Errorf("Expected the minimum threshold in a quiet room, got %v, %v", threshold, ok)
+	}
+
+	// Noisy room: tone RMS about -29 dBFS, above NoisyDB
+	noisy := NewAdaptiveThreshold(DefaultAdaptiveThresholdConfig())
+	calibrate(noisy, 0.05)
+	if threshold, _ := noisy.Threshold(); threshold != 0.8 {
+		t.Errorf("Expected the maximum threshold in a noisy room, got %v", threshold)
+	}
+	if rms := noisy.NoiseRMS(); math.Abs(rms-0.05/math.Sqrt2) > 0.001 {
+		t.Errorf("Expected ambient RMS of %v, got %v", 0.05/math.Sqrt2, rms)
+	}
+
+	// Halfway between QuietDB and NoisyDB maps halfway between the bounds
+	if threshold := noisy.thresholdForNoise(math.Pow(10, -45.0/20)); math.Abs(threshold-0.55) > 1e-9 {
+		t.Errorf("Expected 0.55 at -45 dBFS, got %v", threshold)
+	}
+	if threshold := noisy.thresholdForNoise(0); threshold != 0.3 {
+		t.Errorf("Expected the minimum threshold for digital silence, got %v", threshold)
+	}
+}
+
+func TestAdaptiveThresholdTracksSilence(t *testing.T) {
+	config := DefaultAdaptiveThresholdConfig()
+	config.Smoothing = 0.5
+	adaptive := NewAdaptiveThreshold(config)
+	calibrate(adaptive, 0.0005)
+
+	// After calibration the estimate follows louder ambient audio gradually
+	chunk := generateTone(200, 0.05, 16000, 1600)
+	adaptive.Observe(chunk, 16000)
+	first, _ := adaptive.Threshold()
+	for i := 0; i < 20; i++ {
+		adaptive.Observe(chunk, 16000)
+	}
+	last, _ := adaptive.Threshold()
+	if first <= 0.3 || first >= last || last != 0.8 {
+		t.Errorf("Expected the threshold to rise gradually to the maximum, got %v then %v", first, last)
+	}
+
+	// Local detector threshold sits a margin above the noise but never below the base
+	if energy := adaptive.EnergyThreshold(0.02); math.Abs(energy-adaptive.NoiseRMS()*energyMargin) > 1e-9 {
+		t.Errorf("Expected energy threshold %v, got %v", adaptive.NoiseRMS()*energyMargin, energy)
+	}
+	if energy := NewAdaptiveThreshold(config).EnergyThreshold(0.02); energy != 0.02 {
+		t.Errorf("Expected the base energy threshold before calibration, got %v", energy)
+	}
+}
+
+func TestAdaptiveThresholdBounds(t *testing.T) {
+	// Swapped and out of range bounds are corrected
+	adaptive := NewAdaptiveThreshold(AdaptiveThresholdConfig{MinThreshold: 1.5, MaxThreshold: 0.2})
+	calibrate(adaptive, 0.05)
+	if threshold, _ := adaptive.Threshold(); threshold != 1 {
+		t.Errorf("Expected the threshold clamped to 1, got %v", threshold)
+	}
+	if adaptive.config.MinThreshold != 0.2 || adaptive.config.QuietDB != -60 {
+		t.Errorf("Unexpected corrected config: %+v", adaptive.config)
+	}
+}
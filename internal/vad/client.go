@@ -2,6 +2,7 @@ package vad
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,9 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"audio-assistant/internal/audio"
+	"audio-assistant/internal/retry"
 )
 
 // Client represents a VAD HTTP client
@@ -50,6 +54,20 @@ type DetectStatistics struct {
 	ThresholdUsed       float64 `json:"threshold_used"`
 }
 
+// Add accumulates the statistics of another detection, e.g. successive chunks of one recording
+// Durations and segment counts are summed and the speech ratio recomputed, the sample rate and threshold come from other
+func (s *DetectStatistics) Add(other DetectStatistics) {
+	s.TotalSegments += other.TotalSegments
+	s.TotalSpeechDuration += other.TotalSpeechDuration
+	s.TotalAudioDuration += other.TotalAudioDuration
+	s.SampleRate = other.SampleRate
+	s.ThresholdUsed = other.ThresholdUsed
+	s.SpeechRatio = 0
+	if s.TotalAudioDuration > 0 {
+		s.SpeechRatio = s.TotalSpeechDuration / s.TotalAudioDuration
+	}
+}
+
 // HealthResponse represents the health check response
 type HealthResponse struct {
 	Status    string `json:"status"`
@@ -66,16 +84,35 @@ type InfoResponse struct {
 // NewClient creates a new VAD client
 func NewClient(baseURL string) *Client {
 	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:    baseURL,
+		httpClient: retry.NewClient(30*time.Second, retry.DefaultMaxRetries, retry.DefaultBaseDelay),
 	}
 }
 
+// SetRetryPolicy sets how transient API failures (429/5xx) are retried
+func (c *Client) SetRetryPolicy(maxRetries int, baseDelay time.Duration) {
+	retry.SetPolicy(c.httpClient, maxRetries, baseDelay)
+}
+
+// SetTransport sends requests through transport (e.g. a proxy or custom TLS config), keeping the retry policy
+// By default http.DefaultTransport is used, which honors HTTP_PROXY and HTTPS_PROXY
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	retry.SetBaseTransport(c.httpClient, transport)
+}
+
+// SetHTTPClient replaces the HTTP client, which is used as is (its own timeout and retries apply)
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
 // Health checks if the VAD service is healthy
 func (c *Client) Health() (*HealthResponse, error) {
-	resp, err := c.httpClient.Get(c.baseURL + "/health")
+	return c.HealthContext(context.Background())
+}
+
+// HealthContext checks if the VAD service is healthy, aborting when ctx is cancelled
+func (c *Client) HealthContext(ctx context.Context) (*HealthResponse, error) {
+	resp, err := c.get(ctx, "/health")
 	if err != nil {
 		return nil, fmt.Errorf("failed to call health endpoint: %w", err)
 	}
@@ -95,7 +132,12 @@ func (c *Client) Health() (*HealthResponse, error) {
 
 // Info gets information about the VAD model
 func (c *Client) Info() (*InfoResponse, error) {
-	resp, err := c.httpClient.Get(c.baseURL + "/info")
+	return c.InfoContext(context.Background())
+}
+
+// InfoContext gets information about the VAD model, aborting when ctx is cancelled
+func (c *Client) InfoContext(ctx context.Context) (*InfoResponse, error) {
+	resp, err := c.get(ctx, "/info")
 	if err != nil {
 		return nil, fmt.Errorf("failed to call info endpoint: %w", err)
 	}
@@ -113,8 +155,22 @@ func (c *Client) Info() (*InfoResponse, error) {
 	return &infoResp, nil
 }
 
+// get sends a GET request bound to ctx
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(httpReq)
+}
+
 // DetectFromFile detects speech activity from an audio file
 func (c *Client) DetectFromFile(audioFilePath string, req *DetectRequest) (*DetectResponse, error) {
+	return c.DetectFromFileContext(context.Background(), audioFilePath, req)
+}
+
+// DetectFromFileContext detects speech activity from an audio file, aborting when ctx is cancelled
+func (c *Client) DetectFromFileContext(ctx context.Context, audioFilePath string, req *DetectRequest) (*DetectResponse, error) {
 	// Open the audio file
 	file, err := os.Open(audioFilePath)
 	if err != nil {
@@ -136,55 +192,16 @@ func (c *Client) DetectFromFile(audioFilePath string, req *DetectRequest) (*Dete
 		return nil, fmt.Errorf("failed to copy file content: %w", err)
 	}
 
-	// Add optional parameters
-	if req != nil {
-		if req.Threshold > 0 {
-			writer.WriteField("threshold", fmt.Sprintf("%.2f", req.Threshold))
-		}
-		if req.MinSpeechDurationMs > 0 {
-			writer.WriteField("min_speech_duration_ms", fmt.Sprintf("%d", req.MinSpeechDurationMs))
-		}
-		if req.MinSilenceDurationMs > 0 {
-			writer.WriteField("min_silence_duration_ms", fmt.Sprintf("%d", req.MinSilenceDurationMs))
-		}
-	}
-
-	writer.Close()
-
-	// Create HTTP request
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/detect", &buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
-
-	// Send request
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse response
-	var detectResp DetectResponse
-	if err := json.NewDecoder(resp.Body).Decode(&detectResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return &detectResp, fmt.Errorf("detection failed with status %d: %s", resp.StatusCode, detectResp.Message)
-	}
-
-	if detectResp.Status != "success" {
-		return &detectResp, fmt.Errorf("detection unsuccessful: %s", detectResp.Message)
-	}
-
-	return &detectResp, nil
+	return c.detect(ctx, &buf, writer, req)
 }
 
 // DetectFromBytes detects speech activity from audio bytes
 func (c *Client) DetectFromBytes(audioData []byte, filename string, req *DetectRequest) (*DetectResponse, error) {
+	return c.DetectFromBytesContext(context.Background(), audioData, filename, req)
+}
+
+// DetectFromBytesContext detects speech activity from audio bytes, aborting when ctx is cancelled
+func (c *Client) DetectFromBytesContext(ctx context.Context, audioData []byte, filename string, req *DetectRequest) (*DetectResponse, error) {
 	// Create multipart form
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
@@ -199,6 +216,11 @@ func (c *Client) DetectFromBytes(audioData []byte, filename string, req *DetectR
 		return nil, fmt.Errorf("failed to write audio data: %w", err)
 	}
 
+	return c.detect(ctx, &buf, writer, req)
+}
+
+// detect adds the optional parameters to the multipart form and posts it to /detect
+func (c *Client) detect(ctx context.Context, buf *bytes.Buffer, writer *multipart.Writer, req *DetectRequest) (*DetectResponse, error) {
 	// Add optional parameters
 	if req != nil {
 		if req.Threshold > 0 {
@@ -215,7 +237,7 @@ func (c *Client) DetectFromBytes(audioData []byte, filename string, req *DetectR
 	writer.Close()
 
 	// Create HTTP request
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/detect", &buf)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/detect", buf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -246,9 +268,44 @@ func (c *Client) DetectFromBytes(audioData []byte, filename string, req *DetectR
 	return &detectResp, nil
 }
 
+// DetectFromSamples detects speech activity from in-memory float samples without a temp file
+func (c *Client) DetectFromSamples(samples []float32, sampleRate int, req *DetectRequest) (*DetectResponse, error) {
+	return c.DetectFromSamplesContext(context.Background(), samples, sampleRate, req)
+}
+
+// DetectFromSamplesContext detects speech activity from in-memory float samples, aborting when ctx is cancelled
+func (c *Client) DetectFromSamplesContext(ctx context.Context, samples []float32, sampleRate int, req *DetectRequest) (*DetectResponse, error) {
+	wavData, err := audio.EncodeWAV(samples, sampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode WAV: %w", err)
+	}
+
+	return c.DetectFromBytesContext(ctx, wavData, "audio.wav", req)
+}
+
+// HasSpeechFromSamples checks if in-memory float samples contain any speech
+func (c *Client) HasSpeechFromSamples(samples []float32, sampleRate int, req *DetectRequest) (bool, error) {
+	return c.HasSpeechFromSamplesContext(context.Background(), samples, sampleRate, req)
+}
+
+// HasSpeechFromSamplesContext checks if in-memory float samples contain any speech, aborting when ctx is cancelled
+func (c *Client) HasSpeechFromSamplesContext(ctx context.Context, samples []float32, sampleRate int, req *DetectRequest) (bool, error) {
+	resp, err := c.DetectFromSamplesContext(ctx, samples, sampleRate, req)
+	if err != nil {
+		return false, err
+	}
+
+	return len(resp.SpeechSegments) > 0, nil
+}
+
 // HasSpeech checks if the audio contains any speech
 func (c *Client) HasSpeech(audioFilePath string, req *DetectRequest) (bool, error) {
-	resp, err := c.DetectFromFile(audioFilePath, req)
+	return c.HasSpeechContext(context.Background(), audioFilePath, req)
+}
+
+// HasSpeechContext checks if the audio contains any speech, aborting when ctx is cancelled
+func (c *Client) HasSpeechContext(ctx context.Context, audioFilePath string, req *DetectRequest) (bool, error) {
+	resp, err := c.DetectFromFileContext(ctx, audioFilePath, req)
 	if err != nil {
 		return false, err
 	}
@@ -258,7 +315,12 @@ func (c *Client) HasSpeech(audioFilePath string, req *DetectRequest) (bool, erro
 
 // HasSpeechFromBytes checks if the audio bytes contain any speech
 func (c *Client) HasSpeechFromBytes(audioData []byte, filename string, req *DetectRequest) (bool, error) {
-	resp, err := c.DetectFromBytes(audioData, filename, req)
+	return c.HasSpeechFromBytesContext(context.Background(), audioData, filename, req)
+}
+
+// HasSpeechFromBytesContext checks if the audio bytes contain any speech, aborting when ctx is cancelled
+func (c *Client) HasSpeechFromBytesContext(ctx context.Context, audioData []byte, filename string, req *DetectRequest) (bool, error) {
+	resp, err := c.DetectFromBytesContext(ctx, audioData, filename, req)
 	if err != nil {
 		return false, err
 	}
@@ -1,21 +1,28 @@
 package vad
 
 import (
+	"context"
+	"errors"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"audio-assistant/internal/audio"
 )
 
 func TestVADClient(t *testing.T) {
-	// Skip test if VAD server is not running
-	client := NewClient("http://localhost:8000")
+	server := NewTestServer()
+	defer server.Close()
+	client := NewClient(server.URL)
 
 	// Test health check
 	health, err := client.Health()
 	if err != nil {
-		t.Skipf("VAD server not available: %v", err)
+		t.Fatalf("Health check failed: %v", err)
 	}
 
 	if health.Status != "healthy" {
@@ -26,11 +33,13 @@ func TestVADClient(t *testing.T) {
 }
 
 func TestVADInfo(t *testing.T) {
-	client := NewClient("http://localhost:8000")
+	server := NewTestServer()
+	defer server.Close()
+	client := NewClient(server.URL)
 
 	info, err := client.Info()
 	if err != nil {
-		t.Skipf("VAD server not available: %v", err)
+		t.Fatalf("Info request failed: %v", err)
 	}
 
 	if info.ModelName == "" {
@@ -47,7 +56,9 @@ func TestVADInfo(t *testing.T) {
 }
 
 func TestVADDetection(t *testing.T) {
-	client := NewClient("http://localhost:8000")
+	server := NewTestServer()
+	defer server.Close()
+	client := NewClient(server.URL)
 
 	// Create test audio data (1 second of sine wave at 440Hz)
 	sampleRate := 16000
@@ -63,8 +74,7 @@ func TestVADDetection(t *testing.T) {
 	}
 
 	// Save to temporary WAV file
-	tempFile := "test_audio.wav"
-	defer os.Remove(tempFile)
+	tempFile := filepath.Join(t.TempDir(), "test_audio.wav")
 
 	if err := audio.SaveToWAV(tempFile, audioData, sampleRate); err != nil {
 		t.Fatalf("Failed to save test audio: %v", err)
@@ -79,15 +89,19 @@ func TestVADDetection(t *testing.T) {
 
 	response, err := client.DetectFromFile(tempFile, req)
 	if err != nil {
-		t.Skipf("VAD server not available: %v", err)
+		t.Fatalf("Detection failed: %v", err)
 	}
 
 	if response.Status != "success" {
 		t.Errorf("Detection failed: %s", response.Message)
 	}
 
-	if response.Statistics.TotalAudioDuration <= 0 {
-		t.Error("Expected total duration to be positive")
+	if math.Abs(response.Statistics.TotalAudioDuration-duration) > 1e-6 {
+		t.Errorf("Expected total duration %.2fs, got %.2fs", duration, response.Statistics.TotalAudioDuration)
+	}
+
+	if len(response.SpeechSegments) != 1 || response.Statistics.ThresholdUsed != 0.3 {
+		t.Errorf("Expected one speech segment at threshold 0.3, got %+v", response)
 	}
 
 	t.Logf("Detection result: %d speech segments, total duration: %.2fs, speech ratio: %.2f",
@@ -98,6 +112,64 @@ func TestVADDetection(t *testing.T) {
 	}
 }
 
+func TestVADDetectionShortAudio(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	// 50ms is below the default minimum speech duration
+	hasSpeech, err := client.HasSpeechFromSamples(make([]float32, 800), 16000, nil)
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	if hasSpeech {
+		t.Error("Expected no speech for audio shorter than the minimum speech duration")
+	}
+}
+
+func TestVADDetectionErrors(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	client := NewClient(server.URL)
+	samples := make([]float32, 16000)
+
+	// HTTP error status
+	server.FailDetect(http.StatusBadRequest, "bad audio")
+	resp, err := client.DetectFromSamples(samples, 16000, nil)
+	if err == nil {
+		t.Fatal("Expected error for HTTP 400 response")
+	}
+	if resp == nil || resp.Message != "bad audio" {
+		t.Errorf("Expected error response to be returned, got %+v", resp)
+	}
+
+	// 200 with an unsuccessful status
+	server.FailDetect(http.StatusOK, "model not loaded")
+	if _, err := client.DetectFromSamples(samples, 16000, nil); err == nil {
+		t.Error("Expected error for unsuccessful detection status")
+	}
+
+	// Invalid audio upload
+	server.ClearFailure()
+	if _, err := client.DetectFromBytes([]byte("not a wav"), "audio.wav", nil); err == nil {
+		t.Error("Expected error for invalid WAV upload")
+	}
+
+	if server.DetectCount() != 3 {
+		t.Errorf("Expected 3 detect requests, got %d", server.DetectCount())
+	}
+}
+
+func TestVADHealthUnavailable(t *testing.T) {
+	server := NewTestServer()
+	client := NewClient(server.URL)
+	server.Close()
+
+	if _, err := client.Health(); err == nil {
+		t.Error("Expected error when the VAD server is down")
+	}
+}
+
 func TestVADService(t *testing.T) {
 	// Create audio input (this might fail if no audio device is available)
 	audioInput, err := audio.NewInput()
@@ -141,3 +213,38 @@ func TestVADService(t *testing.T) {
 
 	t.Log("VAD service test completed successfully")
 }
+
+func TestClientContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.DetectFromSamplesContext(ctx, make([]float32, 1600), 16000, nil)
+	if err == nil {
+		t.Fatal("Expected error for cancelled request")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected request to abort promptly, took %v", elapsed)
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := client.HealthContext(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected health check to honour a cancelled context, got %v", err)
+	}
+}
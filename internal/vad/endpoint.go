@@ -0,0 +1,219 @@
+package vad
+
+import (
+	"math"
+	"time"
+)
+
+// Endpointer decides when the user's turn has ended
+type Endpointer interface {
+	// Update feeds one audio frame and its VAD result, returns true when the turn should end
+	Update(samples []float32, sampleRate int, hasSpeech bool, now time.Time) bool
+	// Reset clears state before a new turn
+	Reset()
+}
+
+// SilenceEndpointer ends the turn after a fixed duration of silence
+type SilenceEndpointer struct {
+	silenceDuration time.Duration
+	silenceStart    time.Time
+}
+
+// NewSilenceEndpointer creates an endpointer based on silence duration
+func NewSilenceEndpointer(minSilenceDurationMs int) *SilenceEndpointer {
+	return &SilenceEndpointer{
+		silenceDuration: time.Duration(minSilenceDurationMs) * time.Millisecond,
+	}
+}
+
+// Update implements Endpointer
+func (e *SilenceEndpointer) Update(samples []float32, sampleRate int, hasSpeech bool, now time.Time) bool {
+	if hasSpeech {
+		e.silenceStart = time.Time{}
+		return false
+	}
+
+	if e.silenceStart.IsZero() {
+		e.silenceStart = now
+	}
+
+	return now.Sub(e.silenceStart) > e.silenceDuration
+}
+
+// Reset implements Endpointer
+func (e *SilenceEndpointer) Reset() {
+	e.silenceStart = time.Time{}
+}
+
+// EnergyEndpointerConfig represents energy decay endpointer configuration
+type EnergyEndpointerConfig struct {
+	SilenceDurationMs int     // How long energy must stay low before ending the turn
+	QuestionExtraMs   int     // Extra tolerance after question-like (rising) intonation
+	DecayRatio        float64 // Energy is considered decayed below peak*DecayRatio
+	Smoothing         float64 // Envelope smoothing factor (0-1, higher = slower)
+}
+
+// DefaultEnergyEndpointerConfig returns default energy endpointer configuration
+func DefaultEnergyEndpointerConfig() EnergyEndpointerConfig {
+	return EnergyEndpointerConfig{
+		SilenceDurationMs: 1000,
+		QuestionExtraMs:   800,
+		DecayRatio:        0.1,
+		Smoothing:         0.5,
+	}
+}
+
+// EnergyEndpointer ends the turn only after the energy envelope has decayed and stayed low
+type EnergyEndpointer struct {
+	config       EnergyEndpointerConfig
+	envelope     float64
+	peak         float64
+	lowSince     time.Time
+	questionLike bool
+	pitches      []float64
+}
+
+// maxPitchHistory is the number of voiced frames used to judge intonation
+const maxPitchHistory = 6
+
+// NewEnergyEndpointer creates an endpointer based on trailing energy decay
+func NewEnergyEndpointer(config EnergyEndpointerConfig) *EnergyEndpointer {
+	defaults := DefaultEnergyEndpointerConfig()
+	if config.SilenceDurationMs <= 0 {
+		config.SilenceDurationMs = defaults.SilenceDurationMs
+	}
+	if config.QuestionExtraMs < 0 {
+		config.QuestionExtraMs = 0
+	}
+	if config.DecayRatio <= 0 || config.DecayRatio >= 1 {
+		config.DecayRatio = defaults.DecayRatio
+	}
+	if config.Smoothing < 0 || config.Smoothing >= 1 {
+		config.Smoothing = defaults.Smoothing
+	}
+
+	return &EnergyEndpointer{config: config}
+}
+
+// Update implements Endpointer
+func (e *EnergyEndpointer) Update(samples []float32, sampleRate int, hasSpeech bool, now time.Time) bool {
+	rms := frameRMS(samples)
+	e.envelope = e.config.Smoothing*e.envelope + (1-e.config.Smoothing)*rms
+	if e.envelope > e.peak {
+		e.peak = e.envelope
+	}
+
+	if hasSpeech {
+		if pitch := estimatePitch(samples, sampleRate); pitch > 0 {
+			e.pitches = append(e.pitches, pitch)
+			if len(e.pitches) > maxPitchHistory {
+				e.pitches = e.pitches[1:]
+			}
+		}
+		e.lowSince = time.Time{}
+		return false
+	}
+
+	// Energy still high (e.g. trailing syllable), keep waiting
+	if e.peak > 0 && e.envelope >= e.peak*e.config.DecayRatio {
+		e.lowSince = time.Time{}
+		return false
+	}
+
+	if e.lowSince.IsZero() {
+		e.lowSince = now
+		e.questionLike = isRisingIntonation(e.pitches)
+	}
+
+	tolerance := time.Duration(e.config.SilenceDurationMs) * time.Millisecond
+	if e.questionLike {
+		tolerance += time.Duration(e.config.QuestionExtraMs) * time.Millisecond
+	}
+
+	return now.Sub(e.lowSince) >= tolerance
+}
+
+// Reset implements Endpointer
+func (e *EnergyEndpointer) Reset() {
+	e.envelope = 0
+	e.peak = 0
+	e.lowSince = time.Time{}
+	e.questionLike = false
+	e.pitches = e.pitches[:0]
+}
+
+// frameRMS computes the root mean square energy of a frame
+func frameRMS(samples []float32) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// estimatePitch estimates the fundamental frequency using autocorrelation, returns 0 if unvoiced
+func estimatePitch(samples []float32, sampleRate int) float64 {
+	if sampleRate <= 0 {
+		return 0
+	}
+
+	// Human voice range roughly 70-400 Hz
+	minLag := sampleRate / 400
+	maxLag := sampleRate / 70
+	if minLag < 1 || len(samples) < 2*maxLag {
+		return 0
+	}
+
+	var energy float64
+	for _, s := range samples {
+		energy += float64(s) * float64(s)
+	}
+	if energy == 0 {
+		return 0
+	}
+
+	bestLag := 0
+	bestCorr := 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		var corr float64
+		for i := 0; i+lag < len(samples); i++ {
+			corr += float64(samples[i]) * float64(samples[i+lag])
+		}
+		corr /= energy
+		if corr > bestCorr {
+			bestCorr = corr
+			bestLag = lag
+		}
+	}
+
+	// Weak periodicity means the frame is unvoiced
+	if bestLag == 0 || bestCorr < 0.3 {
+		return 0
+	}
+
+	return float64(sampleRate) / float64(bestLag)
+}
+
+// isRisingIntonation reports whether the pitch rises towards the end of the utterance
+func isRisingIntonation(pitches []float64) bool {
+	if len(pitches) < 3 {
+		return false
+	}
+
+	split := len(pitches) * 2 / 3
+	var head, tail float64
+	for _, p := range pitches[:split] {
+		head += p
+	}
+	for _, p := range pitches[split:] {
+		tail += p
+	}
+	head /= float64(split)
+	tail /= float64(len(pitches) - split)
+
+	return tail > head*1.1
+}
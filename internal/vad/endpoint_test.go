@@ -0,0 +1,93 @@
+package vad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// generateTone creates a sine tone frame for endpointer tests
+func generateTone(frequency float64, amplitude float32, sampleRate, numSamples int) []float32 {
+	samples := make([]float32, numSamples)
+	for i := range samples {
+		samples[i] = amplitude * float32(math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+func TestSilenceEndpointer(t *testing.T) {
+	endpointer := NewSilenceEndpointer(1000)
+	start := time.Now()
+	frame := make([]float32, 800)
+
+	if endpointer.Update(frame, 16000, true, start) {
+		t.Error("Expected no endpoint while speaking")
+	}
+	if endpointer.Update(frame, 16000, false, start.Add(500*time.Millisecond)) {
+		t.Error("Expected no endpoint before silence duration elapsed")
+	}
+	if !endpointer.Update(frame, 16000, false, start.Add(1600*time.Millisecond)) {
+		t.Error("Expected endpoint after silence duration elapsed")
+	}
+}
+
+func TestEnergyEndpointerWaitsForDecay(t *testing.T) {
+	config := DefaultEnergyEndpointerConfig()
+	config.QuestionExtraMs = 0
+	endpointer := NewEnergyEndpointer(config)
+
+	sampleRate := 16000
+	now := time.Now()
+	speech := generateTone(150, 0.5, sampleRate, 800)
+	trailing := generateTone(150, 0.3, sampleRate, 800)
+	silence := make([]float32, 800)
+
+	endpointer.Update(speech, sampleRate, true, now)
+
+	// VAD reports no speech but energy is still high: keep listening
+	for i := 1; i <= 30; i++ {
+		if endpointer.Update(trailing, sampleRate, false, now.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatal("Expected no endpoint while trailing energy is high")
+		}
+	}
+
+	// Energy decays and stays low
+	var ended bool
+	base := now.Add(1500 * time.Millisecond)
+	for i := 0; i <= 30 && !ended; i++ {
+		ended = endpointer.Update(silence, sampleRate, false, base.Add(time.Duration(i)*50*time.Millisecond))
+	}
+	if !ended {
+		t.Error("Expected endpoint after energy decayed and stayed low")
+	}
+}
+
+func TestEnergyEndpointerQuestionTolerance(t *testing.T) {
+	sampleRate := 16000
+	silence := make([]float32, 800)
+
+	run := func(frequencies []float64) time.Duration {
+		endpointer := NewEnergyEndpointer(DefaultEnergyEndpointerConfig())
+		now := time.Now()
+		for _, f := range frequencies {
+			endpointer.Update(generateTone(f, 0.5, sampleRate, 1600), sampleRate, true, now)
+		}
+		for i := 0; i < 100; i++ {
+			elapsed := time.Duration(i) * 50 * time.Millisecond
+			if endpointer.Update(silence, sampleRate, false, now.Add(elapsed)) {
+				return elapsed
+			}
+		}
+		return -1
+	}
+
+	flat := run([]float64{150, 150, 150, 150, 150, 150})
+	rising := run([]float64{150, 150, 150, 150, 220, 240})
+
+	if flat < 0 || rising < 0 {
+		t.Fatalf("Expected both turns to end, got flat=%v rising=%v", flat, rising)
+	}
+	if rising <= flat {
+		t.Errorf("Expected longer tolerance after rising intonation, got flat=%v rising=%v", flat, rising)
+	}
+}
@@ -0,0 +1,33 @@
+package vad
+
+import (
+	"math"
+
+	"audio-assistant/internal/audio"
+)
+
+// noiseGateFrameMs is the frame length used to measure energy for the noise gate
+const noiseGateFrameMs = 20
+
+// noiseGate returns a copy of samples with every frame quieter than floorDB (dBFS) zeroed
+// Steady background hum below the floor is removed while speech frames pass unchanged
+func noiseGate(samples []float32, sampleRate int, floorDB float64) []float32 {
+	gated := make([]float32, len(samples))
+	copy(gated, samples)
+	if sampleRate <= 0 {
+		return gated
+	}
+
+	floor := math.Pow(10, floorDB/20)
+	frameSize := max(sampleRate*noiseGateFrameMs/1000, 1)
+	for start := 0; start < len(gated); start += frameSize {
+		frame := gated[start:min(start+frameSize, len(gated))]
+		if audio.RMS(frame) < floor {
+			for i := range frame {
+				frame[i] = 0
+			}
+		}
+	}
+
+	return gated
+}
@@ -0,0 +1,94 @@
+package vad
+
+import (
+	"math"
+	"testing"
+)
+
+// humSignal generates 60Hz mains hum at the given amplitude
+func humSignal(seconds float64, sampleRate int, amplitude float64) []float32 {
+	samples := make([]float32, int(seconds*float64(sampleRate)))
+	for i := range samples {
+		samples[i] = float32(amplitude * math.Sin(2*math.Pi*60*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// humPlusSpeech mixes constant hum into a silence/speech/silence signal
+func humPlusSpeech(sampleRate int) []float32 {
+	signal := buildSignal(sampleRate, 0.5, 0.5, 0.5)
+	hum := humSignal(float64(len(signal))/float64(sampleRate), sampleRate, 0.05)
+	for i := range signal {
+		signal[i] += hum[i]
+	}
+	return signal
+}
+
+func TestNoiseGate(t *testing.T) {
+	const sampleRate = 16000
+	signal := humPlusSpeech(sampleRate)
+
+	gated := noiseGate(signal, sampleRate, -25)
+	if len(gated) != len(signal) {
+		t.Fatalf("Expected %d samples, got %d", len(signal), len(gated))
+	}
+
+	// Hum-only regions are zeroed, the speech region passes unchanged
+	for _, i := range []int{sampleRate / 10, sampleRate * 13 / 10} {
+		if gated[i] != 0 {
+			t.Errorf("Expected hum at sample %d to be gated, got %f", i, gated[i])
+		}
+	}
+	if mid := sampleRate * 3 / 4; gated[mid] != signal[mid] {
+		t.Errorf("Expected speech sample %d to pass, got %f (was %f)", mid, gated[mid], signal[mid])
+	}
+	if signal[sampleRate/10] == 0 {
+		t.Error("Expected noiseGate to leave its input untouched")
+	}
+}
+
+func TestServiceNoiseFloor(t *testing.T) {
+	const sampleRate = 16000
+	server := NewTestServer()
+	url := server.URL
+	server.Close()
+
+	config := DefaultConfig()
+	config.ServerURL = url
+	config.MaxRetries = 0
+	config.TempDir = t.TempDir()
+	config.LocalEnergyThreshold = 0.02 // the hum alone (RMS ~0.035) is above the detector threshold
+
+	newService := func(floorDB float64) *Service {
+		config.NoiseFloorDB = floorDB
+		service := NewService(config, nil)
+		if err := service.Start(); err != nil {
+			t.Fatalf("Failed to start service: %v", err)
+		}
+		return service
+	}
+
+	ungated := newService(0)
+	defer ungated.Stop()
+	response, err := ungated.DetectFromAudioData(humPlusSpeech(sampleRate), sampleRate)
+	if err != nil {
+		t.Fatalf("DetectFromAudioData failed: %v", err)
+	}
+	if len(response.SpeechSegments) != 1 || response.SpeechSegments[0].Duration < 1.4 {
+		t.Fatalf("Expected hum to be reported as speech without the gate, got %+v", response.SpeechSegments)
+	}
+
+	gated := newService(-25)
+	defer gated.Stop()
+	response, err = gated.DetectFromAudioData(humPlusSpeech(sampleRate), sampleRate)
+	if err != nil {
+		t.Fatalf("DetectFromAudioData failed: %v", err)
+	}
+	if len(response.SpeechSegments) != 1 {
+		t.Fatalf("Expected one speech segment, got %+v", response.SpeechSegments)
+	}
+	segment := response.SpeechSegments[0]
+	if math.Abs(segment.Start-0.5) > 0.05 || math.Abs(segment.End-1.0) > 0.05 {
+		t.Errorf("Expected segment around 0.5-1.0s, got %.3f-%.3f", segment.Start, segment.End)
+	}
+}
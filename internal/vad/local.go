@@ -0,0 +1,188 @@
+package vad
+
+import (
+	"math"
+)
+
+// LocalDetectorConfig represents local energy-based VAD configuration
+type LocalDetectorConfig struct {
+	FrameSizeMs          int     // Analysis frame length
+	EnergyThreshold      float64 // Minimum frame RMS considered speech
+	MaxZeroCrossingRate  float64 // Frames with a higher zero-crossing rate are treated as noise (0 disables)
+	MinSpeechDurationMs  int     // Shorter speech runs are dropped
+	MinSilenceDurationMs int     // Shorter gaps between speech runs are merged
+}
+
+// DefaultLocalDetectorConfig returns default local VAD configuration
+func DefaultLocalDetectorConfig() LocalDetectorConfig {
+	return LocalDetectorConfig{
+		FrameSizeMs:          30,
+		EnergyThreshold:      0.02,
+		MaxZeroCrossingRate:  0.35,
+		MinSpeechDurationMs:  250,
+		MinSilenceDurationMs: 100,
+	}
+}
+
+// LocalDetector is a pure-Go VAD based on short-term energy and zero-crossing rate
+// It needs no server and is used as a fallback when the VAD service is unreachable
+type LocalDetector struct {
+	config LocalDetectorConfig
+}
+
+// NewLocalDetector creates a local VAD detector
+func NewLocalDetector(config LocalDetectorConfig) *LocalDetector {
+	defaults := DefaultLocalDetectorConfig()
+	if config.FrameSizeMs <= 0 {
+		config.FrameSizeMs = defaults.FrameSizeMs
+	}
+	if config.EnergyThreshold <= 0 {
+		config.EnergyThreshold = defaults.EnergyThreshold
+	}
+	if config.MaxZeroCrossingRate < 0 {
+		config.MaxZeroCrossingRate = 0
+	}
+	if config.MinSpeechDurationMs < 0 {
+		config.MinSpeechDurationMs = 0
+	}
+	if config.MinSilenceDurationMs < 0 {
+		config.MinSilenceDurationMs = 0
+	}
+
+	return &LocalDetector{config: config}
+}
+
+// Config returns the detector configuration
+func (d *LocalDetector) Config() LocalDetectorConfig {
+	return d.config
+}
+
+// SetEnergyThreshold replaces the frame RMS threshold, e.g. after adaptive calibration
+// Non-positive values are ignored. It must not be called concurrently with detection
+func (d *LocalDetector) SetEnergyThreshold(threshold float64) {
+	if threshold > 0 {
+		d.config.EnergyThreshold = threshold
+	}
+}
+
+// Detect returns the speech segments found in the samples
+func (d *LocalDetector) Detect(samples []float32, sampleRate int) []SpeechSegment {
+	segments := []SpeechSegment{}
+	if sampleRate <= 0 || len(samples) == 0 {
+		return segments
+	}
+
+	frameSize := sampleRate * d.config.FrameSizeMs / 1000
+	if frameSize < 1 {
+		frameSize = 1
+	}
+	frameDuration := float64(frameSize) / float64(sampleRate)
+	totalDuration := float64(len(samples)) / float64(sampleRate)
+
+	// Classify frames and collect raw speech runs
+	var runs [][2]float64
+	inSpeech := false
+	var runStart float64
+	for start := 0; start < len(samples); start += frameSize {
+		end := start + frameSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		frameTime := float64(start) / float64(sampleRate)
+		if d.isSpeechFrame(samples[start:end]) {
+			if !inSpeech {
+				inSpeech = true
+				runStart = frameTime
+			}
+		} else if inSpeech {
+			inSpeech = false
+			runs = append(runs, [2]float64{runStart, frameTime})
+		}
+	}
+	if inSpeech {
+		runs = append(runs, [2]float64{runStart, totalDuration})
+	}
+
+	// Merge runs separated by short gaps
+	minSilence := float64(d.config.MinSilenceDurationMs) / 1000
+	var merged [][2]float64
+	for _, run := range runs {
+		if n := len(merged); n > 0 && run[0]-merged[n-1][1] < minSilence {
+			merged[n-1][1] = run[1]
+			continue
+		}
+		merged = append(merged, run)
+	}
+
+	// Drop runs that are too short to be speech, a single frame is never enough
+	minSpeech := math.Max(float64(d.config.MinSpeechDurationMs)/1000, frameDuration)
+	for _, run := range merged {
+		if duration := run[1] - run[0]; duration >= minSpeech {
+			segments = append(segments, SpeechSegment{Start: run[0], End: run[1], Duration: duration})
+		}
+	}
+
+	return segments
+}
+
+// DetectResponse runs detection and wraps the result like a VAD server response
+func (d *LocalDetector) DetectResponse(samples []float32, sampleRate int) *DetectResponse {
+	segments := d.Detect(samples, sampleRate)
+
+	var audioDuration, speechDuration float64
+	if sampleRate > 0 {
+		audioDuration = float64(len(samples)) / float64(sampleRate)
+	}
+	for _, seg := range segments {
+		speechDuration += seg.Duration
+	}
+
+	stats := DetectStatistics{
+		TotalSegments:       len(segments),
+		TotalSpeechDuration: speechDuration,
+		TotalAudioDuration:  audioDuration,
+		SampleRate:          sampleRate,
+		ThresholdUsed:       d.config.EnergyThreshold,
+	}
+	if audioDuration > 0 {
+		stats.SpeechRatio = speechDuration / audioDuration
+	}
+
+	return &DetectResponse{
+		Status:         "success",
+		SpeechSegments: segments,
+		Statistics:     stats,
+	}
+}
+
+// HasSpeech reports whether the samples contain any speech segment
+func (d *LocalDetector) HasSpeech(samples []float32, sampleRate int) bool {
+	return len(d.Detect(samples, sampleRate)) > 0
+}
+
+// isSpeechFrame classifies a frame by energy and zero-crossing rate
+func (d *LocalDetector) isSpeechFrame(frame []float32) bool {
+	if frameRMS(frame) < d.config.EnergyThreshold {
+		return false
+	}
+	if d.config.MaxZeroCrossingRate > 0 && zeroCrossingRate(frame) > d.config.MaxZeroCrossingRate {
+		return false
+	}
+	return true
+}
+
+// zeroCrossingRate returns the fraction of adjacent samples that change sign
+func zeroCrossingRate(frame []float32) float64 {
+	if len(frame) < 2 {
+		return 0
+	}
+
+	crossings := 0
+	for i := 1; i < len(frame); i++ {
+		if (frame[i-1] < 0) != (frame[i] < 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(frame)-1)
+}
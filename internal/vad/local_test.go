@@ -0,0 +1,180 @@
+package vad
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"audio-assistant/internal/logging"
+)
+
+// voicedBurst generates a harmonic tone resembling a voiced vowel
+func voicedBurst(seconds float64, sampleRate int) []float32 {
+	n := int(seconds * float64(sampleRate))
+	samples := make([]float32, n)
+	for i := range samples {
+		t := float64(i) / float64(sampleRate)
+		v := 0.3*math.Sin(2*math.Pi*150*t) + 0.15*math.Sin(2*math.Pi*300*t) + 0.05*math.Sin(2*math.Pi*450*t)
+		samples[i] = float32(v)
+	}
+	return samples
+}
+
+// buildSignal concatenates alternating silence and speech bursts, starting with silence
+func buildSignal(sampleRate int, durations ...float64) []float32 {
+	var samples []float32
+	for i, d := range durations {
+		if i%2 == 0 {
+			samples = append(samples, make([]float32, int(d*float64(sampleRate)))...)
+		} else {
+			samples = append(samples, voicedBurst(d, sampleRate)...)
+		}
+	}
+	return samples
+}
+
+func TestLocalDetectorSegments(t *testing.T) {
+	const sampleRate = 16000
+	detector := NewLocalDetector(DefaultLocalDetectorConfig())
+	frame := float64(detector.Config().FrameSizeMs) / 1000
+
+	// silence 0.3s, speech 0.5s, silence 0.6s, speech 0.6s, silence 0.3s
+	samples := buildSignal(sampleRate, 0.3, 0.5, 0.6, 0.6, 0.3)
+	segments := detector.Detect(samples, sampleRate)
+
+	expected := []SpeechSegment{{Start: 0.3, End: 0.8}, {Start: 1.4, End: 2.0}}
+	if len(segments) != len(expected) {
+		t.Fatalf("Expected %d segments, got %d: %+v", len(expected), len(segments), segments)
+	}
+	for i, seg := range segments {
+		if math.Abs(seg.Start-expected[i].Start) > frame || math.Abs(seg.End-expected[i].End) > frame {
+			t.Errorf("Segment %d: expected %.2f-%.2f, got %.3f-%.3f", i, expected[i].Start, expected[i].End, seg.Start, seg.End)
+		}
+		if math.Abs(seg.Duration-(seg.End-seg.Start)) > 1e-9 {
+			t.Errorf("Segment %d: duration %.3f does not match bounds", i, seg.Duration)
+		}
+	}
+}
+
+func TestLocalDetectorSilence(t *testing.T) {
+	detector := NewLocalDetector(DefaultLocalDetectorConfig())
+	if detector.HasSpeech(make([]float32, 16000), 16000) {
+		t.Error("Expected no speech in silence")
+	}
+	if segments := detector.Detect(nil, 16000); len(segments) != 0 {
+		t.Errorf("Expected no segments for empty input, got %+v", segments)
+	}
+}
+
+func TestLocalDetectorShortBurstAndGap(t *testing.T) {
+	const sampleRate = 16000
+	detector := NewLocalDetector(DefaultLocalDetectorConfig())
+
+	// A 60ms click is shorter than MinSpeechDurationMs and should be dropped
+	if segments := detector.Detect(buildSignal(sampleRate, 0.3, 0.06, 0.3), sampleRate); len(segments) != 0 {
+		t.Errorf("Expected short burst to be dropped, got %+v", segments)
+	}
+
+	// A 60ms pause is shorter than MinSilenceDurationMs and should be merged
+	segments := detector.Detect(buildSignal(sampleRate, 0.3, 0.4, 0.06, 0.4, 0.3), sampleRate)
+	if len(segments) != 1 {
+		t.Fatalf("Expected short gap to be merged into one segment, got %+v", segments)
+	}
+	if segments[0].Duration < 0.8 {
+		t.Errorf("Expected merged segment of about 0.86s, got %.3f", segments[0].Duration)
+	}
+}
+
+func TestLocalDetectorRejectsNoise(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]float32, 16000)
+	for i := range noise {
+		noise[i] = float32(rng.Float64()*0.6 - 0.3)
+	}
+
+	detector := NewLocalDetector(DefaultLocalDetectorConfig())
+	if detector.HasSpeech(noise, 16000) {
+		t.Error("Expected loud white noise to be rejected by the zero-crossing rate check")
+	}
+
+	config := DefaultLocalDetectorConfig()
+	config.MaxZeroCrossingRate = 0
+	if !NewLocalDetector(config).HasSpeech(noise, 16000) {
+		t.Error("Expected noise to pass when the zero-crossing check is disabled")
+	}
+}
+
+func TestLocalDetectorThreshold(t *testing.T) {
+	config := DefaultLocalDetectorConfig()
+	config.EnergyThreshold = 0.5
+	config.FrameSizeMs = 20
+
+	detector := NewLocalDetector(config)
+	if detector.Config().FrameSizeMs != 20 {
+		t.Errorf("Expected frame size 20ms, got %d", detector.Config().FrameSizeMs)
+	}
+	if detector.HasSpeech(buildSignal(16000, 0.2, 0.5, 0.2), 16000) {
+		t.Error("Expected quiet burst to fall below a high energy threshold")
+	}
+}
+
+func TestServiceLocalFallback(t *testing.T) {
+	server := NewTestServer()
+	url := server.URL
+	server.Close()
+
+	config := DefaultConfig()
+	config.ServerURL = url
+	config.MaxRetries = 0
+	config.TempDir = t.TempDir()
+
+	service := NewService(config, nil)
+	if err := service.Start(); err != nil {
+		t.Fatalf("Expected fallback to local detector, got %v", err)
+	}
+	defer service.Stop()
+
+	if !service.IsLocal() {
+		t.Fatal("Expected service to use the local detector")
+	}
+
+	response, err := service.DetectFromAudioData(buildSignal(16000, 0.3, 0.5, 0.3), 16000)
+	if err != nil {
+		t.Fatalf("DetectFromAudioData failed: %v", err)
+	}
+	if len(response.SpeechSegments) != 1 {
+		t.Errorf("Expected one speech segment, got %+v", response.SpeechSegments)
+	}
+
+	config.LocalFallback = false
+	strict := NewService(config, nil)
+	if err := strict.Start(); err == nil {
+		t.Error("Expected Start to fail when fallback is disabled")
+	}
+}
+
+func TestServiceLogsFallback(t *testing.T) {
+	server := NewTestServer()
+	url := server.URL
+	server.Close()
+
+	recorder := logging.NewRecorder()
+	config := DefaultConfig()
+	config.ServerURL = url
+	config.MaxRetries = 0
+	config.TempDir = t.TempDir()
+	config.Logger = recorder
+
+	service := NewService(config, nil)
+	if err := service.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	service.Stop()
+
+	if !recorder.Contains(logging.LevelWarn, "falling back to local energy detector") {
+		t.Errorf("Expected fallback warning, got %+v", recorder.Entries())
+	}
+	if !recorder.Contains(logging.LevelInfo, "VAD service started (local)") || !recorder.Contains(logging.LevelInfo, "VAD service stopped") {
+		t.Errorf("Expected start and stop info lines, got %+v", recorder.Entries())
+	}
+}
@@ -1,13 +1,15 @@
 package vad
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
 	"audio-assistant/internal/audio"
+	"audio-assistant/internal/logging"
+	"audio-assistant/internal/retry"
 )
 
 // Service manages VAD operations and integrates with audio module
@@ -19,6 +21,20 @@ type Service struct {
 	stopChan   chan struct{}
 	resultChan chan *DetectResponse
 	tempDir    string
+
+	// Model info fetched on Start, used to adapt audio to the server's expectations
+	modelInfo       *InfoResponse
+	resampleNoticed bool
+
+	// Local detector used when the server is unreachable at Start
+	localDetector *LocalDetector
+	localFallback bool
+	useLocal      bool
+
+	// Noise gate applied before detection
+	noiseFloorDB float64
+
+	logger logging.Logger
 }
 
 // Config represents VAD service configuration
@@ -28,6 +44,15 @@ type Config struct {
 	MinSpeechDurationMs  int
 	MinSilenceDurationMs int
 	TempDir              string
+	MaxRetries           int               // Retries for transient server failures (429/5xx)
+	RetryBaseDelay       time.Duration     // Initial backoff delay, doubled on each retry
+	LocalFallback        bool              // Use the local energy detector when the server health check fails
+	LocalEnergyThreshold float64           // Frame RMS threshold for the local detector
+	LocalFrameSizeMs     int               // Frame length for the local detector
+	NoiseFloorDB         float64           // Frames quieter than this level (dBFS, e.g. -40) are zeroed before detection, 0 disables the gate
+	Transport            http.RoundTripper // Underlying transport (proxy, custom TLS), nil uses http.DefaultTransport
+	HTTPClient           *http.Client      // Replaces the default client entirely when set
+	Logger               logging.Logger    // Receives service logs, nil uses the standard log package
 }
 
 // DefaultConfig returns default VAD configuration
@@ -38,6 +63,11 @@ func DefaultConfig() *Config {
 		MinSpeechDurationMs:  250,
 		MinSilenceDurationMs: 100,
 		TempDir:              "temp",
+		MaxRetries:           retry.DefaultMaxRetries,
+		RetryBaseDelay:       retry.DefaultBaseDelay,
+		LocalFallback:        true,
+		LocalEnergyThreshold: DefaultLocalDetectorConfig().EnergyThreshold,
+		LocalFrameSizeMs:     DefaultLocalDetectorConfig().FrameSizeMs,
 	}
 }
 
@@ -47,15 +77,38 @@ func NewService(config *Config, audioInput *audio.Input) *Service {
 		config = DefaultConfig()
 	}
 
+	logger := logging.OrStd(config.Logger)
+
 	// Ensure temp directory exists
 	if err := os.MkdirAll(config.TempDir, 0755); err != nil {
-		log.Printf("Warning: failed to create temp directory %s: %v", config.TempDir, err)
+		logger.Warn("failed to create temp directory %s: %v", config.TempDir, err)
 		config.TempDir = "."
 	}
 
+	client := NewClient(config.ServerURL)
+	client.SetRetryPolicy(config.MaxRetries, config.RetryBaseDelay)
+	if config.Transport != nil {
+		client.SetTransport(config.Transport)
+	}
+	if config.HTTPClient != nil {
+		client.SetHTTPClient(config.HTTPClient)
+	}
+
+	localDetector := NewLocalDetector(LocalDetectorConfig{
+		FrameSizeMs:          config.LocalFrameSizeMs,
+		EnergyThreshold:      config.LocalEnergyThreshold,
+		MaxZeroCrossingRate:  DefaultLocalDetectorConfig().MaxZeroCrossingRate,
+		MinSpeechDurationMs:  config.MinSpeechDurationMs,
+		MinSilenceDurationMs: config.MinSilenceDurationMs,
+	})
+
 	return &Service{
-		client:     NewClient(config.ServerURL),
-		audioInput: audioInput,
+		client:        client,
+		localDetector: localDetector,
+		localFallback: config.LocalFallback,
+		noiseFloorDB:  config.NoiseFloorDB,
+		logger:        logger,
+		audioInput:    audioInput,
 		vadConfig: &DetectRequest{
 			Threshold:            config.Threshold,
 			MinSpeechDurationMs:  config.MinSpeechDurationMs,
@@ -69,29 +122,43 @@ func NewService(config *Config, audioInput *audio.Input) *Service {
 
 // Start starts the VAD service
 func (s *Service) Start() error {
+	return s.StartContext(context.Background())
+}
+
+// StartContext starts the VAD service, bounding the health and info requests by ctx
+func (s *Service) StartContext(ctx context.Context) error {
 	if s.isRunning {
 		return fmt.Errorf("VAD service is already running")
 	}
 
 	// Check if VAD server is healthy
-	health, err := s.client.Health()
+	health, err := s.client.HealthContext(ctx)
 	if err != nil {
-		return fmt.Errorf("VAD server health check failed: %w", err)
+		if !s.localFallback {
+			return fmt.Errorf("VAD server health check failed: %w", err)
+		}
+		s.logger.Warn("VAD server unreachable (%v), falling back to local energy detector", err)
+		s.useLocal = true
+		s.isRunning = true
+		s.logger.Info("VAD service started (local)")
+		return nil
 	}
 
-	log.Printf("VAD server is healthy: %s", health.Status)
+	s.logger.Info("VAD server is healthy: %s", health.Status)
 
 	// Get model info
-	info, err := s.client.Info()
+	info, err := s.client.InfoContext(ctx)
 	if err != nil {
-		log.Printf("Warning: failed to get VAD model info: %v", err)
+		s.logger.Warn("failed to get VAD model info: %v", err)
 	} else {
-		log.Printf("VAD model: %s, sample rate: %d Hz, window size: %d ms",
+		s.logger.Info("VAD model: %s, sample rate: %d Hz, window size: %d ms",
 			info.ModelName, info.SampleRate, info.WindowSizeMs)
+		s.modelInfo = info
 	}
 
+	s.useLocal = false
 	s.isRunning = true
-	log.Println("VAD service started")
+	s.logger.Info("VAD service started")
 
 	return nil
 }
@@ -104,7 +171,7 @@ func (s *Service) Stop() {
 
 	close(s.stopChan)
 	s.isRunning = false
-	log.Println("VAD service stopped")
+	s.logger.Info("VAD service stopped")
 }
 
 // IsRunning returns whether the service is running
@@ -112,23 +179,47 @@ func (s *Service) IsRunning() bool {
 	return s.isRunning
 }
 
+// Ping checks that the VAD server answers /health
+// It reports the server state even when detection has fallen back to the local detector
+func (s *Service) Ping(ctx context.Context) error {
+	_, err := s.client.HealthContext(ctx)
+	return err
+}
+
+// IsLocal returns whether detection runs on the local fallback detector
+func (s *Service) IsLocal() bool {
+	return s.useLocal
+}
+
 // DetectFromAudioData detects speech activity from audio data
 func (s *Service) DetectFromAudioData(audioData []float32, sampleRate int) (*DetectResponse, error) {
+	return s.DetectFromAudioDataContext(context.Background(), audioData, sampleRate)
+}
+
+// DetectFromAudioDataContext detects speech activity from audio data, aborting when ctx is cancelled
+func (s *Service) DetectFromAudioDataContext(ctx context.Context, audioData []float32, sampleRate int) (*DetectResponse, error) {
 	if !s.isRunning {
 		return nil, fmt.Errorf("VAD service is not running")
 	}
 
-	// Create temporary WAV file
-	tempFile := filepath.Join(s.tempDir, fmt.Sprintf("vad_temp_%d.wav", time.Now().UnixNano()))
-	defer os.Remove(tempFile) // Clean up temp file
+	// Silence background hum below the noise floor so it isn't reported as speech
+	if s.noiseFloorDB < 0 {
+		audioData = noiseGate(audioData, sampleRate, s.noiseFloorDB)
+	}
+
+	if s.useLocal {
+		return s.localDetector.DetectResponse(audioData, sampleRate), nil
+	}
 
-	// Save audio data to WAV file
-	if err := audio.SaveToWAV(tempFile, audioData, sampleRate); err != nil {
-		return nil, fmt.Errorf("failed to save audio to WAV: %w", err)
+	// Match the model's sample rate and window size
+	if s.modelInfo != nil && s.modelInfo.SampleRate > 0 && sampleRate != s.modelInfo.SampleRate && !s.resampleNoticed {
+		s.logger.Info("VAD notice: resampling audio from %d Hz to model rate %d Hz", sampleRate, s.modelInfo.SampleRate)
+		s.resampleNoticed = true
 	}
+	audioData, sampleRate = adaptToModel(audioData, sampleRate, s.modelInfo)
 
-	// Detect speech activity
-	response, err := s.client.DetectFromFile(tempFile, s.vadConfig)
+	// Detect speech activity from memory, no temp file per chunk
+	response, err := s.client.DetectFromSamplesContext(ctx, audioData, sampleRate, s.vadConfig)
 	if err != nil {
 		return nil, fmt.Errorf("VAD detection failed: %w", err)
 	}
@@ -136,13 +227,49 @@ func (s *Service) DetectFromAudioData(audioData []float32, sampleRate int) (*Det
 	return response, nil
 }
 
+// adaptToModel resamples audio to the model sample rate and pads it to a whole number of windows
+func adaptToModel(audioData []float32, sampleRate int, info *InfoResponse) ([]float32, int) {
+	if info == nil || info.SampleRate <= 0 {
+		return audioData, sampleRate
+	}
+
+	if sampleRate != info.SampleRate {
+		audioData = audio.ResampleLinear(audioData, sampleRate, info.SampleRate)
+		sampleRate = info.SampleRate
+	}
+
+	windowSamples := sampleRate * info.WindowSizeMs / 1000
+	if windowSamples > 0 {
+		if remainder := len(audioData) % windowSamples; remainder != 0 || len(audioData) == 0 {
+			padded := make([]float32, len(audioData)+windowSamples-remainder)
+			copy(padded, audioData)
+			audioData = padded
+		}
+	}
+
+	return audioData, sampleRate
+}
+
 // DetectFromFile detects speech activity from an audio file
 func (s *Service) DetectFromFile(filePath string) (*DetectResponse, error) {
+	return s.DetectFromFileContext(context.Background(), filePath)
+}
+
+// DetectFromFileContext detects speech activity from an audio file, aborting when ctx is cancelled
+func (s *Service) DetectFromFileContext(ctx context.Context, filePath string) (*DetectResponse, error) {
 	if !s.isRunning {
 		return nil, fmt.Errorf("VAD service is not running")
 	}
 
-	response, err := s.client.DetectFromFile(filePath, s.vadConfig)
+	if s.useLocal {
+		audioData, sampleRate, err := audio.LoadFromWAV(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load audio file: %w", err)
+		}
+		return s.localDetector.DetectResponse(audioData, sampleRate), nil
+	}
+
+	response, err := s.client.DetectFromFileContext(ctx, filePath, s.vadConfig)
 	if err != nil {
 		return nil, fmt.Errorf("VAD detection failed: %w", err)
 	}
@@ -180,6 +307,16 @@ func (s *Service) GetSpeechSegments(audioData []float32, sampleRate int) ([]Spee
 	return response.SpeechSegments, nil
 }
 
+// GetStatistics returns the detection statistics for audio data: speech ratio, durations and the threshold used
+func (s *Service) GetStatistics(audioData []float32, sampleRate int) (*DetectStatistics, error) {
+	response, err := s.DetectFromAudioData(audioData, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.Statistics, nil
+}
+
 // GetSpeechSegmentsFromFile returns speech segments from audio file
 func (s *Service) GetSpeechSegmentsFromFile(filePath string) ([]SpeechSegment, error) {
 	response, err := s.DetectFromFile(filePath)
@@ -196,7 +333,12 @@ func (s *Service) UpdateConfig(threshold float64, minSpeechMs, minSilenceMs int)
 	s.vadConfig.MinSpeechDurationMs = minSpeechMs
 	s.vadConfig.MinSilenceDurationMs = minSilenceMs
 
-	log.Printf("VAD config updated: threshold=%.2f, min_speech=%dms, min_silence=%dms",
+	localConfig := s.localDetector.Config()
+	localConfig.MinSpeechDurationMs = minSpeechMs
+	localConfig.MinSilenceDurationMs = minSilenceMs
+	s.localDetector = NewLocalDetector(localConfig)
+
+	s.logger.Info("VAD config updated: threshold=%.2f, min_speech=%dms, min_silence=%dms",
 		threshold, minSpeechMs, minSilenceMs)
 }
 
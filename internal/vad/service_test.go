@@ -0,0 +1,198 @@
+package vad
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"audio-assistant/internal/audio"
+)
+
+func TestAdaptToModel(t *testing.T) {
+	info := &InfoResponse{ModelName: "silero", SampleRate: 16000, WindowSizeMs: 32}
+
+	// 48 kHz input should be resampled to 16 kHz and padded to whole 512-sample windows
+	input := make([]float32, 4800) // 100 ms
+	adapted, rate := adaptToModel(input, 48000, info)
+	if rate != 16000 {
+		t.Errorf("Expected sample rate 16000, got %d", rate)
+	}
+	if len(adapted)%512 != 0 || len(adapted) < 1600 {
+		t.Errorf("Expected padded length multiple of 512 and >= 1600, got %d", len(adapted))
+	}
+
+	// Matching rate and whole windows should be left untouched
+	input = make([]float32, 1024)
+	adapted, rate = adaptToModel(input, 16000, info)
+	if rate != 16000 || len(adapted) != 1024 {
+		t.Errorf("Expected unchanged audio, got %d samples at %d Hz", len(adapted), rate)
+	}
+
+	// Without model info nothing changes
+	adapted, rate = adaptToModel(input, 44100, nil)
+	if rate != 44100 || len(adapted) != 1024 {
+		t.Errorf("Expected passthrough without model info, got %d samples at %d Hz", len(adapted), rate)
+	}
+}
+
+func TestDetectFromSamples(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	samples := make([]float32, 8000) // 500ms at 16kHz
+
+	hasSpeech, err := client.HasSpeechFromSamples(samples, 16000, &DetectRequest{Threshold: 0.5})
+	if err != nil {
+		t.Fatalf("HasSpeechFromSamples failed: %v", err)
+	}
+	if !hasSpeech {
+		t.Error("Expected speech to be detected")
+	}
+}
+
+func BenchmarkDetectTempFile(b *testing.B) {
+	server := NewTestServer()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	samples := make([]float32, 800)
+	dir := b.TempDir()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tempFile := filepath.Join(dir, "bench.wav")
+		if err := audio.SaveToWAV(tempFile, samples, 16000); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := client.DetectFromFile(tempFile, nil); err != nil {
+			b.Fatal(err)
+		}
+		os.Remove(tempFile)
+	}
+}
+
+func BenchmarkDetectInMemory(b *testing.B) {
+	server := NewTestServer()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	samples := make([]float32, 800)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.DetectFromSamples(samples, 16000, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestServicePing(t *testing.T) {
+	server := NewTestServer()
+
+	config := DefaultConfig()
+	config.ServerURL = server.URL
+	config.MaxRetries = 0
+	config.TempDir = t.TempDir()
+
+	service := NewService(config, nil)
+	if err := service.Ping(context.Background()); err != nil {
+		t.Errorf("Expected healthy server, got %v", err)
+	}
+
+	server.Close()
+	if err := service.Ping(context.Background()); err == nil {
+		t.Error("Expected Ping to fail once the server is down")
+	}
+}
+
+// recordingTransport records outbound request URLs and answers with an empty JSON object
+type recordingTransport struct {
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"status":"healthy"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestServiceCustomTransport(t *testing.T) {
+	transport := &recordingTransport{}
+	config := DefaultConfig()
+	config.TempDir = t.TempDir()
+	config.Transport = transport
+
+	service := NewService(config, nil)
+	if err := service.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if len(transport.urls) != 1 || transport.urls[0] != "http://localhost:8000/health" {
+		t.Errorf("Expected request through the custom transport, got %v", transport.urls)
+	}
+}
+
+// detectTransport answers health checks and returns a fixed detection result for every other request
+type detectTransport struct {
+	body string
+}
+
+func (t *detectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := t.body
+	status := http.StatusOK
+	switch req.URL.Path {
+	case "/health":
+		body = `{"status":"healthy"}`
+	case "/info":
+		status = http.StatusNotFound
+		body = `{}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestServiceGetStatistics(t *testing.T) {
+	config := DefaultConfig()
+	config.TempDir = t.TempDir()
+	config.MaxRetries = 0
+	config.Transport = &detectTransport{body: `{
+		"status": "success",
+		"speech_segments": [{"start": 0.1, "end": 0.4, "duration": 0.3}],
+		"statistics": {"total_segments": 1, "total_speech_duration": 0.3, "total_audio_duration": 0.5,
+			"speech_ratio": 0.6, "sample_rate": 16000, "threshold_used": 0.45}
+	}`}
+
+	service := NewService(config, nil)
+	if err := service.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer service.Stop()
+
+	stats, err := service.GetStatistics(make([]float32, 8000), 16000)
+	if err != nil {
+		t.Fatalf("GetStatistics failed: %v", err)
+	}
+	expected := DetectStatistics{TotalSegments: 1, TotalSpeechDuration: 0.3, TotalAudioDuration: 0.5, SpeechRatio: 0.6, SampleRate: 16000, ThresholdUsed: 0.45}
+	if *stats != expected {
+		t.Errorf("Expected statistics to pass through unchanged, got %+v", *stats)
+	}
+
+	// Accumulating chunks recomputes the ratio over the whole recording
+	total := *stats
+	total.Add(DetectStatistics{TotalAudioDuration: 0.5, SampleRate: 16000, ThresholdUsed: 0.45})
+	if total.TotalSegments != 1 || total.TotalAudioDuration != 1.0 || total.SpeechRatio != 0.3 {
+		t.Errorf("Unexpected accumulated statistics: %+v", total)
+	}
+}
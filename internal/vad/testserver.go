@@ -0,0 +1,171 @@
+package vad
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Test server defaults, mirroring the Silero model served by scripts/vad_service.py
+const (
+	testServerModelName          = "silero_vad (test server)"
+	testServerSampleRate         = 16000
+	testServerWindowSizeMs       = 32
+	testServerDefaultMinSpeechMs = 250
+)
+
+// TestServer is an in-process VAD server for tests and local runs without the Python service
+// Detection is deterministic: audio at least MinSpeechDurationMs long is reported as one
+// speech segment covering the whole clip, shorter audio has no speech
+type TestServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	failStatus  int
+	failMessage string
+	detectCount int
+}
+
+// NewTestServer starts a VAD test server, callers must Close it
+func NewTestServer() *TestServer {
+	s := &TestServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/info", s.handleInfo)
+	mux.HandleFunc("/detect", s.handleDetect)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// FailDetect makes subsequent /detect calls fail
+// A statusCode of 200 returns an unsuccessful response body instead of an HTTP error
+func (s *TestServer) FailDetect(statusCode int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failStatus = statusCode
+	s.failMessage = message
+}
+
+// ClearFailure makes /detect succeed again
+func (s *TestServer) ClearFailure() {
+	s.FailDetect(0, "")
+}
+
+// DetectCount returns how many /detect requests were received
+func (s *TestServer) DetectCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.detectCount
+}
+
+func (s *TestServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, HealthResponse{
+		Status:    "healthy",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+func (s *TestServer) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, InfoResponse{
+		ModelName:    testServerModelName,
+		SampleRate:   testServerSampleRate,
+		WindowSizeMs: testServerWindowSizeMs,
+	})
+}
+
+func (s *TestServer) handleDetect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeTestJSON(w, http.StatusMethodNotAllowed, DetectResponse{Status: "error", Message: "method not allowed"})
+		return
+	}
+
+	s.mu.Lock()
+	s.detectCount++
+	failStatus, failMessage := s.failStatus, s.failMessage
+	s.mu.Unlock()
+
+	if failStatus != 0 {
+		writeTestJSON(w, failStatus, DetectResponse{Status: "error", Message: failMessage})
+		return
+	}
+
+	file, _, err := r.FormFile("audio_file")
+	if err != nil {
+		writeTestJSON(w, http.StatusBadRequest, DetectResponse{Status: "error", Message: "missing audio_file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeTestJSON(w, http.StatusBadRequest, DetectResponse{Status: "error", Message: err.Error()})
+		return
+	}
+
+	duration, sampleRate, err := wavDuration(data)
+	if err != nil {
+		writeTestJSON(w, http.StatusBadRequest, DetectResponse{Status: "error", Message: err.Error()})
+		return
+	}
+
+	threshold := formFloat(r, "threshold", 0.5)
+	minSpeechMs := formFloat(r, "min_speech_duration_ms", testServerDefaultMinSpeechMs)
+
+	resp := DetectResponse{
+		Status:         "success",
+		SpeechSegments: []SpeechSegment{},
+		Statistics: DetectStatistics{
+			TotalAudioDuration: duration,
+			SampleRate:         sampleRate,
+			ThresholdUsed:      threshold,
+		},
+	}
+	if duration > 0 && duration*1000 >= minSpeechMs {
+		resp.SpeechSegments = append(resp.SpeechSegments, SpeechSegment{Start: 0, End: duration, Duration: duration})
+		resp.Statistics.TotalSegments = 1
+		resp.Statistics.TotalSpeechDuration = duration
+		resp.Statistics.SpeechRatio = 1
+	}
+
+	writeTestJSON(w, http.StatusOK, resp)
+}
+
+// wavDuration reads the duration and sample rate from a canonical 44-byte header WAV file
+func wavDuration(data []byte) (float64, int, error) {
+	if len(data) < 44 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0, 0, fmt.Errorf("invalid WAV file")
+	}
+
+	channels := int(binary.LittleEndian.Uint16(data[22:24]))
+	sampleRate := int(binary.LittleEndian.Uint32(data[24:28]))
+	bitsPerSample := int(binary.LittleEndian.Uint16(data[34:36]))
+	if channels <= 0 || sampleRate <= 0 || bitsPerSample <= 0 {
+		return 0, 0, fmt.Errorf("invalid WAV format")
+	}
+
+	bytesPerSecond := sampleRate * channels * bitsPerSample / 8
+	return float64(len(data)-44) / float64(bytesPerSecond), sampleRate, nil
+}
+
+// formFloat parses a numeric form field, returning fallback when missing or invalid
+func formFloat(r *http.Request, key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(r.FormValue(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,101 @@
+package vad
+
+// MinWindowMs is the shortest detection window NewWindow creates
+const MinWindowMs = 200
+
+// Window keeps the most recent audio for chunked detection
+// Input chunks are usually shorter than the minimum speech duration, so detecting each chunk
+// on its own never finds speech. Callers push every chunk, run detection over the whole window
+// and use HasSpeech and Statistics to read the result for the newest chunk
+type Window struct {
+	sampleRate int
+	size       int
+	samples    []float32
+	lastChunk  int
+	inSpeech   bool // Whether the previous chunk passed to Statistics had speech
+}
+
+// NewWindow creates a window of durationMs, durations shorter than MinWindowMs use MinWindowMs
+func NewWindow(sampleRate, durationMs int) *Window {
+	return &Window{
+		sampleRate: sampleRate,
+		size:       sampleRate * max(durationMs, MinWindowMs) / 1000,
+	}
+}
+
+// WindowMsFor returns a window long enough to hold speech of minSpeechMs that started before the newest chunk
+func WindowMsFor(minSpeechMs int) int {
+	return 2 * minSpeechMs
+}
+
+// Push appends a chunk and returns the window, which shares storage and is valid until the next Push or Reset
+// A chunk longer than the window replaces it entirely
+func (w *Window) Push(chunk []float32) []float32 {
+	w.samples = append(w.samples, chunk...)
+	if excess := len(w.samples) - max(w.size, len(chunk)); excess > 0 {
+		w.samples = append(w.samples[:0], w.samples[excess:]...)
+	}
+	w.lastChunk = len(chunk)
+	return w.samples
+}
+
+// Samples returns a copy of the window, e.g. to keep the start of an utterance detected late
+func (w *Window) Samples() []float32 {
+	return append([]float32(nil), w.samples...)
+}
+
+// Reset empties the window, e.g. when the input is interrupted by playback
+func (w *Window) Reset() {
+	w.samples = w.samples[:0]
+	w.lastChunk = 0
+	w.inSpeech = false
+}
+
+// HasSpeech reports whether any segment detected in the window reaches into the newest chunk
+func (w *Window) HasSpeech(segments []SpeechSegment) bool {
+	return w.tailSpeech(segments) > 0
+}
+
+// Statistics reduces a detection over the window to the newest chunk, call it once per Push
+// Summing the results with DetectStatistics.Add gives the statistics of the whole stream:
+// durations cover only the newest chunk and a segment is counted in the chunk where speech resumes
+func (w *Window) Statistics(response *DetectResponse) DetectStatistics {
+	speech := w.tailSpeech(response.SpeechSegments)
+	stats := DetectStatistics{
+		TotalSpeechDuration: speech,
+		TotalAudioDuration:  w.seconds(w.lastChunk),
+		SampleRate:          response.Statistics.SampleRate,
+		ThresholdUsed:       response.Statistics.ThresholdUsed,
+	}
+	if stats.SampleRate == 0 {
+		stats.SampleRate = w.sampleRate
+	}
+	if speech > 0 && !w.inSpeech {
+		stats.TotalSegments = 1
+	}
+	if stats.TotalAudioDuration > 0 {
+		stats.SpeechRatio = speech / stats.TotalAudioDuration
+	}
+	w.inSpeech = speech > 0
+	return stats
+}
+
+// tailSpeech returns the seconds of speech within the newest chunk
+func (w *Window) tailSpeech(segments []SpeechSegment) float64 {
+	end := w.seconds(len(w.samples))
+	tailStart := end - w.seconds(w.lastChunk)
+
+	var speech float64
+	for _, seg := range segments {
+		speech += max(0, min(seg.End, end)-max(seg.Start, tailStart))
+	}
+	return speech
+}
+
+// seconds converts a sample count to seconds
+func (w *Window) seconds(samples int) float64 {
+	if w.sampleRate <= 0 {
+		return 0
+	}
+	return float64(samples) / float64(w.sampleRate)
+}
@@ -0,0 +1,108 @@
+package vad
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindowPush(t *testing.T) {
+	w := NewWindow(1000, 300)
+
+	for i := 1; i <= 5; i++ {
+		chunk := make([]float32, 100)
+		for j := range chunk {
+			chunk[j] = float32(i)
+		}
+		samples := w.Push(chunk)
+		if want := min(i*100, 300); len(samples) != want {
+			t.Fatalf("Push %d: expected %d samples, got %d", i, want, len(samples))
+		}
+	}
+
+	// The window keeps the newest chunks in order
+	samples := w.Samples()
+	if samples[0] != 3 || samples[299] != 5 {
+		t.Errorf("Expected chunks 3 to 5, got %v ... %v", samples[0], samples[299])
+	}
+
+	// A chunk longer than the window replaces it
+	if samples := w.Push(make([]float32, 500)); len(samples) != 500 {
+		t.Errorf("Expected a long chunk to fill the window, got %d samples", len(samples))
+	}
+
+	w.Reset()
+	if len(w.Samples()) != 0 {
+		t.Error("Expected Reset to empty the window")
+	}
+
+	if short := NewWindow(1000, 50); short.size != MinWindowMs {
+		t.Errorf("Expected the minimum window of %dms, got %d samples", MinWindowMs, short.size)
+	}
+}
+
+func TestWindowHasSpeech(t *testing.T) {
+	w := NewWindow(1000, 1000)
+	for i := 0; i < 10; i++ {
+		w.Push(make([]float32, 100))
+	}
+
+	tests := []struct {
+		name     string
+		segments []SpeechSegment
+		expected bool
+	}{
+		{"no segments", nil, false},
+		{"speech reaching the newest chunk", []SpeechSegment{{Start: 0.3, End: 1.0}}, true},
+		{"speech ended before the newest chunk", []SpeechSegment{{Start: 0.2, End: 0.85}}, false},
+		{"speech ending inside the newest chunk", []SpeechSegment{{Start: 0.2, End: 0.95}}, true},
+	}
+	for _, tt := range tests {
+		if got := w.HasSpeech(tt.segments); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestWindowStatistics(t *testing.T) {
+	config := DefaultLocalDetectorConfig()
+	config.MinSpeechDurationMs = 500
+	detector := NewLocalDetector(config)
+	w := NewWindow(16000, WindowMsFor(config.MinSpeechDurationMs))
+
+	chunk := func(amplitude float64) []float32 {
+		samples := make([]float32, 1024)
+		for i := range samples {
+			samples[i] = float32(amplitude * math.Sin(2*math.Pi*220*float64(i)/16000))
+		}
+		return samples
+	}
+
+	// 1 second of silence, 2 seconds of speech, 1 second of silence, in 64ms chunks
+	var total DetectStatistics
+	var firstSpeech int
+	for i := 0; i < 62; i++ {
+		amplitude := 0.0
+		if i >= 16 && i < 47 {
+			amplitude = 0.3
+		}
+		stats := w.Statistics(detector.DetectResponse(w.Push(chunk(amplitude)), 16000))
+		if stats.TotalSpeechDuration > 0 && firstSpeech == 0 {
+			firstSpeech = i
+		}
+		total.Add(stats)
+	}
+
+	if total.TotalAudioDuration < 3.96 || total.TotalAudioDuration > 3.97 {
+		t.Errorf("Expected each chunk counted once (3.968s), got %v", total.TotalAudioDuration)
+	}
+	if total.TotalSegments != 1 {
+		t.Errorf("Expected one segment, got %d", total.TotalSegments)
+	}
+	// Detection starts once speech has lasted the minimum duration
+	if firstSpeech < 16+7 || firstSpeech > 16+9 {
+		t.Errorf("Expected speech detected about 500ms after it starts, got chunk %d", firstSpeech)
+	}
+	if total.TotalSpeechDuration <= 1 || total.TotalSpeechDuration > 2 {
+		t.Errorf("Expected between 1 and 2 seconds of detected speech, got %v", total.TotalSpeechDuration)
+	}
+}